@@ -0,0 +1,420 @@
+// Package ast declares the typed syntax tree returned by the parser.
+//
+// The tree is built from the productions reduced by the LR(1) parser, but it
+// is independent of the token stack: every node has a kind of its own and the
+// span of the source it was parsed from, so that later passes can walk it
+// without parsing the source again.
+package ast
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Pos is a position in the source: the offset in bytes, and the line and the column in runes, both counted from 0.
+type Pos struct {
+	Offset int64
+	Line   int64
+	Column int64
+}
+
+func (p Pos) String() string {
+	return fmt.Sprintf("%d:%d", p.Line, p.Column)
+}
+
+// Before reports whether p comes before q in the source.
+func (p Pos) Before(q Pos) bool {
+	return p.Offset < q.Offset
+}
+
+// Span is the part of the source covered by a node, from the first character of
+// its first token to the position after its last token.
+type Span struct {
+	Start Pos
+	End   Pos
+}
+
+// Range returns the span itself, so that every node embedding a Span implements Node.
+func (s Span) Range() Span {
+	return s
+}
+
+func (s Span) String() string {
+	return fmt.Sprintf("%v-%v", s.Start, s.End)
+}
+
+// Join returns the smallest span covering both s and t.
+func (s Span) Join(t Span) Span {
+	if t.Start.Before(s.Start) {
+		s.Start = t.Start
+	}
+	if s.End.Before(t.End) {
+		s.End = t.End
+	}
+	return s
+}
+
+// Node is implemented by every node of the tree.
+type Node interface {
+	Range() Span
+}
+
+// Expr is implemented by the expression nodes.
+type Expr interface {
+	Node
+	exprNode()
+}
+
+// Stmt is implemented by the statement nodes.
+type Stmt interface {
+	Node
+	stmtNode()
+}
+
+// Decl is implemented by the declaration nodes.
+type Decl interface {
+	Node
+	declNode()
+}
+
+// Type is implemented by the nodes of a type specifier.
+type Type interface {
+	Node
+	typeNode()
+}
+
+// File is the root of the tree: the top-level declarations followed by the main block.
+// The comments of the file are kept in source order apart from the tree, and Walk does not visit them.
+type File struct {
+	Span
+	Decls    []Decl
+	Main     *BlockStmt
+	Comments []*Comment
+}
+
+// Comment is a // or /* */ comment, whose text includes the markers.
+type Comment struct {
+	Span
+	Text string
+}
+
+// CommentGroup is a run of // comments on consecutive lines, such as the doc comment of a declaration.
+type CommentGroup struct {
+	List []*Comment
+}
+
+// Range returns the span from the first comment to the end of the last one.
+func (g *CommentGroup) Range() Span {
+	return g.List[0].Span.Join(g.List[len(g.List)-1].Span)
+}
+
+// Text returns the text of the comments without the markers and the space after them, one line per comment.
+func (g *CommentGroup) Text() string {
+	if g == nil {
+		return ""
+	}
+	lines := make([]string, len(g.List))
+	for i, comment := range g.List {
+		line := strings.TrimPrefix(comment.Text, "//")
+		lines[i] = strings.TrimPrefix(line, " ")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Types
+
+type (
+	// BasicType is a builtin type such as int or float64.
+	BasicType struct {
+		Span
+		Name string
+	}
+
+	// StructType refers to a declared struct, as in struct Point.
+	StructType struct {
+		Span
+		Name *Ident
+	}
+
+	// ArrayType is Elem[Len], where Len is a number or the name of a constant.
+	ArrayType struct {
+		Span
+		Elem Type
+		Len  Expr
+	}
+
+	// PointerType is *Elem.
+	PointerType struct {
+		Span
+		Elem Type
+	}
+)
+
+// Declarations
+//
+// The Doc of a declaration is its doc comment, the // comments each on a line of its own
+// on the lines right before it, or nil.
+
+type (
+	// FuncDecl is a function with its parameters, its optional result type and its body.
+	FuncDecl struct {
+		Span
+		Doc    *CommentGroup
+		Name   *Ident
+		Params []*Field
+		Result Type
+		Body   *BlockStmt
+	}
+
+	// StructDecl is type Name struct { fields }.
+	StructDecl struct {
+		Span
+		Doc    *CommentGroup
+		Name   *Ident
+		Fields []*Field
+	}
+
+	// ConstDecl is const Name = Value;.
+	ConstDecl struct {
+		Span
+		Doc   *CommentGroup
+		Name  *Ident
+		Value Expr
+	}
+
+	// VarDecl is a variable with an optional initial value, which is a CompositeLit
+	// for an initializer list.
+	VarDecl struct {
+		Span
+		Doc   *CommentGroup
+		Type  Type
+		Name  *Ident
+		Value Expr
+	}
+
+	// Field is a parameter of a function or a field of a struct.
+	Field struct {
+		Span
+		Doc  *CommentGroup
+		Type Type
+		Name *Ident
+	}
+)
+
+// Statements
+
+type (
+	// BlockStmt is a braced list of statements; the declarations of the block are DeclStmts.
+	BlockStmt struct {
+		Span
+		List []Stmt
+	}
+
+	// DeclStmt is a declaration inside a block.
+	DeclStmt struct {
+		Span
+		Decl Decl
+	}
+
+	// AssignStmt is Target = Value, where Target is a location or a dereference.
+	AssignStmt struct {
+		Span
+		Target Expr
+		Value  Expr
+	}
+
+	// IncDecStmt is X++ or X-- as a statement, or ++X or --X when Prefix is set.
+	IncDecStmt struct {
+		Span
+		X      Expr
+		Op     string
+		Prefix bool
+	}
+
+	// ExprStmt is a function call used as a statement.
+	ExprStmt struct {
+		Span
+		X Expr
+	}
+
+	// IfStmt is if (Cond) Then, with an optional Else.
+	IfStmt struct {
+		Span
+		Cond Expr
+		Then Stmt
+		Else Stmt
+	}
+
+	// WhileStmt is while (Cond) Body.
+	WhileStmt struct {
+		Span
+		Cond Expr
+		Body Stmt
+	}
+
+	// DoWhileStmt is do Body while (Cond);.
+	DoWhileStmt struct {
+		Span
+		Body Stmt
+		Cond Expr
+	}
+
+	// ForStmt is for (Init; Cond; Post) Body, where Init and Post may be nil.
+	ForStmt struct {
+		Span
+		Init Stmt
+		Cond Expr
+		Post Stmt
+		Body Stmt
+	}
+
+	// SwitchStmt is switch (Tag) { cases }.
+	SwitchStmt struct {
+		Span
+		Tag   Expr
+		Cases []*CaseClause
+	}
+
+	// CaseClause is case Value: Body, or default: Body when Value is nil.
+	CaseClause struct {
+		Span
+		Value Expr
+		Body  []Stmt
+	}
+
+	// BranchStmt is break or continue, with an optional label.
+	BranchStmt struct {
+		Span
+		Tok   string
+		Label *Ident
+	}
+
+	// LabeledStmt is Label: Stmt.
+	LabeledStmt struct {
+		Span
+		Label *Ident
+		Stmt  Stmt
+	}
+
+	// ReturnStmt is return with an optional result.
+	ReturnStmt struct {
+		Span
+		Result Expr
+	}
+)
+
+// Expressions
+
+type (
+	// Ident is a name.
+	Ident struct {
+		Span
+		Name string
+	}
+
+	// BasicLit is a literal; Kind is one of num, real, str, char, true and false.
+	BasicLit struct {
+		Span
+		Kind  string
+		Value string
+	}
+
+	// CompositeLit is a braced initializer list, whose elements may be nested lists.
+	CompositeLit struct {
+		Span
+		Elems []Expr
+	}
+
+	// ParenExpr is (X).
+	ParenExpr struct {
+		Span
+		X Expr
+	}
+
+	// IndexExpr is X[Index].
+	IndexExpr struct {
+		Span
+		X     Expr
+		Index Expr
+	}
+
+	// SelectorExpr is X.Sel.
+	SelectorExpr struct {
+		Span
+		X   Expr
+		Sel *Ident
+	}
+
+	// CallExpr is Fun(Args).
+	CallExpr struct {
+		Span
+		Fun  *Ident
+		Args []Expr
+	}
+
+	// CastExpr is Type(X).
+	CastExpr struct {
+		Span
+		Type *BasicType
+		X    Expr
+	}
+
+	// UnaryExpr is Op X, where Op is one of !, -, * and &.
+	UnaryExpr struct {
+		Span
+		Op string
+		X  Expr
+	}
+
+	// IncDecExpr is X++ or X--, or ++X or --X when Prefix is set.
+	IncDecExpr struct {
+		Span
+		X      Expr
+		Op     string
+		Prefix bool
+	}
+
+	// BinaryExpr is X Op Y.
+	BinaryExpr struct {
+		Span
+		X  Expr
+		Op string
+		Y  Expr
+	}
+)
+
+func (*BasicType) typeNode()   {}
+func (*StructType) typeNode()  {}
+func (*ArrayType) typeNode()   {}
+func (*PointerType) typeNode() {}
+
+func (*FuncDecl) declNode()   {}
+func (*StructDecl) declNode() {}
+func (*ConstDecl) declNode()  {}
+func (*VarDecl) declNode()    {}
+
+func (*BlockStmt) stmtNode()   {}
+func (*DeclStmt) stmtNode()    {}
+func (*AssignStmt) stmtNode()  {}
+func (*IncDecStmt) stmtNode()  {}
+func (*ExprStmt) stmtNode()    {}
+func (*IfStmt) stmtNode()      {}
+func (*WhileStmt) stmtNode()   {}
+func (*DoWhileStmt) stmtNode() {}
+func (*ForStmt) stmtNode()     {}
+func (*SwitchStmt) stmtNode()  {}
+func (*BranchStmt) stmtNode()  {}
+func (*LabeledStmt) stmtNode() {}
+func (*ReturnStmt) stmtNode()  {}
+
+func (*Ident) exprNode()        {}
+func (*BasicLit) exprNode()     {}
+func (*CompositeLit) exprNode() {}
+func (*ParenExpr) exprNode()    {}
+func (*IndexExpr) exprNode()    {}
+func (*SelectorExpr) exprNode() {}
+func (*CallExpr) exprNode()     {}
+func (*CastExpr) exprNode()     {}
+func (*UnaryExpr) exprNode()    {}
+func (*IncDecExpr) exprNode()   {}
+func (*BinaryExpr) exprNode()   {}
@@ -0,0 +1,219 @@
+package ast
+
+import (
+	"fmt"
+	"slices"
+
+	"app/lexer"
+)
+
+// Visitor is called by Walk for every node of the tree.
+// If the visitor returned for a node is not nil, Walk visits the children of the node
+// with it, and then calls its Visit with nil.
+type Visitor interface {
+	Visit(node Node) (w Visitor)
+}
+
+// Walk traverses the tree rooted at node in depth-first order.
+func Walk(v Visitor, node Node) {
+	if v = v.Visit(node); v == nil {
+		return
+	}
+
+	switch n := node.(type) {
+	case *File:
+		for _, decl := range n.Decls {
+			Walk(v, decl)
+		}
+		if n.Main != nil {
+			Walk(v, n.Main)
+		}
+
+	// types
+	case *BasicType:
+	case *StructType:
+		Walk(v, n.Name)
+	case *ArrayType:
+		Walk(v, n.Elem)
+		Walk(v, n.Len)
+	case *PointerType:
+		Walk(v, n.Elem)
+
+	// declarations
+	case *FuncDecl:
+		Walk(v, n.Name)
+		for _, param := range n.Params {
+			Walk(v, param)
+		}
+		if n.Result != nil {
+			Walk(v, n.Result)
+		}
+		Walk(v, n.Body)
+	case *StructDecl:
+		Walk(v, n.Name)
+		for _, field := range n.Fields {
+			Walk(v, field)
+		}
+	case *ConstDecl:
+		Walk(v, n.Name)
+		Walk(v, n.Value)
+	case *VarDecl:
+		Walk(v, n.Type)
+		Walk(v, n.Name)
+		if n.Value != nil {
+			Walk(v, n.Value)
+		}
+	case *Field:
+		Walk(v, n.Type)
+		Walk(v, n.Name)
+
+	// statements
+	case *BlockStmt:
+		walkStmts(v, n.List)
+	case *DeclStmt:
+		Walk(v, n.Decl)
+	case *AssignStmt:
+		Walk(v, n.Target)
+		Walk(v, n.Value)
+	case *IncDecStmt:
+		Walk(v, n.X)
+	case *ExprStmt:
+		Walk(v, n.X)
+	case *IfStmt:
+		Walk(v, n.Cond)
+		Walk(v, n.Then)
+		if n.Else != nil {
+			Walk(v, n.Else)
+		}
+	case *WhileStmt:
+		Walk(v, n.Cond)
+		Walk(v, n.Body)
+	case *DoWhileStmt:
+		Walk(v, n.Body)
+		Walk(v, n.Cond)
+	case *ForStmt:
+		if n.Init != nil {
+			Walk(v, n.Init)
+		}
+		Walk(v, n.Cond)
+		if n.Post != nil {
+			Walk(v, n.Post)
+		}
+		Walk(v, n.Body)
+	case *SwitchStmt:
+		Walk(v, n.Tag)
+		for _, clause := range n.Cases {
+			Walk(v, clause)
+		}
+	case *CaseClause:
+		if n.Value != nil {
+			Walk(v, n.Value)
+		}
+		walkStmts(v, n.Body)
+	case *BranchStmt:
+		if n.Label != nil {
+			Walk(v, n.Label)
+		}
+	case *LabeledStmt:
+		Walk(v, n.Label)
+		Walk(v, n.Stmt)
+	case *ReturnStmt:
+		if n.Result != nil {
+			Walk(v, n.Result)
+		}
+
+	// expressions
+	case *Ident, *BasicLit:
+	case *CompositeLit:
+		for _, elem := range n.Elems {
+			Walk(v, elem)
+		}
+	case *ParenExpr:
+		Walk(v, n.X)
+	case *IndexExpr:
+		Walk(v, n.X)
+		Walk(v, n.Index)
+	case *SelectorExpr:
+		Walk(v, n.X)
+		Walk(v, n.Sel)
+	case *CallExpr:
+		Walk(v, n.Fun)
+		for _, arg := range n.Args {
+			Walk(v, arg)
+		}
+	case *CastExpr:
+		Walk(v, n.Type)
+		Walk(v, n.X)
+	case *UnaryExpr:
+		Walk(v, n.X)
+	case *IncDecExpr:
+		Walk(v, n.X)
+	case *BinaryExpr:
+		Walk(v, n.X)
+		Walk(v, n.Y)
+
+	default:
+		panic(fmt.Sprintf("ast.Walk: unexpected node type %T", n))
+	}
+
+	v.Visit(nil)
+}
+
+func walkStmts(v Visitor, list []Stmt) {
+	for _, stmt := range list {
+		Walk(v, stmt)
+	}
+}
+
+type inspector func(Node) bool
+
+func (f inspector) Visit(node Node) Visitor {
+	if f(node) {
+		return f
+	}
+	return nil
+}
+
+// Inspect traverses the tree rooted at node in depth-first order, calling f for every node
+// and then f(nil) after the children. The children of a node are skipped when f returns false.
+func Inspect(node Node, f func(Node) bool) {
+	Walk(inspector(f), node)
+}
+
+// NoLint reports whether the rule is suppressed at pos by a // nolint comment, either on the
+// line of pos or in the doc comment of a declaration containing pos. See lexer.NoLint.
+func (f *File) NoLint(pos Pos, rule string) bool {
+	suppresses := func(comment *Comment) bool {
+		rules, ok := lexer.NoLint(comment.Text)
+		return ok && (len(rules) == 0 || slices.Contains(rules, rule))
+	}
+	for _, comment := range f.Comments {
+		if comment.Start.Line == pos.Line && suppresses(comment) {
+			return true
+		}
+	}
+	suppressed := false
+	Inspect(f, func(node Node) bool {
+		if suppressed || node == nil || pos.Before(node.Range().Start) || !pos.Before(node.Range().End) {
+			return false
+		}
+		var doc *CommentGroup
+		switch n := node.(type) {
+		case *FuncDecl:
+			doc = n.Doc
+		case *StructDecl:
+			doc = n.Doc
+		case *ConstDecl:
+			doc = n.Doc
+		case *VarDecl:
+			doc = n.Doc
+		case *Field:
+			doc = n.Doc
+		}
+		if doc != nil {
+			suppressed = slices.ContainsFunc(doc.List, suppresses)
+		}
+		return !suppressed
+	})
+	return suppressed
+}
@@ -0,0 +1,64 @@
+package ast_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"app/ast"
+)
+
+type recorder struct {
+	visited []string
+	skip    string
+}
+
+func (r *recorder) Visit(node ast.Node) ast.Visitor {
+	if node == nil {
+		r.visited = append(r.visited, "end")
+		return nil
+	}
+	name := fmt.Sprintf("%T", node)
+	if ident, ok := node.(*ast.Ident); ok {
+		name = ident.Name
+	}
+	r.visited = append(r.visited, name)
+	if name == r.skip {
+		return nil
+	}
+	return r
+}
+
+func TestWalk(t *testing.T) {
+	// while (i < n) i++;
+	stmt := &ast.WhileStmt{
+		Cond: &ast.BinaryExpr{X: &ast.Ident{Name: "i"}, Op: "<", Y: &ast.Ident{Name: "n"}},
+		Body: &ast.IncDecStmt{X: &ast.Ident{Name: "i"}, Op: "++"},
+	}
+
+	r := &recorder{}
+	ast.Walk(r, stmt)
+	expected := "*ast.WhileStmt *ast.BinaryExpr i end n end end *ast.IncDecStmt i end end end"
+	if got := strings.Join(r.visited, " "); got != expected {
+		t.Errorf("Expected %s, got %s", expected, got)
+	}
+
+	r = &recorder{skip: "*ast.BinaryExpr"}
+	ast.Walk(r, stmt)
+	expected = "*ast.WhileStmt *ast.BinaryExpr *ast.IncDecStmt i end end end"
+	if got := strings.Join(r.visited, " "); got != expected {
+		t.Errorf("Expected %s, got %s", expected, got)
+	}
+}
+
+func TestSpan(t *testing.T) {
+	a := ast.Span{Start: ast.Pos{Offset: 14, Line: 1, Column: 4}, End: ast.Pos{Offset: 18, Line: 1, Column: 8}}
+	b := ast.Span{Start: ast.Pos{Offset: 9, Line: 0, Column: 9}, End: ast.Pos{Offset: 12, Line: 1, Column: 2}}
+	expected := ast.Span{Start: ast.Pos{Offset: 9, Line: 0, Column: 9}, End: ast.Pos{Offset: 18, Line: 1, Column: 8}}
+	if got := a.Join(b); got != expected {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+	if got := (&ast.Ident{Span: a}).Range(); got != a {
+		t.Errorf("Expected %v, got %v", a, got)
+	}
+}
@@ -3,6 +3,8 @@ package config
 import (
 	"flag"
 	"strings"
+
+	"app/utils/i18n"
 )
 
 var Config = struct {
@@ -12,29 +14,65 @@ var Config = struct {
 		UsingNoBufferedReader bool
 	}
 
+	Dot struct {
+		Automaton string // lexer or parser
+		States    string // the states drawn, split by |, or path for the states of the input
+		Input     string // the input whose path is highlighted
+		Items     bool
+	}
+
 	Path   string
 	Files  []string
 	Silent bool
+	Lang   i18n.Locale
 }{}
 
 func ReadFlag() {
-	t := flag.String("t", "lexer", "Target to run: lexer or parser")
+	t := flag.String("t", "lexer", "Target to run: lexer, parser, dot or lsp (a language server on the standard input and output)")
 	lnb := flag.Bool("lexer--no-buffered", false, "Use no buffered reader for lexer")
 	b := flag.Bool("b", false, "Enable benchmark mode")
 	s := flag.Bool("s", false, "Stop writing results to file")
 	f := flag.String("f", "", "File to run tests on in the folder, split by |, eg. 1.in|2.in|3.in")
+	da := flag.String("dot", "parser", "Automaton written as a Graphviz graph by the dot target: lexer (the DFA of lexgen) or parser (the LR(1) states)")
+	ds := flag.String("dot-states", "", "States drawn by the dot target, split by |, eg. 0|1|5, or path for the states the input went through")
+	di := flag.String("dot-input", "", "Input whose path is highlighted by the dot target: a lexeme for the lexer, a program for the parser")
+	dit := flag.Bool("dot-items", false, "List the items of the LR(1) states in the dot target")
+	lang := flag.String("lang", "", "Language of the messages: zh or en, by default the one of LC_ALL, LC_MESSAGES or LANG, else zh")
 	flag.Parse()
 
+	Config.Lang = readLang(*lang)
+	i18n.SetLocale(Config.Lang)
 	Config.Target = *t
 	Config.Lexer.UsingNoBufferedReader = *lnb
 	if *b {
 		Config.Path = "tests/benchmark/"
-		println("Benchmark mode enabled")
+		println(i18n.T("Benchmark mode enabled"))
 	} else {
 		Config.Path = "tests/"
 	}
+	Config.Dot.Automaton = *da
+	Config.Dot.States = *ds
+	Config.Dot.Input = *di
+	Config.Dot.Items = *dit
 	Config.Silent = *s
 	if *f != "" {
 		Config.Files = strings.Split(*f, "|")
 	}
 }
+
+// readLang returns the locale of the -lang flag, or else of the environment, or else Chinese.
+func readLang(lang string) i18n.Locale {
+	if locale, ok := i18n.Parse(lang); ok {
+		return locale
+	}
+	locale, ok := i18n.FromEnvironment()
+	if !ok {
+		locale = i18n.ZH
+	}
+	if lang != "" {
+		// in the language used instead
+		i18n.SetLocale(locale)
+		println(i18n.T("Unknown language:"), lang)
+	}
+	return locale
+}
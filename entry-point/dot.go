@@ -0,0 +1,85 @@
+package entrypoint
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	. "app/config"
+	"app/lexer"
+	"app/lexgen"
+	"app/parser"
+	"app/utils/dot"
+	"app/utils/i18n"
+	"app/utils/log"
+)
+
+// DotExport writes the automaton chosen by the -dot flag to the standard output as a Graphviz graph,
+// e.g. go run . -t dot -dot parser -dot-input "{ int a; }" -dot-states path | dot -Tpng -o lr.png
+func DotExport() {
+	options := dot.Options{}
+	w := bufio.NewWriter(os.Stdout)
+	var err error
+	switch Config.Dot.Automaton {
+	case "lexer":
+		var l *lexgen.Lexer
+		if l, err = lexgen.New(lexgen.LanguageSpecs()); err != nil {
+			break
+		}
+		if Config.Dot.Input != "" {
+			options.Path = l.Trace(Config.Dot.Input)
+		}
+		if options.States, err = dotStates(options.Path); err != nil {
+			break
+		}
+		err = l.DOT(w, options)
+	case "parser":
+		p := parser.NewParser()
+		p.EnsureTable()
+		if Config.Dot.Input != "" {
+			// the path up to a syntax error is still drawn
+			options.Path, err = p.Trace(lexer.NewLexer(strings.NewReader(Config.Dot.Input)))
+			if err != nil {
+				fmt.Fprintln(os.Stderr, log.Sprintf(log.Argument{FrontColor: log.Yellow, Highlight: true, Format: "!!! %s", Args: []any{err.Error()}}))
+			}
+		}
+		if options.States, err = dotStates(options.Path); err != nil {
+			break
+		}
+		err = p.DOT(w, options, Config.Dot.Items)
+	default:
+		err = i18n.Errorf("unknown automaton: %s", Config.Dot.Automaton)
+	}
+	if err == nil {
+		err = w.Flush()
+	}
+	if err != nil {
+		fmt.Println(
+			log.Sprintf(log.Argument{FrontColor: log.Red, Highlight: true, Format: i18n.T("!!! System Error: %s"), Args: []any{err.Error()}}),
+		)
+	}
+}
+
+// dotStates returns the states of the -dot-states flag.
+func dotStates(path *dot.Path) ([]int, error) {
+	switch Config.Dot.States {
+	case "":
+		return nil, nil
+	case "path":
+		if path == nil {
+			return nil, i18n.Errorf("-dot-states path needs -dot-input")
+		}
+		return path.States, nil
+	}
+	var states []int
+	for _, s := range strings.Split(Config.Dot.States, "|") {
+		state, err := strconv.Atoi(s)
+		if err != nil {
+			return nil, i18n.Errorf("illegal state %s", s)
+		}
+		states = append(states, state)
+	}
+	return states, nil
+}
@@ -0,0 +1,27 @@
+package entrypoint
+
+import "app/utils/i18n"
+
+// the Chinese messages of the console, keyed by the English ones
+func init() {
+	i18n.Register(i18n.ZH, map[string]string{
+		"***  Lexer Test  ***\n":       "***  词法分析测试  ***\n",
+		"*** Parser Test ***\n":        "***  语法分析测试  ***\n",
+		"*** Got ":                     "*** 共 ",
+		"Files ***\n":                  "个文件 ***\n",
+		"!!! Starting tests... !!!\n":  "!!! 开始测试... !!!\n",
+		"!!! System Error: %s":         "!!! 系统错误：%s",
+		">> Test for":                  ">> 测试",
+		"finished, consume":            "完成，耗时",
+		"!!! All tests finished !!!\n": "!!! 所有测试已完成 !!!\n",
+		"!!! This may take a while to prepare the parser !!!\n": "!!! 准备语法分析器可能需要一些时间 !!!\n",
+		"!!! Parser prepared, consume":                          "!!! 语法分析器已就绪，耗时",
+		"Error: %s\n":                                           "错误：%s\n",
+		"Benchmark mode enabled":                                "已启用基准测试模式",
+		"Unknown mode:":                                         "未知模式：",
+		"Unknown language:":                                     "未知语言：",
+		"unknown automaton: %s":                                 "未知自动机：%s",
+		"-dot-states path needs -dot-input":                     "-dot-states path 需要 -dot-input",
+		"illegal state %s":                                      "非法状态 %s",
+	})
+}
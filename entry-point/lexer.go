@@ -13,6 +13,7 @@ import (
 	. "app/config"
 	"app/lexer"
 	. "app/utils"
+	"app/utils/i18n"
 	"app/utils/log"
 	"app/utils/mmap"
 )
@@ -34,10 +35,10 @@ func LexerTest() {
 	}
 	fmt.Print(log.Sprintf(
 		Divider(),
-		log.Argument{Highlight: true, Format: "***  Lexer Test  ***\n", Args: []any{}},
-		log.Argument{Highlight: true, Format: "*** Got ", Args: []any{}},
+		log.Argument{Highlight: true, Format: i18n.T("***  Lexer Test  ***\n"), Args: []any{}},
+		log.Argument{Highlight: true, Format: i18n.T("*** Got "), Args: []any{}},
 		log.Argument{FrontColor: log.Magenta, Highlight: true, Format: "%d ", Args: []any{len(files)}},
-		log.Argument{Highlight: true, Format: "Files ***\n", Args: []any{}},
+		log.Argument{Highlight: true, Format: i18n.T("Files ***\n"), Args: []any{}},
 		Divider(),
 	))
 
@@ -61,7 +62,7 @@ func LexerTest() {
 	}
 
 	fmt.Print(log.Sprintf(
-		log.Argument{FrontColor: log.Red, Highlight: true, Format: "!!! Starting tests... !!!\n", Args: []any{}},
+		log.Argument{FrontColor: log.Red, Highlight: true, Format: i18n.T("!!! Starting tests... !!!\n"), Args: []any{}},
 		Divider(),
 	))
 
@@ -89,21 +90,21 @@ func LexerTest() {
 			err = StartSingleLexerTest(file.Path, writer)
 			if err != nil {
 				fmt.Println(
-					log.Sprintf(log.Argument{FrontColor: log.Red, Highlight: true, Format: "!!! System Error: %s", Args: []any{err.Error()}}),
+					log.Sprintf(log.Argument{FrontColor: log.Red, Highlight: true, Format: i18n.T("!!! System Error: %s"), Args: []any{err.Error()}}),
 				)
 			}
 
 			err = writer.Flush()
 			if err != nil {
 				fmt.Println(
-					log.Sprintf(log.Argument{FrontColor: log.Red, Highlight: true, Format: "!!! System Error: %s", Args: []any{err.Error()}}),
+					log.Sprintf(log.Argument{FrontColor: log.Red, Highlight: true, Format: i18n.T("!!! System Error: %s"), Args: []any{err.Error()}}),
 				)
 			}
 
 			fmt.Println(
-				log.Sprintf(log.Argument{Highlight: true, Format: ">> Test for", Args: []any{}}),
+				log.Sprintf(log.Argument{Highlight: true, Format: i18n.T(">> Test for"), Args: []any{}}),
 				log.Sprintf(log.Argument{FrontColor: log.Green, Highlight: true, Format: "%s", Args: []any{file.Path}}),
-				log.Sprintf(log.Argument{Highlight: true, Format: "finished, consume", Args: []any{}}),
+				log.Sprintf(log.Argument{Highlight: true, Format: i18n.T("finished, consume"), Args: []any{}}),
 				log.Sprintf(log.Argument{FrontColor: log.Green, Highlight: true, Format: "%d ms", Args: []any{time.Since(st).Milliseconds()}}),
 			)
 		}(file)
@@ -112,7 +113,7 @@ func LexerTest() {
 
 	fmt.Print(log.Sprintf(
 		Divider(),
-		log.Argument{FrontColor: log.Red, Highlight: true, Format: "!!! All tests finished !!!\n", Args: []any{}},
+		log.Argument{FrontColor: log.Red, Highlight: true, Format: i18n.T("!!! All tests finished !!!\n"), Args: []any{}},
 		Divider(),
 	))
 }
@@ -134,7 +135,7 @@ func StartSingleLexerTest(filename string, writer io.Writer) error {
 	for {
 		token, err := l.NextToken()
 		if !Config.Silent && err != nil && !errors.Is(err, io.EOF) {
-			_, err2 := fmt.Fprintf(writer, "Error: %s\n", err.Error())
+			_, err2 := fmt.Fprint(writer, i18n.Sprintf("Error: %s\n", err.Error()))
 			if err2 != nil {
 				return err2
 			}
@@ -0,0 +1,24 @@
+package entrypoint
+
+import (
+	"fmt"
+	"os"
+
+	"app/lsp"
+	"app/parser"
+	"app/utils/i18n"
+	"app/utils/log"
+)
+
+// LanguageServer serves the language server protocol on the standard input and output, e.g. for VS Code.
+func LanguageServer() {
+	// the protocol owns the standard output, so what the lexer and the parser print goes to the standard error
+	stdout := os.Stdout
+	os.Stdout = os.Stderr
+	if err := lsp.NewServer(parser.NewParser()).Serve(os.Stdin, stdout); err != nil {
+		fmt.Println(
+			log.Sprintf(log.Argument{FrontColor: log.Red, Highlight: true, Format: i18n.T("!!! System Error: %s"), Args: []any{err.Error()}}),
+		)
+		os.Exit(1)
+	}
+}
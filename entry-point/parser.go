@@ -13,6 +13,7 @@ import (
 	"app/lexer"
 	"app/parser"
 	. "app/utils"
+	"app/utils/i18n"
 	"app/utils/log"
 	"app/utils/mmap"
 )
@@ -35,10 +36,10 @@ func ParserTest() {
 	}
 	fmt.Print(log.Sprintf(
 		Divider(),
-		log.Argument{Highlight: true, Format: "*** Parser Test ***\n", Args: []any{}},
-		log.Argument{Highlight: true, Format: "*** Got ", Args: []any{}},
+		log.Argument{Highlight: true, Format: i18n.T("*** Parser Test ***\n"), Args: []any{}},
+		log.Argument{Highlight: true, Format: i18n.T("*** Got "), Args: []any{}},
 		log.Argument{FrontColor: log.Magenta, Highlight: true, Format: "%d ", Args: []any{len(files)}},
-		log.Argument{Highlight: true, Format: "Files ***\n", Args: []any{}},
+		log.Argument{Highlight: true, Format: i18n.T("Files ***\n"), Args: []any{}},
 		Divider(),
 	))
 
@@ -60,9 +61,9 @@ func ParserTest() {
 	}
 
 	fmt.Print(log.Sprintf(
-		log.Argument{FrontColor: log.Red, Highlight: true, Format: "!!! Starting tests... !!!\n", Args: []any{}},
+		log.Argument{FrontColor: log.Red, Highlight: true, Format: i18n.T("!!! Starting tests... !!!\n"), Args: []any{}},
 		Divider(),
-		log.Argument{FrontColor: log.Red, Highlight: true, Format: "!!! This may take a while to prepare the parser !!!\n", Args: []any{}},
+		log.Argument{FrontColor: log.Red, Highlight: true, Format: i18n.T("!!! This may take a while to prepare the parser !!!\n"), Args: []any{}},
 	))
 
 	st := time.Now()
@@ -71,7 +72,7 @@ func ParserTest() {
 	p.EnsureTable()
 
 	fmt.Print(log.Sprintf(
-		log.Argument{FrontColor: log.Green, Highlight: true, Format: "!!! Parser prepared, consume", Args: []any{}},
+		log.Argument{FrontColor: log.Green, Highlight: true, Format: i18n.T("!!! Parser prepared, consume"), Args: []any{}},
 		log.Argument{FrontColor: log.Green, Highlight: true, Format: " %d ms", Args: []any{time.Since(st).Milliseconds()}},
 		log.Argument{FrontColor: log.Green, Highlight: true, Format: "!!!\n", Args: []any{}},
 	))
@@ -96,21 +97,21 @@ func ParserTest() {
 			err = StartSingleParserTest(file.Path, writer)
 			if err != nil {
 				fmt.Println(
-					log.Sprintf(log.Argument{FrontColor: log.Red, Highlight: true, Format: "!!! System Error: %s", Args: []any{err.Error()}}),
+					log.Sprintf(log.Argument{FrontColor: log.Red, Highlight: true, Format: i18n.T("!!! System Error: %s"), Args: []any{err.Error()}}),
 				)
 			}
 
 			err = writer.Flush()
 			if err != nil {
 				fmt.Println(
-					log.Sprintf(log.Argument{FrontColor: log.Red, Highlight: true, Format: "!!! System Error: %s", Args: []any{err.Error()}}),
+					log.Sprintf(log.Argument{FrontColor: log.Red, Highlight: true, Format: i18n.T("!!! System Error: %s"), Args: []any{err.Error()}}),
 				)
 			}
 
 			fmt.Println(
-				log.Sprintf(log.Argument{Highlight: true, Format: ">> Test for", Args: []any{}}),
+				log.Sprintf(log.Argument{Highlight: true, Format: i18n.T(">> Test for"), Args: []any{}}),
 				log.Sprintf(log.Argument{FrontColor: log.Green, Highlight: true, Format: "%s", Args: []any{file.Path}}),
-				log.Sprintf(log.Argument{Highlight: true, Format: "finished, consume", Args: []any{}}),
+				log.Sprintf(log.Argument{Highlight: true, Format: i18n.T("finished, consume"), Args: []any{}}),
 				log.Sprintf(log.Argument{FrontColor: log.Green, Highlight: true, Format: "%d ms", Args: []any{time.Since(st).Milliseconds()}}),
 			)
 		}(file)
@@ -119,7 +120,7 @@ func ParserTest() {
 
 	fmt.Print(log.Sprintf(
 		Divider(),
-		log.Argument{FrontColor: log.Red, Highlight: true, Format: "!!! All tests finished !!!\n", Args: []any{}},
+		log.Argument{FrontColor: log.Red, Highlight: true, Format: i18n.T("!!! All tests finished !!!\n"), Args: []any{}},
 		Divider(),
 	))
 }
@@ -135,7 +136,7 @@ func StartSingleParserTest(filename string, writer io.Writer) error {
 			panic(err)
 		}
 	}(file)
-	l := lexer.NewLexer(file)
+	l := lexer.NewRecoveringLexer(file)
 
 	p.Parse(l, func(s string) {
 		_, _ = fmt.Fprint(writer, s)
@@ -0,0 +1,395 @@
+// Package format prints the AST of the course language in its canonical form:
+// one statement per line, indented with tabs, with the operators spaced out
+// and the comments of the source kept next to the code they were written at.
+package format
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"unicode"
+
+	"app/ast"
+	"app/lexer"
+	"app/parser"
+)
+
+var (
+	_parser     *parser.Parser
+	_parserOnce sync.Once
+)
+
+// Source parses src and returns it in the canonical form.
+// Only the syntax matters: the errors found by the rules of the productions, such as
+// an undeclared variable, do not keep the source from being formatted.
+func Source(src []byte) ([]byte, error) {
+	_parserOnce.Do(func() {
+		_parser = parser.NewParser()
+		_parser.EnsureTable()
+	})
+	file, err := _parser.Parse(lexer.NewLexer(bytes.NewReader(src)), func(string) {})
+	if file == nil {
+		return nil, err
+	}
+	return File(file), nil
+}
+
+// File prints the file in the canonical form.
+func File(file *ast.File) []byte {
+	p := &printer{comments: file.Comments}
+	p.file(file)
+	return []byte(p.buf.String())
+}
+
+type printer struct {
+	buf      strings.Builder
+	indent   int
+	comments []*ast.Comment // the comments not printed yet
+}
+
+func (p *printer) print(args ...string) {
+	for _, s := range args {
+		p.buf.WriteString(s)
+	}
+}
+
+func (p *printer) newline() {
+	p.print("\n", strings.Repeat("\t", p.indent))
+}
+
+// commentsBefore prints the comments before pos, each on a line of its own.
+func (p *printer) commentsBefore(pos ast.Pos) bool {
+	printed := false
+	for len(p.comments) > 0 && p.comments[0].Start.Before(pos) {
+		p.newline()
+		p.print(p.comments[0].Text)
+		p.comments = p.comments[1:]
+		printed = true
+	}
+	return printed
+}
+
+// trailingComments prints the comments inside the node just printed, and those after it on the same line unless
+// next, the start of the node after it, is on that line too: they are then printed before that node,
+// see commentsBefore. It is only called at the end of a line, since a // comment ends the line.
+func (p *printer) trailingComments(end, next ast.Pos) {
+	for len(p.comments) > 0 {
+		start := p.comments[0].Start
+		if !start.Before(end) && (start.Line != end.Line || next.Line == end.Line) {
+			break
+		}
+		p.print(" ", p.comments[0].Text)
+		p.comments = p.comments[1:]
+	}
+}
+
+// nextStart returns the start of the node after the i-th of the list, or end after the last one.
+func nextStart[N ast.Node](list []N, i int, end ast.Pos) ast.Pos {
+	if i+1 < len(list) {
+		return list[i+1].Range().Start
+	}
+	return end
+}
+
+func (p *printer) file(file *ast.File) {
+	// a blank line separates the declarations, and a comment sticks to the declaration after it
+	start, blank := true, false
+	separate := func() {
+		if !start {
+			p.print("\n")
+			if blank {
+				p.print("\n")
+			}
+		}
+		start = false
+	}
+	item := func(node ast.Node, next ast.Pos, print func()) {
+		for len(p.comments) > 0 && p.comments[0].Start.Before(node.Range().Start) {
+			separate()
+			p.print(p.comments[0].Text)
+			p.comments = p.comments[1:]
+			blank = false
+		}
+		separate()
+		print()
+		p.trailingComments(node.Range().End, next)
+		blank = true
+	}
+
+	eof := ast.Pos{Offset: math.MaxInt64, Line: math.MaxInt64}
+	for i, decl := range file.Decls {
+		next := nextStart(file.Decls, i, eof)
+		if i+1 == len(file.Decls) && file.Main != nil {
+			next = file.Main.Start
+		}
+		item(decl, next, func() { p.decl(decl) })
+	}
+	if file.Main != nil {
+		item(file.Main, eof, func() { p.block(file.Main) })
+	}
+	for _, comment := range p.comments {
+		separate()
+		p.print(comment.Text)
+		blank = false
+	}
+	p.comments = nil
+	p.print("\n")
+}
+
+func (p *printer) decl(decl ast.Decl) {
+	switch d := decl.(type) {
+	case *ast.FuncDecl:
+		params := make([]string, len(d.Params))
+		for i, param := range d.Params {
+			params[i] = typeString(param.Type) + " " + param.Name.Name
+		}
+		p.print("func ", d.Name.Name, "(", strings.Join(params, ", "), ") ")
+		if d.Result != nil {
+			p.print(typeString(d.Result), " ")
+		}
+		p.block(d.Body)
+	case *ast.StructDecl:
+		p.print("type ", d.Name.Name, " struct {")
+		p.indent++
+		for i, field := range d.Fields {
+			p.commentsBefore(field.Start)
+			p.newline()
+			p.print(typeString(field.Type), " ", field.Name.Name, ";")
+			p.trailingComments(field.End, nextStart(d.Fields, i, d.End))
+		}
+		inner := p.commentsBefore(d.End) || len(d.Fields) > 0
+		p.indent--
+		if inner {
+			p.newline()
+		}
+		p.print("}")
+	case *ast.ConstDecl:
+		p.print("const ", d.Name.Name, " = ", expr(d.Value), ";")
+	case *ast.VarDecl:
+		p.print(typeString(d.Type), " ", d.Name.Name)
+		if d.Value != nil {
+			p.print(" = ", expr(d.Value))
+		}
+		p.print(";")
+	default:
+		panic(fmt.Sprintf("format: unexpected declaration %T", d))
+	}
+}
+
+func (p *printer) block(block *ast.BlockStmt) {
+	p.print("{")
+	p.indent++
+	p.stmtList(block.List, block.End)
+	inner := p.commentsBefore(block.End) || len(block.List) > 0
+	p.indent--
+	if inner {
+		p.newline()
+	}
+	p.print("}")
+}
+
+// stmtList prints the statements, followed in the source by a node starting at end.
+func (p *printer) stmtList(list []ast.Stmt, end ast.Pos) {
+	for i, stmt := range list {
+		p.commentsBefore(stmt.Range().Start)
+		p.newline()
+		p.stmt(stmt)
+		p.trailingComments(stmt.Range().End, nextStart(list, i, end))
+	}
+}
+
+// body prints the statement controlled by if, else, while, do or for on the same line.
+func (p *printer) body(stmt ast.Stmt) {
+	p.print(" ")
+	p.stmt(stmt)
+}
+
+func (p *printer) stmt(stmt ast.Stmt) {
+	switch s := stmt.(type) {
+	case *ast.BlockStmt:
+		p.block(s)
+	case *ast.DeclStmt:
+		p.decl(s.Decl)
+	case *ast.AssignStmt, *ast.IncDecStmt:
+		p.print(simpleStmt(s), ";")
+	case *ast.ExprStmt:
+		p.print(expr(s.X), ";")
+	case *ast.IfStmt:
+		p.print("if (", expr(s.Cond), ")")
+		p.body(s.Then)
+		if s.Else != nil {
+			p.print(" else")
+			p.body(s.Else)
+		}
+	case *ast.WhileStmt:
+		p.print("while (", expr(s.Cond), ")")
+		p.body(s.Body)
+	case *ast.DoWhileStmt:
+		p.print("do")
+		p.body(s.Body)
+		p.print(" while (", expr(s.Cond), ");")
+	case *ast.ForStmt:
+		p.print("for (", simpleStmt(s.Init), "; ", expr(s.Cond), "; ", simpleStmt(s.Post), ")")
+		p.body(s.Body)
+	case *ast.SwitchStmt:
+		p.print("switch (", expr(s.Tag), ") {")
+		for i, clause := range s.Cases {
+			p.commentsBefore(clause.Start)
+			p.newline()
+			if clause.Value != nil {
+				p.print("case ", expr(clause.Value), ":")
+			} else {
+				p.print("default:")
+			}
+			p.indent++
+			p.stmtList(clause.Body, nextStart(s.Cases, i, s.End))
+			p.indent--
+		}
+		if p.commentsBefore(s.End) || len(s.Cases) > 0 {
+			p.newline()
+		}
+		p.print("}")
+	case *ast.BranchStmt:
+		p.print(s.Tok)
+		if s.Label != nil {
+			p.print(" ", s.Label.Name)
+		}
+		p.print(";")
+	case *ast.LabeledStmt:
+		p.print(s.Label.Name, ":")
+		p.body(s.Stmt)
+	case *ast.ReturnStmt:
+		p.print("return")
+		if s.Result != nil {
+			p.print(" ", expr(s.Result))
+		}
+		p.print(";")
+	default:
+		panic(fmt.Sprintf("format: unexpected statement %T", s))
+	}
+}
+
+// simpleStmt returns the assignment or the increment of a for clause, without the semicolon.
+func simpleStmt(stmt ast.Stmt) string {
+	switch s := stmt.(type) {
+	case nil:
+		return ""
+	case *ast.AssignStmt:
+		return expr(s.Target) + " = " + expr(s.Value)
+	case *ast.IncDecStmt:
+		if s.Prefix {
+			return s.Op + expr(s.X)
+		}
+		return expr(s.X) + s.Op
+	}
+	panic(fmt.Sprintf("format: unexpected simple statement %T", stmt))
+}
+
+func typeString(typ ast.Type) string {
+	switch t := typ.(type) {
+	case *ast.BasicType:
+		return t.Name
+	case *ast.StructType:
+		return "struct " + t.Name.Name
+	case *ast.ArrayType:
+		return typeString(t.Elem) + "[" + expr(t.Len) + "]"
+	case *ast.PointerType:
+		return "*" + typeString(t.Elem)
+	}
+	panic(fmt.Sprintf("format: unexpected type %T", typ))
+}
+
+// expr returns the expression as written; the grouping is kept by the ParenExprs of the tree.
+func expr(e ast.Expr) string {
+	switch x := e.(type) {
+	case *ast.Ident:
+		return x.Name
+	case *ast.BasicLit:
+		return literal(x)
+	case *ast.CompositeLit:
+		elems := make([]string, len(x.Elems))
+		for i, elem := range x.Elems {
+			elems[i] = expr(elem)
+		}
+		return "{" + strings.Join(elems, ", ") + "}"
+	case *ast.ParenExpr:
+		return "(" + expr(x.X) + ")"
+	case *ast.IndexExpr:
+		return expr(x.X) + "[" + expr(x.Index) + "]"
+	case *ast.SelectorExpr:
+		return expr(x.X) + "." + x.Sel.Name
+	case *ast.CallExpr:
+		args := make([]string, len(x.Args))
+		for i, arg := range x.Args {
+			args[i] = expr(arg)
+		}
+		return x.Fun.Name + "(" + strings.Join(args, ", ") + ")"
+	case *ast.CastExpr:
+		return x.Type.Name + "(" + expr(x.X) + ")"
+	case *ast.UnaryExpr:
+		operand := expr(x.X)
+		// - -a must not become the decrement --a
+		if strings.HasPrefix(operand, x.Op) {
+			return x.Op + " " + operand
+		}
+		return x.Op + operand
+	case *ast.IncDecExpr:
+		if x.Prefix {
+			return x.Op + expr(x.X)
+		}
+		return expr(x.X) + x.Op
+	case *ast.BinaryExpr:
+		return expr(x.X) + " " + x.Op + " " + expr(x.Y)
+	}
+	panic(fmt.Sprintf("format: unexpected expression %T", e))
+}
+
+func literal(lit *ast.BasicLit) string {
+	switch lit.Kind {
+	case "str":
+		return `"` + escape(lit.Value, '"') + `"`
+	case "char":
+		return "'" + escape(lit.Value, '\'') + "'"
+	default:
+		return lit.Value
+	}
+}
+
+// escape writes the value of a string or a char literal with the escapes understood by the lexer.
+func escape(value string, quote rune) string {
+	var b strings.Builder
+	for _, r := range value {
+		switch r {
+		case '\\':
+			b.WriteString(`\\`)
+		case quote:
+			b.WriteString(`\` + string(quote))
+		case '\n':
+			b.WriteString(`\n`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\b':
+			b.WriteString(`\b`)
+		case '\f':
+			b.WriteString(`\f`)
+		case '\a':
+			b.WriteString(`\a`)
+		case '\v':
+			b.WriteString(`\v`)
+		default:
+			switch {
+			case unicode.IsPrint(r):
+				b.WriteRune(r)
+			case r > 0xffff:
+				_, _ = fmt.Fprintf(&b, `\U%08x`, r)
+			default:
+				_, _ = fmt.Fprintf(&b, `\u%04x`, r)
+			}
+		}
+	}
+	return b.String()
+}
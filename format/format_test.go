@@ -0,0 +1,177 @@
+package format_test
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"app/ast"
+	"app/format"
+	"app/lexer"
+	"app/parser"
+)
+
+var (
+	sharedParser     *parser.Parser
+	sharedParserOnce sync.Once
+)
+
+func parse(t *testing.T, src string) *ast.File {
+	t.Helper()
+	sharedParserOnce.Do(func() {
+		sharedParser = parser.NewParser()
+		sharedParser.EnsureTable()
+	})
+	file, _ := sharedParser.Parse(lexer.NewLexer(strings.NewReader(src)), func(string) {})
+	if file == nil {
+		t.Fatalf("Expected %q to parse", src)
+	}
+	return file
+}
+
+// withoutSpans clears the spans of the tree, which differ between the source and its formatted form.
+func withoutSpans(v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if !v.IsNil() {
+			withoutSpans(v.Elem())
+		}
+	case reflect.Slice:
+		for i := range v.Len() {
+			withoutSpans(v.Index(i))
+		}
+	case reflect.Struct:
+		if v.Type() == reflect.TypeOf(ast.Span{}) {
+			v.Set(reflect.Zero(v.Type()))
+			return
+		}
+		for i := range v.NumField() {
+			withoutSpans(v.Field(i))
+		}
+	default:
+	}
+}
+
+// expectRoundTrip checks that parse(format(parse(src))) equals parse(src) and that the formatted form is stable.
+func expectRoundTrip(t *testing.T, src string) string {
+	t.Helper()
+	file := parse(t, src)
+	formatted := string(format.File(file))
+	again := parse(t, formatted)
+	if formattedAgain := string(format.File(again)); formattedAgain != formatted {
+		t.Errorf("Expected the formatted form to be stable, got\n%s\nthen\n%s", formatted, formattedAgain)
+	}
+	withoutSpans(reflect.ValueOf(file))
+	withoutSpans(reflect.ValueOf(again))
+	if !reflect.DeepEqual(file, again) {
+		t.Errorf("Expected the formatted form to parse to the same AST, got\n%s", formatted)
+	}
+	return formatted
+}
+
+func TestFile(t *testing.T) {
+	src := `// Point is a point.
+type Point struct { int x; /* the ordinate */ int y; }
+const N=2;
+func add(int a,int b) int { return a+b; } // the sum
+{
+	// the points
+	struct Point[N] ps; int[2] a={1,-(-2)};
+	*int p;byte c='\''; string s="a\"b\n";
+	p=&a[0];
+	for(;ps[0].x<N;ps[0].x++) if(a[1]>0){a[1]--;}else if (a[1]<0) a[1]++;
+	while(a[0]<0) ps[1].y=add(*p,int(1.5));
+	switch(a[0]){case 1: break; default: a[0]=- -a[1];}
+	l: while(true) { do { continue l; } while (false); }
+	// the end
+}`
+	expected := `// Point is a point.
+type Point struct {
+	int x;
+	/* the ordinate */
+	int y;
+}
+
+const N = 2;
+
+func add(int a, int b) int {
+	return a + b;
+} // the sum
+
+{
+	// the points
+	struct Point[N] ps;
+	int[2] a = {1, -(-2)};
+	*int p;
+	byte c = '\'';
+	string s = "a\"b\n";
+	p = &a[0];
+	for (; ps[0].x < N; ps[0].x++) if (a[1] > 0) {
+		a[1]--;
+	} else if (a[1] < 0) a[1]++;
+	while (a[0] < 0) ps[1].y = add(*p, int(1.5));
+	switch (a[0]) {
+	case 1:
+		break;
+	default:
+		a[0] = - -a[1];
+	}
+	l: while (true) {
+		do {
+			continue l;
+		} while (false);
+	}
+	// the end
+}
+`
+	if got := expectRoundTrip(t, src); got != expected {
+		t.Errorf("Expected\n%s\ngot\n%s", expected, got)
+	}
+}
+
+func TestFile_Comments(t *testing.T) {
+	tests := []struct {
+		src      string
+		expected string
+	}{
+		// a comment before a node on its line is printed before the node
+		{src: "{\n\tint a; a = 1; /* c1 */ a = 2; // c3\n}", expected: "{\n\tint a;\n\ta = 1;\n\t/* c1 */\n\ta = 2; // c3\n}\n"},
+		// a comment inside a statement follows it
+		{src: "{ int a; a = 1 + /* mid */ 2; }", expected: "{\n\tint a;\n\ta = 1 + 2; /* mid */\n}\n"},
+	}
+	for _, tt := range tests {
+		if got := expectRoundTrip(t, tt.src); got != tt.expected {
+			t.Errorf("Expected %q for %q, got %q", tt.expected, tt.src, got)
+		}
+	}
+}
+
+func TestFile_Samples(t *testing.T) {
+	files, err := filepath.Glob("../tests/parser/*.in")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range files {
+		src, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expectRoundTrip(t, string(src))
+	}
+}
+
+func TestSource(t *testing.T) {
+	got, err := format.Source([]byte("{int a;a=1;}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "{\n\tint a;\n\ta = 1;\n}\n"; string(got) != expected {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+	if _, err := format.Source([]byte("{ a = ; }")); err == nil {
+		t.Errorf("Expected an error for a syntax error")
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 
 	. "app/utils/collections"
+	"app/utils/i18n"
 )
 
 type ItemType uint8
@@ -13,6 +14,7 @@ const (
 	TYPE
 	INTEGER
 	FLOAT
+	IMAGINARY
 	STRING
 	CHAR
 	OPERATOR
@@ -21,41 +23,49 @@ const (
 	IMPORT
 	PACKAGE
 	IDENTIFIER
+	COMMENT
+	ILLEGAL
 
 	EXTRA = 0xff
 )
 
-// ItemType stand for the type of token
+// ItemType stand for the type of token, the name is in the current locale, see i18n
 func (i ItemType) ToString() string {
 	switch i {
 	case EOF:
-		return "文件结束符"
+		return i18n.T("end of file")
 	case TYPE:
-		return "类型"
+		return i18n.T("type")
 	case INTEGER:
-		return "整数"
+		return i18n.T("integer")
 	case FLOAT:
-		return "浮点数"
+		return i18n.T("float")
+	case IMAGINARY:
+		return i18n.T("imaginary")
 	case STRING:
-		return "字符串"
+		return i18n.T("string")
 	case CHAR:
-		return "字符"
+		return i18n.T("char")
 	case OPERATOR:
-		return "运算符"
+		return i18n.T("operator")
 	case DELIMITER:
-		return "分隔符"
+		return i18n.T("delimiter")
 	case RESERVED:
-		return "保留字"
+		return i18n.T("reserved word")
 	case IMPORT:
-		return "导入"
+		return i18n.T("import")
 	case PACKAGE:
-		return "包"
+		return i18n.T("package")
 	case IDENTIFIER:
-		return "标识符"
+		return i18n.T("identifier")
+	case COMMENT:
+		return i18n.T("comment")
+	case ILLEGAL:
+		return i18n.T("illegal token")
 	case EXTRA:
-		return "拓展类型"
+		return i18n.T("extra")
 	default:
-		return "未知类型"
+		return i18n.T("unknown")
 	}
 }
 
@@ -89,6 +99,7 @@ const (
 	TypeByte
 	ConstantInt
 	ConstantFloat
+	ConstantImaginary
 	ConstantChar
 	ConstantStringDoubleQuote
 	ConstantStringBacktick
@@ -196,6 +207,8 @@ func (t TokenSpecificType) ToString() string {
 		return "constant_int"
 	case ConstantFloat:
 		return "constant_float"
+	case ConstantImaginary:
+		return "constant_imaginary"
 	case ConstantChar:
 		return "constant_char"
 	case ConstantStringDoubleQuote:
@@ -333,12 +346,41 @@ func (t TokenSpecificType) ToString() string {
 	}
 }
 
+// Span is the part of the input stream covered by a token, from its first rune to the position after its last one.
+// The offsets are counted in bytes of UTF-8, the lines and the columns from 0, and the columns in runes.
+type Span struct {
+	StartOffset, EndOffset int64
+	StartLine, StartCol    int64
+	EndLine, EndCol        int64
+}
+
+func (s Span) String() string {
+	return fmt.Sprintf("%d:%d-%d:%d", s.StartLine, s.StartCol, s.EndLine, s.EndCol)
+}
+
 type Token struct {
 	Type      ItemType
 	Val       string
 	Line, Pos int64
+	Span      Span
+
+	// Constant is the normalised value of a number, e.g. 31 for 0x1F and 1e-09 for 1E-9, see normalizeNumber
+	Constant string
+	// Value is the value of a literal: an int64 for an INTEGER, or a uint64 above math.MaxInt64, a float64 for
+	// a FLOAT, a complex128 for an IMAGINARY, the decoded string of a STRING, the rune of a CHAR, and the bool
+	// of true and false. It is nil for the other tokens.
+	Value any
+
+	// Diagnostic is the lexical error of an ILLEGAL token
+	Diagnostic string
+
+	// Leading and Trailing are the whitespace and the comments before the token and after it up to
+	// the end of its line, only kept by a lexer in KeepTrivia mode
+	Leading, Trailing []Trivia
 
 	_type TokenSpecificType
+	// where the lexing of the next token resumed, after the token and its trailing trivia, see Relex
+	_resume position
 }
 
 // SpecificType returns the specific type of the token
@@ -396,6 +438,34 @@ func (t *Token) AllocSize() int {
 	return -1
 }
 
+// NewToken returns a token read by a lexer other than Lexer, such as a generated one.
+// The specific type is derived from the type and the value, except for a STRING token,
+// whose specific type tells the quotes it was written with.
+// It returns an error for a number out of the range of its kind, as ReadNumber does.
+func NewToken(typ ItemType, val string, span Span, specific TokenSpecificType) (Token, error) {
+	t := Token{Type: typ, Val: val, Line: span.StartLine, Pos: span.StartCol, Span: span}
+	t.parse()
+	if typ == STRING {
+		t._type = specific
+	}
+	if typ == INTEGER || typ == FLOAT || typ == IMAGINARY {
+		value, invalid := numberValue(typ, val)
+		if invalid != nil {
+			return Token{Span: span}, invalid.error(val, span.StartLine, span.StartCol)
+		}
+		t.Constant, t.Value = normalizeNumber(typ, val), value
+	}
+	return t, nil
+}
+
+// NewTypeToken returns the token of the basic type named val, e.g. int.
+// Its specific type is Unknown if there is no such basic type.
+func NewTypeToken(val string) *Token {
+	t := &Token{Type: TYPE, Val: val}
+	t.parseType()
+	return t
+}
+
 func (t *Token) parseType() {
 	switch t.Val {
 	case "int":
@@ -441,10 +511,16 @@ func (t *Token) parseConstant() {
 		t._type = ConstantInt
 	case FLOAT:
 		t._type = ConstantFloat
+	case IMAGINARY:
+		t._type = ConstantImaginary
 	case CHAR:
 		t._type = ConstantChar
+		if runes := []rune(t.Val); len(runes) == 1 {
+			t.Value = runes[0]
+		}
 	// No need to parse string, it should be parsed in the lexer
 	case STRING:
+		t.Value = t.Val
 	default:
 		t._type = Unknown
 	}
@@ -550,6 +626,7 @@ func (t *Token) parseReservedWord() {
 		t._type = ReservedWordElse
 	case "false":
 		t._type = ReservedWordFalse
+		t.Value = false
 	case "for":
 		t._type = ReservedWordFor
 	case "func":
@@ -580,6 +657,7 @@ func (t *Token) parseReservedWord() {
 		t._type = ReservedWordSwitch
 	case "true":
 		t._type = ReservedWordTrue
+		t.Value = true
 	case "type":
 		t._type = ReservedWordType
 	case "var":
@@ -601,7 +679,7 @@ func (t *Token) parse() {
 	switch t.Type {
 	case TYPE:
 		t.parseType()
-	case INTEGER, FLOAT, CHAR, STRING:
+	case INTEGER, FLOAT, IMAGINARY, CHAR, STRING:
 		t.parseConstant()
 	case OPERATOR:
 		t.parseOperator()
@@ -0,0 +1,80 @@
+package lexer
+
+import "app/utils/i18n"
+
+// the Chinese messages of the lexer, keyed by the English ones
+func init() {
+	i18n.Register(i18n.ZH, map[string]string{
+		// the names of the item types
+		"end of file":   "文件结束符",
+		"type":          "类型",
+		"integer":       "整数",
+		"float":         "浮点数",
+		"imaginary":     "虚数",
+		"string":        "字符串",
+		"char":          "字符",
+		"operator":      "运算符",
+		"delimiter":     "分隔符",
+		"reserved word": "保留字",
+		"import":        "导入",
+		"package":       "包",
+		"identifier":    "标识符",
+		"comment":       "注释",
+		"illegal token": "非法记号",
+		"extra":         "拓展类型",
+		"unknown":       "未知类型",
+
+		"Lexer: using no buffered reader": "词法分析器：不使用缓冲读取",
+		"Lexer: using buffered reader":    "词法分析器：使用缓冲读取",
+
+		// the lexical errors
+		"lexer is not initialized":                                      "词法分析器未初始化",
+		"unknown character: %c, at line %d, pos %d":                     "未知字符：%c，位于第 %d 行，第 %d 列",
+		"string not closed, line %d, pos %d":                            "字符串未闭合，第 %d 行，第 %d 列",
+		"illegal hex for unicode[lower] %s, at line %d, pos %d":         "非法的 Unicode 十六进制数[小写] %s，位于第 %d 行，第 %d 列",
+		"illegal hex for unicode[upper] %s, at line %d, pos %d":         "非法的 Unicode 十六进制数[大写] %s，位于第 %d 行，第 %d 列",
+		"illegal octal %s, at line %d, pos %d":                          "非法的八进制数 %s，位于第 %d 行，第 %d 列",
+		"illegal escape \\%s, at line %d, pos %d":                       "非法的转义 \\%s，位于第 %d 行，第 %d 列",
+		"illegal unicode[lower] %s, at line %d, pos %d":                 "非法的 Unicode[小写] %s，位于第 %d 行，第 %d 列",
+		"illegal unicode[upper] %s, at line %d, pos %d":                 "非法的 Unicode[大写] %s，位于第 %d 行，第 %d 列",
+		"char not closed, line %d, pos %d":                              "字符未闭合，第 %d 行，第 %d 列",
+		"illegal char[too long] %s, at line %d, pos %d":                 "非法字符[过长] %s，位于第 %d 行，第 %d 列",
+		"illegal char[unmatched unicode length] %s, at line %d, pos %d": "非法字符[Unicode 长度不匹配] %s，位于第 %d 行，第 %d 列",
+		"illegal char[escapeAsUnicode] %s, at line %d, pos %d":          "非法字符[转义为 Unicode] %s，位于第 %d 行，第 %d 列",
+		"illegal operator %s, at line %d, pos %d":                       "非法运算符 %s，位于第 %d 行，第 %d 列",
+		"illegal number[%s] %s: %s, at line %d, pos %d":                 "非法数字[%s] %s：%s，位于第 %d 行，第 %d 列",
+		"%s %s, at line %d, pos %d":                                     "%s %s，位于第 %d 行，第 %d 列",
+		"illegal number":                                                "非法数字",
+		"illegal escape":                                                "非法的转义",
+		"illegal char":                                                  "非法字符",
+		"string not closed":                                             "字符串未闭合",
+		"invalid digit %q":                                              "无效的数字 %q",
+		"invalid radix point in %s":                                     "%s中出现无效的小数点",
+		"%s has no digits":                                              "%s没有数字",
+		"%q exponent requires decimal mantissa":                         "%q 指数需要十进制尾数",
+		"%q exponent requires hexadecimal mantissa":                     "%q 指数需要十六进制尾数",
+		"exponent has no digits":                                        "指数没有数字",
+		"hexadecimal mantissa requires a 'p' exponent":                  "十六进制尾数需要 'p' 指数",
+		"invalid digit %q in %s":                                        "%[2]s中出现无效的数字 %[1]q",
+		"'_' must separate successive digits":                           "'_' 必须位于两个数字之间",
+		"unexpected radix point":                                        "多余的小数点",
+		"invalid suffix %q":                                             "无效的后缀 %q",
+		"constant overflows uint64":                                     "常量溢出 uint64",
+		"constant overflows float64":                                    "常量溢出 float64",
+		"hexadecimal literal":                                           "十六进制字面量",
+		"octal literal":                                                 "八进制字面量",
+		"binary literal":                                                "二进制字面量",
+		"decimal literal":                                               "十进制字面量",
+
+		// the kinds of the number errors
+		"digit":         "数字",
+		"hex":           "十六进制",
+		"octal":         "八进制",
+		"binary":        "二进制",
+		"exponent":      "指数",
+		"separator":     "分隔符",
+		"too many dots": "小数点过多",
+		"suffix":        "后缀",
+		"overflow":      "溢出",
+	})
+}
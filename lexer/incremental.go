@@ -0,0 +1,179 @@
+package lexer
+
+import (
+	"bytes"
+	"reflect"
+	"unicode/utf8"
+)
+
+// Edit is a change of a source: the Deleted bytes from the Offset are replaced by the Inserted text.
+type Edit struct {
+	Offset   int64
+	Deleted  int64
+	Inserted string
+}
+
+// Apply returns the source after the edit.
+func (e Edit) Apply(src []byte) []byte {
+	edited := make([]byte, 0, int64(len(src))-e.Deleted+int64(len(e.Inserted)))
+	edited = append(edited, src[:e.Offset]...)
+	edited = append(edited, e.Inserted...)
+	return append(edited, src[e.Offset+e.Deleted:]...)
+}
+
+// TokenRange is the range of the tokens changed by an edit: the old tokens from Start to OldEnd, excluded,
+// are replaced by the new tokens from Start to NewEnd.
+type TokenRange struct {
+	Start, OldEnd, NewEnd int
+}
+
+// Tokenize reads all the tokens of the source with the mode, up to the EOF token included.
+// The lexer recovers from the lexical errors, which are ILLEGAL tokens, see Recover.
+func Tokenize(src []byte, mode Mode) []Token {
+	return lexFrom(src, position{}, mode, nil)
+}
+
+// NewTokenLexer creates a Lexer returning the tokens, as read by Tokenize or Relex, instead of reading a source.
+// After the last token, which is the EOF token, NextToken returns the EOF token again.
+func NewTokenLexer(tokens []Token) *Lexer {
+	return &Lexer{_tokens: tokens, _replay: true}
+}
+
+// replayToken returns the next token of a Lexer created by NewTokenLexer.
+func (l *Lexer) replayToken() Token {
+	if len(l._tokens) == 0 {
+		return Token{Type: EOF}
+	}
+	token := l._tokens[0]
+	if token.Type != EOF {
+		l._tokens = l._tokens[1:]
+	}
+	return token
+}
+
+// Relex updates the tokens of a source after an edit, as read by Tokenize with the same mode, and returns
+// them with the range of the tokens changed. src is the source after the edit.
+//
+// Only the region around the edit is read again: from where the lexing resumed after the last token which the
+// edit cannot change, until the lexing resumes at the same place of the source as it did before the edit.
+// The tokens after that are the old ones moved by the edit, out of the range.
+func Relex(src []byte, tokens []Token, edit Edit, mode Mode) ([]Token, TokenRange) {
+	editEnd := edit.Offset + int64(len(edit.Inserted))
+	delta := editEnd - edit.Offset - edit.Deleted
+
+	// the lexer looks a rune ahead, and two after a token for its trailing trivia, as in a /b
+	start := 0
+	for start < len(tokens) && tokens[start].Type != EOF && tokens[start]._resume.offset+2*utf8.UTFMax <= edit.Offset {
+		start++
+	}
+	at := position{}
+	if start > 0 {
+		at = tokens[start-1]._resume
+	}
+
+	// the old token after which the lexing resumes where it does after a new one, both after the edit
+	old := start
+	synced := func(resume position) bool {
+		if resume.offset < editEnd {
+			return false
+		}
+		for old < len(tokens) && tokens[old]._resume.offset < resume.offset-delta {
+			old++
+		}
+		return old < len(tokens) && tokens[old].Type != EOF && tokens[old]._resume.offset == resume.offset-delta
+	}
+	relexed := lexFrom(src, at, mode, synced)
+	result := append(tokens[:start:start], relexed...)
+	changed := TokenRange{Start: start, OldEnd: len(tokens), NewEnd: len(result)}
+
+	// the text is the same after the edit, so is the move of the positions from the old place to the new one
+	from, to := tokens[len(tokens)-1]._resume, result[len(result)-1]._resume
+	if last := result[len(result)-1]; last.Type != EOF {
+		from, to = tokens[old]._resume, last._resume
+		changed.OldEnd = old + 1
+		for _, token := range tokens[old+1:] {
+			if token.Diagnostic != "" {
+				// the position is written in the diagnostic, so the token is read again where it moved
+				token = lexFrom(src, result[len(result)-1]._resume, mode, func(position) bool { return true })[0]
+			} else {
+				token = token.moved(from, to)
+			}
+			result = append(result, token)
+		}
+	}
+
+	// the tokens read again and not changed are left out of the range
+	for changed.Start < changed.OldEnd && changed.Start < changed.NewEnd && reflect.DeepEqual(tokens[changed.Start], result[changed.Start]) {
+		changed.Start++
+	}
+	for changed.Start < changed.OldEnd && changed.Start < changed.NewEnd && result[changed.NewEnd-1].Span.StartOffset >= editEnd &&
+		reflect.DeepEqual(tokens[changed.OldEnd-1].moved(from, to), result[changed.NewEnd-1]) {
+		changed.OldEnd--
+		changed.NewEnd--
+	}
+	return result, changed
+}
+
+// lexFrom reads the tokens of the source from the position, which is where the lexing of a token resumes,
+// up to the EOF token included, or up to the first token after which stop is true.
+func lexFrom(src []byte, at position, mode Mode, stop func(resume position) bool) []Token {
+	l := NewLexerWithMode(bytes.NewReader(src[at.offset:]), mode|Recover)
+	l._at, l._line, l._pos = at, at.line, at.col
+	if at.line == 0 {
+		// the position on the first line counts from 1
+		l._pos++
+	}
+	var tokens []Token
+	for {
+		// the lexical errors are ILLEGAL tokens, so the error is nil or io.EOF
+		token, _ := l.NextToken()
+		tokens = append(tokens, token)
+		if token.Type == EOF || stop != nil && stop(token._resume) {
+			return tokens
+		}
+	}
+}
+
+// moved returns the token moved by an edit before it, which made the lexing resume at to instead of from.
+// The positions on the line of from move with it, the others only change their line.
+func (t Token) moved(from, to position) Token {
+	move := func(p position) position {
+		if p.line == from.line {
+			p.col += to.col - from.col
+		}
+		p.line += to.line - from.line
+		p.offset += to.offset - from.offset
+		return p
+	}
+	moveSpan := func(s Span) Span {
+		start := move(position{s.StartOffset, s.StartLine, s.StartCol})
+		end := move(position{s.EndOffset, s.EndLine, s.EndCol})
+		return Span{start.offset, end.offset, start.line, start.col, end.line, end.col}
+	}
+	moveTrivia := func(trivia []Trivia) []Trivia {
+		if trivia == nil {
+			return nil
+		}
+		moved := make([]Trivia, len(trivia))
+		for i, tr := range trivia {
+			moved[i] = Trivia{Kind: tr.Kind, Text: tr.Text, Span: moveSpan(tr.Span)}
+		}
+		return moved
+	}
+
+	t.Span = moveSpan(t.Span)
+	t.Leading, t.Trailing = moveTrivia(t.Leading), moveTrivia(t.Trailing)
+	t._resume = move(t._resume)
+	if t.Type != EOF {
+		// the legacy position, which counts from 1 on the first line, see nextRune
+		first := func(line int64) int64 {
+			if line == 0 {
+				return 1
+			}
+			return 0
+		}
+		p := move(position{0, t.Line, t.Pos - first(t.Line)})
+		t.Line, t.Pos = p.line, p.col+first(p.line)
+	}
+	return t
+}
@@ -3,7 +3,6 @@ package lexer
 import (
 	"bufio"
 	"errors"
-	"fmt"
 	"io"
 	"strings"
 	"sync/atomic"
@@ -11,14 +10,36 @@ import (
 
 	"app/config"
 	"app/utils"
+	"app/utils/i18n"
 )
 
 var _PrintNoBufferReaderOnce = atomic.Bool{}
 
 type Lexer struct {
-	_reader      io.RuneScanner
-	_line, _pos  int64
-	_lineLengths []int64
+	_reader     io.RuneScanner
+	_line, _pos int64
+	_comments   []Token
+
+	_mode   Mode
+	_lexeme []rune   // the runes read for the current token
+	_trivia []Trivia // the trivia read before the current token
+
+	// the token read ahead to split the trivia between it and the previous token, see KeepTrivia
+	_next      *Token
+	_nextError error
+
+	// the position after the last rune read, the one before it, and the start of the current token
+	_at, _prev, _start position
+	// the line and the position before the last rune read, restored by retract as _prev is
+	_prevLine, _prevPos int64
+
+	// the tokens returned instead of reading if _replay is set, see NewTokenLexer
+	_tokens []Token
+	_replay bool
+}
+
+type position struct {
+	offset, line, col int64
 }
 
 // NewLexer creates a new Lexer instance with the given io.Reader.
@@ -26,9 +47,9 @@ func NewLexer(r io.Reader) *Lexer {
 	var reader io.RuneScanner
 	if _PrintNoBufferReaderOnce.CompareAndSwap(false, true) {
 		if config.Config.Lexer.UsingNoBufferedReader {
-			println("Lexer: using no buffered reader")
+			println(i18n.T("Lexer: using no buffered reader"))
 		} else {
-			println("Lexer: using buffered reader")
+			println(i18n.T("Lexer: using buffered reader"))
 		}
 	}
 	if config.Config.Lexer.UsingNoBufferedReader {
@@ -36,27 +57,162 @@ func NewLexer(r io.Reader) *Lexer {
 	}
 	reader = bufio.NewReader(r)
 	return &Lexer{
-		_reader:      reader,
-		_line:        0,
-		_pos:         1,
-		_lineLengths: []int64{},
+		_reader: reader,
+		_line:   0,
+		_pos:    1,
 	}
 }
 
+// Mode is a set of flags changing what the lexer returns.
+type Mode uint8
+
+const (
+	// Recover makes NextToken return an ILLEGAL token with the bad lexeme and the error as diagnostic
+	// instead of the error of a lexical error, and the next call goes on after the lexeme.
+	Recover Mode = 1 << iota
+	// KeepComments makes NextToken return the comments as COMMENT tokens.
+	KeepComments
+	// KeepTrivia attaches the whitespace and the comments around a token to it, see Token.Leading.
+	KeepTrivia
+	// CollectComments keeps the comments read for Comments, see CollectComments.
+	CollectComments
+)
+
+// NewLexerWithMode creates a new Lexer instance with the given io.Reader and mode.
+func NewLexerWithMode(r io.Reader, mode Mode) *Lexer {
+	l := NewLexer(r)
+	l._mode = mode
+	return l
+}
+
+// NewRecoveringLexer creates a new Lexer instance with the given io.Reader, which does not stop at a lexical error.
+// Instead of returning the error, NextToken returns an ILLEGAL token with the bad lexeme and the error as
+// diagnostic, and the next call goes on after the lexeme.
+func NewRecoveringLexer(r io.Reader) *Lexer {
+	return NewLexerWithMode(r, Recover)
+}
+
 // NextToken reads the next token from the input stream and returns it.
 func (l *Lexer) NextToken() (Token, error) {
+	if l._replay {
+		return l.replayToken(), nil
+	}
 	if l._reader == nil {
-		return Token{}, fmt.Errorf("lexer is not initialized")
+		return Token{}, i18n.Errorf("lexer is not initialized")
+	}
+	if l._mode&KeepTrivia == 0 {
+		return l.readToken()
+	}
+
+	// the trivia after a token up to the end of its line trail it, so the next token is read ahead
+	var token Token
+	var err error
+	if l._next != nil {
+		token, err = *l._next, l._nextError
+		l._next = nil
+	} else {
+		token, err = l.readToken()
+	}
+	if token.Type == EOF || err != nil {
+		return token, err
+	}
+	next, nextErr := l.readToken()
+	i := 0
+	for i < len(next.Leading) {
+		i++
+		if next.Leading[i-1].Kind == TriviaNewline {
+			break
+		}
 	}
+	token.Trailing, next.Leading = next.Leading[:i], next.Leading[i:]
+	if len(next.Leading) > 0 {
+		token._resume = position{next.Leading[0].Span.StartOffset, next.Leading[0].Span.StartLine, next.Leading[0].Span.StartCol}
+	} else {
+		// as the first token, which has no trivia before it
+		next.Leading = nil
+		token._resume = position{next.Span.StartOffset, next.Span.StartLine, next.Span.StartCol}
+	}
+	l._next, l._nextError = &next, nextErr
+	return token, err
+}
+
+// readToken reads the next token with its leading trivia, recovering from a lexical error if asked to.
+func (l *Lexer) readToken() (Token, error) {
 	token, err := l.nextToken()
+	if l._mode&Recover != 0 && err != nil && !errors.Is(err, io.EOF) {
+		l.resync()
+		token, err = Token{Type: ILLEGAL, Val: strings.TrimSuffix(string(l._lexeme), "\n"), Line: l._line, Pos: l._pos, Diagnostic: err.Error()}, nil
+	}
+	if token.Type != COMMENT {
+		// a line comment ends before its newline, so it keeps the span set when it was read
+		token.Span = l.span(l._start, l._at)
+	}
+	token.Leading, l._trivia = l._trivia, nil
+	token._resume = l._at
 	token.parse()
 	return token, err
 }
 
+// keepTrivia adds the text between start and end to the trivia of the next token.
+func (l *Lexer) keepTrivia(kind TriviaKind, text string, start, end position) {
+	if l._mode&KeepTrivia == 0 || text == "" {
+		return
+	}
+	l._trivia = append(l._trivia, Trivia{Kind: kind, Text: text, Span: l.span(start, end)})
+}
+
+func (l *Lexer) span(start, end position) Span {
+	return Span{
+		StartOffset: start.offset,
+		EndOffset:   end.offset,
+		StartLine:   start.line,
+		StartCol:    start.col,
+		EndLine:     end.line,
+		EndCol:      end.col,
+	}
+}
+
+// resync skips the rest of a string or char literal broken by an illegal escape, so that
+// the lexing goes on after its closing quote instead of inside the literal.
+func (l *Lexer) resync() {
+	if len(l._lexeme) == 0 || (l._lexeme[0] != '"' && l._lexeme[0] != '\'') {
+		return
+	}
+	quote := l._lexeme[0]
+	escape := false
+	for _, r := range l._lexeme[1:] {
+		if r == '\n' || r == quote && !escape {
+			return
+		}
+		escape = !escape && r == '\\'
+	}
+	for {
+		r, err := l.nextRune()
+		if err != nil || r == '\n' || r == quote && !escape {
+			return
+		}
+		escape = !escape && r == '\\'
+	}
+}
+
+// Comments returns the comments read so far in CollectComments mode, in the order they appear in the input stream.
+// The text of a comment includes its markers, and its line and position are those of its first character.
+func (l *Lexer) Comments() []Token {
+	return l._comments
+}
+
+// CollectComments adds the CollectComments mode, so that the comments read from then on are kept for Comments.
+// Otherwise they are not retained, unless they are returned as tokens or trivia.
+func (l *Lexer) CollectComments() {
+	l._mode |= CollectComments
+}
+
 // nextToken is a helper function that reads the next token from the input stream.
 // It handles whitespace, comments, strings, characters, words, numbers, and operators.
 func (l *Lexer) nextToken() (Token, error) {
 	err := l.skipWhiteSpace()
+	l._lexeme = l._lexeme[:0]
+	l._start = l._at
 	if errors.Is(err, io.EOF) {
 		return Token{Type: EOF}, nil
 	}
@@ -71,24 +227,25 @@ func (l *Lexer) nextToken() (Token, error) {
 		if errors.Is(err, io.EOF) {
 			l.retract()
 		} else {
-			if nextRune == '/' {
-				err := l.skipAnnotation()
-				if err != nil {
+			if nextRune == '/' || nextRune == '*' {
+				skip := l.skipAnnotation
+				if nextRune == '*' {
+					skip = l.skipAnnotation2
+				}
+				comment, err := skip()
+				if l._mode&KeepComments != 0 {
 					if errors.Is(err, io.EOF) {
-						return Token{Type: EOF}, nil
+						err = nil
 					}
-					return Token{}, err
+					return comment, err
 				}
-				return l.NextToken()
-			} else if nextRune == '*' {
-				err := l.skipAnnotation2()
 				if err != nil {
 					if errors.Is(err, io.EOF) {
 						return Token{Type: EOF}, nil
 					}
 					return Token{}, err
 				}
-				return l.NextToken()
+				return l.nextToken()
 			} else {
 				l.retract()
 			}
@@ -115,6 +272,17 @@ func (l *Lexer) nextToken() (Token, error) {
 		return l.ReadNumber(r)
 	}
 
+	if r == '.' {
+		// a float such as .5, or else the delimiter
+		nr, err := l.nextRune()
+		if err == nil {
+			l.retract()
+			if utils.IsDigit(nr) {
+				return l.ReadNumber(r)
+			}
+		}
+	}
+
 	if _Operators.ContainsFunc(func(s string) bool {
 		return strings.HasPrefix(s, string(r))
 	}) {
@@ -125,85 +293,135 @@ func (l *Lexer) nextToken() (Token, error) {
 		return Token{Type: DELIMITER, Val: string(r), Line: l._line, Pos: l._pos}, nil
 	}
 
-	return Token{}, fmt.Errorf("unknown character: %c, at line %d, pos %d", r, l._line, l._pos)
+	return Token{}, i18n.Errorf("unknown character: %c, at line %d, pos %d", r, l._line, l._pos)
 }
 
 // nextRune reads the next rune from the input stream and updates the line and position counters.
-// It also handles line breaks and updates the line lengths slice.
 func (l *Lexer) nextRune() (rune, error) {
-	r, _, err := l._reader.ReadRune()
+	r, size, err := l._reader.ReadRune()
 	if err != nil {
 		return 0, err
 	}
+	l._prev, l._prevLine, l._prevPos = l._at, l._line, l._pos
+	l._at.offset += int64(size)
+	if r == '\n' {
+		l._at.line++
+		l._at.col = 0
+	} else {
+		l._at.col++
+	}
 	if r == '\n' {
 		l._line++
-		l._lineLengths = append(l._lineLengths, l._pos)
 		l._pos = 0
 	} else {
 		l._pos++
 	}
+	l._lexeme = append(l._lexeme, r)
 	return r, nil
 }
 
 // retract moves the position back by one rune in the input stream.
 // It updates the line and position counters accordingly.
 func (l *Lexer) retract() {
-	_ = l._reader.UnreadRune()
-	if l._pos > 0 {
-		l._pos--
-	} else if l._line > 1 {
-		l._line--
-		l._pos = l._lineLengths[l._line-1]
-		l._lineLengths = l._lineLengths[:l._line]
+	if err := l._reader.UnreadRune(); err == nil {
+		l._at, l._line, l._pos = l._prev, l._prevLine, l._prevPos
+		if len(l._lexeme) > 0 {
+			l._lexeme = l._lexeme[:len(l._lexeme)-1]
+		}
 	}
 }
 
 // skipWhiteSpace skips over whitespace characters in the input stream.
 // It continues reading until a non-whitespace character is found or EOF is reached.
+// The whitespace is kept as trivia, with a newline apart from the spaces before it.
 func (l *Lexer) skipWhiteSpace() error {
+	start, spaces := l._at, ""
 	for {
+		at := l._at
 		r, err := l.nextRune()
 		if err != nil {
+			l.keepTrivia(TriviaWhitespace, spaces, start, at)
 			return err
 		}
 		if !unicode.IsSpace(r) {
 			l.retract()
+			l.keepTrivia(TriviaWhitespace, spaces, start, at)
 			return nil
 		}
+		if r == '\n' {
+			l.keepTrivia(TriviaWhitespace, spaces, start, at)
+			l.keepTrivia(TriviaNewline, "\n", at, l._at)
+			start, spaces = l._at, ""
+			continue
+		}
+		spaces += string(r)
 	}
 }
 
 // skipAnnotation skips over single-line comments in the input stream.
 // It continues reading until a newline character is found or EOF is reached.
-func (l *Lexer) skipAnnotation() error {
+// It returns the comment, without the newline.
+func (l *Lexer) skipAnnotation() (comment Token, err error) {
+	comment = Token{Type: COMMENT, Val: "//", Line: l._line, Pos: l._pos - 2}
+	end := l._at
+	newline := false
+	defer func() {
+		comment.Val = strings.TrimSuffix(comment.Val, "\r")
+		comment.Span = l.span(l._start, end)
+		l.collectComment(comment)
+		if l._mode&KeepComments == 0 {
+			l.keepTrivia(TriviaLineComment, comment.Val, l._start, end)
+			if newline {
+				l.keepTrivia(TriviaNewline, "\n", l._prev, l._at)
+			}
+		}
+	}()
 	for {
 		r, err := l.nextRune()
 		if err != nil {
-			return err
+			return comment, err
 		}
 		if r == '\n' {
-			return nil
+			newline = true
+			return comment, nil
+		}
+		comment.Val += string(r)
+		if r != '\r' {
+			end = l._at
 		}
 	}
 }
 
 // skipAnnotation2 skips over multi-line comments in the input stream.
 // It continues reading until the closing comment sequence "*/" is found or EOF is reached.
-func (l *Lexer) skipAnnotation2() error {
+// It returns the comment.
+func (l *Lexer) skipAnnotation2() (comment Token, err error) {
+	comment = Token{Type: COMMENT, Val: "/*", Line: l._line, Pos: l._pos - 2}
+	defer func() {
+		comment.Span = l.span(l._start, l._at)
+		l.collectComment(comment)
+		if l._mode&KeepComments == 0 {
+			l.keepTrivia(TriviaBlockComment, comment.Val, l._start, l._at)
+		}
+	}()
+	star := false
 	for {
-		r1, err := l.nextRune()
+		r, err := l.nextRune()
 		if err != nil {
-			return err
+			return comment, err
 		}
-		if r1 == '*' {
-			r2, err := l.nextRune()
-			if err != nil {
-				return err
-			}
-			if r2 == '/' {
-				return nil
-			}
+		comment.Val += string(r)
+		if star && r == '/' {
+			return comment, nil
 		}
+		star = r == '*'
+	}
+}
+
+// collectComment keeps the comment for Comments in CollectComments mode.
+func (l *Lexer) collectComment(comment Token) {
+	if l._mode&CollectComments != 0 {
+		l._comments = append(l._comments, comment)
 	}
 }
 
@@ -223,7 +441,7 @@ func (l *Lexer) ReadString() (Token, error) {
 		r, err := l.nextRune()
 		if err != nil {
 			if errors.Is(err, io.EOF) {
-				return Token{Type: EOF}, fmt.Errorf("string not closed, line %d, pos %d", l._line, l._pos)
+				return Token{Type: EOF}, i18n.Errorf("string not closed, line %d, pos %d", l._line, l._pos)
 			} else {
 				return Token{}, err
 			}
@@ -241,7 +459,7 @@ func (l *Lexer) ReadString() (Token, error) {
 		if escape {
 			if escapeAsUnicodeLower {
 				if !utils.IsHex(r) {
-					return Token{}, fmt.Errorf("illegal hex for unicode[lower] %s, at line %d, pos %d", u, l._line, l._pos)
+					return Token{}, i18n.Errorf("illegal hex for unicode[lower] %s, at line %d, pos %d", u, l._line, l._pos)
 				} else {
 					widthOfUnicode++
 					u += string(r)
@@ -255,7 +473,7 @@ func (l *Lexer) ReadString() (Token, error) {
 				}
 			} else if escapeAsUnicodeUpper {
 				if !utils.IsHex(r) {
-					return Token{}, fmt.Errorf("illegal hex for unicode[upper] %s, at line %d, pos %d", u, l._line, l._pos)
+					return Token{}, i18n.Errorf("illegal hex for unicode[upper] %s, at line %d, pos %d", u, l._line, l._pos)
 				} else {
 					widthOfUnicode++
 					u += string(r)
@@ -269,7 +487,7 @@ func (l *Lexer) ReadString() (Token, error) {
 				}
 			} else if escapeAsOctal {
 				if !utils.IsOctal(r) {
-					return Token{}, fmt.Errorf("illegal octal %s, at line %d, pos %d", o, l._line, l._pos)
+					return Token{}, i18n.Errorf("illegal octal %s, at line %d, pos %d", o, l._line, l._pos)
 				} else {
 					widthOfOctal++
 					o += string(r)
@@ -292,7 +510,7 @@ func (l *Lexer) ReadString() (Token, error) {
 				case '0': // escape octal
 					escapeAsOctal = true
 				default:
-					return Token{}, fmt.Errorf("illegal escape \\%s, at line %d, pos %d", string(r), l._line, l._pos)
+					return Token{}, i18n.Errorf("illegal escape \\%s, at line %d, pos %d", string(r), l._line, l._pos)
 				}
 			}
 			if !escapeAsUnicodeLower && !escapeAsUnicodeUpper && !escapeAsOctal {
@@ -302,21 +520,21 @@ func (l *Lexer) ReadString() (Token, error) {
 		}
 		if r == '\n' {
 			if errors.Is(err, io.EOF) {
-				return Token{Type: EOF}, fmt.Errorf("string not closed, line %d, pos %d", l._line-1, l._lineLengths[l._line-1])
+				return Token{Type: EOF}, i18n.Errorf("string not closed, line %d, pos %d", l._start.line, l._start.col)
 			} else {
-				return Token{}, fmt.Errorf("string not closed, line %d, pos %d", l._line-1, l._lineLengths[l._line-1])
+				return Token{}, i18n.Errorf("string not closed, line %d, pos %d", l._start.line, l._start.col)
 			}
 		}
 		s += string(r)
 	}
 	if escapeAsUnicodeLower {
-		return Token{}, fmt.Errorf("illegal unicode[lower] %s, at line %d, pos %d", u, l._line, l._pos)
+		return Token{}, i18n.Errorf("illegal unicode[lower] %s, at line %d, pos %d", u, l._line, l._pos)
 	}
 	if escapeAsUnicodeUpper {
-		return Token{}, fmt.Errorf("illegal unicode[upper] %s, at line %d, pos %d", u, l._line, l._pos)
+		return Token{}, i18n.Errorf("illegal unicode[upper] %s, at line %d, pos %d", u, l._line, l._pos)
 	}
 	if escapeAsOctal {
-		return Token{}, fmt.Errorf("illegal octal %s, at line %d, pos %d", o, l._line, l._pos)
+		return Token{}, i18n.Errorf("illegal octal %s, at line %d, pos %d", o, l._line, l._pos)
 	}
 	return Token{Type: STRING, Val: s, Line: l._line, Pos: l._pos, _type: ConstantStringDoubleQuote}, nil
 }
@@ -328,7 +546,7 @@ func (l *Lexer) ReadString2() (Token, error) {
 		r, err := l.nextRune()
 		if err != nil {
 			if errors.Is(err, io.EOF) {
-				return Token{Type: EOF}, fmt.Errorf("string not closed, line %d, pos %d", l._line, l._pos)
+				return Token{Type: EOF}, i18n.Errorf("string not closed, line %d, pos %d", l._line, l._pos)
 			} else {
 				return Token{}, err
 			}
@@ -354,7 +572,7 @@ func (l *Lexer) ReadChar() (Token, error) {
 		r, err := l.nextRune()
 		if err != nil {
 			if errors.Is(err, io.EOF) {
-				return Token{Type: EOF}, fmt.Errorf("char not closed, line %d, pos %d", l._line, l._pos)
+				return Token{Type: EOF}, i18n.Errorf("char not closed, line %d, pos %d", l._line, l._pos)
 			} else {
 				return Token{}, err
 			}
@@ -401,22 +619,22 @@ func (l *Lexer) ReadChar() (Token, error) {
 	}
 	// check if the char is valid[not starting with \ and too long]
 	if width > 1 && (!escapeAsUnicodeLower && !escapeAsUnicodeUpper) {
-		return Token{}, fmt.Errorf("illegal char[too long] %s, at line %d, pos %d", s, l._line, l._pos)
+		return Token{}, i18n.Errorf("illegal char[too long] %s, at line %d, pos %d", s, l._line, l._pos)
 	}
 	if escapeAsUnicodeLower {
 		if width != 5 {
-			return Token{}, fmt.Errorf("illegal char[unmatched unicode length] %s, at line %d, pos %d", s, l._line, l._pos)
+			return Token{}, i18n.Errorf("illegal char[unmatched unicode length] %s, at line %d, pos %d", s, l._line, l._pos)
 		}
 		return Token{Type: CHAR, Val: string(utils.HexToRune(s[1:])), Line: l._line, Pos: l._pos}, nil
 	}
 	if escapeAsUnicodeUpper {
 		if width != 9 {
-			return Token{}, fmt.Errorf("illegal char[unmatched unicode length] %s, at line %d, pos %d", s, l._line, l._pos)
+			return Token{}, i18n.Errorf("illegal char[unmatched unicode length] %s, at line %d, pos %d", s, l._line, l._pos)
 		}
 		return Token{Type: CHAR, Val: string(utils.HexToRune(s[1:])), Line: l._line, Pos: l._pos}, nil
 	}
 	if (escapeAsUnicodeLower || escapeAsUnicodeUpper) && illegalUnicode {
-		return Token{}, fmt.Errorf("illegal char[escapeAsUnicode] %s, at line %d, pos %d", s, l._line, l._pos)
+		return Token{}, i18n.Errorf("illegal char[escapeAsUnicode] %s, at line %d, pos %d", s, l._line, l._pos)
 	}
 	return Token{Type: CHAR, Val: s, Line: l._line, Pos: l._pos}, nil
 }
@@ -446,15 +664,22 @@ func (l *Lexer) ReadWord(r rune) (Token, error) {
 	}
 }
 
-// ReadNumber reads a number (integer or float) from the input stream.
-// It handles digits, letters, underscores, and hexadecimal numbers.
+// ReadNumber reads a number (integer, float or imaginary) from the input stream, as written in Go.
+// It reads the digits, letters, underscores and dots after r, and the sign of an exponent, then checks them.
 func (l *Lexer) ReadNumber(r rune) (Token, error) {
 	s := string(r)
-	illegalSuffix := false
 	tokenWhenWrong := Token{}
 	var errWhenPassed error
 	for {
 		nr, err := l.nextRune()
+		if err == nil && (nr == '+' || nr == '-') {
+			// 0x1e+2 is 0x1e + 2, while 1e+2 and 0x1p+2 are floats
+			hex := strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0X")
+			if e := s[len(s)-1]; e == 'p' || e == 'P' || !hex && (e == 'e' || e == 'E') {
+				s += string(nr)
+				continue
+			}
+		}
 		if err != nil || !(utils.IsDigit(nr) || utils.IsLetter(nr) || nr == '_' || nr == '.') {
 			if errors.Is(err, io.EOF) {
 				tokenWhenWrong.Type = EOF
@@ -463,49 +688,21 @@ func (l *Lexer) ReadNumber(r rune) (Token, error) {
 			l.retract()
 			break
 		}
-		if utils.IsLetter(nr) || nr == '_' {
-			illegalSuffix = true
-		}
 		s += string(nr)
 	}
-	if illegalSuffix && !strings.HasPrefix(s, "0x") && !strings.HasPrefix(s, "0X") {
-		return tokenWhenWrong, fmt.Errorf("illegal number[suffix] %s, at line %d, pos %d", s, l._line, l._pos)
+	typ, invalid := checkNumber(s)
+	var value any
+	if invalid == nil {
+		value, invalid = numberValue(typ, s)
 	}
-	dotCount := strings.Count(s, ".")
-	if dotCount == 1 {
-		if strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0X") {
-			return tokenWhenWrong, fmt.Errorf("illegal number[hex] %s, at line %d, pos %d", s, l._line, l._pos)
-		}
-		if strings.HasPrefix(s, "00") {
-			parts := strings.Split(s, ".")
-			l := utils.RemoveLeadingZeros(parts[0])
-			if l == "" {
-				l = "0"
-			}
-			s = l + "." + parts[1]
-		}
-		return Token{Type: FLOAT, Val: s, Line: l._line, Pos: l._pos}, errWhenPassed
-	} else if dotCount == 0 {
-		if strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0X") {
-			if len(s) < 3 {
-				return tokenWhenWrong, fmt.Errorf("illegal number[hex] %s, at line %d, pos %d", s, l._line, l._pos)
-			}
-			if strings.ContainsFunc(s[2:], func(r rune) bool {
-				return !utils.IsHex(r)
-			}) {
-				return tokenWhenWrong, fmt.Errorf("illegal number[hex] %s, at line %d, pos %d", s, l._line, l._pos)
-			} else {
-				return Token{Type: INTEGER, Val: s, Line: l._line, Pos: l._pos}, errWhenPassed
-			}
-		} else if strings.HasPrefix(s, "0") {
-			if len(s) > 1 {
-				return tokenWhenWrong, fmt.Errorf("illegal number[integer] %s, at line %d, pos %d", s, l._line, l._pos)
-			}
-		}
-		return Token{Type: INTEGER, Val: s, Line: l._line, Pos: l._pos}, errWhenPassed
-	} else {
-		return tokenWhenWrong, fmt.Errorf("illegal number[too many dots] %s, at line %d, pos %d", s, l._line, l._pos)
+	if invalid != nil {
+		return tokenWhenWrong, invalid.error(s, l._line, l._pos)
+	}
+	val := s
+	if typ == FLOAT {
+		val = trimFloat(s)
 	}
+	return Token{Type: typ, Val: val, Constant: normalizeNumber(typ, s), Value: value, Line: l._line, Pos: l._pos}, errWhenPassed
 }
 
 // ReadOperator reads an operator from the input stream.
@@ -546,7 +743,7 @@ func (l *Lexer) ReadOperator(r rune) (Token, error) {
 
 	if bestMatch == "" {
 		// impossible to reach here
-		return tokenWhenError, fmt.Errorf("illegal operator %s, at line %d, pos %d", prefix, l._line, l._pos)
+		return tokenWhenError, i18n.Errorf("illegal operator %s, at line %d, pos %d", prefix, l._line, l._pos)
 	}
 
 	// retract to the best match
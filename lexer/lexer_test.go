@@ -4,10 +4,16 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
+	"unicode/utf8"
 
 	"app/lexer"
+	"app/utils/i18n"
 	"app/utils/log"
 )
 
@@ -15,6 +21,12 @@ const (
 	Silent = false
 )
 
+// the tests check the messages in English
+func TestMain(m *testing.M) {
+	i18n.SetLocale(i18n.EN)
+	os.Exit(m.Run())
+}
+
 func LexerAct(str string) (tokens []lexer.Token, errCount int) {
 	l := lexer.NewLexer(strings.NewReader(str))
 	for {
@@ -308,20 +320,59 @@ abc@ 123#
 // 非法浮点数
 123.456.789
 
-// 不支持的科学计数法
-1e10
+// 非法指数
+1e
+1e+
+0x1p
+0o1e2
+0b1p2
 
-// 不支持的八进制数
-0777
+// 非法八进制数
+09
+0o8
 
-// 不支持的二进制数
-0b1010
+// 非法二进制数
+0b102
+0b1.0
 
-// 错误前缀
-001
+// 非法分隔符
+1__0
+1_
+1_.5
 `,
 		expectedTokens: make([]lexer.Token, 0),
-		errorCount:     11,
+		errorCount:     19,
+	},
+	{
+		name: "Go Number Literals",
+		str: `0o17 0777 001 0b1010 1_000_000 0x_FF
+1e10 1E-9 .5 1. 0x1p-2 0x1.8p+1 00.5e1
+2i 1.5i 0x10i 017i
+0x1e+2 a.5`,
+		expectedTokens: []lexer.Token{
+			{Type: lexer.INTEGER, Val: "0o17"},
+			{Type: lexer.INTEGER, Val: "0777"},
+			{Type: lexer.INTEGER, Val: "001"},
+			{Type: lexer.INTEGER, Val: "0b1010"},
+			{Type: lexer.INTEGER, Val: "1_000_000"},
+			{Type: lexer.INTEGER, Val: "0x_FF"},
+			{Type: lexer.FLOAT, Val: "1e10"},
+			{Type: lexer.FLOAT, Val: "1E-9"},
+			{Type: lexer.FLOAT, Val: ".5"},
+			{Type: lexer.FLOAT, Val: "1."},
+			{Type: lexer.FLOAT, Val: "0x1p-2"},
+			{Type: lexer.FLOAT, Val: "0x1.8p+1"},
+			{Type: lexer.FLOAT, Val: "0.5e1"},
+			{Type: lexer.IMAGINARY, Val: "2i"},
+			{Type: lexer.IMAGINARY, Val: "1.5i"},
+			{Type: lexer.IMAGINARY, Val: "0x10i"},
+			{Type: lexer.IMAGINARY, Val: "017i"},
+			{Type: lexer.INTEGER, Val: "0x1e"},
+			{Type: lexer.OPERATOR, Val: "+"},
+			{Type: lexer.INTEGER, Val: "2"},
+			{Type: lexer.IDENTIFIER, Val: "a"},
+			{Type: lexer.FLOAT, Val: ".5"},
+		},
 	},
 	{
 		name: "Multiline String Using Double Quotes",
@@ -539,3 +590,415 @@ func TestLexer(t *testing.T) {
 		})
 	}
 }
+
+func TestLexer_Comments(t *testing.T) {
+	// the comments are only kept when asked for
+	l := lexer.NewLexer(strings.NewReader("a /* block */ b"))
+	for token, _ := l.NextToken(); token.Type != lexer.EOF; token, _ = l.NextToken() {
+	}
+	if comments := l.Comments(); len(comments) != 0 {
+		t.Errorf("Expected no comments to be kept, got %v", comments)
+	}
+
+	l = lexer.NewLexerWithMode(strings.NewReader("a // line\r\nb /* block\n**/ c /**/ // last"), lexer.CollectComments)
+	for {
+		token, err := l.NextToken()
+		if err != nil && !errors.Is(err, io.EOF) {
+			t.Fatal(err)
+		}
+		if token.Type == lexer.EOF {
+			break
+		}
+	}
+	expected := []lexer.Token{
+		{Type: lexer.COMMENT, Val: "// line", Line: 0},
+		{Type: lexer.COMMENT, Val: "/* block\n**/", Line: 1},
+		{Type: lexer.COMMENT, Val: "/**/", Line: 2},
+		{Type: lexer.COMMENT, Val: "// last", Line: 2},
+	}
+	comments := l.Comments()
+	if len(comments) != len(expected) {
+		t.Fatalf("Expected %d comments, got %v", len(expected), comments)
+	}
+	for i, comment := range comments {
+		if comment.Type != expected[i].Type || comment.Val != expected[i].Val || comment.Line != expected[i].Line {
+			t.Errorf("Expected comment %d to be %v, got %v", i, expected[i], comment)
+		}
+	}
+}
+
+func TestLexer_CommentTokens(t *testing.T) {
+	l := lexer.NewLexerWithMode(strings.NewReader("a // line\nb /* block */ c // last"), lexer.KeepComments)
+	var got []string
+	for {
+		token, err := l.NextToken()
+		if err != nil && !errors.Is(err, io.EOF) {
+			t.Fatal(err)
+		}
+		if token.Type == lexer.EOF {
+			break
+		}
+		got = append(got, token.Val)
+		if token.Type == lexer.COMMENT && token.Val == "// line" && token.Span.EndOffset != 9 {
+			t.Errorf("Expected the line comment to end before its newline, got %v", token.Span)
+		}
+	}
+	expected := "a|// line|b|/* block */|c|// last"
+	if strings.Join(got, "|") != expected {
+		t.Errorf("Expected %s, got %s", expected, strings.Join(got, "|"))
+	}
+}
+
+func TestLexer_Trivia(t *testing.T) {
+	src := "a = 1; // one\n\n// The b.\n// nolint:unused\n  b /* two */ = 2;\n"
+	l := lexer.NewLexerWithMode(strings.NewReader(src), lexer.KeepTrivia)
+	var tokens []lexer.Token
+	for {
+		token, err := l.NextToken()
+		if err != nil && !errors.Is(err, io.EOF) {
+			t.Fatal(err)
+		}
+		tokens = append(tokens, token)
+		if token.Type == lexer.EOF {
+			break
+		}
+	}
+
+	// the trivia, with the tokens, cover the whole source
+	var b strings.Builder
+	for _, token := range tokens {
+		for _, trivia := range token.Leading {
+			b.WriteString(trivia.Text)
+		}
+		if token.Type != lexer.EOF {
+			b.WriteString(src[token.Span.StartOffset:token.Span.EndOffset])
+		}
+		for _, trivia := range token.Trailing {
+			if src[trivia.Span.StartOffset:trivia.Span.EndOffset] != trivia.Text {
+				t.Errorf("Expected the span of %q to cover it, got %v", trivia.Text, trivia.Span)
+			}
+			b.WriteString(trivia.Text)
+		}
+	}
+	if b.String() != src {
+		t.Errorf("Expected the tokens and the trivia to give back %q, got %q", src, b.String())
+	}
+
+	semicolon, bToken := tokens[3], tokens[4]
+	if kinds := fmt.Sprint(semicolon.Trailing); !strings.Contains(kinds, "// one") || len(semicolon.Trailing) != 3 {
+		t.Errorf("Expected the comment on the line of ; to trail it, got %v", semicolon.Trailing)
+	}
+	doc := bToken.Doc()
+	if len(doc) != 2 || doc[0].Text != "// The b." || doc[1].Text != "// nolint:unused" {
+		t.Errorf("Expected the doc comment of b, got %v", doc)
+	}
+	if !bToken.NoLint("unused") || bToken.NoLint("shadow") || tokens[0].NoLint("unused") {
+		t.Errorf("Expected only the unused rule to be suppressed at b")
+	}
+	if bToken.Trailing[1].Kind != lexer.TriviaBlockComment {
+		t.Errorf("Expected /* two */ to trail b, got %v", bToken.Trailing)
+	}
+}
+
+func TestNoLint(t *testing.T) {
+	cases := []struct {
+		comment string
+		rules   []string
+		ok      bool
+	}{
+		{"// nolint", nil, true},
+		{"//nolint:unused,shadow", []string{"unused", "shadow"}, true},
+		{"// nolint:unused // kept for the tests", []string{"unused"}, true},
+		{"/* nolint */", nil, false},
+		{"// nolint is not used here", nil, false},
+	}
+	for _, c := range cases {
+		rules, ok := lexer.NoLint(c.comment)
+		if ok != c.ok || fmt.Sprint(rules) != fmt.Sprint(c.rules) {
+			t.Errorf("Expected %q to give %v %v, got %v %v", c.comment, c.rules, c.ok, rules, ok)
+		}
+	}
+}
+
+func TestLexer_Recovering(t *testing.T) {
+	l := lexer.NewRecoveringLexer(strings.NewReader("a = 0x1g + 1 # \"x\\qy\" b 'ab' \"open\nc"))
+	var tokens []lexer.Token
+	for {
+		token, err := l.NextToken()
+		if err != nil && !errors.Is(err, io.EOF) {
+			t.Fatalf("Expected no error from a recovering lexer, got %v", err)
+		}
+		if token.Type == lexer.EOF {
+			break
+		}
+		tokens = append(tokens, token)
+	}
+	expected := []lexer.Token{
+		{Type: lexer.IDENTIFIER, Val: "a"},
+		{Type: lexer.OPERATOR, Val: "="},
+		{Type: lexer.ILLEGAL, Val: "0x1g"},
+		{Type: lexer.OPERATOR, Val: "+"},
+		{Type: lexer.INTEGER, Val: "1"},
+		{Type: lexer.ILLEGAL, Val: "#"},
+		{Type: lexer.ILLEGAL, Val: `"x\qy"`},
+		{Type: lexer.IDENTIFIER, Val: "b"},
+		{Type: lexer.ILLEGAL, Val: "'ab'"},
+		{Type: lexer.ILLEGAL, Val: `"open`},
+		{Type: lexer.IDENTIFIER, Val: "c"},
+	}
+	if len(tokens) != len(expected) {
+		t.Fatalf("Expected %d tokens, got %v", len(expected), tokens)
+	}
+	for i, token := range tokens {
+		if token.Type != expected[i].Type || token.Val != expected[i].Val {
+			t.Errorf("Expected token %d to be %v, got %v", i, expected[i], token)
+		}
+		if (token.Type == lexer.ILLEGAL) != (token.Diagnostic != "") {
+			t.Errorf("Expected a diagnostic only for an ILLEGAL token, got %q for %v", token.Diagnostic, token)
+		}
+	}
+}
+
+func TestLexer_Span(t *testing.T) {
+	src := "a = \"日本\"\n\t/* 注释\n*/ b // c\nx"
+	l := lexer.NewLexerWithMode(strings.NewReader(src), lexer.CollectComments)
+	file := lexer.NewSourceFile("span.go", []byte(src))
+	expected := []struct {
+		val  string
+		span lexer.Span
+	}{
+		{"a", lexer.Span{StartOffset: 0, EndOffset: 1, StartLine: 0, StartCol: 0, EndLine: 0, EndCol: 1}},
+		{"=", lexer.Span{StartOffset: 2, EndOffset: 3, StartLine: 0, StartCol: 2, EndLine: 0, EndCol: 3}},
+		{"日本", lexer.Span{StartOffset: 4, EndOffset: 12, StartLine: 0, StartCol: 4, EndLine: 0, EndCol: 8}},
+		{"b", lexer.Span{StartOffset: 27, EndOffset: 28, StartLine: 2, StartCol: 3, EndLine: 2, EndCol: 4}},
+		{"x", lexer.Span{StartOffset: 34, EndOffset: 35, StartLine: 3, StartCol: 0, EndLine: 3, EndCol: 1}},
+	}
+	for _, e := range expected {
+		token, err := l.NextToken()
+		if err != nil && !errors.Is(err, io.EOF) {
+			t.Fatal(err)
+		}
+		if token.Val != e.val || token.Span != e.span {
+			t.Errorf("Expected %s at %+v, got %s at %+v", e.val, e.span, token.Val, token.Span)
+		}
+		if got := file.Span(token.Span.StartOffset, token.Span.EndOffset); got != token.Span {
+			t.Errorf("Expected the source file to map the offsets of %s to %+v, got %+v", e.val, token.Span, got)
+		}
+	}
+
+	comments := l.Comments()
+	if len(comments) != 2 || file.Text(comments[0].Span) != "/* 注释\n*/" || file.Text(comments[1].Span) != "// c" {
+		t.Errorf("Expected the spans of the comments to cover their text, got %v", comments)
+	}
+
+	if file.LineCount() != 4 || file.Line(1) != "\t/* 注释" || file.Offset(1, 5) != 20 || file.Offset(1, 99) != 23 {
+		t.Errorf("Expected the lines of the source file to be mapped, got %d lines, %q, %d", file.LineCount(), file.Line(1), file.Offset(1, 5))
+	}
+}
+
+func TestReadNumber(t *testing.T) {
+	cases := []struct {
+		number, constant string
+		err              string
+	}{
+		{number: "0x1F", constant: "31"},
+		{number: "0b1_01", constant: "5"},
+		{number: "0777", constant: "511"},
+		{number: "0o17", constant: "15"},
+		{number: "1E-9", constant: "1e-09"},
+		{number: "1e+5", constant: "100000.0"},
+		{number: "0x1p-2", constant: "0.25"},
+		{number: ".5", constant: "0.5"},
+		{number: "017i", constant: "17.0i"},
+		{number: "09", err: "illegal number[octal] 09: invalid digit '9' in octal literal"},
+		{number: "0b102", err: "illegal number[binary] 0b102: invalid digit '2' in binary literal"},
+		{number: "0b1.0", err: "illegal number[binary] 0b1.0: invalid radix point in binary literal"},
+		{number: "0x1.8", err: "illegal number[hex] 0x1.8: hexadecimal mantissa requires a 'p' exponent"},
+		{number: "0o1e2", err: "illegal number[exponent] 0o1e2: 'e' exponent requires decimal mantissa"},
+		{number: "1e+", err: "illegal number[exponent] 1e+: exponent has no digits"},
+		{number: "1__0", err: "illegal number[separator] 1__0: '_' must separate successive digits"},
+		{number: "1.2.3", err: "illegal number[too many dots] 1.2.3: unexpected radix point"},
+		{number: "12ab", err: `illegal number[suffix] 12ab: invalid suffix "ab"`},
+		{number: "１２", err: "illegal number[digit] １２: invalid digit '１'"},
+		{number: "0x1_0000_0000_0000_0000", err: "illegal number[overflow] 0x1_0000_0000_0000_0000: constant overflows uint64"},
+		{number: "1e400", err: "illegal number[overflow] 1e400: constant overflows float64"},
+	}
+	for _, c := range cases {
+		token, err := lexer.NewLexer(strings.NewReader(c.number)).NextToken()
+		if errors.Is(err, io.EOF) {
+			err = nil
+		}
+		switch {
+		case c.err != "" && (err == nil || !strings.HasPrefix(err.Error(), c.err+", at line")):
+			t.Errorf("Expected the error %s for %s, got %v", c.err, c.number, err)
+		case c.err == "" && (err != nil || token.Constant != c.constant):
+			t.Errorf("Expected the constant %s for %s, got %s and %v", c.constant, c.number, token.Constant, err)
+		}
+	}
+}
+
+func TestLexer_Value(t *testing.T) {
+	l := lexer.NewLexer(strings.NewReader(`0x1F 18446744073709551615 0x1p-2 2i "a\tb" '\u4e2d' true false a`))
+	var values []any
+	for {
+		token, err := l.NextToken()
+		if err != nil && !errors.Is(err, io.EOF) {
+			t.Fatal(err)
+		}
+		if token.Type != lexer.EOF {
+			values = append(values, token.Value)
+		}
+		if token.Type == lexer.EOF || err != nil {
+			break
+		}
+	}
+	expected := []any{int64(31), uint64(18446744073709551615), 0.25, 2i, "a\tb", '中', true, false, nil}
+	if fmt.Sprintf("%#v", values) != fmt.Sprintf("%#v", expected) {
+		t.Errorf("Expected the values %#v, got %#v", expected, values)
+	}
+}
+
+func TestLexer_Locale(t *testing.T) {
+	defer i18n.SetLocale(i18n.SetLocale(i18n.ZH))
+	cases := []struct {
+		locale    i18n.Locale
+		kind, err string
+	}{
+		{locale: i18n.ZH, kind: "标识符", err: "非法数字[八进制] 09：八进制字面量中出现无效的数字 '9'，位于第 0 行，第 5 列"},
+		{locale: i18n.EN, kind: "identifier", err: "illegal number[octal] 09: invalid digit '9' in octal literal, at line 0, pos 5"},
+		// the unknown locales fall back on English
+		{locale: "fr", kind: "identifier", err: "illegal number[octal] 09: invalid digit '9' in octal literal, at line 0, pos 5"},
+	}
+	for _, c := range cases {
+		i18n.SetLocale(c.locale)
+		l := lexer.NewLexer(strings.NewReader("a 09"))
+		token, err := l.NextToken()
+		if err != nil || token.Type.ToString() != c.kind {
+			t.Errorf("Expected the kind %s in %s, got %s and %v", c.kind, c.locale, token.Type.ToString(), err)
+		}
+		if _, err = l.NextToken(); err == nil || err.Error() != c.err {
+			t.Errorf("Expected the error %s in %s, got %v", c.err, c.locale, err)
+		}
+	}
+}
+
+func TestRelex(t *testing.T) {
+	cases := []struct {
+		src   string
+		edit  lexer.Edit
+		start int
+		old   int
+		new   int
+	}{
+		// x = 1 + 2 to x = 10 + 2: only the number changes
+		{src: "x = 1 + 2", edit: lexer.Edit{Offset: 5, Inserted: "0"}, start: 2, old: 3, new: 3},
+		// a comment opened in the middle swallows the rest of the line
+		{src: "a / b\nc", edit: lexer.Edit{Offset: 3, Inserted: "/"}, start: 1, old: 3, new: 1},
+		// the diagnostic of an unclosed string tells where it ends
+		{src: "s := \"ab\nx", edit: lexer.Edit{Offset: 7, Deleted: 1}, start: 3, old: 4, new: 4},
+		// the tokens after the edit are only moved
+		{src: "a  b", edit: lexer.Edit{Offset: 1, Inserted: " "}, start: 1, old: 1, new: 1},
+	}
+	for _, c := range cases {
+		tokens := lexer.Tokenize([]byte(c.src), 0)
+		src := c.edit.Apply([]byte(c.src))
+		relexed, changed := lexer.Relex(src, tokens, c.edit, 0)
+		if expected := lexer.Tokenize(src, 0); !reflect.DeepEqual(relexed, expected) {
+			t.Errorf("Expected the tokens %v of %q, got %v", expected, src, relexed)
+		}
+		if expected := (lexer.TokenRange{Start: c.start, OldEnd: c.old, NewEnd: c.new}); changed != expected {
+			t.Errorf("Expected the changed range %+v for %q, got %+v", expected, src, changed)
+		}
+	}
+}
+
+func TestNewTokenLexer(t *testing.T) {
+	tokens := lexer.Tokenize([]byte("x = 1; // one\ny = @;"), lexer.KeepComments)
+	l := lexer.NewTokenLexer(tokens)
+	var read []lexer.Token
+	for {
+		token, err := l.NextToken()
+		if err != nil {
+			t.Fatalf("Unexpected error %v", err)
+		}
+		read = append(read, token)
+		if token.Type == lexer.EOF {
+			break
+		}
+	}
+	if !reflect.DeepEqual(read, tokens) {
+		t.Errorf("Expected the tokens %v, got %v", tokens, read)
+	}
+	if token, err := l.NextToken(); err != nil || token.Type != lexer.EOF {
+		t.Errorf("Expected the EOF token again, got %v, %v", token, err)
+	}
+}
+
+// checkRelex checks that the tokens re-lexed after the edit are those of the edited source, and that
+// the tokens out of the changed range are the old ones
+func checkRelex(t *testing.T, src []byte, edit lexer.Edit, mode lexer.Mode) {
+	tokens := lexer.Tokenize(src, mode)
+	edited := edit.Apply(src)
+	relexed, changed := lexer.Relex(edited, tokens, edit, mode)
+	if expected := lexer.Tokenize(edited, mode); !reflect.DeepEqual(relexed, expected) {
+		for i := range min(len(relexed), len(expected)) {
+			if !reflect.DeepEqual(relexed[i], expected[i]) {
+				t.Fatalf("Expected the token %d of %q after %+v in mode %d to be %+v, got %+v", i, edited, edit, mode, expected[i], relexed[i])
+			}
+		}
+		t.Fatalf("Expected %d tokens of %q after %+v in mode %d, got %d", len(expected), edited, edit, mode, len(relexed))
+	}
+	if changed.Start > changed.OldEnd || changed.Start > changed.NewEnd || len(tokens)-changed.OldEnd != len(relexed)-changed.NewEnd ||
+		!reflect.DeepEqual(tokens[:changed.Start], relexed[:changed.Start]) {
+		t.Fatalf("Unexpected changed range %+v of %q after %+v in mode %d", changed, edited, edit, mode)
+	}
+}
+
+// the pieces of the random edits, which open and close the literals and the comments, and split the tokens
+var relexPieces = []string{" ", "\n", "\t", "\r\n", "//", "/*", "*/", "/", "*", "\"", "'", "`", "\\", "\\n", "\\u12",
+	"0x", "0b1", "1e", "1.5", ".", "_", "i", "+", "-", "<", "=", "!", "&", "a", "x1", "if", "int", "中", ";", "{", "}", "?"}
+
+func TestRelex_RandomEdits(t *testing.T) {
+	files, err := filepath.Glob("../tests/lexer/*.in")
+	if err != nil || len(files) == 0 {
+		t.Fatal("Expected the samples of tests/lexer")
+	}
+	r := rand.New(rand.NewPCG(49, 50))
+	for _, name := range files {
+		src, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for range 25 {
+			offset := r.IntN(len(src) + 1)
+			for offset < len(src) && !utf8.RuneStart(src[offset]) {
+				offset++
+			}
+			deleted := min(r.IntN(8), len(src)-offset)
+			for offset+deleted < len(src) && !utf8.RuneStart(src[offset+deleted]) {
+				deleted++
+			}
+			inserted := ""
+			for range r.IntN(3) {
+				inserted += relexPieces[r.IntN(len(relexPieces))]
+			}
+			edit := lexer.Edit{Offset: int64(offset), Deleted: int64(deleted), Inserted: inserted}
+			for _, mode := range []lexer.Mode{0, lexer.KeepComments, lexer.KeepTrivia, lexer.KeepComments | lexer.KeepTrivia} {
+				checkRelex(t, src, edit, mode)
+			}
+		}
+	}
+}
+
+func FuzzRelex(f *testing.F) {
+	f.Add("x := 1 + 2 // one\ny := \"a\\tb\"", 5, 1, "0x1p", uint8(0))
+	f.Add("/* a */ b /c\n'd'", 10, 0, "/", uint8(lexer.KeepTrivia))
+	f.Add("s := `raw\nstring` + 0b1_0", 6, 3, "\"", uint8(lexer.KeepComments|lexer.KeepTrivia))
+	f.Fuzz(func(t *testing.T, src string, offset, deleted int, inserted string, mode uint8) {
+		if offset < 0 || deleted < 0 || offset > len(src) || deleted > len(src)-offset {
+			t.Skip()
+		}
+		mode &= uint8(lexer.KeepComments | lexer.KeepTrivia)
+		checkRelex(t, []byte(src), lexer.Edit{Offset: int64(offset), Deleted: int64(deleted), Inserted: inserted}, lexer.Mode(mode))
+	})
+}
@@ -0,0 +1,272 @@
+package lexer
+
+import (
+	"math"
+	"math/big"
+	"strings"
+	"unicode/utf8"
+
+	"app/utils"
+	"app/utils/i18n"
+)
+
+// numberError is what is wrong with a number, with its kind shown in the error, e.g. hex.
+// The message is in the current locale, the kind is translated when the error is made.
+type numberError struct {
+	kind, msg string
+}
+
+func (e *numberError) error(s string, line, pos int64) error {
+	return i18n.Errorf("illegal number[%s] %s: %s, at line %d, pos %d", i18n.T(e.kind), s, e.msg, line, pos)
+}
+
+// checkNumber checks a number as read by ReadNumber against the numeric literals of Go:
+// decimal, legacy octal (0777), 0x, 0o and 0b integers with _ between the digits, decimal and
+// hexadecimal floats with their exponents (1e-9, .5, 0x1p-2), and the imaginary ones (2i).
+// It returns the type of the number, or what is wrong with it.
+func checkNumber(s string) (ItemType, *numberError) {
+	if i := strings.IndexFunc(s, func(r rune) bool { return r >= utf8.RuneSelf && utils.IsDigit(r) }); i >= 0 {
+		r, _ := utf8.DecodeRuneInString(s[i:])
+		return 0, &numberError{"digit", i18n.Sprintf("invalid digit %q", r)}
+	}
+
+	typ, base, prefix := INTEGER, 10, byte(0)
+	i, invalid, digsep := 0, -1, 0
+	// digits reads the digits of the base and the separators, and keeps the first digit out of the base
+	digits := func(base int) {
+		for ; i < len(s); i++ {
+			c, ds := s[i], 1
+			switch {
+			case c == '_':
+				ds = 2
+			case base <= 10 && isDecimal(c):
+				if c >= '0'+byte(base) && invalid < 0 {
+					invalid = i
+				}
+			case base == 16 && isHexDigit(c):
+			default:
+				return
+			}
+			digsep |= ds
+		}
+	}
+	at := func(i int) byte {
+		if i < len(s) {
+			return s[i]
+		}
+		return 0
+	}
+
+	if s[0] != '.' {
+		if s[0] == '0' {
+			i++
+			switch lower(at(i)) {
+			case 'x':
+				i++
+				base, prefix = 16, 'x'
+			case 'o':
+				i++
+				base, prefix = 8, 'o'
+			case 'b':
+				i++
+				base, prefix = 2, 'b'
+			default:
+				// the legacy octal literals, unless they turn out to be floats
+				base, prefix = 8, '0'
+				digsep = 1
+			}
+		}
+		digits(base)
+	}
+	if at(i) == '.' {
+		typ = FLOAT
+		if prefix == 'o' || prefix == 'b' {
+			return 0, &numberError{numberKind(prefix), i18n.Sprintf("invalid radix point in %s", literalName(prefix))}
+		}
+		i++
+		digits(base)
+	}
+	if digsep&1 == 0 {
+		return 0, &numberError{numberKind(prefix), i18n.Sprintf("%s has no digits", literalName(prefix))}
+	}
+
+	if e := lower(at(i)); e == 'e' || e == 'p' {
+		switch {
+		case e == 'e' && prefix != 0 && prefix != '0':
+			return 0, &numberError{"exponent", i18n.Sprintf("%q exponent requires decimal mantissa", at(i))}
+		case e == 'p' && prefix != 'x':
+			return 0, &numberError{"exponent", i18n.Sprintf("%q exponent requires hexadecimal mantissa", at(i))}
+		}
+		i++
+		typ = FLOAT
+		if at(i) == '+' || at(i) == '-' {
+			i++
+		}
+		before := digsep
+		digsep = 0
+		digits(10)
+		if digsep&1 == 0 {
+			return 0, &numberError{"exponent", i18n.T("exponent has no digits")}
+		}
+		digsep |= before
+	} else if prefix == 'x' && typ == FLOAT {
+		return 0, &numberError{"hex", i18n.T("hexadecimal mantissa requires a 'p' exponent")}
+	}
+
+	if at(i) == 'i' {
+		i++
+		typ = IMAGINARY
+	}
+	// the digits of a float are decimal, as those of an imaginary number in the legacy octal form like 089i
+	if invalid >= 0 && (typ == INTEGER || typ == IMAGINARY && prefix != '0') {
+		return 0, &numberError{numberKind(prefix), i18n.Sprintf("invalid digit %q in %s", s[invalid], literalName(prefix))}
+	}
+	if digsep&2 != 0 && invalidSeparator(s[:i]) >= 0 {
+		return 0, &numberError{"separator", i18n.T("'_' must separate successive digits")}
+	}
+
+	switch {
+	case i == len(s):
+		return typ, nil
+	case s[i] == '.':
+		return 0, &numberError{"too many dots", i18n.T("unexpected radix point")}
+	default:
+		return 0, &numberError{"suffix", i18n.Sprintf("invalid suffix %q", s[i:])}
+	}
+}
+
+// invalidSeparator returns the index of the first _ of the number which is not between two digits,
+// or between the prefix and a digit, or -1 if there is none.
+func invalidSeparator(s string) int {
+	x := byte(' ') // the prefix, only x matters for the hexadecimal digits
+	d := byte('.') // the previous rune: _, 0 for a digit, or . for anything else
+	i := 0
+	if len(s) >= 2 && s[0] == '0' {
+		if x = lower(s[1]); x == 'x' || x == 'o' || x == 'b' {
+			d, i = '0', 2
+		}
+	}
+	for ; i < len(s); i++ {
+		p := d
+		d = s[i]
+		switch {
+		case d == '_':
+			if p != '0' {
+				return i
+			}
+		case isDecimal(d) || x == 'x' && isHexDigit(d):
+			d = '0'
+		default:
+			if p == '_' {
+				return i - 1
+			}
+			d = '.'
+		}
+	}
+	if d == '_' {
+		return len(s) - 1
+	}
+	return -1
+}
+
+// normalizeNumber returns the constant of a number checked by checkNumber: an integer in decimal,
+// a float in the shortest decimal form which reads back as the same float64, always with a radix
+// point or an exponent, and an imaginary number as such a float followed by i.
+func normalizeNumber(typ ItemType, s string) string {
+	switch typ {
+	case INTEGER:
+		n, ok := new(big.Int).SetString(s, 0)
+		if !ok {
+			return ""
+		}
+		return n.String()
+	case FLOAT:
+		f, _, err := big.ParseFloat(s, 0, 53, big.ToNearestEven)
+		if err != nil {
+			return ""
+		}
+		text := f.Text('g', -1)
+		if !strings.ContainsAny(text, ".e") {
+			text += ".0"
+		}
+		return text
+	case IMAGINARY:
+		// like Go, 017i is decimal and not octal, which big.ParseFloat does as well
+		return normalizeNumber(FLOAT, s[:len(s)-1]) + "i"
+	}
+	return ""
+}
+
+// numberValue returns the value of a number checked by checkNumber, see Token.Value,
+// or an error if it is out of the range of the largest type of its kind, uint64 or float64.
+func numberValue(typ ItemType, s string) (any, *numberError) {
+	if typ == INTEGER {
+		n, _ := new(big.Int).SetString(s, 0)
+		switch {
+		case n.IsInt64():
+			return n.Int64(), nil
+		case n.IsUint64():
+			return n.Uint64(), nil
+		}
+		return nil, &numberError{"overflow", i18n.T("constant overflows uint64")}
+	}
+	f, _, _ := big.ParseFloat(strings.TrimSuffix(s, "i"), 0, 53, big.ToNearestEven)
+	value, _ := f.Float64()
+	if math.IsInf(value, 0) {
+		return nil, &numberError{"overflow", i18n.T("constant overflows float64")}
+	}
+	if typ == IMAGINARY {
+		return complex(0, value), nil
+	}
+	return value, nil
+}
+
+// trimFloat drops the extra leading zeros of a float, as in 00.5.
+func trimFloat(s string) string {
+	if !strings.HasPrefix(s, "00") || !strings.Contains(s, ".") {
+		return s
+	}
+	whole, fraction, _ := strings.Cut(s, ".")
+	if whole = utils.RemoveLeadingZeros(whole); whole[0] == '_' {
+		whole = "0" + whole
+	}
+	return whole + "." + fraction
+}
+
+// literalName returns the name of the literals of the prefix, as in the errors of Go.
+func literalName(prefix byte) string {
+	switch prefix {
+	case 'x':
+		return i18n.T("hexadecimal literal")
+	case 'o', '0':
+		return i18n.T("octal literal")
+	case 'b':
+		return i18n.T("binary literal")
+	}
+	return i18n.T("decimal literal")
+}
+
+// numberKind returns the kind of the errors of the literals of the prefix.
+func numberKind(prefix byte) string {
+	switch prefix {
+	case 'x':
+		return "hex"
+	case 'o', '0':
+		return "octal"
+	case 'b':
+		return "binary"
+	}
+	return "integer"
+}
+
+func lower(c byte) byte {
+	return c | 0x20
+}
+
+func isDecimal(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+func isHexDigit(c byte) bool {
+	return isDecimal(c) || 'a' <= lower(c) && lower(c) <= 'f'
+}
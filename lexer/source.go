@@ -0,0 +1,92 @@
+package lexer
+
+import (
+	"sort"
+	"unicode/utf8"
+)
+
+// SourceFile is the content of a source file together with the offsets of its lines,
+// which maps the byte offsets of a Span to lines and columns and back.
+type SourceFile struct {
+	Name    string
+	Content []byte
+
+	_lines []int64 // the offset of the first byte of each line
+}
+
+// NewSourceFile creates a new SourceFile instance with the given name and content.
+func NewSourceFile(name string, content []byte) *SourceFile {
+	lines := []int64{0}
+	for i, b := range content {
+		if b == '\n' {
+			lines = append(lines, int64(i+1))
+		}
+	}
+	return &SourceFile{Name: name, Content: content, _lines: lines}
+}
+
+// LineCount returns the number of lines, a final line without newline included.
+func (f *SourceFile) LineCount() int {
+	return len(f._lines)
+}
+
+// LineStart returns the offset of the first byte of the line, counted from 0.
+func (f *SourceFile) LineStart(line int64) int64 {
+	if line < 0 {
+		return 0
+	}
+	if line >= int64(len(f._lines)) {
+		return int64(len(f.Content))
+	}
+	return f._lines[line]
+}
+
+// Line returns the content of the line, without its newline.
+func (f *SourceFile) Line(line int64) string {
+	start, end := f.LineStart(line), f.LineStart(line+1)
+	if end > start && f.Content[end-1] == '\n' {
+		end--
+	}
+	return string(f.Content[start:end])
+}
+
+// Position returns the line and the column, in runes, of the offset.
+// An offset out of the content is clamped to its beginning or its end.
+func (f *SourceFile) Position(offset int64) (line, col int64) {
+	offset = max(0, min(offset, int64(len(f.Content))))
+	line = int64(sort.Search(len(f._lines), func(i int) bool {
+		return f._lines[i] > offset
+	})) - 1
+	return line, int64(utf8.RuneCount(f.Content[f._lines[line]:offset]))
+}
+
+// Offset returns the offset of the line and the column, in runes.
+// A column past the end of the line is clamped to the end of the line.
+func (f *SourceFile) Offset(line, col int64) int64 {
+	start := f.LineStart(line)
+	offset := start
+	for ; col > 0 && offset < int64(len(f.Content)) && f.Content[offset] != '\n'; col-- {
+		_, size := utf8.DecodeRune(f.Content[offset:])
+		offset += int64(size)
+	}
+	return offset
+}
+
+// Span returns the span between the offsets.
+func (f *SourceFile) Span(start, end int64) Span {
+	startLine, startCol := f.Position(start)
+	endLine, endCol := f.Position(end)
+	return Span{
+		StartOffset: max(0, min(start, int64(len(f.Content)))),
+		EndOffset:   max(0, min(end, int64(len(f.Content)))),
+		StartLine:   startLine,
+		StartCol:    startCol,
+		EndLine:     endLine,
+		EndCol:      endCol,
+	}
+}
+
+// Text returns the content covered by the span.
+func (f *SourceFile) Text(span Span) string {
+	return string(f.Content[span.StartOffset:span.EndOffset])
+}
@@ -0,0 +1,127 @@
+package lexer
+
+import (
+	"slices"
+	"strings"
+)
+
+// TriviaKind is the kind of the text between two tokens.
+type TriviaKind uint8
+
+const (
+	TriviaWhitespace   TriviaKind = iota // spaces and tabs
+	TriviaNewline                        // a single \n
+	TriviaLineComment                    // a // comment, without its newline
+	TriviaBlockComment                   // a /* */ comment
+)
+
+func (k TriviaKind) String() string {
+	switch k {
+	case TriviaWhitespace:
+		return "whitespace"
+	case TriviaNewline:
+		return "newline"
+	case TriviaLineComment:
+		return "line comment"
+	case TriviaBlockComment:
+		return "block comment"
+	default:
+		return "unknown"
+	}
+}
+
+// Trivia is whitespace or a comment, which the parser ignores but a formatter or a linter needs.
+type Trivia struct {
+	Kind TriviaKind
+	Text string
+	Span Span
+}
+
+// IsComment reports whether the trivia is a comment.
+func (t Trivia) IsComment() bool {
+	return t.Kind == TriviaLineComment || t.Kind == TriviaBlockComment
+}
+
+// Doc returns the doc comment of the token: the run of // comments each on a line of its own,
+// directly before the token. A blank line or a /* */ comment ends the run.
+func (t *Token) Doc() []Trivia {
+	var doc []Trivia
+	newlines := 0
+	for i := len(t.Leading) - 1; i >= 0; i-- {
+		switch trivia := t.Leading[i]; trivia.Kind {
+		case TriviaWhitespace:
+		case TriviaNewline:
+			if newlines++; newlines > 1 {
+				return doc
+			}
+		case TriviaLineComment:
+			if !ownLine(t.Leading[:i]) {
+				return doc
+			}
+			doc = append([]Trivia{trivia}, doc...)
+			newlines = 0
+		default:
+			return doc
+		}
+	}
+	return doc
+}
+
+// ownLine reports whether nothing but whitespace follows the last newline of the trivia.
+// The leading trivia of a token starts at a new line, since the trivia after a token
+// up to the end of its line trail it.
+func ownLine(trivia []Trivia) bool {
+	for i := len(trivia) - 1; i >= 0; i-- {
+		switch trivia[i].Kind {
+		case TriviaWhitespace:
+		case TriviaNewline:
+			return true
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// NoLint parses a lint suppression comment, // nolint or //nolint:rule1,rule2, and returns the
+// suppressed rules. An empty list suppresses every rule; ok is false if the comment is not a suppression.
+// A suppression may be followed by an explanation: // nolint:unused // kept for the tests.
+func NoLint(comment string) (rules []string, ok bool) {
+	text, found := strings.CutPrefix(comment, "//")
+	if !found {
+		return nil, false
+	}
+	text = strings.TrimSpace(text)
+	text, _, _ = strings.Cut(text, "//")
+	text = strings.TrimSpace(text)
+	if text == "nolint" {
+		return nil, true
+	}
+	list, found := strings.CutPrefix(text, "nolint:")
+	if !found || strings.ContainsAny(list, " \t") {
+		return nil, false
+	}
+	for _, rule := range strings.Split(list, ",") {
+		if rule != "" {
+			rules = append(rules, rule)
+		}
+	}
+	return rules, true
+}
+
+// NoLint reports whether the rule is suppressed at the token, by a // nolint comment trailing it
+// or in its doc comment.
+func (t *Token) NoLint(rule string) bool {
+	comments := t.Doc()
+	for _, trivia := range t.Trailing {
+		if trivia.Kind == TriviaLineComment {
+			comments = append(comments, trivia)
+		}
+	}
+	for _, comment := range comments {
+		if rules, ok := NoLint(comment.Text); ok && (len(rules) == 0 || slices.Contains(rules, rule)) {
+			return true
+		}
+	}
+	return false
+}
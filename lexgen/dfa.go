@@ -0,0 +1,294 @@
+package lexgen
+
+import (
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// DFA is the deterministic automaton of the specs. Its input is a class of runes rather than a rune:
+// the runes are split into intervals, and the intervals that no state tells apart share a class.
+// The start state is 0, and a missing move is -1.
+type DFA struct {
+	bounds  []rune // the first rune of each interval, from 0
+	classes []int  // the class of each interval
+	ascii   [128]int
+	moves   [][]int // the next state by state and class
+	accept  []int   // the index of the spec accepted at each state, or -1
+}
+
+// NumStates returns the number of states of the automaton.
+func (d *DFA) NumStates() int {
+	return len(d.moves)
+}
+
+// NumClasses returns the number of classes of runes.
+func (d *DFA) NumClasses() int {
+	if len(d.moves) == 0 {
+		return 0
+	}
+	return len(d.moves[0])
+}
+
+// Accept returns the index of the spec accepted at the state, or -1.
+func (d *DFA) Accept(state int) int {
+	return d.accept[state]
+}
+
+// Next returns the state after reading r at the state, or -1.
+func (d *DFA) Next(state int, r rune) int {
+	return d.moves[state][d.class(r)]
+}
+
+func (d *DFA) class(r rune) int {
+	if r >= 0 && r < 128 {
+		return d.ascii[r]
+	}
+	i := sort.Search(len(d.bounds), func(i int) bool {
+		return d.bounds[i] > r
+	}) - 1
+	return d.classes[i]
+}
+
+// buildDFA converts the automaton by subset construction. A state accepting several specs accepts
+// the one that wins by better.
+func buildDFA(a *NFA, better func(i, j int) bool) *DFA {
+	// the intervals of runes that every edge either covers or does not
+	points := []rune{0}
+	for _, s := range a.states {
+		for _, e := range s.edges {
+			for _, r := range e.set {
+				points = append(points, r.lo, r.hi+1)
+			}
+		}
+	}
+	slices.Sort(points)
+	points = slices.Compact(points)
+	if points[len(points)-1] > unicode.MaxRune {
+		points = points[:len(points)-1]
+	}
+	interval := func(r rune) int {
+		i, _ := slices.BinarySearch(points, r)
+		return i
+	}
+	// the intervals covered by each edge, by state and edge
+	covered := make([][][]int, len(a.states))
+	for i, s := range a.states {
+		covered[i] = make([][]int, len(s.edges))
+		for j, e := range s.edges {
+			for _, r := range e.set {
+				for k := interval(r.lo); k < len(points) && points[k] <= r.hi; k++ {
+					covered[i][j] = append(covered[i][j], k)
+				}
+			}
+		}
+	}
+
+	d := &DFA{bounds: points, classes: make([]int, len(points))}
+	for k := range points {
+		d.classes[k] = k
+	}
+	index := map[string]int{}
+	var sets [][]int
+	add := func(set []int) int {
+		key := setKey(set)
+		if i, ok := index[key]; ok {
+			return i
+		}
+		index[key] = len(sets)
+		sets = append(sets, set)
+		accept := -1
+		for _, s := range set {
+			if i := a.states[s].accept; i >= 0 && (accept < 0 || better(i, accept)) {
+				accept = i
+			}
+		}
+		d.accept = append(d.accept, accept)
+		return len(sets) - 1
+	}
+	add(a.closure([]int{a.start}))
+	for i := 0; i < len(sets); i++ {
+		next := make([][]int, len(points))
+		for _, s := range sets[i] {
+			for j, e := range a.states[s].edges {
+				for _, k := range covered[s][j] {
+					next[k] = append(next[k], e.to)
+				}
+			}
+		}
+		moves := make([]int, len(points))
+		for k, targets := range next {
+			moves[k] = -1
+			if len(targets) > 0 {
+				moves[k] = add(a.closure(targets))
+			}
+		}
+		d.moves = append(d.moves, moves)
+	}
+	d.compress()
+	return d
+}
+
+func setKey(set []int) string {
+	var b strings.Builder
+	for _, s := range set {
+		b.WriteString(strconv.Itoa(s))
+		b.WriteByte(',')
+	}
+	return b.String()
+}
+
+// compress merges the intervals whose moves are the same in every state into a class.
+func (d *DFA) compress() {
+	columns := map[string]int{}
+	moves := make([][]int, len(d.moves))
+	for k, old := range d.classes {
+		column := make([]int, len(d.moves))
+		for state := range d.moves {
+			column[state] = d.moves[state][old]
+		}
+		key := setKey(column)
+		class, ok := columns[key]
+		if !ok {
+			class = len(columns)
+			columns[key] = class
+			for state := range moves {
+				moves[state] = append(moves[state], column[state])
+			}
+		}
+		d.classes[k] = class
+	}
+	d.moves = moves
+	d.indexASCII()
+}
+
+func (d *DFA) indexASCII() {
+	for r := range rune(128) {
+		i := sort.Search(len(d.bounds), func(i int) bool {
+			return d.bounds[i] > r
+		}) - 1
+		d.ascii[r] = d.classes[i]
+	}
+}
+
+// minimize merges the states that no input tells apart, by Hopcroft's algorithm, and numbers the
+// states in the order they are reached from the start state.
+func (d *DFA) minimize() {
+	n, classes := len(d.moves), d.NumClasses()
+	// a dead state stands for the missing moves, so that every state has a move for every class
+	dead := n
+	move := func(state, class int) int {
+		if state == dead || d.moves[state][class] < 0 {
+			return dead
+		}
+		return d.moves[state][class]
+	}
+	accept := func(state int) int {
+		if state == dead {
+			return -1
+		}
+		return d.accept[state]
+	}
+
+	// the states reaching each state by each class
+	inverse := make([][][]int, classes)
+	for c := range classes {
+		inverse[c] = make([][]int, n+1)
+		for s := range n + 1 {
+			t := move(s, c)
+			inverse[c][t] = append(inverse[c][t], s)
+		}
+	}
+
+	// the first partition groups the states by the spec they accept
+	block := make([]int, n+1)
+	var blocks [][]int
+	byAccept := map[int]int{}
+	for s := range n + 1 {
+		b, ok := byAccept[accept(s)]
+		if !ok {
+			b = len(blocks)
+			byAccept[accept(s)] = b
+			blocks = append(blocks, nil)
+		}
+		block[s] = b
+		blocks[b] = append(blocks[b], s)
+	}
+	pending := map[int]bool{}
+	for b := range blocks {
+		pending[b] = true
+	}
+
+	for len(pending) > 0 {
+		var splitter int
+		for b := range pending {
+			splitter = b
+			break
+		}
+		delete(pending, splitter)
+		members := append([]int(nil), blocks[splitter]...)
+		for c := range classes {
+			// the states moving into the splitter by c, grouped by their block
+			into := map[int][]int{}
+			for _, t := range members {
+				for _, s := range inverse[c][t] {
+					into[block[s]] = append(into[block[s]], s)
+				}
+			}
+			for b, in := range into {
+				if len(in) == len(blocks[b]) {
+					continue
+				}
+				inside := make(map[int]bool, len(in))
+				for _, s := range in {
+					inside[s] = true
+				}
+				var out []int
+				for _, s := range blocks[b] {
+					if !inside[s] {
+						out = append(out, s)
+					}
+				}
+				nb := len(blocks)
+				blocks[b] = in
+				blocks = append(blocks, out)
+				for _, s := range out {
+					block[s] = nb
+				}
+				if pending[b] || len(out) <= len(in) {
+					pending[nb] = true
+				} else {
+					pending[b] = true
+				}
+			}
+		}
+	}
+
+	// the blocks become the states, numbered from the start state breadth first, without the dead one
+	number := map[int]int{block[0]: 0}
+	order := []int{block[0]}
+	var moves [][]int
+	var accepts []int
+	for i := 0; i < len(order); i++ {
+		s := blocks[order[i]][0]
+		row := make([]int, classes)
+		for c := range classes {
+			row[c] = -1
+			t := block[move(s, c)]
+			if t == block[dead] {
+				continue
+			}
+			if _, ok := number[t]; !ok {
+				number[t] = len(order)
+				order = append(order, t)
+			}
+			row[c] = number[t]
+		}
+		moves = append(moves, row)
+		accepts = append(accepts, accept(s))
+	}
+	d.moves, d.accept = moves, accepts
+	d.compress()
+}
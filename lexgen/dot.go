@@ -0,0 +1,69 @@
+package lexgen
+
+import (
+	"io"
+	"strconv"
+	"unicode"
+
+	"app/utils/dot"
+)
+
+// DOT writes the DFA as a Graphviz graph. An accepting state has a double circle and the name of
+// its spec, and the moves from a state to another are merged into one edge labeled with their runes.
+func (l *Lexer) DOT(w io.Writer, options dot.Options) error {
+	d := l.dfa
+	g := dot.NewGraph(w, "DFA", "shape=circle, fontname=monospace")
+	for state := range d.NumStates() {
+		if !options.Shows(state) {
+			continue
+		}
+		label, attrs := strconv.Itoa(state), ""
+		if spec := d.Accept(state); spec >= 0 {
+			label += "\n" + l.specs[spec].Name
+			attrs = "shape=doublecircle"
+		}
+		g.Node(state, label, attrs, options.OnPath(state))
+	}
+	g.Start(0)
+	for state := range d.NumStates() {
+		if !options.Shows(state) {
+			continue
+		}
+		// the runes of the moves to each state, in the order of the first rune
+		var targets []int
+		runes := map[int]charSet{}
+		for k, class := range d.classes {
+			next := d.moves[state][class]
+			if next < 0 || !options.Shows(next) {
+				continue
+			}
+			if _, ok := runes[next]; !ok {
+				targets = append(targets, next)
+			}
+			hi := rune(unicode.MaxRune)
+			if k+1 < len(d.bounds) {
+				hi = d.bounds[k+1] - 1
+			}
+			runes[next] = append(runes[next], runeRange{d.bounds[k], hi})
+		}
+		for _, next := range targets {
+			g.Edge(state, next, runes[next].normalize().String(), options.MoveOnPath(state, next))
+		}
+	}
+	return g.Close()
+}
+
+// Trace runs the DFA on the lexeme and returns the path it took, which stops where no move is left.
+func (l *Lexer) Trace(lexeme string) *dot.Path {
+	path := &dot.Path{States: []int{0}}
+	state := 0
+	for _, r := range lexeme {
+		next := l.dfa.Next(state, r)
+		if next < 0 {
+			break
+		}
+		path.Move(state, next)
+		state = next
+	}
+	return path
+}
@@ -0,0 +1,169 @@
+// Package lexgen generates table-driven lexers from token specs, for the lexer-generator lab.
+//
+// Each spec is a regular expression with a priority. The patterns are compiled into an NFA by
+// Thompson's construction, which is turned into a DFA by subset construction and minimized by
+// Hopcroft's algorithm. The scanner then runs the DFA for the longest match at each position;
+// among the specs matching the longest lexeme, the one with the highest priority wins, and then
+// the one listed first.
+package lexgen
+
+import (
+	"fmt"
+	"unicode/utf8"
+
+	"app/lexer"
+	"app/utils/i18n"
+)
+
+// Spec defines a kind of token by a regular expression, see parseRegexp for its syntax.
+type Spec struct {
+	Name     string
+	Pattern  string
+	Priority int
+	Type     lexer.ItemType
+
+	// Skip drops the lexeme, as for whitespace and comments.
+	Skip bool
+	// Error reports the lexeme as a lexical error with this message, as for an unclosed string.
+	// The message is shown in the current locale, see i18n.
+	Error string
+	// Value converts the lexeme into the value of the token; the value is the lexeme if it is nil.
+	Value func(lexeme string) string
+	// Specific is the specific type of a STRING token, see lexer.NewToken.
+	Specific lexer.TokenSpecificType
+}
+
+// Lexer is a lexer generated from specs.
+type Lexer struct {
+	specs []Spec
+	nfa   *NFA
+	dfa   *DFA
+}
+
+// New generates the lexer of the specs.
+func New(specs []Spec) (*Lexer, error) {
+	patterns := make([]*node, len(specs))
+	for i, spec := range specs {
+		n, err := parseRegexp(spec.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("spec %s: %w", spec.Name, err)
+		}
+		patterns[i] = n
+	}
+	nfa := buildNFA(patterns)
+	dfa := buildDFA(nfa, func(i, j int) bool {
+		if specs[i].Priority != specs[j].Priority {
+			return specs[i].Priority > specs[j].Priority
+		}
+		return i < j
+	})
+	dfa.minimize()
+	return &Lexer{specs: specs, nfa: nfa, dfa: dfa}, nil
+}
+
+// NFA returns the automaton built by Thompson's construction.
+func (l *Lexer) NFA() *NFA {
+	return l.nfa
+}
+
+// DFA returns the minimized automaton, whose accepted specs are indexes of Specs.
+func (l *Lexer) DFA() *DFA {
+	return l.dfa
+}
+
+// Specs returns the specs of the lexer.
+func (l *Lexer) Specs() []Spec {
+	return l.specs
+}
+
+// Scan returns a scanner of src.
+func (l *Lexer) Scan(src []byte) *Scanner {
+	return &Scanner{lexer: l, src: src}
+}
+
+// Scanner reads the tokens of a source with a generated lexer.
+type Scanner struct {
+	lexer  *Lexer
+	src    []byte
+	offset int64
+	line   int64
+	col    int64
+}
+
+// NextToken reads the next token. At the end of the source, it returns an EOF token.
+// A lexeme matched by an error spec, a number out of range, or a rune that no spec matches, is returned as an error
+// and skipped, so that the next call goes on after it.
+func (s *Scanner) NextToken() (lexer.Token, error) {
+	for {
+		if s.offset >= int64(len(s.src)) {
+			span := s.span(s.offset, s.line, s.col)
+			return lexer.Token{Type: lexer.EOF, Span: span, Line: span.StartLine, Pos: span.StartCol}, nil
+		}
+		start, line, col := s.offset, s.line, s.col
+		spec, end := s.longestMatch()
+		if spec < 0 {
+			r, size := utf8.DecodeRune(s.src[start:])
+			s.advance(start + int64(size))
+			return lexer.Token{Span: s.span(start, line, col)}, i18n.Errorf("unknown character: %c, at line %d, pos %d", r, line, col)
+		}
+		s.advance(end)
+		lexeme := string(s.src[start:end])
+		span := s.span(start, line, col)
+		switch def := s.lexer.specs[spec]; {
+		case def.Skip:
+			continue
+		case def.Error != "":
+			return lexer.Token{Span: span}, i18n.Errorf("%s %s, at line %d, pos %d", i18n.T(def.Error), lexeme, line, col)
+		default:
+			if def.Value != nil {
+				lexeme = def.Value(lexeme)
+			}
+			return lexer.NewToken(def.Type, lexeme, span, def.Specific)
+		}
+	}
+}
+
+// longestMatch runs the DFA from the offset, and returns the spec accepted at the end of the
+// longest match and that end, or -1 if no spec matches.
+func (s *Scanner) longestMatch() (spec int, end int64) {
+	dfa := s.lexer.dfa
+	spec, end = -1, s.offset
+	state := 0
+	for offset := s.offset; offset < int64(len(s.src)); {
+		r, size := utf8.DecodeRune(s.src[offset:])
+		if state = dfa.Next(state, r); state < 0 {
+			break
+		}
+		offset += int64(size)
+		if accept := dfa.Accept(state); accept >= 0 {
+			spec, end = accept, offset
+		}
+	}
+	return spec, end
+}
+
+// advance moves the position to the offset, counting the lines and the columns on the way.
+func (s *Scanner) advance(offset int64) {
+	for s.offset < offset {
+		r, size := utf8.DecodeRune(s.src[s.offset:])
+		s.offset += int64(size)
+		if r == '\n' {
+			s.line++
+			s.col = 0
+		} else {
+			s.col++
+		}
+	}
+}
+
+// span returns the span from the start to the current position.
+func (s *Scanner) span(start, line, col int64) lexer.Span {
+	return lexer.Span{
+		StartOffset: start,
+		EndOffset:   s.offset,
+		StartLine:   line,
+		StartCol:    col,
+		EndLine:     s.line,
+		EndCol:      s.col,
+	}
+}
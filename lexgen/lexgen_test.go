@@ -0,0 +1,187 @@
+package lexgen_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"app/lexer"
+	"app/lexgen"
+	"app/utils/dot"
+)
+
+func newLexer(t *testing.T, specs []lexgen.Spec) *lexgen.Lexer {
+	t.Helper()
+	l, err := lexgen.New(specs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return l
+}
+
+func TestNew_Minimize(t *testing.T) {
+	// the textbook automaton of (a|b)*abb has 4 states once minimized
+	l := newLexer(t, []lexgen.Spec{{Name: "abb", Pattern: `(a|b)*abb`, Type: lexer.IDENTIFIER}})
+	if got := l.DFA().NumStates(); got != 4 {
+		t.Errorf("Expected 4 states, got %d", got)
+	}
+	if got := l.DFA().NumClasses(); got != 3 {
+		t.Errorf("Expected the classes a, b and the other runes, got %d", got)
+	}
+
+	// x{2,3} and xx|xxx are the same language
+	a := newLexer(t, []lexgen.Spec{{Name: "x", Pattern: `x{2,3}`}}).DFA()
+	b := newLexer(t, []lexgen.Spec{{Name: "x", Pattern: `xxx|xx`}}).DFA()
+	if a.NumStates() != b.NumStates() || a.NumStates() != 4 {
+		t.Errorf("Expected 4 states for both, got %d and %d", a.NumStates(), b.NumStates())
+	}
+}
+
+func TestNew_BadPattern(t *testing.T) {
+	for _, pattern := range []string{`(a`, `[a`, `*a`, `a{3,2}`, `\p{Nope}`, `\q`} {
+		if _, err := lexgen.New([]lexgen.Spec{{Name: "bad", Pattern: pattern}}); err == nil {
+			t.Errorf("Expected an error for %q", pattern)
+		}
+	}
+}
+
+func TestScanner(t *testing.T) {
+	l := newLexer(t, []lexgen.Spec{
+		{Name: "space", Pattern: `[ \n]+`, Skip: true},
+		{Name: "if", Pattern: `if`, Priority: 1, Type: lexer.RESERVED},
+		{Name: "id", Pattern: `[a-z]+`, Type: lexer.IDENTIFIER},
+		{Name: "num", Pattern: `[0-9]+(\.[0-9]+)?`, Type: lexer.FLOAT},
+		{Name: "op", Pattern: `<|<=|<<|=`, Type: lexer.OPERATOR},
+		{Name: "str", Pattern: `"[^"]*"`, Type: lexer.STRING, Value: func(s string) string { return s[1 : len(s)-1] }},
+	})
+	s := l.Scan([]byte("if iff <<= 1.5 \"中\" #\nx"))
+	var got []string
+	for {
+		token, err := s.NextToken()
+		if err != nil {
+			got = append(got, "error@"+token.Span.String())
+			continue
+		}
+		if token.Type == lexer.EOF {
+			break
+		}
+		got = append(got, fmt.Sprintf("%s:%s", token.Type.ToString(), token.Val))
+	}
+	expected := "保留字:if 标识符:iff 运算符:<< 运算符:= 浮点数:1.5 字符串:中 error@0:19-0:20 标识符:x"
+	if strings.Join(got, " ") != expected {
+		t.Errorf("Expected %s, got %s", expected, strings.Join(got, " "))
+	}
+}
+
+type lexeme struct {
+	typ        lexer.ItemType
+	val        string
+	constant   string
+	start, end int64
+	err        bool
+}
+
+func (l lexeme) String() string {
+	if l.err {
+		return fmt.Sprintf("error at %d-%d", l.start, l.end)
+	}
+	return fmt.Sprintf("(%s, %q) at %d-%d", l.typ.ToString(), l.val, l.start, l.end)
+}
+
+// handWritten returns the lexemes of the hand-written lexer, which goes on after an error.
+func handWritten(src []byte) []lexeme {
+	l := lexer.NewLexer(bytes.NewReader(src))
+	var lexemes []lexeme
+	for {
+		token, err := l.NextToken()
+		eof := errors.Is(err, io.EOF)
+		switch {
+		case err != nil && !eof:
+			lexemes = append(lexemes, lexeme{start: token.Span.StartOffset, end: token.Span.EndOffset, err: true})
+		case token.Type != lexer.EOF:
+			lexemes = append(lexemes, lexeme{token.Type, token.Val, token.Constant, token.Span.StartOffset, token.Span.EndOffset, false})
+		}
+		if token.Type == lexer.EOF || eof {
+			return lexemes
+		}
+	}
+}
+
+func generated(l *lexgen.Lexer, src []byte) []lexeme {
+	s := l.Scan(src)
+	var lexemes []lexeme
+	for {
+		token, err := s.NextToken()
+		switch {
+		case err != nil:
+			lexemes = append(lexemes, lexeme{start: token.Span.StartOffset, end: token.Span.EndOffset, err: true})
+		case token.Type == lexer.EOF:
+			return lexemes
+		default:
+			lexemes = append(lexemes, lexeme{token.Type, token.Val, token.Constant, token.Span.StartOffset, token.Span.EndOffset, false})
+		}
+	}
+}
+
+func TestLanguageSpecs(t *testing.T) {
+	l := newLexer(t, lexgen.LanguageSpecs())
+	files, err := filepath.Glob("../tests/lexer/*.in")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 {
+		t.Fatal("Expected the samples of tests/lexer")
+	}
+	for _, name := range files {
+		src, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected, got := handWritten(src), generated(l, src)
+		for i := range max(len(expected), len(got)) {
+			if i >= len(expected) || i >= len(got) || expected[i] != got[i] {
+				e, g := "nothing", "nothing"
+				if i < len(expected) {
+					e = expected[i].String()
+				}
+				if i < len(got) {
+					g = got[i].String()
+				}
+				t.Errorf("%s: expected %s, got %s", name, e, g)
+				break
+			}
+		}
+	}
+}
+
+func TestLexer_DOT(t *testing.T) {
+	l := newLexer(t, []lexgen.Spec{{Name: "abb", Pattern: `(a|b)*abb`}})
+	path := l.Trace("babb")
+	if len(path.States) != 5 || l.DFA().Accept(path.States[4]) != 0 {
+		t.Fatalf("Expected babb to reach the accepting state in 4 moves, got %v", path)
+	}
+	var b strings.Builder
+	if err := l.DOT(&b, dot.Options{Path: path}); err != nil {
+		t.Fatal(err)
+	}
+	graph := b.String()
+	if strings.Count(graph, " -> ") != 1+8 || !strings.Contains(graph, `label="3\labb", shape=doublecircle, color=red`) {
+		t.Errorf("Expected the start arrow and the 8 moves of the 4 states, with the path to the accepting state, got\n%s", graph)
+	}
+	if !strings.Contains(graph, `0 -> 0 [label="b", color=red`) {
+		t.Errorf("Expected the first move of the path, the loop on b, to be highlighted, got\n%s", graph)
+	}
+
+	b.Reset()
+	if err := l.DOT(&b, dot.Options{States: []int{0}}); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Count(b.String(), " -> ") != 1+1 {
+		t.Errorf("Expected only the loop of the state 0, got\n%s", b.String())
+	}
+}
@@ -0,0 +1,142 @@
+package lexgen
+
+import "slices"
+
+// NFA is the nondeterministic automaton of the specs, built by Thompson's construction:
+// every spec gets a fragment with one start and one end state, and the start state
+// of the automaton has an ε-move to the start of each fragment.
+type NFA struct {
+	states []nfaState
+	start  int
+}
+
+type nfaState struct {
+	epsilons []int
+	edges    []nfaEdge
+	accept   int // the index of the spec accepted at the state, or -1
+}
+
+type nfaEdge struct {
+	set charSet
+	to  int
+}
+
+// fragment is the part of the automaton built for a node, entered at start and left at end,
+// which has no move yet.
+type fragment struct {
+	start, end int
+}
+
+// NumStates returns the number of states of the automaton.
+func (a *NFA) NumStates() int {
+	return len(a.states)
+}
+
+func (a *NFA) newState() int {
+	a.states = append(a.states, nfaState{accept: -1})
+	return len(a.states) - 1
+}
+
+func (a *NFA) epsilon(from, to int) {
+	a.states[from].epsilons = append(a.states[from].epsilons, to)
+}
+
+// buildNFA builds the automaton accepting the i-th pattern with the spec i.
+func buildNFA(patterns []*node) *NFA {
+	a := &NFA{}
+	a.start = a.newState()
+	for i, pattern := range patterns {
+		f := a.compile(pattern)
+		a.epsilon(a.start, f.start)
+		a.states[f.end].accept = i
+	}
+	return a
+}
+
+func (a *NFA) compile(n *node) fragment {
+	switch n.op {
+	case opChars:
+		f := fragment{a.newState(), a.newState()}
+		a.states[f.start].edges = append(a.states[f.start].edges, nfaEdge{n.set, f.end})
+		return f
+	case opConcat:
+		f := a.compile(n.subs[0])
+		for _, sub := range n.subs[1:] {
+			next := a.compile(sub)
+			a.epsilon(f.end, next.start)
+			f.end = next.end
+		}
+		return f
+	case opAlt:
+		f := fragment{a.newState(), a.newState()}
+		for _, sub := range n.subs {
+			alt := a.compile(sub)
+			a.epsilon(f.start, alt.start)
+			a.epsilon(alt.end, f.end)
+		}
+		return f
+	case opRepeat:
+		return a.repeat(n.subs[0], n.min, n.max)
+	default:
+		f := fragment{a.newState(), a.newState()}
+		a.epsilon(f.start, f.end)
+		return f
+	}
+}
+
+// repeat builds x{min,max} as min copies of x followed by x* when max is -1,
+// or by max-min copies of x?.
+func (a *NFA) repeat(x *node, min, max int) fragment {
+	f := fragment{a.newState(), -1}
+	f.end = f.start
+	for range min {
+		next := a.compile(x)
+		a.epsilon(f.end, next.start)
+		f.end = next.end
+	}
+	if max == -1 {
+		// x*: the end of x goes back to its start, and x may be skipped
+		loop := a.compile(x)
+		end := a.newState()
+		a.epsilon(f.end, loop.start)
+		a.epsilon(f.end, end)
+		a.epsilon(loop.end, loop.start)
+		a.epsilon(loop.end, end)
+		f.end = end
+		return f
+	}
+	for range max - min {
+		optional := a.compile(x)
+		end := a.newState()
+		a.epsilon(f.end, optional.start)
+		a.epsilon(f.end, end)
+		a.epsilon(optional.end, end)
+		f.end = end
+	}
+	return f
+}
+
+// closure adds the states reached from the states by ε-moves, and returns them sorted.
+func (a *NFA) closure(states []int) []int {
+	seen := make(map[int]bool, len(states))
+	stack := append([]int(nil), states...)
+	for _, s := range states {
+		seen[s] = true
+	}
+	for len(stack) > 0 {
+		s := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		for _, t := range a.states[s].epsilons {
+			if !seen[t] {
+				seen[t] = true
+				stack = append(stack, t)
+			}
+		}
+	}
+	closed := make([]int, 0, len(seen))
+	for s := range seen {
+		closed = append(closed, s)
+	}
+	slices.Sort(closed)
+	return closed
+}
@@ -0,0 +1,429 @@
+package lexgen
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// charSet is a set of runes as sorted, disjoint and non-adjacent ranges.
+type charSet []runeRange
+
+type runeRange struct {
+	lo, hi rune // both included
+}
+
+func single(r rune) charSet {
+	return charSet{{r, r}}
+}
+
+// normalize sorts the ranges and merges the overlapping and adjacent ones.
+func (s charSet) normalize() charSet {
+	sort.Slice(s, func(i, j int) bool { return s[i].lo < s[j].lo })
+	var merged charSet
+	for _, r := range s {
+		if n := len(merged); n > 0 && r.lo <= merged[n-1].hi+1 {
+			merged[n-1].hi = max(merged[n-1].hi, r.hi)
+			continue
+		}
+		merged = append(merged, r)
+	}
+	return merged
+}
+
+func (s charSet) union(t charSet) charSet {
+	return append(append(charSet{}, s...), t...).normalize()
+}
+
+func (s charSet) negate() charSet {
+	var negated charSet
+	next := rune(0)
+	for _, r := range s {
+		if r.lo > next {
+			negated = append(negated, runeRange{next, r.lo - 1})
+		}
+		next = r.hi + 1
+	}
+	if next <= unicode.MaxRune {
+		negated = append(negated, runeRange{next, unicode.MaxRune})
+	}
+	return negated
+}
+
+func (s charSet) minus(t charSet) charSet {
+	return s.negate().union(t).negate()
+}
+
+func fromTable(table *unicode.RangeTable) charSet {
+	var s charSet
+	add := func(lo, hi, stride rune) {
+		if stride == 1 {
+			s = append(s, runeRange{lo, hi})
+			return
+		}
+		for c := lo; c <= hi; c += stride {
+			s = append(s, runeRange{c, c})
+		}
+	}
+	for _, r := range table.R16 {
+		add(rune(r.Lo), rune(r.Hi), rune(r.Stride))
+	}
+	for _, r := range table.R32 {
+		add(rune(r.Lo), rune(r.Hi), rune(r.Stride))
+	}
+	return s.normalize()
+}
+
+// op is the kind of a node of a parsed regular expression.
+type op uint8
+
+const (
+	opChars  op = iota // a rune of set
+	opEmpty            // the empty string
+	opConcat           // subs one after another
+	opAlt              // one of subs
+	opRepeat           // subs[0] from min to max times, max is -1 for no bound
+)
+
+type node struct {
+	op       op
+	set      charSet
+	subs     []*node
+	min, max int
+}
+
+// parseRegexp parses a regular expression of the syntax below, where a rune is matched
+// as a whole, not byte by byte:
+//
+//	x|y  xy  x*  x+  x?  x{n}  x{n,}  x{n,m}  (x)
+//	.             any rune but \n
+//	[a-z_] [^"]   a class, or a negated one
+//	[\p{L}-[a]]   a class without the runes of another one
+//	\n \t \r \f \v \\ \. ...   an escaped rune
+//	\d \w \s      [0-9], [0-9A-Za-z_] and the white space of unicode.IsSpace
+//	\p{L} \P{L}   a category, a script or a property of the unicode package, or its negation
+func parseRegexp(pattern string) (*node, error) {
+	p := &regexpParser{src: []rune(pattern)}
+	n, err := p.alternation()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.src) {
+		return nil, p.errorf("unexpected %q", p.src[p.pos])
+	}
+	return n, nil
+}
+
+type regexpParser struct {
+	src []rune
+	pos int
+}
+
+func (p *regexpParser) errorf(format string, args ...any) error {
+	return fmt.Errorf("regexp %q, at %d: %s", string(p.src), p.pos, fmt.Sprintf(format, args...))
+}
+
+func (p *regexpParser) peek(r rune) bool {
+	return p.pos < len(p.src) && p.src[p.pos] == r
+}
+
+func (p *regexpParser) alternation() (*node, error) {
+	var alts []*node
+	for {
+		n, err := p.concatenation()
+		if err != nil {
+			return nil, err
+		}
+		alts = append(alts, n)
+		if !p.peek('|') {
+			break
+		}
+		p.pos++
+	}
+	if len(alts) == 1 {
+		return alts[0], nil
+	}
+	return &node{op: opAlt, subs: alts}, nil
+}
+
+func (p *regexpParser) concatenation() (*node, error) {
+	var items []*node
+	for p.pos < len(p.src) && !p.peek('|') && !p.peek(')') {
+		n, err := p.repetition()
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, n)
+	}
+	switch len(items) {
+	case 0:
+		return &node{op: opEmpty}, nil
+	case 1:
+		return items[0], nil
+	default:
+		return &node{op: opConcat, subs: items}, nil
+	}
+}
+
+func (p *regexpParser) repetition() (*node, error) {
+	n, err := p.atom()
+	if err != nil {
+		return nil, err
+	}
+	for p.pos < len(p.src) {
+		var lo, hi int
+		switch p.src[p.pos] {
+		case '*':
+			lo, hi = 0, -1
+		case '+':
+			lo, hi = 1, -1
+		case '?':
+			lo, hi = 0, 1
+		case '{':
+			if lo, hi, err = p.bounds(); err != nil {
+				return nil, err
+			}
+			n = &node{op: opRepeat, subs: []*node{n}, min: lo, max: hi}
+			continue
+		default:
+			return n, nil
+		}
+		p.pos++
+		n = &node{op: opRepeat, subs: []*node{n}, min: lo, max: hi}
+	}
+	return n, nil
+}
+
+// bounds parses {n}, {n,} or {n,m}.
+func (p *regexpParser) bounds() (lo, hi int, err error) {
+	end := p.pos
+	for end < len(p.src) && p.src[end] != '}' {
+		end++
+	}
+	if end == len(p.src) {
+		return 0, 0, p.errorf("missing }")
+	}
+	text := string(p.src[p.pos+1 : end])
+	low, high, comma := text, text, false
+	for i, r := range text {
+		if r == ',' {
+			low, high, comma = text[:i], text[i+1:], true
+			break
+		}
+	}
+	if lo, err = strconv.Atoi(low); err != nil {
+		return 0, 0, p.errorf("bad repetition {%s}", text)
+	}
+	hi = lo
+	if comma && high == "" {
+		hi = -1
+	} else if hi, err = strconv.Atoi(high); err != nil || hi < lo {
+		return 0, 0, p.errorf("bad repetition {%s}", text)
+	}
+	p.pos = end + 1
+	return lo, hi, nil
+}
+
+func (p *regexpParser) atom() (*node, error) {
+	r := p.src[p.pos]
+	p.pos++
+	switch r {
+	case '(':
+		n, err := p.alternation()
+		if err != nil {
+			return nil, err
+		}
+		if !p.peek(')') {
+			return nil, p.errorf("missing )")
+		}
+		p.pos++
+		return n, nil
+	case '[':
+		set, err := p.class()
+		if err != nil {
+			return nil, err
+		}
+		return &node{op: opChars, set: set}, nil
+	case '.':
+		return &node{op: opChars, set: single('\n').negate()}, nil
+	case '\\':
+		set, err := p.escape()
+		if err != nil {
+			return nil, err
+		}
+		return &node{op: opChars, set: set}, nil
+	case '*', '+', '?', '{':
+		return nil, p.errorf("missing operand of %q", r)
+	default:
+		return &node{op: opChars, set: single(r)}, nil
+	}
+}
+
+// class parses a class after its opening bracket.
+func (p *regexpParser) class() (charSet, error) {
+	negated := p.peek('^')
+	if negated {
+		p.pos++
+	}
+	var set charSet
+	for first := true; first || !p.peek(']'); first = false {
+		if p.pos >= len(p.src) {
+			return nil, p.errorf("missing ]")
+		}
+		if p.peek('-') && p.pos+1 < len(p.src) && p.src[p.pos+1] == '[' {
+			p.pos += 2
+			sub, err := p.class()
+			if err != nil {
+				return nil, err
+			}
+			set = set.normalize().minus(sub)
+			if !p.peek(']') {
+				return nil, p.errorf("a subtracted class must end the class")
+			}
+			break
+		}
+		lo, err := p.classRune()
+		if err != nil {
+			return nil, err
+		}
+		if len(lo) > 1 || !p.peek('-') || p.pos+1 >= len(p.src) || p.src[p.pos+1] == ']' || p.src[p.pos+1] == '[' {
+			set = append(set, lo...)
+			continue
+		}
+		p.pos++
+		hi, err := p.classRune()
+		if err != nil {
+			return nil, err
+		}
+		if len(hi) > 1 || hi[0].lo < lo[0].lo {
+			return nil, p.errorf("bad range")
+		}
+		set = append(set, runeRange{lo[0].lo, hi[0].lo})
+	}
+	p.pos++
+	set = set.normalize()
+	if negated {
+		set = set.negate()
+	}
+	return set, nil
+}
+
+// classRune parses a rune of a class, or an escape standing for several runes.
+func (p *regexpParser) classRune() (charSet, error) {
+	r := p.src[p.pos]
+	p.pos++
+	if r == '\\' {
+		return p.escape()
+	}
+	return single(r), nil
+}
+
+// escape parses an escape after its backslash.
+func (p *regexpParser) escape() (charSet, error) {
+	if p.pos >= len(p.src) {
+		return nil, p.errorf("trailing \\")
+	}
+	r := p.src[p.pos]
+	p.pos++
+	switch r {
+	case 'n':
+		return single('\n'), nil
+	case 't':
+		return single('\t'), nil
+	case 'r':
+		return single('\r'), nil
+	case 'f':
+		return single('\f'), nil
+	case 'v':
+		return single('\v'), nil
+	case 'd':
+		return charSet{{'0', '9'}}, nil
+	case 'w':
+		return charSet{{'0', '9'}, {'A', 'Z'}, {'_', '_'}, {'a', 'z'}}, nil
+	case 's':
+		return fromTable(unicode.White_Space), nil
+	case 'p', 'P':
+		if !p.peek('{') {
+			return nil, p.errorf("missing { after \\%c", r)
+		}
+		end := p.pos
+		for end < len(p.src) && p.src[end] != '}' {
+			end++
+		}
+		if end == len(p.src) {
+			return nil, p.errorf("missing }")
+		}
+		name := string(p.src[p.pos+1 : end])
+		p.pos = end + 1
+		table := unicode.Categories[name]
+		if table == nil {
+			table = unicode.Scripts[name]
+		}
+		if table == nil {
+			table = unicode.Properties[name]
+		}
+		if table == nil {
+			return nil, p.errorf("unknown unicode class %s", name)
+		}
+		if r == 'P' {
+			return fromTable(table).negate(), nil
+		}
+		return fromTable(table), nil
+	default:
+		if r < utf8.RuneSelf && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			return nil, p.errorf("unknown escape \\%c", r)
+		}
+		return single(r), nil
+	}
+}
+
+// String returns the set written as a class, or as its negation if shorter, for the labels of diagrams.
+// A set of too many ranges is cut short with an ellipsis.
+func (s charSet) String() string {
+	prefix := ""
+	if negated := s.negate(); len(negated) < len(s) {
+		s, prefix = negated, "^"
+	}
+	if len(s) == 1 && s[0].lo == s[0].hi && prefix == "" {
+		return classRune(s[0].lo)
+	}
+	const limit = 8
+	var b strings.Builder
+	b.WriteString("[" + prefix)
+	for i, r := range s {
+		if i == limit {
+			fmt.Fprintf(&b, "…%d more", len(s)-limit)
+			break
+		}
+		b.WriteString(classRune(r.lo))
+		if r.hi > r.lo {
+			b.WriteString("-" + classRune(r.hi))
+		}
+	}
+	b.WriteString("]")
+	return b.String()
+}
+
+func classRune(r rune) string {
+	switch {
+	case strings.ContainsRune(`\-[]^`, r):
+		return `\` + string(r)
+	case unicode.IsPrint(r):
+		return string(r)
+	case r == '\n':
+		return `\n`
+	case r == '\t':
+		return `\t`
+	case r == '\r':
+		return `\r`
+	case r == '\f':
+		return `\f`
+	case r == '\v':
+		return `\v`
+	default:
+		return fmt.Sprintf(`\x{%x}`, r)
+	}
+}
@@ -0,0 +1,150 @@
+package lexgen
+
+import (
+	"strings"
+
+	"app/lexer"
+	"app/utils"
+)
+
+// The priorities of the specs of LanguageSpecs: a keyword wins over an identifier of the same
+// length, and a token over an error spec matching the same lexeme.
+const (
+	priorityError = iota
+	priorityToken
+	priorityKeyword
+)
+
+const (
+	hex         = `\p{Hex_Digit}`
+	decimals    = `[0-9](_?[0-9])*`
+	hexDecimals = `[0-9a-fA-F](_?[0-9a-fA-F])*`
+	integer     = `0|[1-9](_?[0-9])*|0(_?[0-7])+|0[xX]_?` + hexDecimals + `|0[oO](_?[0-7])+|0[bB](_?[01])+`
+	exponent    = `[eE][+-]?` + decimals
+	float       = decimals + `\.(` + decimals + `)?(` + exponent + `)?|` + decimals + exponent + `|\.` + decimals + `(` + exponent + `)?|` +
+		`0[xX](_?` + hexDecimals + `(\.(` + hexDecimals + `)?)?|\.` + hexDecimals + `)[pP][+-]?` + decimals
+	// a number is read up to the sign of an exponent, which is not one after an e of a hexadecimal number
+	numberChars  = `([\p{L}\p{Nd}_.]|[eEpP][+-])*`
+	hexChars     = `([\p{L}\p{Nd}_.]|[pP][+-])*`
+	stringChars  = `([^"\\\n]|\\[ntrbfav"\\]|\\u` + hex + `{4}|\\U` + hex + `{8}|\\0[0-7]{2})*`
+	stringEscape = `\\(u` + hex + `{0,3}|U` + hex + `{0,7}|0[0-7]?)?`
+)
+
+// LanguageSpecs returns the specs of the tokens read by lexer.Lexer, including its lexical errors:
+// a malformed lexeme is matched as a whole by an error spec, as far as the hand-written lexer reads it.
+func LanguageSpecs() []Spec {
+	specs := []Spec{
+		{Name: "whitespace", Pattern: `\s+`, Skip: true},
+		{Name: "line comment", Pattern: `//[^\n]*`, Skip: true},
+		{Name: "block comment", Pattern: `/\*([^*]|\*+[^*/])*\*+/`, Skip: true},
+		// like the hand-written lexer, an unclosed block comment ends the input silently
+		{Name: "unclosed block comment", Pattern: `/\*([^*]|\*+[^*/])*\**`, Skip: true},
+	}
+	for _, word := range []string{"int", "float", "string", "bool", "byte", "int8", "int16", "int32", "int64",
+		"uint", "uint8", "uint16", "uint32", "uint64", "float32", "float64"} {
+		specs = append(specs, Spec{Name: word, Pattern: word, Priority: priorityKeyword, Type: lexer.TYPE})
+	}
+	for _, word := range []string{"break", "case", "chan", "const", "continue", "default", "defer", "do", "else",
+		"false", "for", "func", "go", "goto", "if", "import", "interface", "map", "package", "range", "return",
+		"select", "struct", "switch", "true", "type", "var", "rune", "while"} {
+		specs = append(specs, Spec{Name: word, Pattern: word, Priority: priorityKeyword, Type: lexer.RESERVED})
+	}
+	for _, op := range []string{"+", "-", "*", "/", "%", "=", "==", "!=", "<", "<=", ">", ">=", "&&", "||",
+		"++", "--", "!", "&", "|", "^", "<<", ">>"} {
+		specs = append(specs, Spec{Name: op, Pattern: quote(op), Priority: priorityToken, Type: lexer.OPERATOR})
+	}
+	for _, delim := range []string{"(", ")", "{", "}", "[", "]", ",", ";", ".", ":"} {
+		specs = append(specs, Spec{Name: delim, Pattern: quote(delim), Priority: priorityToken, Type: lexer.DELIMITER})
+	}
+	return append(specs,
+		Spec{Name: "identifier", Pattern: `[\p{L}_][\p{L}\p{Nd}_]*`, Priority: priorityToken, Type: lexer.IDENTIFIER},
+
+		Spec{Name: "integer", Pattern: integer, Priority: priorityToken, Type: lexer.INTEGER},
+		Spec{Name: "float", Pattern: float, Priority: priorityToken, Type: lexer.FLOAT, Value: floatValue},
+		Spec{Name: "imaginary", Pattern: `(` + decimals + `|` + integer + `|` + float + `)i`, Priority: priorityToken, Type: lexer.IMAGINARY},
+		Spec{Name: "illegal number", Pattern: `(\.\p{Nd}|[\p{Nd}-[0]])` + numberChars + `|0|0[\p{L}\p{Nd}_.-[xX]]` + numberChars +
+			`|0[eEpP][+-]` + numberChars, Error: "illegal number"},
+		Spec{Name: "illegal hex number", Pattern: `0[xX]` + hexChars, Error: "illegal number"},
+
+		Spec{Name: "string", Pattern: `"` + stringChars + `"`, Priority: priorityToken, Type: lexer.STRING,
+			Value: stringValue, Specific: lexer.ConstantStringDoubleQuote},
+		Spec{Name: "unclosed string", Pattern: `"` + stringChars + `(\n|` + stringEscape + `)?`, Error: "string not closed"},
+		Spec{Name: "illegal escape", Pattern: `"` + stringChars + `\\([^ntrbfav"\\uU0]|u` + hex + `{0,3}[^\p{Hex_Digit}\\]|U` +
+			hex + `{0,7}[^\p{Hex_Digit}\\]|0[0-7]?[^0-7\\])`, Error: "illegal escape"},
+		Spec{Name: "raw string", Pattern: "`[^`]*`", Priority: priorityToken, Type: lexer.STRING,
+			Value: rawStringValue, Specific: lexer.ConstantStringBacktick},
+		Spec{Name: "unclosed raw string", Pattern: "`[^`]*", Error: "string not closed"},
+
+		Spec{Name: "char", Pattern: `'([^'\\]|\\[^uU])?'`, Priority: priorityToken, Type: lexer.CHAR, Value: charValue},
+		Spec{Name: "unicode char", Pattern: `'\\(u[^'\\]{4}|U[^'\\]{8})'`, Priority: priorityToken, Type: lexer.CHAR, Value: charValue},
+		Spec{Name: "illegal char", Pattern: `'([^'\\]|\\(.|\n))*'?`, Error: "illegal char"},
+	)
+}
+
+// quote escapes the metacharacters of a literal pattern.
+func quote(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if strings.ContainsRune(`\.+*?()|[]{}^$`, r) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// floatValue drops the extra leading zeros of a float, as in 00.5.
+func floatValue(lexeme string) string {
+	if !strings.HasPrefix(lexeme, "00") || !strings.Contains(lexeme, ".") {
+		return lexeme
+	}
+	whole, fraction, _ := strings.Cut(lexeme, ".")
+	if whole = utils.RemoveLeadingZeros(whole); whole[0] == '_' {
+		whole = "0" + whole
+	}
+	return whole + "." + fraction
+}
+
+func stringValue(lexeme string) string {
+	runes := []rune(lexeme[1 : len(lexeme)-1])
+	var b strings.Builder
+	for i := 0; i < len(runes); i++ {
+		if runes[i] != '\\' {
+			b.WriteRune(runes[i])
+			continue
+		}
+		i++
+		switch runes[i] {
+		case '\\':
+			b.WriteByte('\\')
+		case 'u':
+			b.WriteRune(utils.HexToRune(string(runes[i+1 : i+5])))
+			i += 4
+		case 'U':
+			b.WriteRune(utils.HexToRune(string(runes[i+1 : i+9])))
+			i += 8
+		case '0':
+			b.WriteRune(utils.OctalToRune(string(runes[i+1 : i+3])))
+			i += 2
+		default:
+			b.WriteString(utils.AppendEscape(runes[i]))
+		}
+	}
+	return b.String()
+}
+
+func rawStringValue(lexeme string) string {
+	return lexeme[1 : len(lexeme)-1]
+}
+
+func charValue(lexeme string) string {
+	runes := []rune(lexeme[1 : len(lexeme)-1])
+	switch {
+	case len(runes) < 2:
+		return string(runes)
+	case runes[1] == 'u' || runes[1] == 'U':
+		return string(utils.HexToRune(string(runes[2:])))
+	default:
+		return utils.AppendEscape(runes[1])
+	}
+}
@@ -0,0 +1,138 @@
+package lsp
+
+import (
+	"errors"
+	"unicode/utf8"
+
+	"app/lexer"
+	"app/parser"
+	"app/utils/i18n"
+)
+
+// tokenMode is the mode the tokens of a document are read with, the comments are highlighted too.
+const tokenMode = lexer.KeepComments
+
+// document is an open document with its tokens, kept up to date by Relex as it is edited, and the index and
+// the errors of its last parse.
+type document struct {
+	uri     string
+	version int
+	source  *lexer.SourceFile
+	tokens  []lexer.Token
+	index   *parser.Index
+	errs    []error
+}
+
+func newDocument(uri string, version int, text string) *document {
+	d := &document{uri: uri, version: version}
+	d.replace(text)
+	return d
+}
+
+// replace replaces the whole text of the document.
+func (d *document) replace(text string) {
+	d.source = lexer.NewSourceFile(d.uri, []byte(text))
+	d.tokens = lexer.Tokenize(d.source.Content, tokenMode)
+}
+
+// edit replaces the range of the document by the text and lexes again the region of the edit.
+func (d *document) edit(r Range, text string) {
+	start, end := d.offset(r.Start), d.offset(r.End)
+	edit := lexer.Edit{Offset: start, Deleted: max(0, end-start), Inserted: text}
+	content := edit.Apply(d.source.Content)
+	d.source = lexer.NewSourceFile(d.uri, content)
+	d.tokens, _ = lexer.Relex(content, d.tokens, edit, tokenMode)
+}
+
+// parse parses the tokens of the document again. A panic of the parser is an error of the document,
+// so that a bug shown by one text does not end the session of the editor.
+func (d *document) parse(p *parser.Parser) {
+	defer func() {
+		if r := recover(); r != nil {
+			d.index, d.errs = nil, []error{i18n.Errorf("internal error of the parser: %v", r)}
+		}
+	}()
+	index, err := p.Index(lexer.NewTokenLexer(d.tokens))
+	d.index, d.errs = index, unjoin(err)
+}
+
+// unjoin returns the errors joined by errors.Join, the nested ones included.
+func unjoin(err error) []error {
+	if err == nil {
+		return nil
+	}
+	joined, ok := err.(interface{ Unwrap() []error })
+	if !ok {
+		return []error{err}
+	}
+	var errs []error
+	for _, err := range joined.Unwrap() {
+		errs = append(errs, unjoin(err)...)
+	}
+	return errs
+}
+
+// diagnostics returns the errors of the document: the lexical errors, which are at an ILLEGAL token, and the
+// syntax and semantic errors. The errors without a span are at the beginning of the document.
+func (d *document) diagnostics() []Diagnostic {
+	illegal := map[lexer.Span]bool{}
+	for _, token := range d.tokens {
+		if token.Type == lexer.ILLEGAL {
+			illegal[token.Span] = true
+		}
+	}
+	diagnostics := make([]Diagnostic, 0, len(d.errs))
+	for _, err := range d.errs {
+		diagnostic := Diagnostic{Severity: SeverityError, Source: "parser", Message: err.Error()}
+		var e *parser.Error
+		if errors.As(err, &e) {
+			diagnostic.Range = d.rangeOf(e.Span)
+			if illegal[e.Span] {
+				diagnostic.Source = "lexer"
+			}
+		}
+		diagnostics = append(diagnostics, diagnostic)
+	}
+	return diagnostics
+}
+
+// position returns the position of the offset, whose character counts the UTF-16 code units of the line before it.
+func (d *document) position(offset int64) Position {
+	line, _ := d.source.Position(offset)
+	offset = max(0, min(offset, int64(len(d.source.Content))))
+	return Position{Line: line, Character: utf16Len(d.source.Content[d.source.LineStart(line):offset])}
+}
+
+// offset returns the offset of the position, clamped to the end of its line.
+func (d *document) offset(p Position) int64 {
+	content := d.source.Content
+	offset := d.source.LineStart(p.Line)
+	for character := int64(0); character < p.Character && offset < int64(len(content)) && content[offset] != '\n'; {
+		r, size := utf8.DecodeRune(content[offset:])
+		character += utf16RuneLen(r)
+		offset += int64(size)
+	}
+	return offset
+}
+
+func (d *document) rangeOf(span lexer.Span) Range {
+	return Range{Start: d.position(span.StartOffset), End: d.position(span.EndOffset)}
+}
+
+// utf16Len returns the number of UTF-16 code units of the text, an invalid byte is one.
+func utf16Len(text []byte) int64 {
+	n := int64(0)
+	for len(text) > 0 {
+		r, size := utf8.DecodeRune(text)
+		n += utf16RuneLen(r)
+		text = text[size:]
+	}
+	return n
+}
+
+func utf16RuneLen(r rune) int64 {
+	if r >= 0x10000 {
+		return 2
+	}
+	return 1
+}
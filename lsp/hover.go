@@ -0,0 +1,62 @@
+package lsp
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"app/parser"
+	"app/utils/i18n"
+)
+
+// describe returns the description of an item shown on hover, in the words of the scopes listed by the parser.
+func describe(index *parser.Index, item *parser.SymbolTableItem) string {
+	var lines []string
+	switch {
+	case owner(index, item) != nil:
+		lines = append(lines, i18n.Sprintf("Field: %s, Type: %s, Offset: %d", item.Variable, item.UnderlyingType, item.Address))
+	case item.Type == parser.SymbolTableItemTypeFunction:
+		lines = append(lines, i18n.Sprintf("Function: %s, Type: %s, Entry: L%d, Frame Size: %d", item.Variable, item.UnderlyingType, item.Address, item.FrameSize))
+	case item.Type == parser.SymbolTableItemTypeStruct:
+		lines = append(lines, i18n.Sprintf("Struct: %s, Size: %d, Alignment: %d", item.Variable, item.VariableSize, item.Alignment))
+		for _, field := range item.Fields {
+			lines = append(lines, i18n.Sprintf("Field: %s, Type: %s, Offset: %d", field.Variable, field.UnderlyingType, field.Address))
+		}
+	case item.Type == parser.SymbolTableItemTypeConstant:
+		lines = append(lines, i18n.Sprintf("Constant: %s, Type: %s, Value: %s", item.Variable, item.UnderlyingType, item.Value))
+	case item.Local:
+		lines = append(lines, i18n.Sprintf("Variable: %s, Type: %s, Address: fp+%#x", item.Variable, item.UnderlyingType, item.Address))
+	default:
+		lines = append(lines, i18n.Sprintf("Variable: %s, Type: %s, Address: %#x", item.Variable, item.UnderlyingType, item.Address))
+	}
+	if item.Type == parser.SymbolTableItemTypeArray {
+		dimensions := ""
+		for _, dim := range item.Dimension {
+			dimensions += fmt.Sprintf("[%d]", dim)
+		}
+		lines = append(lines, i18n.Sprintf("Array Size: %d, Element Size: %d, Dimensions: %s", item.ArraySize, item.ArrayElementSize, dimensions))
+	}
+	return "```\n" + strings.Join(lines, "\n") + "\n```"
+}
+
+// owner returns the struct type declaring the field, or nil if the item is not a field.
+func owner(index *parser.Index, field *parser.SymbolTableItem) *parser.SymbolTableItem {
+	for _, scope := range index.Scopes {
+		for _, item := range scope.Items {
+			if slices.Contains(item.Fields, field) {
+				return item
+			}
+		}
+	}
+	return nil
+}
+
+// isParam tells if the item is a parameter of a function.
+func isParam(index *parser.Index, param *parser.SymbolTableItem) bool {
+	for _, scope := range index.Scopes {
+		if scope.Function != nil && slices.Contains(scope.Function.Params, param) {
+			return true
+		}
+	}
+	return false
+}
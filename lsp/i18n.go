@@ -0,0 +1,18 @@
+package lsp
+
+import "app/utils/i18n"
+
+// the Chinese messages of the language server, keyed by the English ones
+func init() {
+	i18n.Register(i18n.ZH, map[string]string{
+		"exit without shutdown":                              "未关闭服务器就退出",
+		"internal error of the parser: %v":                   "语法分析器内部错误：%v",
+		"Field: %s, Type: %s, Offset: %d":                    "字段：%s，类型：%s，偏移：%d",
+		"Function: %s, Type: %s, Entry: L%d, Frame Size: %d": "函数：%s，类型：%s，入口：L%d，栈帧大小：%d",
+		"Struct: %s, Size: %d, Alignment: %d":                "结构体：%s，大小：%d，对齐：%d",
+		"Constant: %s, Type: %s, Value: %s":                  "常量：%s，类型：%s，值：%s",
+		"Variable: %s, Type: %s, Address: fp+%#x":            "变量：%s，类型：%s，地址：fp+%#x",
+		"Variable: %s, Type: %s, Address: %#x":               "变量：%s，类型：%s，地址：%#x",
+		"Array Size: %d, Element Size: %d, Dimensions: %s":   "数组大小：%d，元素大小：%d，维度：%s",
+	})
+}
@@ -0,0 +1,127 @@
+package lsp
+
+import (
+	"app/lexer"
+	"app/parser"
+)
+
+// the semantic token types and modifiers, in the order of the legend
+const (
+	tokenKeyword uint32 = iota
+	tokenType
+	tokenFunction
+	tokenVariable
+	tokenParameter
+	tokenProperty
+	tokenStruct
+	tokenNumber
+	tokenString
+	tokenComment
+	tokenOperator
+)
+
+const (
+	modifierDeclaration uint32 = 1 << iota
+	modifierReadonly
+)
+
+var legend = SemanticTokensLegend{
+	TokenTypes:     []string{"keyword", "type", "function", "variable", "parameter", "property", "struct", "number", "string", "comment", "operator"},
+	TokenModifiers: []string{"declaration", "readonly"},
+}
+
+// tokenKind returns the semantic token type of a token from its specific type, and false for the tokens which
+// are not highlighted, the delimiters and the ILLEGAL ones. The identifiers are refined by what they refer to,
+// see identifierKind.
+func tokenKind(token lexer.Token) (uint32, bool) {
+	switch specific := token.SpecificType(); {
+	case specific >= lexer.TypeInt && specific <= lexer.TypeByte:
+		return tokenType, true
+	case specific >= lexer.ConstantInt && specific <= lexer.ConstantImaginary:
+		return tokenNumber, true
+	case specific >= lexer.ConstantChar && specific <= lexer.ConstantStringBacktick:
+		return tokenString, true
+	case specific >= lexer.OperatorPlus && specific <= lexer.OperatorRightShift:
+		return tokenOperator, true
+	case specific == lexer.ConstantBoolTrue || specific == lexer.ConstantBoolFalse,
+		specific >= lexer.ReservedWordBreak && specific <= lexer.ReservedWordWhile:
+		return tokenKeyword, true
+	case specific == lexer.Identifier:
+		return tokenVariable, true
+	}
+	// the comments and the tokens of other lexers have no specific type
+	switch token.Type {
+	case lexer.COMMENT:
+		return tokenComment, true
+	case lexer.IDENTIFIER:
+		return tokenVariable, true
+	}
+	return 0, false
+}
+
+// identifierKind returns the semantic token type and modifiers of an identifier from the item it refers to.
+func identifierKind(index *parser.Index, id parser.Identifier) (uint32, uint32) {
+	item := index.Resolve(id)
+	if item == nil {
+		return tokenVariable, 0
+	}
+	modifiers := uint32(0)
+	if item.Declaration == id.Token.Span {
+		modifiers |= modifierDeclaration
+	}
+	switch {
+	case item.Type == parser.SymbolTableItemTypeFunction:
+		return tokenFunction, modifiers
+	case item.Type == parser.SymbolTableItemTypeStruct:
+		return tokenStruct, modifiers
+	case item.Type == parser.SymbolTableItemTypeConstant:
+		return tokenVariable, modifiers | modifierReadonly
+	case owner(index, item) != nil:
+		return tokenProperty, modifiers
+	case isParam(index, item):
+		return tokenParameter, modifiers
+	}
+	return tokenVariable, modifiers
+}
+
+// semanticTokens returns the semantic tokens of the document, encoded relatively to each other as the protocol
+// wants: the line from the previous token, the character from the previous token on the same line, the length,
+// the type and the modifiers. A token over several lines, e.g. a comment, is split into one token per line.
+func (d *document) semanticTokens() []uint32 {
+	var data []uint32
+	var previous Position
+	emit := func(start, end int64, kind, modifiers uint32) {
+		p := d.position(start)
+		length := d.position(end).Character - p.Character
+		if length <= 0 {
+			return
+		}
+		character := p.Character
+		if p.Line == previous.Line {
+			character -= previous.Character
+		}
+		data = append(data, uint32(p.Line-previous.Line), uint32(character), uint32(length), kind, modifiers)
+		previous = p
+	}
+	for _, token := range d.tokens {
+		kind, ok := tokenKind(token)
+		if !ok {
+			continue
+		}
+		modifiers := uint32(0)
+		if token.Type == lexer.IDENTIFIER && d.index != nil {
+			if id, ok := d.index.At(token.Span.StartOffset); ok && id.Token.Span.StartOffset == token.Span.StartOffset {
+				kind, modifiers = identifierKind(d.index, id)
+			}
+		}
+		for line := token.Span.StartLine; line <= token.Span.EndLine; line++ {
+			start, end := max(token.Span.StartOffset, d.source.LineStart(line)), token.Span.EndOffset
+			if line < token.Span.EndLine {
+				// the newline is not part of the token on its line
+				end = d.source.LineStart(line+1) - 1
+			}
+			emit(start, end, kind, modifiers)
+		}
+	}
+	return data
+}
@@ -0,0 +1,240 @@
+// Package lsp is a language server of the course language, which speaks the Language Server Protocol over
+// JSON-RPC, e.g. on the standard input and output of the -t lsp target, so that an editor such as VS Code shows:
+//   - the lexical, syntax and semantic errors of a document as it is edited;
+//   - the item of an identifier from the symbol table on hover: its type, its address and its dimensions;
+//   - the declaration of an identifier, looked up in the scopes it was read in;
+//   - the semantic tokens of the document, from the specific types of its tokens.
+//
+// The documents are synchronised incrementally: the tokens are only read again around the edits, see lexer.Relex,
+// and the parser reads them rather than the text after each change.
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+
+	"app/parser"
+	"app/utils/i18n"
+)
+
+// Server is a language server, which serves one client.
+type Server struct {
+	parser    *parser.Parser
+	documents map[string]*document
+
+	out         io.Writer
+	err         error // the first error writing a notification
+	initialized bool
+	shutdown    bool
+}
+
+// NewServer creates a new Server instance parsing the documents with the parser.
+func NewServer(p *parser.Parser) *Server {
+	p.EnsureTable()
+	return &Server{parser: p, documents: map[string]*document{}}
+}
+
+// Serve reads the messages of the client from r and writes the responses and the notifications of the server
+// to w, until the exit notification or the end of r. It returns an error if the client exits without asking the
+// server to shut down first, or if the messages cannot be read.
+func (s *Server) Serve(r io.Reader, w io.Writer) error {
+	s.out = w
+	reader := bufio.NewReader(r)
+	for {
+		m, err := readMessage(reader)
+		var rerr *responseError
+		switch {
+		case errors.As(err, &rerr):
+			null := json.RawMessage("null")
+			if err := writeMessage(w, &message{ID: &null, Error: rerr}); err != nil {
+				return err
+			}
+			continue
+		case errors.Is(err, io.EOF):
+			return nil
+		case err != nil:
+			return err
+		}
+
+		if m.Method == "exit" {
+			if !s.shutdown {
+				return i18n.Errorf("exit without shutdown")
+			}
+			return nil
+		}
+		if m.ID == nil {
+			// the notifications have no response, those with malformed params are dropped
+			s.notify(m)
+			if s.err != nil {
+				return s.err
+			}
+			continue
+		}
+		response := &message{ID: m.ID}
+		result, err := s.handle(m)
+		if err == nil {
+			response.Result, err = json.Marshal(result)
+		}
+		if err != nil {
+			if !errors.As(err, &rerr) {
+				rerr = &responseError{Code: codeInvalidParams, Message: err.Error()}
+			}
+			response.Result, response.Error = nil, rerr
+		}
+		if err := writeMessage(w, response); err != nil {
+			return err
+		}
+	}
+}
+
+// handle returns the result of a request.
+func (s *Server) handle(m *message) (any, error) {
+	if !s.initialized && m.Method != "initialize" {
+		return nil, &responseError{Code: codeNotInitialized, Message: "the server is not initialized"}
+	}
+	if s.shutdown {
+		return nil, &responseError{Code: codeInvalidRequest, Message: "the server is shut down"}
+	}
+	switch m.Method {
+	case "initialize":
+		s.initialized = true
+		return s.initialize(), nil
+	case "shutdown":
+		s.shutdown = true
+		return nil, nil
+	case "textDocument/hover":
+		var params TextDocumentPositionParams
+		if err := json.Unmarshal(m.Params, &params); err != nil {
+			return nil, err
+		}
+		return s.hover(params), nil
+	case "textDocument/definition":
+		var params TextDocumentPositionParams
+		if err := json.Unmarshal(m.Params, &params); err != nil {
+			return nil, err
+		}
+		return s.definition(params), nil
+	case "textDocument/semanticTokens/full":
+		var params SemanticTokensParams
+		if err := json.Unmarshal(m.Params, &params); err != nil {
+			return nil, err
+		}
+		tokens := SemanticTokens{Data: []uint32{}}
+		if d := s.documents[params.TextDocument.URI]; d != nil {
+			tokens.Data = append(tokens.Data, d.semanticTokens()...)
+		}
+		return tokens, nil
+	}
+	return nil, &responseError{Code: codeMethodNotFound, Message: "method not found: " + m.Method}
+}
+
+func (s *Server) initialize() InitializeResult {
+	result := InitializeResult{
+		Capabilities: ServerCapabilities{
+			PositionEncoding:       "utf-16",
+			TextDocumentSync:       TextDocumentSyncOptions{OpenClose: true, Change: incrementalSync},
+			HoverProvider:          true,
+			DefinitionProvider:     true,
+			SemanticTokensProvider: SemanticTokensOptions{Legend: legend, Full: true},
+		},
+	}
+	result.ServerInfo.Name = "compiler-lab"
+	return result
+}
+
+// notify handles a notification, the unknown ones are ignored as the protocol wants.
+func (s *Server) notify(m *message) {
+	if !s.initialized || s.shutdown {
+		return
+	}
+	switch m.Method {
+	case "textDocument/didOpen":
+		var params DidOpenTextDocumentParams
+		if json.Unmarshal(m.Params, &params) != nil {
+			return
+		}
+		d := newDocument(params.TextDocument.URI, params.TextDocument.Version, params.TextDocument.Text)
+		s.documents[d.uri] = d
+		s.publishDiagnostics(d)
+	case "textDocument/didChange":
+		var params DidChangeTextDocumentParams
+		if json.Unmarshal(m.Params, &params) != nil {
+			return
+		}
+		d := s.documents[params.TextDocument.URI]
+		if d == nil {
+			return
+		}
+		for _, change := range params.ContentChanges {
+			if change.Range == nil {
+				d.replace(change.Text)
+			} else {
+				d.edit(*change.Range, change.Text)
+			}
+		}
+		d.version = params.TextDocument.Version
+		s.publishDiagnostics(d)
+	case "textDocument/didClose":
+		var params DidCloseTextDocumentParams
+		if json.Unmarshal(m.Params, &params) != nil {
+			return
+		}
+		delete(s.documents, params.TextDocument.URI)
+		// the errors of a closed document are cleared
+		s.send("textDocument/publishDiagnostics", PublishDiagnosticsParams{URI: params.TextDocument.URI, Diagnostics: []Diagnostic{}})
+	}
+}
+
+// publishDiagnostics parses the document again and sends its errors.
+func (s *Server) publishDiagnostics(d *document) {
+	d.parse(s.parser)
+	s.send("textDocument/publishDiagnostics", PublishDiagnosticsParams{URI: d.uri, Version: d.version, Diagnostics: d.diagnostics()})
+}
+
+// send sends a notification to the client.
+func (s *Server) send(method string, params any) {
+	body, err := json.Marshal(params)
+	if err == nil {
+		err = writeMessage(s.out, &message{Method: method, Params: body})
+	}
+	if s.err == nil {
+		s.err = err
+	}
+}
+
+// hover returns the item of the identifier at the position, or nil if there is none.
+func (s *Server) hover(params TextDocumentPositionParams) *Hover {
+	d, id, item := s.resolve(params)
+	if item == nil {
+		return nil
+	}
+	return &Hover{
+		Contents: MarkupContent{Kind: "markdown", Value: describe(d.index, item)},
+		Range:    d.rangeOf(id.Token.Span),
+	}
+}
+
+// definition returns the declaration of the identifier at the position, or nil if there is none.
+func (s *Server) definition(params TextDocumentPositionParams) *Location {
+	d, _, item := s.resolve(params)
+	if item == nil {
+		return nil
+	}
+	return &Location{URI: d.uri, Range: d.rangeOf(item.Declaration)}
+}
+
+// resolve returns the document, the identifier at the position in it and the item it refers to,
+// the item is nil if there is none.
+func (s *Server) resolve(params TextDocumentPositionParams) (*document, parser.Identifier, *parser.SymbolTableItem) {
+	d := s.documents[params.TextDocument.URI]
+	if d == nil || d.index == nil {
+		return nil, parser.Identifier{}, nil
+	}
+	id, ok := d.index.At(d.offset(params.Position))
+	if !ok {
+		return d, id, nil
+	}
+	return d, id, d.index.Resolve(id)
+}
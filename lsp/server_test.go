@@ -0,0 +1,307 @@
+package lsp_test
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"app/lsp"
+	"app/parser"
+	"app/utils/i18n"
+)
+
+// the tests check the messages in English
+func TestMain(m *testing.M) {
+	i18n.SetLocale(i18n.EN)
+	os.Exit(m.Run())
+}
+
+var (
+	sharedParser     *parser.Parser
+	sharedParserOnce sync.Once
+)
+
+type response struct {
+	ID     *int            `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+	Result json.RawMessage `json:"result"`
+	Error  *struct {
+		Code int `json:"code"`
+	} `json:"error"`
+}
+
+// serve sends the messages to a new server, and returns the messages it sent back and its error.
+func serve(t *testing.T, messages ...string) ([]response, error) {
+	t.Helper()
+	sharedParserOnce.Do(func() {
+		sharedParser = parser.NewParser()
+	})
+	return serveWith(t, sharedParser, messages...)
+}
+
+// serveWith is serve with a server of the parser.
+func serveWith(t *testing.T, p *parser.Parser, messages ...string) ([]response, error) {
+	t.Helper()
+	var in, out bytes.Buffer
+	for _, m := range messages {
+		fmt.Fprintf(&in, "Content-Length: %d\r\n\r\n%s", len(m), m)
+	}
+	err := lsp.NewServer(p).Serve(&in, &out)
+
+	var responses []response
+	r := bufio.NewReader(&out)
+	for {
+		var length int
+		if _, err := fmt.Fscanf(r, "Content-Length: %d\r\n\r\n", &length); err != nil {
+			break
+		}
+		body := make([]byte, length)
+		if _, err := io.ReadFull(r, body); err != nil {
+			t.Fatal(err)
+		}
+		var resp response
+		if err := json.Unmarshal(body, &resp); err != nil {
+			t.Fatalf("Expected a JSON-RPC message, got %s: %v", body, err)
+		}
+		responses = append(responses, resp)
+	}
+	return responses, err
+}
+
+func request(id int, method string, params string) string {
+	return fmt.Sprintf(`{"jsonrpc": "2.0", "id": %d, "method": %q, "params": %s}`, id, method, params)
+}
+
+func notification(method string, params string) string {
+	return fmt.Sprintf(`{"jsonrpc": "2.0", "method": %q, "params": %s}`, method, params)
+}
+
+func open(text string) string {
+	return notification("textDocument/didOpen", fmt.Sprintf(`{"textDocument": {"uri": "file:///a.in", "languageId": "fzu", "version": 1, "text": %q}}`, text))
+}
+
+func at(method string, id int, line, character int) string {
+	return request(id, method, fmt.Sprintf(`{"textDocument": {"uri": "file:///a.in"}, "position": {"line": %d, "character": %d}}`, line, character))
+}
+
+var lifecycle = []string{request(0, "initialize", `{}`), notification("initialized", `{}`)}
+
+func shutdown(id int) []string {
+	return []string{request(id, "shutdown", `null`), notification("exit", `null`)}
+}
+
+// result returns the result of the response to the request id, decoded in v.
+func result(t *testing.T, responses []response, id int, v any) {
+	t.Helper()
+	for _, resp := range responses {
+		if resp.ID != nil && *resp.ID == id {
+			if resp.Error != nil {
+				t.Fatalf("Expected a result for %d, got the error %d", id, resp.Error.Code)
+			}
+			if err := json.Unmarshal(resp.Result, v); err != nil {
+				t.Fatal(err)
+			}
+			return
+		}
+	}
+	t.Fatalf("Expected a response to %d", id)
+}
+
+// diagnostics returns the diagnostics published, in order.
+func diagnostics(t *testing.T, responses []response) [][]lsp.Diagnostic {
+	t.Helper()
+	var published [][]lsp.Diagnostic
+	for _, resp := range responses {
+		if resp.Method == "textDocument/publishDiagnostics" {
+			var params lsp.PublishDiagnosticsParams
+			if err := json.Unmarshal(resp.Params, &params); err != nil {
+				t.Fatal(err)
+			}
+			published = append(published, params.Diagnostics)
+		}
+	}
+	return published
+}
+
+const src = `func f(int a) int {
+    return a;
+}
+{
+    int[2][3] m;
+    int x;
+    x = f(1) + y;
+    m[1][2] = x #;
+}`
+
+func TestServer_Diagnostics(t *testing.T) {
+	change := notification("textDocument/didChange", `{"textDocument": {"uri": "file:///a.in", "version": 2}, "contentChanges": [
+		{"range": {"start": {"line": 6, "character": 15}, "end": {"line": 6, "character": 16}}, "text": "x"},
+		{"range": {"start": {"line": 7, "character": 15}, "end": {"line": 7, "character": 17}}, "text": ""}
+	]}`)
+	closing := notification("textDocument/didClose", `{"textDocument": {"uri": "file:///a.in"}}`)
+	responses, err := serve(t, append(append(lifecycle, open(src), change, closing), shutdown(1)...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	published := diagnostics(t, responses)
+	if len(published) != 3 {
+		t.Fatalf("Expected the diagnostics to be published on open, change and close, got %v", published)
+	}
+	expected := []lsp.Diagnostic{
+		{Range: lsp.Range{Start: lsp.Position{Line: 7, Character: 16}, End: lsp.Position{Line: 7, Character: 17}}, Severity: lsp.SeverityError, Source: "lexer", Message: "unknown character: #, at line 7, pos 17"},
+		{Range: lsp.Range{Start: lsp.Position{Line: 6, Character: 15}, End: lsp.Position{Line: 6, Character: 16}}, Severity: lsp.SeverityError, Source: "parser", Message: "item y not found in any scope"},
+	}
+	if fmt.Sprint(published[0]) != fmt.Sprint(expected) {
+		t.Errorf("Expected %v, got %v", expected, published[0])
+	}
+	if len(published[1]) != 0 || len(published[2]) != 0 {
+		t.Errorf("Expected no diagnostics once fixed and closed, got %v", published[1:])
+	}
+}
+
+func TestServer_ParserPanic(t *testing.T) {
+	// a rule with a bug panics on the literal 42
+	p := parser.NewParser()
+	for i, production := range p.Grammar.Productions {
+		if production.Head == "factor" && slices.Equal(production.Body, []parser.Symbol{"num"}) {
+			rule := production.Rule
+			p.Grammar.Productions[i].Rule = func(w *parser.Walker) error {
+				if top, _ := w.Tokens.Peek(); top.Token.Val == "42" {
+					panic("42")
+				}
+				return rule(w)
+			}
+		}
+	}
+	change := notification("textDocument/didChange", `{"textDocument": {"uri": "file:///a.in", "version": 2}, "contentChanges": [{"text": "{ int a; a = 1; }"}]}`)
+	responses, err := serveWith(t, p, append(append(lifecycle, open("{ int a; a = 42; }"), change), shutdown(1)...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	published := diagnostics(t, responses)
+	if len(published) != 2 {
+		t.Fatalf("Expected the diagnostics to be published on open and change, got %v", published)
+	}
+	if len(published[0]) != 1 || published[0][0].Message != "internal error of the parser: 42" {
+		t.Errorf("Expected the panic as a diagnostic, got %v", published[0])
+	}
+	if len(published[1]) != 0 {
+		t.Errorf("Expected no diagnostics once changed, got %v", published[1])
+	}
+}
+
+func TestServer_Navigation(t *testing.T) {
+	responses, err := serve(t, append(append(lifecycle, open(src),
+		at("textDocument/hover", 1, 7, 4),
+		at("textDocument/hover", 2, 1, 11),
+		at("textDocument/hover", 3, 4, 5),
+		at("textDocument/definition", 4, 6, 8),
+		at("textDocument/definition", 5, 6, 15),
+	), shutdown(6)...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var hover *lsp.Hover
+	result(t, responses, 1, &hover)
+	if hover == nil || !strings.Contains(hover.Contents.Value, "Variable: m, Type: !ptr<int>, Address: 0x10000000") ||
+		!strings.Contains(hover.Contents.Value, "Dimensions: [2][3]") {
+		t.Errorf("Expected the array m on hover, got %+v", hover)
+	}
+	result(t, responses, 2, &hover)
+	if hover == nil || !strings.Contains(hover.Contents.Value, "Variable: a, Type: int, Address: fp+0x0") {
+		t.Errorf("Expected the parameter a on hover, got %+v", hover)
+	}
+	result(t, responses, 3, &hover)
+	if hover != nil {
+		t.Errorf("Expected no hover on a type, got %+v", hover)
+	}
+
+	var location *lsp.Location
+	result(t, responses, 4, &location)
+	expected := lsp.Location{URI: "file:///a.in", Range: lsp.Range{Start: lsp.Position{Line: 0, Character: 5}, End: lsp.Position{Line: 0, Character: 6}}}
+	if location == nil || *location != expected {
+		t.Errorf("Expected the definition of f at %v, got %v", expected, location)
+	}
+	result(t, responses, 5, &location)
+	if location != nil {
+		t.Errorf("Expected no definition of an undeclared variable, got %v", location)
+	}
+}
+
+func TestServer_Fields(t *testing.T) {
+	text := "type P struct { int x; int y; }\n{\n    struct P p;\n    p.y = p.x;\n}"
+	responses, err := serve(t, append(append(lifecycle, open(text),
+		at("textDocument/hover", 1, 3, 6),
+		at("textDocument/definition", 2, 3, 12),
+	), shutdown(3)...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var hover *lsp.Hover
+	result(t, responses, 1, &hover)
+	if hover == nil || !strings.Contains(hover.Contents.Value, "Field: y, Type: int") {
+		t.Errorf("Expected the field y on hover, got %+v", hover)
+	}
+	var location *lsp.Location
+	result(t, responses, 2, &location)
+	if location == nil || location.Range.Start != (lsp.Position{Line: 0, Character: 20}) {
+		t.Errorf("Expected the definition of the field x, got %v", location)
+	}
+}
+
+func TestServer_SemanticTokens(t *testing.T) {
+	// the character of a position counts UTF-16 code units, two for the emoji
+	text := "{\n    string s = \"😀\"; s = s; // ok\n}"
+	responses, err := serve(t, append(append(lifecycle, open(text),
+		request(1, "textDocument/semanticTokens/full", `{"textDocument": {"uri": "file:///a.in"}}`),
+		at("textDocument/definition", 2, 1, 25),
+	), shutdown(3)...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tokens lsp.SemanticTokens
+	result(t, responses, 1, &tokens)
+	expected := []uint32{
+		1, 4, 6, 1, 0, // string
+		0, 7, 1, 3, 1, // s, declared
+		0, 2, 1, 10, 0, // =
+		0, 2, 4, 8, 0, // "😀"
+		0, 6, 1, 3, 0, // s
+		0, 2, 1, 10, 0, // =
+		0, 2, 1, 3, 0, // s
+		0, 3, 5, 9, 0, // // ok
+	}
+	if fmt.Sprint(tokens.Data) != fmt.Sprint(expected) {
+		t.Errorf("Expected the semantic tokens %v, got %v", expected, tokens.Data)
+	}
+	var location *lsp.Location
+	result(t, responses, 2, &location)
+	if location == nil || location.Range.Start != (lsp.Position{Line: 1, Character: 11}) {
+		t.Errorf("Expected the definition of s after the emoji, got %v", location)
+	}
+}
+
+func TestServer_Lifecycle(t *testing.T) {
+	responses, err := serve(t, request(1, "textDocument/hover", `{}`), request(2, "initialize", `{}`), request(3, "unknown", `{}`), notification("exit", `null`))
+	if err == nil {
+		t.Errorf("Expected an error when exiting without shutdown")
+	}
+	codes := map[int]int{}
+	for _, resp := range responses {
+		if resp.ID != nil && resp.Error != nil {
+			codes[*resp.ID] = resp.Error.Code
+		}
+	}
+	if codes[1] != -32002 || codes[2] != 0 || codes[3] != -32601 {
+		t.Errorf("Expected a request before initialize and an unknown method to fail, got %v", codes)
+	}
+}
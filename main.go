@@ -6,6 +6,7 @@ import (
 
 	. "app/config"
 	entrypoint "app/entry-point"
+	"app/utils/i18n"
 	"app/utils/log"
 )
 
@@ -19,8 +20,12 @@ func main() {
 		entrypoint.LexerTest()
 	case "parser":
 		entrypoint.ParserTest()
+	case "dot":
+		entrypoint.DotExport()
+	case "lsp":
+		entrypoint.LanguageServer()
 	default:
-		println("Unknown mode:", Config.Target)
+		println(i18n.T("Unknown mode:"), Config.Target)
 	}
 }
 
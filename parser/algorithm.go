@@ -1,17 +1,23 @@
 package parser
 
 import (
-	"slices"
+	"fmt"
 
 	. "app/utils/collections"
 )
 
 // BuildStates constructs the LR(1) states for the parser based on the grammar.
 // It initializes the initial state with the augmented production and computes the closure of the items.
-// Then, it iterates through the states and computes the GOTO for each symbol, creating new states as needed.
+// Then, it iterates through the states and computes the GOTO for each symbol after a dot, creating new states as needed.
+// A state is found again by the key of its kernel rather than by comparing its closure with all the states,
+// and CLOSURE expands each item once, which keeps the build of the default grammar fast.
 // The resulting states are stored in the Parser's States field.
 // The function ensures that the symbols are built before constructing the states.
+// It panics if the grammar is invalid, see Grammar.Validate.
 func (p *Parser) BuildStates() {
+	if err := p.Grammar.Validate(); err != nil {
+		panic(fmt.Sprintf("invalid grammar:\n%v", err))
+	}
 	p.EnsureSymbols()
 
 	initialItem := LR1Item{
@@ -26,6 +32,9 @@ func (p *Parser) BuildStates() {
 		Transitions: make(map[Symbol]*State),
 	}
 
+	// the states are identified by their kernel, i.e. the items with the dot moved over a symbol,
+	// which is all the items of the state but the ones added by the closure (with the dot at 0)
+	indices := map[string]int{initialState.Items.Key(): 0}
 	initialState.Items = p.CLOSURE(initialState.Items)
 
 	p.States = States{initialState}
@@ -34,27 +43,39 @@ func (p *Parser) BuildStates() {
 	for i := 0; i < length; i++ {
 		state := p.States[i]
 
-		for symbol := range p.Symbols {
-			gotoItems := p.GOTO(state.Items, symbol)
-			if len(gotoItems) == 0 {
-				continue
+		// the symbols are taken in the order of the items, so that the states are numbered the same on every build
+		var symbols []Symbol
+		kernels := map[Symbol]LR1Items{}
+		for _, item := range state.Items {
+			if item.Dot < len(item.Production.Body) && !item.Production.Body[item.Dot].IsEpsilon() {
+				symbol := item.Production.Body[item.Dot]
+				if _, exists := kernels[symbol]; !exists {
+					symbols = append(symbols, symbol)
+				}
+				kernels[symbol] = append(kernels[symbol], LR1Item{
+					Production: item.Production,
+					Dot:        item.Dot + 1,
+					Lookahead:  item.Lookahead,
+				})
 			}
+		}
 
+		for _, symbol := range symbols {
+			kernel := kernels[symbol]
+			key := kernel.Key()
+			if index, exists := indices[key]; exists {
+				state.Transitions[symbol] = p.States[index]
+				continue
+			}
 			newState := &State{
 				Index:       len(p.States),
-				Items:       gotoItems,
+				Items:       p.CLOSURE(kernel),
 				Transitions: make(map[Symbol]*State),
 			}
-			index := slices.IndexFunc(p.States, func(s *State) bool {
-				return s.Equals(newState)
-			})
-			if index == -1 {
-				p.States = append(p.States, newState)
-				state.Transitions[symbol] = newState
-				length++
-			} else {
-				state.Transitions[symbol] = p.States[index]
-			}
+			indices[key] = newState.Index
+			p.States = append(p.States, newState)
+			state.Transitions[symbol] = newState
+			length++
 		}
 	}
 }
@@ -139,6 +160,54 @@ func (p *Parser) BuildFirstSet() {
 	}
 }
 
+// BuildFollowSet constructs the FollowSet for the parser based on the grammar's productions.
+// The FOLLOW set of the start symbol contains TERMINATE.
+func (p *Parser) BuildFollowSet() {
+	p.EnsureFirstSet()
+	p.FollowSet = make(FollowSet)
+
+	productions := append([]Production{p.Grammar.AugmentedProduction}, p.Grammar.Productions...)
+	for _, production := range productions {
+		if _, exists := p.FollowSet[production.Head]; !exists {
+			p.FollowSet[production.Head] = Set[Terminal]{}
+		}
+	}
+	p.FollowSet[p.Grammar.AugmentedProduction.Head].Add(TERMINATE)
+
+	loop := true
+	for loop {
+		loop = false
+		for _, production := range productions {
+			for i, symbol := range production.Body {
+				if symbol.IsEpsilon() || p.Grammar.IsTerminal(symbol) {
+					continue
+				}
+				followSet, exists := p.FollowSet[symbol]
+				if !exists {
+					followSet = Set[Terminal]{}
+					p.FollowSet[symbol] = followSet
+				}
+				// FOLLOW(A) includes FIRST(β) for B → α A β, and FOLLOW(B) when β is nullable
+				for terminal := range p.findLookaheads(production.Body[i+1:], EPSILON) {
+					if terminal.IsEpsilon() {
+						for t := range p.FollowSet[production.Head] {
+							if !followSet.Contains(t) {
+								followSet.Add(t)
+								loop = true
+							}
+						}
+						continue
+					}
+					if !followSet.Contains(terminal) {
+						followSet.Add(terminal)
+						loop = true
+					}
+				}
+			}
+		}
+	}
+}
+
 // CLOSURE computes the closure of a set of LR1 items.
 // It adds new items to the closure based on the productions of the grammar and the lookahead symbols.
 func (p *Parser) CLOSURE(items []LR1Item) []LR1Item {
@@ -147,59 +216,38 @@ func (p *Parser) CLOSURE(items []LR1Item) []LR1Item {
 	closure := make([]LR1Item, len(items))
 	copy(closure, items)
 
-	marks := Set[string]{}
-
-	loop := true
-	for loop {
-		loop = false
-
-		for _, item := range closure {
-			if marks.Contains(item.AsKey()) {
-				continue
-			}
+	// the items are expanded in order, the ones appended along the way included
+	keys := Set[string]{}
+	for _, item := range closure {
+		keys.Add(item.AsKey())
+	}
 
-			marks.Add(item.AsKey())
+	for i := 0; i < len(closure); i++ {
+		item := closure[i]
+		if item.Dot >= len(item.Production.Body) {
+			continue
+		}
 
-			if item.Dot >= len(item.Production.Body) {
-				continue
-			}
+		nextSymbol := item.Production.Body[item.Dot]
+		if p.Grammar.IsTerminal(nextSymbol) {
+			continue
+		}
 
-			nextSymbol := item.Production.Body[item.Dot]
-			if p.Grammar.IsTerminal(nextSymbol) {
+		lookaheads := p.findLookaheads(item.Production.Body[item.Dot+1:], item.Lookahead)
+		for _, production := range p.Grammar.Productions {
+			if production.Head != nextSymbol {
 				continue
 			}
+			for lookahead := range lookaheads {
+				newItem := LR1Item{
+					Production: production,
+					Dot:        0,
+					Lookahead:  lookahead,
+				}
 
-			for _, production := range p.Grammar.Productions {
-				if production.Head == nextSymbol {
-					if production.Body[0].IsEpsilon() {
-						newItem := LR1Item{
-							Production: production,
-							Dot:        0,
-							Lookahead:  item.Lookahead,
-						}
-						if !slices.ContainsFunc(closure, func(i LR1Item) bool {
-							return i.Equals(newItem)
-						}) {
-							closure = append(closure, newItem)
-							loop = true
-						}
-					} else {
-						lookaheads := p.findLookaheads(item.Production.Body[item.Dot+1:], item.Lookahead)
-						for lookahead := range lookaheads {
-							newItem := LR1Item{
-								Production: production,
-								Dot:        0,
-								Lookahead:  lookahead,
-							}
-
-							if !slices.ContainsFunc(closure, func(i LR1Item) bool {
-								return i.Equals(newItem)
-							}) {
-								closure = append(closure, newItem)
-								loop = true
-							}
-						}
-					}
+				if key := newItem.AsKey(); !keys.Contains(key) {
+					keys.Add(key)
+					closure = append(closure, newItem)
 				}
 			}
 		}
@@ -224,8 +272,8 @@ func (p *Parser) GOTO(items LR1Items, symbol Symbol) LR1Items {
 	return p.CLOSURE(gotoItems)
 }
 
-// findLookaheads computes the lookahead symbols for a given set of symbols and a lookahead terminal.
-// It checks if the symbols are empty or if they contain epsilon, and adds the lookahead terminal accordingly.
+// findLookaheads computes the lookahead symbols for a given set of symbols and a lookahead terminal,
+// i.e. FIRST(symbols lookahead). The lookahead terminal is only added when all the symbols are nullable.
 func (p *Parser) findLookaheads(symbols []Symbol, lookahead Terminal) Set[Terminal] {
 	if len(symbols) == 0 {
 		s := Set[Terminal]{}
@@ -236,8 +284,14 @@ func (p *Parser) findLookaheads(symbols []Symbol, lookahead Terminal) Set[Termin
 	flag := true
 	firstSet := Set[Terminal]{}
 	for _, symbol := range symbols {
+		if symbol.IsEpsilon() {
+			continue
+		}
+
 		if p.Grammar.IsTerminal(symbol) {
 			firstSet.Add(Terminal(symbol))
+			flag = false
+			break
 		}
 
 		for terminal := range p.FirstSet[symbol] {
@@ -246,7 +300,7 @@ func (p *Parser) findLookaheads(symbols []Symbol, lookahead Terminal) Set[Termin
 			}
 		}
 
-		if !firstSet.Contains(EPSILON) {
+		if !p.FirstSet[symbol].Contains(EPSILON) {
 			flag = false
 			break
 		}
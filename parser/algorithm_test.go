@@ -2,7 +2,10 @@ package parser_test
 
 import (
 	"fmt"
+	"maps"
+	"slices"
 	"testing"
+	"time"
 
 	. "app/parser"
 	. "app/utils/collections"
@@ -197,6 +200,84 @@ func TestParser_BuildFirstSet(t *testing.T) {
 	}
 }
 
+func TestParser_BuildFollowSet(t *testing.T) {
+	tests := []struct {
+		name        string
+		augmented   Production
+		productions []Production
+		terminals   []Terminal
+		expected    FollowSet
+	}{
+		{
+			name:      "Test1",
+			augmented: Production{Head: "S", Body: []Symbol{"E"}},
+			productions: []Production{
+				{Head: "E", Body: []Symbol{"T", "E'"}},
+				{Head: "E'", Body: []Symbol{"+", "T", "E'"}},
+				{Head: "E'", Body: []Symbol{EPSILON}},
+				{Head: "T", Body: []Symbol{"F", "T'"}},
+				{Head: "T'", Body: []Symbol{"*", "F", "T'"}},
+				{Head: "T'", Body: []Symbol{EPSILON}},
+				{Head: "F", Body: []Symbol{"(", "E", ")"}},
+				{Head: "F", Body: []Symbol{"id"}},
+			},
+			terminals: []Terminal{"id", "+", "*", "(", ")", EPSILON, TERMINATE},
+			expected: FollowSet{
+				"E":  Set[Terminal]{}.AddAll(")", TERMINATE),
+				"E'": Set[Terminal]{}.AddAll(")", TERMINATE),
+				"T":  Set[Terminal]{}.AddAll("+", ")", TERMINATE),
+				"T'": Set[Terminal]{}.AddAll("+", ")", TERMINATE),
+				"F":  Set[Terminal]{}.AddAll("*", "+", ")", TERMINATE),
+			},
+		},
+		{
+			name:      "Test2",
+			augmented: Production{Head: "S'", Body: []Symbol{"S"}},
+			productions: []Production{
+				{Head: "S", Body: []Symbol{"a", "B", "D", "h"}},
+				{Head: "B", Body: []Symbol{"c", "C"}},
+				{Head: "C", Body: []Symbol{"b", "C"}},
+				{Head: "C", Body: []Symbol{EPSILON}},
+				{Head: "D", Body: []Symbol{"E", "F"}},
+				{Head: "E", Body: []Symbol{"g"}},
+				{Head: "E", Body: []Symbol{EPSILON}},
+				{Head: "F", Body: []Symbol{"f"}},
+				{Head: "F", Body: []Symbol{EPSILON}},
+			},
+			terminals: []Terminal{"a", "b", "c", "f", "g", "h", EPSILON, TERMINATE},
+			expected: FollowSet{
+				"S": Set[Terminal]{}.AddAll(TERMINATE),
+				"B": Set[Terminal]{}.AddAll("g", "f", "h"),
+				"C": Set[Terminal]{}.AddAll("g", "f", "h"),
+				"D": Set[Terminal]{}.AddAll("h"),
+				"E": Set[Terminal]{}.AddAll("f", "h"),
+				"F": Set[Terminal]{}.AddAll("h"),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &Parser{
+				Grammar: &Grammar{
+					AugmentedProduction: tt.augmented,
+					Productions:         tt.productions,
+					Terminals:           Set[Terminal]{}.AddAll(tt.terminals...),
+				},
+			}
+			p.BuildFollowSet()
+			for head, expected := range tt.expected {
+				fmt.Printf("FOLLOW(%s) : %v", head, p.FollowSet[head])
+				if !p.FollowSet[head].Equals(expected) {
+					fmt.Println(log.Sprintf(log.Argument{FrontColor: log.Red, Highlight: true, Format: " !!! FAILED", Args: []any{}}))
+					t.Errorf("Expected %v, got %v\n", expected, p.FollowSet[head])
+				} else {
+					fmt.Println(log.Sprintf(log.Argument{FrontColor: log.Green, Highlight: true, Format: " *** PASSED", Args: []any{}}))
+				}
+			}
+		})
+	}
+}
+
 func TestParser_BuildStates(t *testing.T) {
 	tests := []struct {
 		name                string
@@ -301,3 +382,236 @@ func TestParser_BuildStates(t *testing.T) {
 		})
 	}
 }
+
+func TestParser_CLOSURE_Epsilon(t *testing.T) {
+	tests := []struct {
+		name        string
+		productions []Production
+		terminals   []Terminal
+		expected    map[Symbol][]Terminal // the lookaheads of the ε-productions in the closure of S' → • S, $
+	}{
+		{
+			// the lookahead of A → ε is what follows A in S, not the one of S
+			name: "Test1",
+			productions: []Production{
+				{Head: "S", Body: []Symbol{"A", "a", "A", "b"}},
+				{Head: "S", Body: []Symbol{"B", "b", "B", "a"}},
+				{Head: "A", Body: []Symbol{EPSILON}},
+				{Head: "B", Body: []Symbol{EPSILON}},
+			},
+			terminals: []Terminal{"a", "b", EPSILON, TERMINATE},
+			expected:  map[Symbol][]Terminal{"A": {"a"}, "B": {"b"}},
+		},
+		{
+			// the lookaheads of C → ε go through the nullable D, up to the terminal c
+			name: "Test2",
+			productions: []Production{
+				{Head: "S", Body: []Symbol{"C", "D", "c"}},
+				{Head: "C", Body: []Symbol{EPSILON}},
+				{Head: "D", Body: []Symbol{"d"}},
+				{Head: "D", Body: []Symbol{EPSILON}},
+			},
+			terminals: []Terminal{"c", "d", EPSILON, TERMINATE},
+			expected:  map[Symbol][]Terminal{"C": {"c", "d"}},
+		},
+		{
+			// the lookahead of the item is only added when all the symbols after the dot are nullable
+			name: "Test3",
+			productions: []Production{
+				{Head: "S", Body: []Symbol{"E"}},
+				{Head: "E", Body: []Symbol{EPSILON}},
+			},
+			terminals: []Terminal{EPSILON, TERMINATE},
+			expected:  map[Symbol][]Terminal{"E": {TERMINATE}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			augmentedProduction := Production{Head: "S'", Body: []Symbol{"S"}}
+			p := &Parser{
+				Grammar: &Grammar{
+					AugmentedProduction: augmentedProduction,
+					Productions:         tt.productions,
+					Terminals:           Set[Terminal]{}.AddAll(tt.terminals...),
+				},
+			}
+			p.BuildFirstSet()
+			closure := p.CLOSURE([]LR1Item{{Production: augmentedProduction, Dot: 0, Lookahead: TERMINATE}})
+			actual := map[Symbol][]Terminal{}
+			for _, item := range closure {
+				if item.Production.Body[0].IsEpsilon() {
+					actual[item.Production.Head] = append(actual[item.Production.Head], item.Lookahead)
+				}
+			}
+			for head := range actual {
+				slices.Sort(actual[head])
+			}
+			if fmt.Sprint(actual) != fmt.Sprint(tt.expected) {
+				t.Errorf("Expected the lookaheads %v, got %v", tt.expected, actual)
+			}
+		})
+	}
+}
+
+// closureByFixedPoint computes the closure like the textbook: every item is expanded again until nothing is added.
+func closureByFixedPoint(p *Parser, items LR1Items) LR1Items {
+	p.EnsureFirstSet()
+	closure := slices.Clone(items)
+	for loop := true; loop; {
+		loop = false
+		for _, item := range closure {
+			if item.Dot >= len(item.Production.Body) || p.Grammar.IsTerminal(item.Production.Body[item.Dot]) {
+				continue
+			}
+			// FIRST(β a) for the item A → α • B β, a
+			lookaheads := Set[Terminal]{}
+			nullable := true
+			for _, symbol := range item.Production.Body[item.Dot+1:] {
+				nullable = false
+				for terminal := range p.FirstSet[symbol] {
+					if terminal.IsEpsilon() {
+						nullable = true
+					} else {
+						lookaheads.Add(terminal)
+					}
+				}
+				if !nullable {
+					break
+				}
+			}
+			if nullable {
+				lookaheads.Add(item.Lookahead)
+			}
+			for _, production := range p.Grammar.Productions {
+				if production.Head != item.Production.Body[item.Dot] {
+					continue
+				}
+				for lookahead := range lookaheads {
+					newItem := LR1Item{Production: production, Dot: 0, Lookahead: lookahead}
+					if !closure.Contains(newItem) {
+						closure = append(closure, newItem)
+						loop = true
+					}
+				}
+			}
+		}
+	}
+	return closure
+}
+
+// buildStatesByGOTO builds the states like the textbook: the GOTO of every state on every symbol,
+// compared with all the states built so far.
+func buildStatesByGOTO(p *Parser) States {
+	p.EnsureSymbols()
+	symbols := slices.Sorted(maps.Keys(p.Symbols))
+	states := States{{
+		Index:       0,
+		Items:       closureByFixedPoint(p, LR1Items{{Production: p.Grammar.AugmentedProduction, Dot: 0, Lookahead: TERMINATE}}),
+		Transitions: make(map[Symbol]*State),
+	}}
+	for i := 0; i < len(states); i++ {
+		for _, symbol := range symbols {
+			kernel := LR1Items{}
+			for _, item := range states[i].Items {
+				if item.Dot < len(item.Production.Body) && item.Production.Body[item.Dot] == symbol {
+					kernel = append(kernel, LR1Item{Production: item.Production, Dot: item.Dot + 1, Lookahead: item.Lookahead})
+				}
+			}
+			if len(kernel) == 0 {
+				continue
+			}
+			items := closureByFixedPoint(p, kernel)
+			next := &State{Index: len(states), Items: items, Transitions: make(map[Symbol]*State)}
+			if index := slices.IndexFunc(states, next.Equals); index != -1 {
+				next = states[index]
+			} else {
+				states = append(states, next)
+			}
+			states[i].Transitions[symbol] = next
+		}
+	}
+	return states
+}
+
+func TestParser_BuildStates_GOTO(t *testing.T) {
+	expression := NewGrammar().Copy()
+	heads := []Symbol{"bool", "bool'", "join", "bitwise", "equality", "rel", "expr", "term", "unary", "factor", "loc"}
+	expression.AugmentedProduction = Production{Head: "start", Body: []Symbol{"bool"}}
+	expression.Productions = slices.DeleteFunc(expression.Productions, func(p Production) bool {
+		return !slices.Contains(heads, p.Head) || slices.Contains(p.Body, "args")
+	})
+	tests := []struct {
+		name    string
+		grammar *Grammar
+	}{
+		{name: "Expression", grammar: &expression},
+		{name: "Default", grammar: NewGrammar()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.name == "Default" && testing.Short() {
+				t.Skip("the reference build of the default grammar takes half a minute")
+			}
+			p := &Parser{Grammar: tt.grammar}
+			start := time.Now()
+			p.BuildTable()
+			t.Logf("%d states built in %v", len(p.States), time.Since(start))
+			start = time.Now()
+			reference := &Parser{Grammar: tt.grammar}
+			reference.States = buildStatesByGOTO(reference)
+			reference.BuildTable()
+			t.Logf("%d states of the reference built in %v", len(reference.States), time.Since(start))
+			if len(p.States) != len(reference.States) {
+				t.Fatalf("Expected %d states, got %d", len(reference.States), len(p.States))
+			}
+
+			// the states are matched by walking both automata from the state 0
+			matched := map[int]int{0: 0}
+			queue := []int{0}
+			for len(queue) > 0 {
+				i := queue[0]
+				queue = queue[1:]
+				state, other := p.States[i], reference.States[matched[i]]
+				if state.Items.Key() != other.Items.Key() || len(state.Transitions) != len(other.Transitions) {
+					t.Fatalf("Expected the state %d to be the state %d of the reference", i, other.Index)
+				}
+				for symbol, next := range state.Transitions {
+					otherNext, ok := other.Transitions[symbol]
+					if !ok {
+						t.Fatalf("Expected a transition on %s from the state %d of the reference", symbol, other.Index)
+					}
+					if j, ok := matched[next.Index]; !ok {
+						matched[next.Index] = otherNext.Index
+						queue = append(queue, next.Index)
+					} else if j != otherNext.Index {
+						t.Fatalf("Expected the transition on %s from %d to reach %d, got %d", symbol, i, otherNext.Index, j)
+					}
+				}
+			}
+
+			for i := range p.States {
+				j := matched[i]
+				if len(p.Table.ActionTable[i]) != len(reference.Table.ActionTable[j]) {
+					t.Errorf("Expected the actions of the state %d to be the ones of the state %d", i, j)
+				}
+				for terminal, action := range p.Table.ActionTable[i] {
+					if action.Type == SHIFT {
+						action.Number = matched[action.Number]
+					}
+					if other := reference.Table.ActionTable[j][terminal]; action != other {
+						t.Errorf("Expected %v in the state %d on %s, got %v", other, i, terminal, action)
+					}
+				}
+				if len(p.Table.GotoTable[i]) != len(reference.Table.GotoTable[j]) {
+					t.Errorf("Expected the gotos of the state %d to be the ones of the state %d", i, j)
+				}
+				for symbol, next := range p.Table.GotoTable[i] {
+					if other := reference.Table.GotoTable[j][symbol]; matched[next] != other {
+						t.Errorf("Expected the goto on %s from the state %d to be %d, got %d", symbol, i, other, matched[next])
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,305 @@
+package parser
+
+import (
+	"errors"
+	"slices"
+	"strings"
+
+	. "app/utils/collections"
+	"app/utils/i18n"
+)
+
+// GrammarAnalysis is the result of Grammar.Analyze.
+type GrammarAnalysis struct {
+	Nullable  Set[Symbol]
+	FirstSet  FirstSet
+	FollowSet FollowSet
+
+	// Undeclared lists the body symbols that are neither terminals nor heads of a production.
+	Undeclared []Symbol
+	// TerminalHeads lists the heads of productions that are also terminals.
+	TerminalHeads []Symbol
+	// Unreachable lists the non-terminals that can't be derived from the start symbol.
+	Unreachable []Symbol
+	// Unproductive lists the non-terminals that derive no string of terminals.
+	Unproductive []Symbol
+	// LeftRecursion lists the left recursion cycles, e.g. [A B] for A ⇒ B ... ⇒ A ...,
+	// where the leading symbols skipped in a production are nullable.
+	LeftRecursion [][]Symbol
+}
+
+// Analyze computes the nullable symbols, the FIRST and FOLLOW sets of the grammar, and detects
+// undeclared symbols, terminals used as heads, unreachable and unproductive non-terminals, and left recursion.
+// The symbols of the results are in the order of their first appearance in the productions.
+func (g *Grammar) Analyze() GrammarAnalysis {
+	p := &Parser{Grammar: g}
+	p.BuildFollowSet()
+
+	analysis := GrammarAnalysis{
+		Nullable:  g.Nullable(),
+		FirstSet:  p.FirstSet,
+		FollowSet: p.FollowSet,
+	}
+
+	heads := Set[Symbol]{}.AddAll(g.NonTerminals()...)
+	for _, symbol := range g.symbols() {
+		switch {
+		case g.IsTerminal(symbol) && heads.Contains(symbol):
+			analysis.TerminalHeads = append(analysis.TerminalHeads, symbol)
+		case !g.IsTerminal(symbol) && !heads.Contains(symbol) && symbol != g.AugmentedProduction.Head:
+			analysis.Undeclared = append(analysis.Undeclared, symbol)
+		}
+	}
+
+	reachable := g.reachable()
+	productive := g.productive()
+	for _, head := range g.NonTerminals() {
+		if !reachable.Contains(head) {
+			analysis.Unreachable = append(analysis.Unreachable, head)
+		}
+		if !productive.Contains(head) {
+			analysis.Unproductive = append(analysis.Unproductive, head)
+		}
+	}
+
+	analysis.LeftRecursion = g.leftRecursionCycles(analysis.Nullable)
+	return analysis
+}
+
+// Validate checks that the grammar can be used to build a parser, i.e. that all the body symbols are declared,
+// no terminal is used as a head, all the non-terminals are reachable and productive,
+// and no terminal has several precedence declarations.
+// Left recursion is reported by Analyze but is not an error, since it is fine for an LR parser.
+func (g *Grammar) Validate() error {
+	var errs []error
+	if len(g.AugmentedProduction.Body) == 0 {
+		errs = append(errs, i18n.Errorf("the augmented production %s has no body", g.AugmentedProduction.Head))
+	}
+	analysis := g.Analyze()
+	if len(analysis.Undeclared) > 0 {
+		errs = append(errs, i18n.Errorf("undeclared symbols, neither terminals nor heads: %v", analysis.Undeclared))
+	}
+	if len(analysis.TerminalHeads) > 0 {
+		errs = append(errs, i18n.Errorf("terminals used as heads: %v", analysis.TerminalHeads))
+	}
+	if len(analysis.Unreachable) > 0 {
+		errs = append(errs, i18n.Errorf("unreachable non-terminals: %v", analysis.Unreachable))
+	}
+	if len(analysis.Unproductive) > 0 {
+		errs = append(errs, i18n.Errorf("unproductive non-terminals: %v", analysis.Unproductive))
+	}
+	declared := Set[Terminal]{}
+	for _, declaration := range g.Precedences {
+		for _, terminal := range declaration.Terminals {
+			if declared.Contains(terminal) {
+				errs = append(errs, i18n.Errorf("terminal %s has several precedence declarations", terminal))
+			}
+			declared.Add(terminal)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Nullable returns the non-terminals that derive ε.
+func (g *Grammar) Nullable() Set[Symbol] {
+	nullable := Set[Symbol]{}
+	loop := true
+	for loop {
+		loop = false
+		for _, production := range g.Productions {
+			if nullable.Contains(production.Head) {
+				continue
+			}
+			if !slices.ContainsFunc(production.Body, func(symbol Symbol) bool {
+				return !symbol.IsEpsilon() && !nullable.Contains(symbol)
+			}) {
+				nullable.Add(production.Head)
+				loop = true
+			}
+		}
+	}
+	return nullable
+}
+
+// symbols returns the symbols of the productions' bodies, without ε.
+func (g *Grammar) symbols() []Symbol {
+	var symbols []Symbol
+	for _, production := range append([]Production{g.AugmentedProduction}, g.Productions...) {
+		if !slices.Contains(symbols, production.Head) {
+			symbols = append(symbols, production.Head)
+		}
+		for _, symbol := range production.Body {
+			if !symbol.IsEpsilon() && !slices.Contains(symbols, symbol) {
+				symbols = append(symbols, symbol)
+			}
+		}
+	}
+	return symbols
+}
+
+// reachable returns the symbols that can be derived from the start symbol.
+func (g *Grammar) reachable() Set[Symbol] {
+	reachable := Set[Symbol]{}.AddAll(g.AugmentedProduction.Body...)
+	loop := true
+	for loop {
+		loop = false
+		for _, production := range g.Productions {
+			if !reachable.Contains(production.Head) {
+				continue
+			}
+			for _, symbol := range production.Body {
+				if !reachable.Contains(symbol) {
+					reachable.Add(symbol)
+					loop = true
+				}
+			}
+		}
+	}
+	return reachable
+}
+
+// productive returns the symbols that derive a string of terminals.
+func (g *Grammar) productive() Set[Symbol] {
+	productive := Set[Symbol]{}
+	loop := true
+	for loop {
+		loop = false
+		for _, production := range g.Productions {
+			if productive.Contains(production.Head) {
+				continue
+			}
+			if !slices.ContainsFunc(production.Body, func(symbol Symbol) bool {
+				return !symbol.IsEpsilon() && !g.IsTerminal(symbol) && !productive.Contains(symbol)
+			}) {
+				productive.Add(production.Head)
+				loop = true
+			}
+		}
+	}
+	return productive
+}
+
+// leftRecursionCycles finds a cycle for every strongly connected component of the left-corner graph,
+// which has an edge A → X for every production A → α X β with α nullable.
+func (g *Grammar) leftRecursionCycles(nullable Set[Symbol]) [][]Symbol {
+	order := g.NonTerminals()
+	edges := make(map[Symbol][]Symbol)
+	for _, production := range g.Productions {
+		for _, symbol := range production.Body {
+			if symbol.IsEpsilon() {
+				continue
+			}
+			if !g.IsTerminal(symbol) && !slices.Contains(edges[production.Head], symbol) {
+				edges[production.Head] = append(edges[production.Head], symbol)
+			}
+			if !nullable.Contains(symbol) {
+				break
+			}
+		}
+	}
+
+	var cycles [][]Symbol
+	visited := Set[Symbol]{}
+	for _, component := range stronglyConnectedComponents(order, edges) {
+		start := component[0]
+		if visited.Contains(start) {
+			continue
+		}
+		visited.AddAll(component...)
+		if len(component) == 1 && !slices.Contains(edges[start], start) {
+			continue
+		}
+		cycles = append(cycles, shortestCycle(start, Set[Symbol]{}.AddAll(component...), edges))
+	}
+	return cycles
+}
+
+// stronglyConnectedComponents computes the strongly connected components of the graph with Tarjan's algorithm.
+// Each component starts with its first node in order.
+func stronglyConnectedComponents(order []Symbol, edges map[Symbol][]Symbol) [][]Symbol {
+	index := make(map[Symbol]int)
+	lowLink := make(map[Symbol]int)
+	stack := Stack[Symbol]{}
+	onStack := Set[Symbol]{}
+	var components [][]Symbol
+
+	var connect func(v Symbol)
+	connect = func(v Symbol) {
+		index[v] = len(index)
+		lowLink[v] = index[v]
+		stack.Push(v)
+		onStack.Add(v)
+		for _, w := range edges[v] {
+			if _, visited := index[w]; !visited {
+				connect(w)
+				lowLink[v] = min(lowLink[v], lowLink[w])
+			} else if onStack.Contains(w) {
+				lowLink[v] = min(lowLink[v], index[w])
+			}
+		}
+		if lowLink[v] != index[v] {
+			return
+		}
+		var component []Symbol
+		for {
+			w, _ := stack.Pop()
+			onStack.Remove(w)
+			component = append(component, w)
+			if w == v {
+				break
+			}
+		}
+		slices.SortFunc(component, func(a, b Symbol) int {
+			return slices.Index(order, a) - slices.Index(order, b)
+		})
+		components = append(components, component)
+	}
+	for _, v := range order {
+		if _, visited := index[v]; !visited {
+			connect(v)
+		}
+	}
+	slices.SortFunc(components, func(a, b []Symbol) int {
+		return slices.Index(order, a[0]) - slices.Index(order, b[0])
+	})
+	return components
+}
+
+// shortestCycle finds the shortest path from start back to start inside the component.
+func shortestCycle(start Symbol, component Set[Symbol], edges map[Symbol][]Symbol) []Symbol {
+	prev := make(map[Symbol]Symbol)
+	queue := []Symbol{start}
+	for len(queue) > 0 {
+		v := queue[0]
+		queue = queue[1:]
+		for _, w := range edges[v] {
+			if w == start {
+				cycle := []Symbol{v}
+				for v != start {
+					v = prev[v]
+					cycle = append(cycle, v)
+				}
+				slices.Reverse(cycle)
+				return cycle
+			}
+			if _, seen := prev[w]; !seen && component.Contains(w) {
+				prev[w] = v
+				queue = append(queue, w)
+			}
+		}
+	}
+	return nil
+}
+
+// FormatCycle formats a left recursion cycle as A -> B -> A.
+func FormatCycle(cycle []Symbol) string {
+	var sb strings.Builder
+	for _, symbol := range cycle {
+		sb.WriteString(string(symbol))
+		sb.WriteString(" -> ")
+	}
+	if len(cycle) > 0 {
+		sb.WriteString(string(cycle[0]))
+	}
+	return sb.String()
+}
@@ -0,0 +1,90 @@
+package parser_test
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	. "app/parser"
+	. "app/utils/collections"
+)
+
+func TestGrammar_Analyze(t *testing.T) {
+	g := Grammar{
+		AugmentedProduction: Production{Head: "S'", Body: []Symbol{"S"}},
+		Productions: []Production{
+			{Head: "S", Body: []Symbol{"N", "A", "a"}},
+			{Head: "S", Body: []Symbol{"b", "X"}},
+			{Head: "N", Body: []Symbol{EPSILON}},
+			{Head: "A", Body: []Symbol{"S", "c"}},
+			{Head: "A", Body: []Symbol{"U", "c"}},
+			{Head: "A", Body: []Symbol{EPSILON}},
+			{Head: "U", Body: []Symbol{"U", "d"}},
+			{Head: "R", Body: []Symbol{"a"}},
+			{Head: "a", Body: []Symbol{"b"}},
+		},
+		Terminals: Set[Terminal]{}.AddAll("a", "b", "c", "d", EPSILON, TERMINATE),
+	}
+	analysis := g.Analyze()
+	var cycles []string
+	for _, cycle := range analysis.LeftRecursion {
+		cycles = append(cycles, FormatCycle(cycle))
+	}
+	fmt.Printf("Nullable: %v\nUndeclared: %v\nTerminal heads: %v\nUnreachable: %v\nUnproductive: %v\nLeft recursion: %v\n",
+		analysis.Nullable, analysis.Undeclared, analysis.TerminalHeads, analysis.Unreachable, analysis.Unproductive, cycles)
+
+	if !analysis.Nullable.Equals(Set[Symbol]{}.AddAll("N", "A")) {
+		t.Errorf("Expected N and A to be nullable, got %v", analysis.Nullable)
+	}
+	if !analysis.FollowSet["A"].Equals(Set[Terminal]{}.AddAll("a")) {
+		t.Errorf("Expected FOLLOW(A) = {a}, got %v", analysis.FollowSet["A"])
+	}
+	expected := map[string][]Symbol{
+		"undeclared":     {"X"},
+		"terminal heads": {"a"},
+		"unreachable":    {"R"},
+		"unproductive":   {"U"},
+	}
+	actual := map[string][]Symbol{
+		"undeclared":     analysis.Undeclared,
+		"terminal heads": analysis.TerminalHeads,
+		"unreachable":    analysis.Unreachable,
+		"unproductive":   analysis.Unproductive,
+	}
+	for name, symbols := range expected {
+		if !slices.Equal(actual[name], symbols) {
+			t.Errorf("Expected %s %v, got %v", name, symbols, actual[name])
+		}
+	}
+	if !slices.Equal(cycles, []string{"S -> A -> S", "U -> U"}) {
+		t.Errorf("Expected left recursion S -> A -> S and U -> U, got %v", cycles)
+	}
+
+	err := g.Validate()
+	if err == nil {
+		t.Fatalf("Expected the grammar to be invalid")
+	}
+	fmt.Println(err)
+	for _, symbol := range []string{"X", "a", "R", "U"} {
+		if !strings.Contains(err.Error(), symbol) {
+			t.Errorf("Expected %s to be reported, got %v", symbol, err)
+		}
+	}
+
+	defer func() {
+		if r := recover(); r == nil {
+			t.Errorf("Expected BuildStates to panic on an invalid grammar")
+		}
+	}()
+	p := &Parser{Grammar: &g}
+	p.BuildStates()
+}
+
+func TestGrammar_Validate(t *testing.T) {
+	for i, g := range append(slices.Clone(grammars), *NewGrammar()) {
+		if err := g.Validate(); err != nil {
+			t.Errorf("Expected grammar %d to be valid, got %v", i, err)
+		}
+	}
+}
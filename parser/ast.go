@@ -20,6 +20,9 @@ type ASTNode struct {
 	Type    Symbol // Type of the node (e.g., statement, expression, declaration, etc.)
 	Payload any
 
+	// production is the production reduced to the node, nil for the nodes of tokens
+	production *Production
+
 	_genCodeStartLine int
 	_genCodeEndLine   int
 }
@@ -51,3 +54,32 @@ func (p *Parser) Token2ASTNode(token *lexer.Token) *ASTNode {
 		Payload:  nil,
 	}
 }
+
+// span returns the span of the tokens of the node, from its first token to its last one.
+// It is false for a node without tokens, e.g. the one of an ε production.
+func (a *ASTNode) span() (lexer.Span, bool) {
+	if len(a.Children) == 0 {
+		if a.Token == nil || a.Token.Type == lexer.EXTRA {
+			return lexer.Span{}, false
+		}
+		return a.Token.Span, true
+	}
+	var first, last lexer.Span
+	found := false
+	for _, child := range a.Children {
+		if span, ok := child.span(); ok {
+			if !found {
+				first = span
+			}
+			last, found = span, true
+		}
+	}
+	return lexer.Span{
+		StartOffset: first.StartOffset,
+		EndOffset:   last.EndOffset,
+		StartLine:   first.StartLine,
+		StartCol:    first.StartCol,
+		EndLine:     last.EndLine,
+		EndCol:      last.EndCol,
+	}, found
+}
@@ -0,0 +1,461 @@
+package parser
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"app/ast"
+	"app/lexer"
+	"app/utils/i18n"
+)
+
+// BuildAST converts the tree of the rules, whose nodes remember the productions reduced to them,
+// into the typed AST. The root is the node of program → funcs block.
+// The comments, in source order, are kept in the file and give the doc comments of the declarations.
+func BuildAST(root *ASTNode, comments []*ast.Comment) (file *ast.File, err error) {
+	defer func() {
+		// a node whose shape does not match its production means the tree is not a complete parse
+		if r := recover(); r != nil {
+			file, err = nil, i18n.Errorf("cannot build the AST: %v", r)
+		}
+	}()
+	if root == nil || len(root.Children) != 2 {
+		return nil, i18n.Errorf("cannot build the AST: the root is not a program")
+	}
+	b := &astBuilder{comments: comments, code: map[int64]int64{}}
+	b.codeLines(root)
+	file = &ast.File{Span: b.span(root), Comments: comments}
+	for _, decl := range b.list(root.Children[0]) {
+		file.Decls = append(file.Decls, b.topDecl(decl))
+	}
+	file.Main = b.block(root.Children[1])
+	return file, nil
+}
+
+// BuildComments converts the comments kept by the lexer into the comments of the AST.
+func BuildComments(tokens []lexer.Token) []*ast.Comment {
+	comments := make([]*ast.Comment, 0, len(tokens))
+	for _, token := range tokens {
+		comments = append(comments, &ast.Comment{Span: tokenSpan(token.Span), Text: token.Val})
+	}
+	return comments
+}
+
+func tokenSpan(span lexer.Span) ast.Span {
+	return ast.Span{
+		Start: ast.Pos{Offset: span.StartOffset, Line: span.StartLine, Column: span.StartCol},
+		End:   ast.Pos{Offset: span.EndOffset, Line: span.EndLine, Column: span.EndCol},
+	}
+}
+
+type astBuilder struct {
+	comments []*ast.Comment
+	code     map[int64]int64 // the column of the first token of each line with code
+}
+
+// codeLines records the first token of each line under the node.
+func (b *astBuilder) codeLines(n *ASTNode) {
+	if n.production == nil && n.Token != nil && n.Token.Type != lexer.EXTRA {
+		line, col := n.Token.Span.StartLine, n.Token.Span.StartCol
+		if first, ok := b.code[line]; !ok || col < first {
+			b.code[line] = col
+		}
+	}
+	for _, child := range n.Children {
+		b.codeLines(child)
+	}
+}
+
+// doc returns the doc comment of a declaration starting at pos: the // comments each on a line
+// of its own on the lines right before it, if the declaration is the first code of its line.
+func (b *astBuilder) doc(pos ast.Pos) *ast.CommentGroup {
+	if b.code[pos.Line] != pos.Column {
+		return nil
+	}
+	i := sort.Search(len(b.comments), func(i int) bool {
+		return !b.comments[i].Start.Before(pos)
+	})
+	var list []*ast.Comment
+	for line := pos.Line - 1; i > 0; line-- {
+		comment := b.comments[i-1]
+		if !strings.HasPrefix(comment.Text, "//") || comment.Start.Line != line {
+			break
+		}
+		// a comment after code or after another comment is not on a line of its own
+		if _, ok := b.code[line]; ok || i > 1 && b.comments[i-2].End.Line == line {
+			break
+		}
+		list = append([]*ast.Comment{comment}, list...)
+		i--
+	}
+	if len(list) == 0 {
+		return nil
+	}
+	return &ast.CommentGroup{List: list}
+}
+
+// body returns the body of the production reduced to the node.
+func (b *astBuilder) body(n *ASTNode) []Symbol {
+	if n.production == nil {
+		panic(fmt.Sprintf("%q was not reduced by a production", n.raw))
+	}
+	return withoutEpsilon(n.production.Body)
+}
+
+// head returns the head of the production reduced to the node, or the symbol of a token.
+func (b *astBuilder) head(n *ASTNode) Symbol {
+	if n.production == nil {
+		return n.Type
+	}
+	return n.production.Head
+}
+
+// span returns the span of the tokens under the node.
+func (b *astBuilder) span(n *ASTNode) ast.Span {
+	span, _ := b.tokenSpan(n)
+	return span
+}
+
+func (b *astBuilder) tokenSpan(n *ASTNode) (span ast.Span, ok bool) {
+	if n.production == nil && n.Token != nil && n.Token.Type != lexer.EXTRA {
+		return tokenSpan(n.Token.Span), true
+	}
+	for _, child := range n.Children {
+		if s, found := b.tokenSpan(child); found {
+			if ok {
+				span = span.Join(s)
+			} else {
+				span, ok = s, true
+			}
+		}
+	}
+	return span, ok
+}
+
+// list flattens a left-recursive list such as decls → decls decl | ε into its items.
+func (b *astBuilder) list(n *ASTNode) []*ASTNode {
+	var items []*ASTNode
+	for {
+		switch children := n.Children; len(children) {
+		case 0:
+			return items
+		case 1:
+			// params → param_list and args → arg_list, or the first item of a list without ε
+			if strings.HasSuffix(string(b.head(children[0])), "_list") {
+				n = children[0]
+				continue
+			}
+			return append([]*ASTNode{children[0]}, items...)
+		default:
+			// list → list item | list , item
+			items = append([]*ASTNode{children[len(children)-1]}, items...)
+			n = children[0]
+		}
+	}
+}
+
+func (b *astBuilder) ident(n *ASTNode) *ast.Ident {
+	return &ast.Ident{Span: b.span(n), Name: n.Token.Val}
+}
+
+func (b *astBuilder) literal(n *ASTNode) *ast.BasicLit {
+	return &ast.BasicLit{Span: b.span(n), Kind: string(n.Type), Value: n.Token.Val}
+}
+
+func (b *astBuilder) topDecl(n *ASTNode) ast.Decl {
+	switch b.head(n) {
+	case "func_decl":
+		return b.funcDecl(n)
+	case "type_decl":
+		return b.structDecl(n)
+	case "const_decl":
+		return b.constDecl(n)
+	}
+	panic(fmt.Sprintf("unexpected declaration %s", b.head(n)))
+}
+
+// func_decl → func_sig block
+// func_sig → func_head ( params ) | func_head ( params ) type_spec
+// func_head → func id
+func (b *astBuilder) funcDecl(n *ASTNode) *ast.FuncDecl {
+	sig := n.Children[0]
+	span := b.span(n)
+	decl := &ast.FuncDecl{
+		Span: span,
+		Doc:  b.doc(span.Start),
+		Name: b.ident(sig.Children[0].Children[1]),
+		Body: b.block(n.Children[1]),
+	}
+	for _, param := range b.list(sig.Children[2]) {
+		decl.Params = append(decl.Params, b.field(param))
+	}
+	if len(sig.Children) == 5 {
+		decl.Result = b.typeSpec(sig.Children[4])
+	}
+	return decl
+}
+
+// param → type_spec id
+// field → type_spec id ;
+func (b *astBuilder) field(n *ASTNode) *ast.Field {
+	span := b.span(n)
+	return &ast.Field{Span: span, Doc: b.doc(span.Start), Type: b.typeSpec(n.Children[0]), Name: b.ident(n.Children[1])}
+}
+
+// type_decl → type id struct { fields }
+func (b *astBuilder) structDecl(n *ASTNode) *ast.StructDecl {
+	span := b.span(n)
+	decl := &ast.StructDecl{Span: span, Doc: b.doc(span.Start), Name: b.ident(n.Children[1])}
+	for _, field := range b.list(n.Children[4]) {
+		decl.Fields = append(decl.Fields, b.field(field))
+	}
+	return decl
+}
+
+// const_decl → const id = bool ;
+func (b *astBuilder) constDecl(n *ASTNode) *ast.ConstDecl {
+	span := b.span(n)
+	return &ast.ConstDecl{Span: span, Doc: b.doc(span.Start), Name: b.ident(n.Children[1]), Value: b.expr(n.Children[3])}
+}
+
+// type_spec → type_spec [ num ] | type_spec [ id ] | * type_spec | basic | struct id
+func (b *astBuilder) typeSpec(n *ASTNode) ast.Type {
+	children := n.Children
+	switch body := b.body(n); {
+	case len(body) == 4 && body[2] == "num":
+		return &ast.ArrayType{Span: b.span(n), Elem: b.typeSpec(children[0]), Len: b.literal(children[2])}
+	case len(body) == 4:
+		return &ast.ArrayType{Span: b.span(n), Elem: b.typeSpec(children[0]), Len: b.ident(children[2])}
+	case len(body) == 2 && body[0] == "*":
+		return &ast.PointerType{Span: b.span(n), Elem: b.typeSpec(children[1])}
+	case len(body) == 2:
+		return &ast.StructType{Span: b.span(n), Name: b.ident(children[1])}
+	default:
+		return &ast.BasicType{Span: b.span(n), Name: children[0].Token.Val}
+	}
+}
+
+// decl → type_spec id ; | type_spec id = bool ; | type_spec id = { init_list } ; | type_decl | const_decl
+func (b *astBuilder) decl(n *ASTNode) ast.Decl {
+	children := n.Children
+	if len(children) == 1 {
+		return b.topDecl(children[0])
+	}
+	span := b.span(n)
+	decl := &ast.VarDecl{Span: span, Doc: b.doc(span.Start), Type: b.typeSpec(children[0]), Name: b.ident(children[1])}
+	switch len(children) {
+	case 5:
+		decl.Value = b.expr(children[3])
+	case 7:
+		decl.Value = b.compositeLit(children[3:6])
+	}
+	return decl
+}
+
+// compositeLit converts { init_list }, where init → bool | { init_list }.
+func (b *astBuilder) compositeLit(braced []*ASTNode) *ast.CompositeLit {
+	lit := &ast.CompositeLit{Span: b.span(braced[0]).Join(b.span(braced[2]))}
+	for _, init := range b.list(braced[1]) {
+		if len(init.Children) == 3 {
+			lit.Elems = append(lit.Elems, b.compositeLit(init.Children))
+		} else {
+			lit.Elems = append(lit.Elems, b.expr(init.Children[0]))
+		}
+	}
+	return lit
+}
+
+// block → { decls stmts } | { decls }
+func (b *astBuilder) block(n *ASTNode) *ast.BlockStmt {
+	block := &ast.BlockStmt{Span: b.span(n)}
+	block.List = b.decls(n.Children[1])
+	if len(n.Children) == 4 {
+		block.List = append(block.List, b.stmts(n.Children[2])...)
+	}
+	return block
+}
+
+func (b *astBuilder) decls(n *ASTNode) []ast.Stmt {
+	var list []ast.Stmt
+	for _, decl := range b.list(n) {
+		list = append(list, &ast.DeclStmt{Span: b.span(decl), Decl: b.decl(decl)})
+	}
+	return list
+}
+
+// stmts → stmts stmt | ε
+func (b *astBuilder) stmts(n *ASTNode) []ast.Stmt {
+	var list []ast.Stmt
+	for _, stmt := range b.list(n) {
+		list = append(list, b.stmtOrDecl(stmt))
+	}
+	return list
+}
+
+// stmtOrDecl converts stmt → matched_stmt | decl.
+func (b *astBuilder) stmtOrDecl(n *ASTNode) ast.Stmt {
+	if child := n.Children[0]; b.head(child) == "decl" {
+		return &ast.DeclStmt{Span: b.span(child), Decl: b.decl(child)}
+	}
+	return b.stmt(n.Children[0])
+}
+
+func (b *astBuilder) stmt(n *ASTNode) ast.Stmt {
+	children := n.Children
+	span := b.span(n)
+	body := b.body(n)
+	switch body[0] {
+	case "loc":
+		if body[1] == "=" {
+			// loc = bool ;
+			return &ast.AssignStmt{Span: span, Target: b.expr(children[0]), Value: b.expr(children[2])}
+		}
+		// loc ++ ; | loc -- ;
+		return &ast.IncDecStmt{Span: span, X: b.expr(children[0]), Op: children[1].Token.Val}
+	case "*":
+		// * unary = bool ;
+		target := &ast.UnaryExpr{Span: b.span(children[0]).Join(b.span(children[1])), Op: "*", X: b.expr(children[1])}
+		return &ast.AssignStmt{Span: span, Target: target, Value: b.expr(children[3])}
+	case "++", "--":
+		return &ast.IncDecStmt{Span: span, X: b.expr(children[1]), Op: children[0].Token.Val, Prefix: true}
+	case "if":
+		stmt := &ast.IfStmt{Span: span, Cond: b.expr(children[2]), Then: b.stmt(children[4])}
+		if len(children) == 7 {
+			stmt.Else = b.stmt(children[6])
+		}
+		return stmt
+	case "while":
+		return &ast.WhileStmt{Span: span, Cond: b.expr(children[2]), Body: b.stmtOrDecl(children[4])}
+	case "do":
+		return &ast.DoWhileStmt{Span: span, Body: b.stmtOrDecl(children[1]), Cond: b.expr(children[4])}
+	case "break", "continue":
+		stmt := &ast.BranchStmt{Span: span, Tok: string(body[0])}
+		if len(children) == 3 {
+			stmt.Label = b.ident(children[1])
+		}
+		return stmt
+	case "for":
+		return &ast.ForStmt{
+			Span: span,
+			Init: b.forClause(children[2]),
+			Cond: b.expr(children[4]),
+			Post: b.forClause(children[6]),
+			Body: b.stmtOrDecl(children[8]),
+		}
+	case "switch":
+		stmt := &ast.SwitchStmt{Span: span, Tag: b.expr(children[2])}
+		for _, clause := range b.list(children[5]) {
+			stmt.Cases = append(stmt.Cases, b.caseClause(clause))
+		}
+		return stmt
+	case "id":
+		if body[1] == ":" {
+			return &ast.LabeledStmt{Span: span, Label: b.ident(children[0]), Stmt: b.stmt(children[2])}
+		}
+		// id ( args ) ;
+		return &ast.ExprStmt{Span: span, X: b.call(children[:4])}
+	case "return":
+		stmt := &ast.ReturnStmt{Span: span}
+		if len(children) == 3 {
+			stmt.Result = b.expr(children[1])
+		}
+		return stmt
+	case "block":
+		return b.block(children[0])
+	}
+	panic(fmt.Sprintf("unexpected statement %v", body))
+}
+
+// for_init → loc = bool | ε
+// for_step → loc = bool | loc ++ | loc -- | ++ loc | -- loc | ε
+func (b *astBuilder) forClause(n *ASTNode) ast.Stmt {
+	children := n.Children
+	span := b.span(n)
+	switch {
+	case len(children) == 0:
+		return nil
+	case len(children) == 3:
+		return &ast.AssignStmt{Span: span, Target: b.expr(children[0]), Value: b.expr(children[2])}
+	case b.head(children[0]) == "loc":
+		return &ast.IncDecStmt{Span: span, X: b.expr(children[0]), Op: children[1].Token.Val}
+	default:
+		return &ast.IncDecStmt{Span: span, X: b.expr(children[1]), Op: children[0].Token.Val, Prefix: true}
+	}
+}
+
+// case_clause → case bool : stmts | default : stmts
+func (b *astBuilder) caseClause(n *ASTNode) *ast.CaseClause {
+	children := n.Children
+	clause := &ast.CaseClause{Span: b.span(n)}
+	if len(children) == 4 {
+		clause.Value = b.expr(children[1])
+	}
+	clause.Body = b.stmts(children[len(children)-1])
+	return clause
+}
+
+// call converts id ( args ).
+func (b *astBuilder) call(children []*ASTNode) *ast.CallExpr {
+	call := &ast.CallExpr{Span: b.span(children[0]).Join(b.span(children[3])), Fun: b.ident(children[0])}
+	for _, arg := range b.list(children[2]) {
+		call.Args = append(call.Args, b.expr(arg))
+	}
+	return call
+}
+
+func (b *astBuilder) expr(n *ASTNode) ast.Expr {
+	if n.production == nil {
+		// a token in the place of an expression, such as num in loc → loc [ num ]
+		if n.Type == "id" {
+			return b.ident(n)
+		}
+		return b.literal(n)
+	}
+
+	children := n.Children
+	span := b.span(n)
+	body := b.body(n)
+	switch b.head(n) {
+	case "loc":
+		switch len(children) {
+		case 1:
+			return b.ident(children[0])
+		case 3:
+			return &ast.SelectorExpr{Span: span, X: b.expr(children[0]), Sel: b.ident(children[2])}
+		default:
+			return &ast.IndexExpr{Span: span, X: b.expr(children[0]), Index: b.literal(children[2])}
+		}
+	case "unary":
+		switch body[0] {
+		case "factor":
+			return b.expr(children[0])
+		case "++", "--":
+			return &ast.IncDecExpr{Span: span, X: b.expr(children[1]), Op: children[0].Token.Val, Prefix: true}
+		default:
+			return &ast.UnaryExpr{Span: span, Op: children[0].Token.Val, X: b.expr(children[1])}
+		}
+	case "factor":
+		switch {
+		case body[0] == "id":
+			return b.call(children)
+		case body[0] == "basic":
+			typ := &ast.BasicType{Span: b.span(children[0]), Name: children[0].Token.Val}
+			return &ast.CastExpr{Span: span, Type: typ, X: b.expr(children[2])}
+		case body[0] == "(":
+			return &ast.ParenExpr{Span: span, X: b.expr(children[1])}
+		case body[0] == "loc" && len(children) == 2:
+			return &ast.IncDecExpr{Span: span, X: b.expr(children[0]), Op: children[1].Token.Val}
+		case body[0] == "loc":
+			return b.expr(children[0])
+		default:
+			return b.literal(children[0])
+		}
+	}
+
+	// bool → bool', and the levels of the binary operators from bool' → bool' || join to term → term * unary
+	if len(children) == 1 {
+		return b.expr(children[0])
+	}
+	return &ast.BinaryExpr{Span: span, X: b.expr(children[0]), Op: children[1].Token.Val, Y: b.expr(children[2])}
+}
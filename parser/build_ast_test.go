@@ -0,0 +1,295 @@
+package parser_test
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"app/ast"
+	"app/lexer"
+	"app/parser"
+)
+
+func parseAST(src string) (*ast.File, error) {
+	ensureSharedParser()
+	return sharedParser.Parse(lexer.NewLexer(strings.NewReader(src)), func(string) {})
+}
+
+func TestParse_AST(t *testing.T) {
+	src := `type Point struct { int x; int y; }
+const N = 2;
+func add(int a, int b) int {
+	return a + b;
+}
+{
+	struct Point[N] ps;
+	int[2] a = {1, 2};
+	*int p;
+	int i;
+	p = &a[0];
+	for (; i < N; i++) ps[1].x = add(i, *p);
+	switch (a[1]) { case 1: break; default: a[0] = int(1.5); }
+}`
+	file, err := parseAST(src)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if len(file.Decls) != 3 {
+		t.Fatalf("Expected 3 top-level declarations, got %d", len(file.Decls))
+	}
+	st, ok := file.Decls[0].(*ast.StructDecl)
+	if !ok || st.Name.Name != "Point" || len(st.Fields) != 2 || st.Fields[1].Name.Name != "y" {
+		t.Errorf("Expected struct Point with fields x and y, got %#v", file.Decls[0])
+	}
+	if c, ok := file.Decls[1].(*ast.ConstDecl); !ok || c.Name.Name != "N" || c.Value.(*ast.BasicLit).Value != "2" {
+		t.Errorf("Expected const N = 2, got %#v", file.Decls[1])
+	}
+	fn, ok := file.Decls[2].(*ast.FuncDecl)
+	if !ok || fn.Name.Name != "add" || len(fn.Params) != 2 || fn.Result.(*ast.BasicType).Name != "int" {
+		t.Fatalf("Expected func add(int a, int b) int, got %#v", file.Decls[2])
+	}
+	ret := fn.Body.List[0].(*ast.ReturnStmt)
+	if sum, ok := ret.Result.(*ast.BinaryExpr); !ok || sum.Op != "+" {
+		t.Errorf("Expected a + b, got %#v", ret.Result)
+	}
+	expectedSpan := ast.Span{Start: ast.Pos{Offset: 79, Line: 3, Column: 1}, End: ast.Pos{Offset: 92, Line: 3, Column: 14}}
+	if ret.Span != expectedSpan || src[ret.Start.Offset:ret.End.Offset] != "return a + b;" {
+		t.Errorf("Expected the span of the return statement to be %v at %d-%d, got %v at %d-%d", expectedSpan, expectedSpan.Start.Offset, expectedSpan.End.Offset, ret.Span, ret.Start.Offset, ret.End.Offset)
+	}
+
+	var kinds []string
+	for _, stmt := range file.Main.List {
+		kinds = append(kinds, fmt.Sprintf("%T", stmt))
+	}
+	expected := "*ast.DeclStmt *ast.DeclStmt *ast.DeclStmt *ast.DeclStmt *ast.AssignStmt *ast.ForStmt *ast.SwitchStmt"
+	if strings.Join(kinds, " ") != expected {
+		t.Errorf("Expected the statements %s, got %s", expected, strings.Join(kinds, " "))
+	}
+
+	array := file.Main.List[0].(*ast.DeclStmt).Decl.(*ast.VarDecl).Type.(*ast.ArrayType)
+	if array.Len.(*ast.Ident).Name != "N" || array.Elem.(*ast.StructType).Name.Name != "Point" {
+		t.Errorf("Expected struct Point[N], got %#v", array)
+	}
+	if init := file.Main.List[1].(*ast.DeclStmt).Decl.(*ast.VarDecl).Value.(*ast.CompositeLit); len(init.Elems) != 2 {
+		t.Errorf("Expected 2 initial values, got %d", len(init.Elems))
+	}
+	if addr := file.Main.List[4].(*ast.AssignStmt).Value.(*ast.UnaryExpr); addr.Op != "&" {
+		t.Errorf("Expected &a[0], got %#v", addr)
+	}
+
+	loop := file.Main.List[5].(*ast.ForStmt)
+	if loop.Init != nil || loop.Post.(*ast.IncDecStmt).Op != "++" {
+		t.Errorf("Expected an empty init and i++, got %#v", loop)
+	}
+	assign := loop.Body.(*ast.AssignStmt)
+	if sel, ok := assign.Target.(*ast.SelectorExpr); !ok || sel.Sel.Name != "x" {
+		t.Errorf("Expected ps[1].x, got %#v", assign.Target)
+	}
+	if call, ok := assign.Value.(*ast.CallExpr); !ok || call.Fun.Name != "add" || len(call.Args) != 2 {
+		t.Errorf("Expected add(i, *p), got %#v", assign.Value)
+	}
+
+	sw := file.Main.List[6].(*ast.SwitchStmt)
+	if len(sw.Cases) != 2 || sw.Cases[1].Value != nil {
+		t.Fatalf("Expected a case and a default, got %#v", sw.Cases)
+	}
+	if cast, ok := sw.Cases[1].Body[0].(*ast.AssignStmt).Value.(*ast.CastExpr); !ok || cast.Type.Name != "int" {
+		t.Errorf("Expected int(1.5), got %#v", sw.Cases[1].Body[0])
+	}
+
+	// every node lies within its parent
+	var parents []ast.Node
+	ast.Inspect(file, func(node ast.Node) bool {
+		if node == nil {
+			parents = parents[:len(parents)-1]
+			return true
+		}
+		if len(parents) > 0 {
+			parent := parents[len(parents)-1].Range()
+			if span := node.Range(); span.Start.Before(parent.Start) || parent.End.Before(span.End) {
+				t.Errorf("Expected %T at %v to lie within %T at %v", node, span, parents[len(parents)-1], parent)
+			}
+		}
+		parents = append(parents, node)
+		return true
+	})
+}
+
+func TestParse_Doc(t *testing.T) {
+	src := `// Point is a point.
+// nolint:unused
+type Point struct {
+	// the abscissa
+	int x; int y; // the ordinate
+}
+
+// not the doc of N
+
+const N = 2;
+{
+	int a; // a
+	// b is b.
+	int b;
+	a = b; // nolint
+}`
+	file, err := parseAST(src)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	st := file.Decls[0].(*ast.StructDecl)
+	if got := st.Doc.Text(); got != "Point is a point.\nnolint:unused" {
+		t.Errorf("Expected the doc comment of Point, got %q", got)
+	}
+	if got := st.Fields[0].Doc.Text(); got != "the abscissa" {
+		t.Errorf("Expected the doc comment of x, got %q", got)
+	}
+	if st.Fields[1].Doc != nil {
+		t.Errorf("Expected y, which is not the first code of its line, to have no doc comment, got %q", st.Fields[1].Doc.Text())
+	}
+	if doc := file.Decls[1].(*ast.ConstDecl).Doc; doc != nil {
+		t.Errorf("Expected a comment before a blank line not to be a doc comment, got %q", doc.Text())
+	}
+	list := file.Main.List
+	if got := list[1].(*ast.DeclStmt).Decl.(*ast.VarDecl).Doc.Text(); got != "b is b." {
+		t.Errorf("Expected the doc comment of b, got %q", got)
+	}
+	if doc := list[0].(*ast.DeclStmt).Decl.(*ast.VarDecl).Doc; doc != nil {
+		t.Errorf("Expected a trailing comment not to be a doc comment, got %q", doc.Text())
+	}
+
+	if !file.NoLint(st.Fields[1].Name.Start, "unused") || file.NoLint(st.Fields[1].Name.Start, "shadow") {
+		t.Errorf("Expected only the unused rule to be suppressed in Point")
+	}
+	if !file.NoLint(list[2].Range().Start, "shadow") || file.NoLint(list[1].Range().Start, "shadow") {
+		t.Errorf("Expected every rule to be suppressed on the line of a = b only")
+	}
+}
+
+func TestParse_ASTSamples(t *testing.T) {
+	files, err := filepath.Glob("../tests/parser/*.in")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range files {
+		src, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		file, err := parseAST(string(src))
+		if err != nil {
+			t.Errorf("%s: expected no error, got %v", name, err)
+			continue
+		}
+		if file.Main == nil {
+			t.Errorf("%s: expected the main block", name)
+		}
+	}
+
+	if file, err := parseAST(`{ int a; a = ; }`); file != nil || err == nil {
+		t.Errorf("Expected no AST and an error for a syntax error, got %v", err)
+	}
+}
+
+func TestParse_Recovering(t *testing.T) {
+	ensureSharedParser()
+	file, err := sharedParser.Parse(lexer.NewRecoveringLexer(strings.NewReader(`{ int a; a = 1 #; a = 2 0x; }`)), func(string) {})
+	if file == nil {
+		t.Fatalf("Expected the AST once the illegal tokens are skipped, got %v", err)
+	}
+	if err == nil || !strings.Contains(err.Error(), "unknown character: #") || !strings.Contains(err.Error(), "illegal number[hex] 0x") {
+		t.Errorf("Expected both lexical errors, got %v", err)
+	}
+
+	// the errors after a syntax error are reported as well, in the order of the input
+	file, err = sharedParser.Parse(lexer.NewRecoveringLexer(strings.NewReader(`{ int a; a = $; a = ; a = @; }`)), func(string) {})
+	if file != nil || err == nil {
+		t.Fatalf("Expected no AST and an error, got %v", err)
+	}
+	if errs := strings.Split(err.Error(), "\n"); len(errs) != 5 || !strings.Contains(errs[0], "$") || !strings.Contains(errs[3], "@") {
+		t.Errorf("Expected a lexical, two syntax, a lexical and a syntax error, got %q", errs)
+	}
+}
+
+func TestParse_SyntaxErrors(t *testing.T) {
+	ensureSharedParser()
+	tests := []struct {
+		src    string
+		errors []int64 // the offsets of the syntax errors
+	}{
+		{"{ int a; a = 1 2; a = 3; a = * ; }", []int64{15, 31}},
+		{"{ int a; a = 1 a = 2; }", []int64{15}},
+		{"func f() { a = ; } { int b; b = ; }", []int64{15, 32}},
+		{"type P struct { int x int y; } { int a; if (a) { a = ; } else a = 1; a = ; }", []int64{22, 53, 73}},
+		{"{ int a; a = (1 + ; }", []int64{18}},
+		{"{ int a; a = 1; ", []int64{16}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			file, err := sharedParser.Parse(lexer.NewRecoveringLexer(strings.NewReader(tt.src)), func(string) {})
+			if file != nil || err == nil {
+				t.Fatalf("Expected no AST and an error, got %v", err)
+			}
+			errs := err.(interface{ Unwrap() []error }).Unwrap()
+			if len(errs) != len(tt.errors) {
+				t.Fatalf("Expected %d errors, got %v", len(tt.errors), errs)
+			}
+			for i, offset := range tt.errors {
+				var e *parser.Error
+				if !errors.As(errs[i], &e) || e.Span.StartOffset != offset {
+					t.Errorf("Expected the error %q at offset %d, got %+v", errs[i], offset, e)
+				}
+			}
+		})
+	}
+}
+
+func TestParse_Log(t *testing.T) {
+	ensureSharedParser()
+	tests := []struct {
+		src    string
+		errors int
+	}{
+		{"{ int a; a = 1; }", 0},
+		{"{ int a; a = b; a = c; }", 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			// the errors are only logged, once each, and nothing is written to the standard output
+			stdout := os.Stdout
+			r, w, err := os.Pipe()
+			if err != nil {
+				t.Fatal(err)
+			}
+			os.Stdout = w
+			var logs []string
+			_, perr := sharedParser.Parse(lexer.NewLexer(strings.NewReader(tt.src)), func(s string) {
+				logs = append(logs, s)
+			})
+			os.Stdout = stdout
+			w.Close()
+			printed, _ := io.ReadAll(r)
+			if strings.Contains(string(printed), "Error") {
+				t.Errorf("Expected no error on the standard output, got %s", printed)
+			}
+
+			logged, success := 0, false
+			for _, s := range logs {
+				if strings.HasPrefix(s, "Error") {
+					logged++
+				}
+				success = success || strings.Contains(s, "Parsing completed successfully.")
+			}
+			if logged != tt.errors || (perr == nil) != (tt.errors == 0) {
+				t.Errorf("Expected %d errors logged, got %d: %v", tt.errors, logged, perr)
+			}
+			if success != (tt.errors == 0) {
+				t.Errorf("Expected the success to be logged only without errors, got %v", logs)
+			}
+		})
+	}
+}
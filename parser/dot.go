@@ -0,0 +1,124 @@
+package parser
+
+import (
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+
+	"app/lexer"
+	"app/utils/dot"
+)
+
+// DOT writes the LR(1) automaton as a Graphviz graph. A state is a box listing its items if items is set,
+// with the items of the same core on one line, and a transition is labeled with its symbol.
+// The accepting state has a double border.
+func (p *Parser) DOT(w io.Writer, options dot.Options, items bool) error {
+	p.EnsureStates()
+	g := dot.NewGraph(w, "LR(1)", "shape=box, fontname=monospace")
+	for _, state := range p.States {
+		if !options.Shows(state.Index) {
+			continue
+		}
+		label := "I" + strconv.Itoa(state.Index)
+		if items {
+			label += "\n" + stateItems(state)
+		}
+		attrs := ""
+		if p.accepts(state) {
+			attrs = "peripheries=2"
+		}
+		g.Node(state.Index, label, attrs, options.OnPath(state.Index))
+	}
+	g.Start(0)
+	for _, state := range p.States {
+		if !options.Shows(state.Index) {
+			continue
+		}
+		symbols := make([]Symbol, 0, len(state.Transitions))
+		for symbol := range state.Transitions {
+			symbols = append(symbols, symbol)
+		}
+		slices.Sort(symbols)
+		for _, symbol := range symbols {
+			next := state.Transitions[symbol].Index
+			if options.Shows(next) {
+				g.Edge(state.Index, next, string(symbol), options.MoveOnPath(state.Index, next))
+			}
+		}
+	}
+	return g.Close()
+}
+
+func (p *Parser) accepts(state *State) bool {
+	for _, item := range state.Items {
+		if item.Production.Equals(p.Grammar.AugmentedProduction) && item.Dot == len(item.Production.Body) {
+			return true
+		}
+	}
+	return false
+}
+
+// stateItems returns the items of the state, one line per core with its lookaheads.
+func stateItems(state *State) string {
+	var cores []string
+	lookaheads := map[string][]string{}
+	for _, item := range state.Items {
+		var b strings.Builder
+		b.WriteString(string(item.Production.Head) + " →")
+		for i, symbol := range item.Production.Body {
+			if i == item.Dot {
+				b.WriteString(" •")
+			}
+			b.WriteString(" " + string(symbol))
+		}
+		if item.Dot == len(item.Production.Body) {
+			b.WriteString(" •")
+		}
+		core := b.String()
+		if _, ok := lookaheads[core]; !ok {
+			cores = append(cores, core)
+		}
+		lookaheads[core] = append(lookaheads[core], string(item.Lookahead))
+	}
+	var b strings.Builder
+	for _, core := range cores {
+		slices.Sort(lookaheads[core])
+		b.WriteString(core + ", " + strings.Join(lookaheads[core], "/") + "\n")
+	}
+	return b.String()
+}
+
+// Trace runs the LR(1) automaton on the input without running the rules of the productions,
+// and returns the path it took: the states it pushed, by a shift or by the goto after a reduction.
+// The path up to a syntax error is returned together with the error.
+func (p *Parser) Trace(l *lexer.Lexer) (*dot.Path, error) {
+	walker := p.NewWalker()
+	for i := range walker.Grammar.Productions {
+		walker.Grammar.Productions[i].Rule = func(*Walker) error {
+			return nil
+		}
+	}
+	path := &dot.Path{States: []int{0}}
+	for {
+		_, symbol, err := p.nextSymbol(l)
+		if err != nil {
+			return path, err
+		}
+		for {
+			action, err := walker.Next(symbol)
+			if err != nil {
+				return path, err
+			}
+			if action.Type == ACCEPT {
+				return path, nil
+			}
+			to, _ := walker.States.Peek()
+			from, _ := walker.States.PeekAtK(1)
+			path.Move(from, to)
+			if action.Type != REDUCE {
+				break
+			}
+		}
+	}
+}
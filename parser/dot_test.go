@@ -0,0 +1,56 @@
+package parser_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"app/lexer"
+	"app/utils/dot"
+)
+
+func TestParser_DOT(t *testing.T) {
+	ensureSharedParser()
+	path, err := sharedParser.Trace(lexer.NewLexer(strings.NewReader("{ int a; a = 1; }")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if path.States[0] != 0 || len(path.Moves) != len(path.States)-1 {
+		t.Fatalf("Expected a path from the state 0 with a move to each next state, got %v", path)
+	}
+	for i, move := range path.Moves {
+		if move[1] != path.States[i+1] {
+			t.Errorf("Expected the move %d to reach %d, got %v", i, path.States[i+1], move)
+		}
+	}
+	// the last move is the goto on program, to the state accepting $
+	if last := path.Moves[len(path.Moves)-1]; last != [2]int{0, 1} {
+		t.Errorf("Expected the path to end with the goto from 0 to 1, got %v", last)
+	}
+
+	var b strings.Builder
+	if err := sharedParser.DOT(&b, dot.Options{States: path.States, Path: path}, true); err != nil {
+		t.Fatal(err)
+	}
+	graph := b.String()
+	for _, expected := range []string{
+		`digraph "LR(1)" {`,
+		`0 [label="I0\lprogram' → • program, $\l`,
+		`1 [label="I1\lprogram' → program •, $\l", peripheries=2, color=red`,
+		`0 -> 2 [label="funcs", color=red`,
+	} {
+		if !strings.Contains(graph, expected) {
+			t.Errorf("Expected the graph to contain %s, got\n%s", expected, graph)
+		}
+	}
+	for _, move := range path.Moves {
+		if !strings.Contains(graph, fmt.Sprintf("\t%d -> %d [", move[0], move[1])) {
+			t.Errorf("Expected the move %v of the path in the graph", move)
+		}
+	}
+
+	_, err = sharedParser.Trace(lexer.NewLexer(strings.NewReader("{ a = ; }")))
+	if err == nil {
+		t.Errorf("Expected a syntax error")
+	}
+}
@@ -2,10 +2,14 @@ package parser
 
 import (
 	"fmt"
+	"math"
+	"math/big"
+	"slices"
 	"strconv"
 	"strings"
 
 	"app/lexer"
+	"app/utils/i18n"
 )
 
 var GenRules = struct {
@@ -13,86 +17,173 @@ var GenRules = struct {
 	Program                                               Rule
 	BlockDeclsStmts, BlockDecls, BlockStmts, BlockEpsilon Rule
 	Decls, DeclsEpsilon                                   Rule
-	Decl                                                  Rule
-	TypeArray, TypeBasic                                  Rule
+	Decl, DeclTypeDecl, DeclConstDecl, ConstDecl          Rule
+	DeclInit, DeclInitList                                Rule
+	InitList, InitListInit, InitBool, InitBraced          Rule
+	TypeArray, TypeArrayConst, TypePointer, TypeBasic     Rule
+	TypeStruct                                            Rule
+	TypeDecl, Fields, FieldsEpsilon, Field                Rule
 	Stmts, StmtsEpsilon                                   Rule
-	StmtMatchedStmt, StmtUnmatchedStmt, StmtDecls         Rule
-	UnmatchedStmtIf, UnmatchedStmtIfElse                  Rule
+	StmtMatchedStmt, StmtDecl                             Rule
 	MatchedStmtAssign, MatchedStmtIf, MatchedStmtIfElse   Rule
+	MatchedStmtIncDec, MatchedStmtStore                   Rule
 	MatchedStmtWhile, MatchedStmtDoWhile                  Rule
 	MatchedStmtBreak, MatchedStmtBlock                    Rule
-	LocArray, LocId                                       Rule
+	MatchedStmtFor, MatchedStmtLabeled                    Rule
+	MatchedStmtBreakLabel                                 Rule
+	MatchedStmtContinue, MatchedStmtContinueLabel         Rule
+	ForInit, ForInitEpsilon, ForStep, ForStepEpsilon      Rule
+	ForStepIncDec                                         Rule
+	MatchedStmtSwitch, Cases, CasesEpsilon                Rule
+	CaseClause, CaseClauseDefault                         Rule
+	LocArray, LocField, LocId                             Rule
 	Bool, BoolPrime, BoolPrimeJoin                        Rule
-	Join, JoinEquality                                    Rule
+	Join, JoinBitwise                                     Rule
+	BitOr, BitXor, BitAnd, BitwiseEquality                Rule
 	Equality, NotEquality, EqualityRelational             Rule
 	RelationalLess, RelationalGreater                     Rule
 	RelationalLessEqual, RelationalGreaterEqual           Rule
 	RelationalExpr                                        Rule
+	ShiftLeft, ShiftRight                                 Rule
 	ExprPlus, ExprMinus, ExprTerm                         Rule
-	TermMult, TermDiv, TermUnary                          Rule
-	UnaryNot, UnaryNeg, UnaryFactor                       Rule
-	FactorBool, FactorLoc, FactorNum, FactorReal          Rule
-	FactorTrue, FactorFalse                               Rule
+	TermMult, TermDiv, TermMod, TermUnary                 Rule
+	UnaryNot, UnaryNeg, UnaryIncDec, UnaryFactor          Rule
+	UnaryDeref, UnaryAddr                                 Rule
+	FactorBool, FactorLoc, FactorIncDec                   Rule
+	FactorNum, FactorReal, FactorStr, FactorChar          Rule
+	FactorTrue, FactorFalse, FactorCast                   Rule
+	Funcs, FuncsEpsilon, FuncDecl, FuncSig, FuncSigReturn Rule
+	FuncHead, Params, ParamsEpsilon                       Rule
+	ParamList, ParamListParam, Param                      Rule
+	MatchedStmtReturn, MatchedStmtReturnVoid              Rule
+	MatchedStmtCall, FactorCall                           Rule
+	Args, ArgsEpsilon, ArgList, ArgListBool               Rule
 }{
-	Program:                Program,
-	BlockDeclsStmts:        BlockDeclsStmts,
-	BlockDecls:             BlockDecls,
-	BlockStmts:             BlockStmts,
-	BlockEpsilon:           BlockEpsilon,
-	Decls:                  Decls,
-	DeclsEpsilon:           DeclsEpsilon,
-	Decl:                   Decl,
-	TypeArray:              TypeArray,
-	TypeBasic:              TypeBasic,
-	Stmts:                  Stmts,
-	StmtsEpsilon:           StmtsEpsilon,
-	StmtMatchedStmt:        StmtMatchedStmt,
-	StmtUnmatchedStmt:      StmtUnmatchedStmt,
-	StmtDecls:              StmtDecls,
-	UnmatchedStmtIf:        UnmatchedStmtIf,
-	UnmatchedStmtIfElse:    UnmatchedStmtIfElse,
-	MatchedStmtAssign:      MatchedStmtAssign,
-	MatchedStmtIf:          MatchedStmtIf,
-	MatchedStmtIfElse:      MatchedStmtIfElse,
-	MatchedStmtWhile:       MatchedStmtWhile,
-	MatchedStmtDoWhile:     MatchedStmtDoWhile,
-	MatchedStmtBreak:       MatchedStmtBreak,
-	MatchedStmtBlock:       MatchedStmtBlock,
-	LocArray:               LocArray,
-	LocId:                  LocId,
-	Bool:                   Bool,
-	BoolPrime:              BoolPrime,
-	BoolPrimeJoin:          BoolPrimeJoin,
-	Join:                   Join,
-	JoinEquality:           JoinEquality,
-	Equality:               Equality,
-	NotEquality:            NotEquality,
-	EqualityRelational:     EqualityRelational,
-	RelationalLess:         RelationalLess,
-	RelationalGreater:      RelationalGreater,
-	RelationalLessEqual:    RelationalLessEqual,
-	RelationalGreaterEqual: RelationalGreaterEqual,
-	RelationalExpr:         RelationalExpr,
-	ExprPlus:               ExprPlus,
-	ExprMinus:              ExprMinus,
-	ExprTerm:               ExprTerm,
-	TermMult:               TermMult,
-	TermDiv:                TermDiv,
-	TermUnary:              TermUnary,
-	UnaryNot:               UnaryNot,
-	UnaryNeg:               UnaryNeg,
-	UnaryFactor:            UnaryFactor,
-	FactorBool:             FactorBool,
-	FactorLoc:              FactorLoc,
-	FactorNum:              FactorNum,
-	FactorReal:             FactorReal,
-	FactorTrue:             FactorTrue,
-	FactorFalse:            FactorFalse,
+	Program:                  Program,
+	BlockDeclsStmts:          BlockDeclsStmts,
+	BlockDecls:               BlockDecls,
+	BlockStmts:               BlockStmts,
+	BlockEpsilon:             BlockEpsilon,
+	Decls:                    Decls,
+	DeclsEpsilon:             DeclsEpsilon,
+	Decl:                     Decl,
+	DeclTypeDecl:             DeclTypeDecl,
+	DeclConstDecl:            DeclConstDecl,
+	DeclInit:                 DeclInit,
+	DeclInitList:             DeclInitList,
+	InitList:                 InitList,
+	InitListInit:             InitListInit,
+	InitBool:                 InitBool,
+	InitBraced:               InitBraced,
+	ConstDecl:                ConstDecl,
+	TypeArray:                TypeArray,
+	TypeArrayConst:           TypeArrayConst,
+	TypePointer:              TypePointer,
+	TypeBasic:                TypeBasic,
+	TypeStruct:               TypeStruct,
+	TypeDecl:                 TypeDecl,
+	Fields:                   Fields,
+	FieldsEpsilon:            FieldsEpsilon,
+	Field:                    Field,
+	Stmts:                    Stmts,
+	StmtsEpsilon:             StmtsEpsilon,
+	StmtMatchedStmt:          StmtMatchedStmt,
+	StmtDecl:                 StmtDecl,
+	MatchedStmtAssign:        branch(MatchedStmtAssign),
+	MatchedStmtStore:         branch(MatchedStmtStore),
+	MatchedStmtIncDec:        branch(MatchedStmtIncDec),
+	MatchedStmtIf:            branch(MatchedStmtIf),
+	MatchedStmtIfElse:        branch(MatchedStmtIfElse),
+	MatchedStmtWhile:         branch(MatchedStmtWhile),
+	MatchedStmtDoWhile:       branch(MatchedStmtDoWhile),
+	MatchedStmtBreak:         branch(MatchedStmtBreak),
+	MatchedStmtBlock:         branch(MatchedStmtBlock),
+	MatchedStmtFor:           branch(MatchedStmtFor),
+	MatchedStmtLabeled:       branch(MatchedStmtLabeled),
+	MatchedStmtBreakLabel:    branch(MatchedStmtBreakLabel),
+	MatchedStmtContinue:      branch(MatchedStmtContinue),
+	MatchedStmtContinueLabel: branch(MatchedStmtContinueLabel),
+	ForInit:                  ForInit,
+	ForInitEpsilon:           ForInitEpsilon,
+	ForStep:                  ForStep,
+	ForStepEpsilon:           ForStepEpsilon,
+	ForStepIncDec:            ForStepIncDec,
+	MatchedStmtSwitch:        branch(MatchedStmtSwitch),
+	Cases:                    Cases,
+	CasesEpsilon:             CasesEpsilon,
+	CaseClause:               CaseClause,
+	CaseClauseDefault:        CaseClauseDefault,
+	LocArray:                 LocArray,
+	LocField:                 LocField,
+	LocId:                    LocId,
+	Bool:                     Bool,
+	BoolPrime:                BoolPrime,
+	BoolPrimeJoin:            BoolPrimeJoin,
+	Join:                     Join,
+	JoinBitwise:              JoinBitwise,
+	BitOr:                    BitOr,
+	BitXor:                   BitXor,
+	BitAnd:                   BitAnd,
+	BitwiseEquality:          BitwiseEquality,
+	Equality:                 Equality,
+	NotEquality:              NotEquality,
+	EqualityRelational:       EqualityRelational,
+	RelationalLess:           RelationalLess,
+	RelationalGreater:        RelationalGreater,
+	RelationalLessEqual:      RelationalLessEqual,
+	RelationalGreaterEqual:   RelationalGreaterEqual,
+	RelationalExpr:           RelationalExpr,
+	ShiftLeft:                ShiftLeft,
+	ShiftRight:               ShiftRight,
+	ExprPlus:                 ExprPlus,
+	ExprMinus:                ExprMinus,
+	ExprTerm:                 ExprTerm,
+	TermMult:                 TermMult,
+	TermDiv:                  TermDiv,
+	TermMod:                  TermMod,
+	TermUnary:                TermUnary,
+	UnaryNot:                 UnaryNot,
+	UnaryNeg:                 UnaryNeg,
+	UnaryDeref:               UnaryDeref,
+	UnaryAddr:                UnaryAddr,
+	UnaryIncDec:              UnaryIncDec,
+	UnaryFactor:              UnaryFactor,
+	FactorBool:               FactorBool,
+	FactorLoc:                FactorLoc,
+	FactorIncDec:             FactorIncDec,
+	FactorNum:                FactorNum,
+	FactorReal:               FactorReal,
+	FactorStr:                FactorStr,
+	FactorChar:               FactorChar,
+	FactorTrue:               FactorTrue,
+	FactorFalse:              FactorFalse,
+	FactorCast:               FactorCast,
+	Funcs:                    Funcs,
+	FuncsEpsilon:             FuncsEpsilon,
+	FuncDecl:                 FuncDecl,
+	FuncSig:                  FuncSig,
+	FuncSigReturn:            FuncSigReturn,
+	FuncHead:                 FuncHead,
+	Params:                   Params,
+	ParamsEpsilon:            ParamsEpsilon,
+	ParamList:                ParamList,
+	ParamListParam:           ParamListParam,
+	Param:                    Param,
+	MatchedStmtReturn:        branch(MatchedStmtReturn),
+	MatchedStmtReturnVoid:    branch(MatchedStmtReturnVoid),
+	MatchedStmtCall:          branch(MatchedStmtCall),
+	FactorCall:               FactorCall,
+	Args:                     Args,
+	ArgsEpsilon:              ArgsEpsilon,
+	ArgList:                  ArgList,
+	ArgListBool:              ArgListBool,
 }
 
 type _GenRuleArrayPayload struct {
 	Variable  string
 	BasicType *lexer.Token
+	Struct    *SymbolTableItem // the struct type of the elements, instead of BasicType
+	Pointer   string           // the pointer type of the elements, instead of BasicType
 	Dimension []int
 }
 
@@ -116,6 +207,115 @@ func (g _GenRuleArrayPayload) GetAllocSize(base int) int {
 	return base * g.GetArraySize()
 }
 
+// _GenRuleLocPayload is the storage a loc refers to: a variable, a field of a struct or an element of an array.
+type _GenRuleLocPayload struct {
+	Root      *SymbolTableItem // the variable the loc starts from
+	Item      *SymbolTableItem // the variable or the field referred to, or the array whose element is referred to
+	Base      int              // the address of Item
+	Dimension []int            // the indices of the element of Item, if it is an array
+}
+
+func (_GenRuleLocPayload) String() string {
+	return "!<loc>"
+}
+
+// index returns the storage of the element at the index of the array referred to.
+func (p *_GenRuleLocPayload) index(index int) *_GenRuleLocPayload {
+	return &_GenRuleLocPayload{Root: p.Root, Item: p.Item, Base: p.Base, Dimension: append(slices.Clone(p.Dimension), index)}
+}
+
+// Address returns the address of the storage referred to.
+func (p *_GenRuleLocPayload) Address() (int, error) {
+	if len(p.Dimension) == 0 {
+		return p.Base, nil
+	}
+	return p.Item.ElementAddress(p.Base, p.Dimension)
+}
+
+// AddrString returns the operand referring to the storage, $(nullptr) if it is unknown.
+// A constant is referred to by its value.
+func (p *_GenRuleLocPayload) AddrString() string {
+	if p.Item != nil && p.Item.Type == SymbolTableItemTypeConstant && len(p.Dimension) == 0 {
+		return p.Item.Value
+	}
+	addr, err := p.Address()
+	if p.Root == nil || err != nil {
+		return "$(nullptr)"
+	}
+	return p.Root.FormatAddr(addr)
+}
+
+// elementary checks if the storage is not a whole array nor a sub-array.
+func (p *_GenRuleLocPayload) elementary() bool {
+	return p.Item != nil && (p.Item.Type != SymbolTableItemTypeArray || len(p.Dimension) == len(p.Item.Dimension))
+}
+
+// StructType returns the struct type of the storage, nil if it is not a struct.
+func (p *_GenRuleLocPayload) StructType() *SymbolTableItem {
+	if !p.elementary() {
+		return nil
+	}
+	return p.Item.Struct
+}
+
+// ValueType returns the underlying type of the storage, empty if it is unknown.
+func (p *_GenRuleLocPayload) ValueType() string {
+	if p.Item == nil {
+		return ""
+	}
+	if p.Item.Type == SymbolTableItemTypeArray && p.elementary() {
+		return elementType(p.Item.UnderlyingType)
+	}
+	return p.Item.UnderlyingType
+}
+
+// _GenRuleValuePayload is the type of a value which is not found from its operands, e.g. a pointer.
+type _GenRuleValuePayload struct {
+	Type string
+}
+
+func (p _GenRuleValuePayload) String() string {
+	return fmt.Sprintf("!<%s>", p.Type)
+}
+
+type _GenRuleSwitchPayload struct {
+	Dispatch int // line of the jump to the dispatch code
+	End      int // last line of the previous case clause
+	Cases    []*_GenRuleSwitchCase
+	Default  *_GenRuleSwitchCase
+}
+
+func (_GenRuleSwitchPayload) String() string {
+	return "!<switch>"
+}
+
+type _GenRuleSwitchCase struct {
+	Value     int64
+	IsDefault bool
+	IsIllegal bool // the value failed to evaluate, reported by CaseClause
+	Line      int  // first line of the case body
+}
+
+func (_GenRuleSwitchCase) String() string {
+	return "!<case>"
+}
+
+type _GenRuleArgsPayload struct {
+	Args []string // operands of the arguments, in order
+}
+
+func (_GenRuleArgsPayload) String() string {
+	return "!<args>"
+}
+
+const (
+	// a switch is compiled into a jump table if it has at least switchJumpTableMinCases cases
+	// and at least one case for every switchJumpTableMaxSpread values in its range,
+	// otherwise into a compare chain.
+	switchJumpTableMinCases  = 4
+	switchJumpTableMaxSpread = 2
+)
+
 func debugPrintWhenRuleTriggered(w *Walker) error {
 	fmt.Println("Rule triggered")
 	fmt.Println("Current states:", w.States)
@@ -123,17 +323,24 @@ func debugPrintWhenRuleTriggered(w *Walker) error {
 	return nil
 }
 
-// program → block
+// program → funcs block
 func Program(w *Walker) error {
-	children := w.Tokens.PopTopN(w.Tokens.Size())
+	children := w.Tokens.PopTopN(2)
+	funcs, block := children[0], children[1]
+	// L0 jumps over the functions to the main block
+	main := 1
+	if funcs.Type == "funcs" && funcs._genCodeEndLine != MIN_START_LINE {
+		main = funcs._genCodeEndLine + 1
+	}
+	w.EmitGoto(0, main)
 	w.Tokens.Push(&ASTNode{
 		raw:               joinChildren(children),
 		Token:             &lexer.Token{Type: lexer.EXTRA, Val: "program"},
 		Children:          children,
 		Type:              "program",
 		Payload:           nil,
-		_genCodeStartLine: children[0]._genCodeStartLine,
-		_genCodeEndLine:   children[0]._genCodeEndLine + 1,
+		_genCodeStartLine: block._genCodeStartLine,
+		_genCodeEndLine:   block._genCodeEndLine + 1,
 	})
 	w.Emit("exit", "0")
 	n, _ := w.Tokens.Pop()
@@ -145,6 +352,221 @@ func Program(w *Walker) error {
 	return nil
 }
 
+// funcs → funcs func_decl | funcs type_decl | funcs const_decl
+func Funcs(w *Walker) error {
+	children := w.Tokens.PopTopN(2)
+	// a type or constant declaration emits no code
+	end := children[1]._genCodeEndLine
+	if children[1].Type == "type-decl" || children[1].Type == "const-decl" {
+		end = children[0]._genCodeEndLine
+	}
+	w.Tokens.Push(&ASTNode{
+		raw:               joinChildren(children),
+		Token:             &lexer.Token{Type: lexer.EXTRA, Val: "funcs"},
+		Children:          children,
+		Type:              "funcs",
+		Payload:           "!<func>",
+		_genCodeStartLine: min(children[0]._genCodeStartLine, children[1]._genCodeStartLine),
+		_genCodeEndLine:   end,
+	})
+	return nil
+}
+
+// funcs → ε
+func FuncsEpsilon(w *Walker) error {
+	w.Tokens.Push(&ASTNode{
+		raw:               "",
+		Token:             &lexer.Token{Type: lexer.EXTRA, Val: "funcs-epsilon"},
+		Children:          nil,
+		Type:              "funcs-epsilon",
+		Payload:           "!<func>",
+		_genCodeStartLine: MAX_START_LINE,
+		_genCodeEndLine:   MIN_START_LINE,
+	})
+	return nil
+}
+
+// func_decl → func_sig block
+func FuncDecl(w *Walker) error {
+	children := w.Tokens.PopTopN(2)
+	fn := children[0].Payload.(*SymbolTableItem)
+	err := w.SymbolTable.ExitFunctionScope()
+	w.Tokens.Push(&ASTNode{
+		raw:               joinChildren(children),
+		Token:             &lexer.Token{Type: lexer.EXTRA, Val: fn.Variable},
+		Children:          children,
+		Type:              "func-decl",
+		Payload:           fn,
+		_genCodeStartLine: fn.Address,
+		_genCodeEndLine:   w.GetCurrentLabelCount() - 1,
+	})
+	return err
+}
+
+// func_sig → func_head ( params )
+func FuncSig(w *Walker) error {
+	children := w.Tokens.PopTopN(4)
+	fn := children[0].Payload.(*SymbolTableItem)
+	fn.UnderlyingType = funcSignature(fn)
+	w.Tokens.Push(&ASTNode{
+		raw:               joinChildren(children),
+		Token:             &lexer.Token{Type: lexer.EXTRA, Val: fn.Variable},
+		Children:          children,
+		Type:              "func-sig",
+		Payload:           fn,
+		_genCodeStartLine: fn.Address,
+		_genCodeEndLine:   fn.Address,
+	})
+	return nil
+}
+
+// func_sig → func_head ( params ) type_spec
+func FuncSigReturn(w *Walker) error {
+	children := w.Tokens.PopTopN(5)
+	fn, t := children[0].Payload.(*SymbolTableItem), children[4]
+	var err error
+	if t.Type == "type-basic" || t.Type == "type-pointer" {
+		fn.ReturnType = typeName(t)
+		fn.VariableSize = typeSize(w, fn.ReturnType)
+	} else {
+		err = i18n.Errorf("function %s: only return values of a basic or pointer type are supported", fn.Variable)
+	}
+	fn.UnderlyingType = funcSignature(fn)
+	w.Tokens.Push(&ASTNode{
+		raw:               joinChildren(children),
+		Token:             &lexer.Token{Type: lexer.EXTRA, Val: fn.Variable},
+		Children:          children,
+		Type:              "func-sig",
+		Payload:           fn,
+		_genCodeStartLine: fn.Address,
+		_genCodeEndLine:   fn.Address,
+	})
+	return err
+}
+
+func funcSignature(fn *SymbolTableItem) string {
+	params := make([]string, len(fn.Params))
+	for i, param := range fn.Params {
+		params[i] = param.UnderlyingType
+	}
+	if fn.ReturnType == "" {
+		return fmt.Sprintf("func(%s)", strings.Join(params, ", "))
+	}
+	return fmt.Sprintf("func(%s) %s", strings.Join(params, ", "), fn.ReturnType)
+}
+
+// func_head → func id
+func FuncHead(w *Walker) error {
+	children := w.Tokens.PopTopN(2)
+	fn := &SymbolTableItem{
+		Variable:    children[1].Token.Val,
+		Type:        SymbolTableItemTypeFunction,
+		Declaration: children[1].Token.Span,
+	}
+	_, err := w.SymbolTable.Register(fn)
+	// the scope is entered even if the function is a duplicate, so that func_decl → func_sig block can exit it
+	w.SymbolTable.EnterFunctionScope(fn)
+	w.Tokens.Push(&ASTNode{
+		raw:               joinChildren(children),
+		Token:             &lexer.Token{Type: lexer.EXTRA, Val: fn.Variable},
+		Children:          children,
+		Type:              "func-head",
+		Payload:           fn,
+		_genCodeStartLine: fn.Address,
+		_genCodeEndLine:   fn.Address,
+	})
+	return err
+}
+
+// params → param_list
+func Params(w *Walker) error {
+	children := w.Tokens.PopTopN(1)
+	w.Tokens.Push(&ASTNode{
+		raw:               children[0].raw,
+		Token:             &lexer.Token{Type: lexer.EXTRA, Val: "params"},
+		Children:          children,
+		Type:              "params",
+		Payload:           "!<param>",
+		_genCodeStartLine: MAX_START_LINE,
+		_genCodeEndLine:   MIN_START_LINE,
+	})
+	return nil
+}
+
+// params → ε
+func ParamsEpsilon(w *Walker) error {
+	w.Tokens.Push(&ASTNode{
+		raw:               "",
+		Token:             &lexer.Token{Type: lexer.EXTRA, Val: "params-epsilon"},
+		Children:          nil,
+		Type:              "params-epsilon",
+		Payload:           "!<param>",
+		_genCodeStartLine: MAX_START_LINE,
+		_genCodeEndLine:   MIN_START_LINE,
+	})
+	return nil
+}
+
+// param_list → param_list , param
+func ParamList(w *Walker) error {
+	children := w.Tokens.PopTopN(3)
+	w.Tokens.Push(&ASTNode{
+		raw:               joinChildren(children),
+		Token:             &lexer.Token{Type: lexer.EXTRA, Val: "param-list"},
+		Children:          children,
+		Type:              "param-list",
+		Payload:           "!<param>",
+		_genCodeStartLine: MAX_START_LINE,
+		_genCodeEndLine:   MIN_START_LINE,
+	})
+	return nil
+}
+
+// param_list → param
+func ParamListParam(w *Walker) error {
+	children := w.Tokens.PopTopN(1)
+	w.Tokens.Push(&ASTNode{
+		raw:               children[0].raw,
+		Token:             &lexer.Token{Type: lexer.EXTRA, Val: "param-list"},
+		Children:          children,
+		Type:              "param-list",
+		Payload:           "!<param>",
+		_genCodeStartLine: MAX_START_LINE,
+		_genCodeEndLine:   MIN_START_LINE,
+	})
+	return nil
+}
+
+// param → type_spec id
+//
+// Parameters are registered in the scope of the function and allocated at the start of its activation record,
+// where the caller stores the arguments, so no alloc is emitted.
+func Param(w *Walker) error {
+	children := w.Tokens.PopTopN(2)
+	t, id := children[0], children[1]
+	var err error
+	if t.Type == "type-basic" || t.Type == "type-pointer" {
+		item, _ := typeItem(t, id.Token.Val)
+		item.Declaration = id.Token.Span
+		if _, err = w.SymbolTable.Register(item); err == nil {
+			fn := w.SymbolTable.CurrentFunction()
+			fn.Params = append(fn.Params, item)
+		}
+	} else {
+		err = i18n.Errorf("parameter %s: only parameters of a basic or pointer type are supported", id.Token.Val)
+	}
+	w.Tokens.Push(&ASTNode{
+		raw:               joinChildren(children),
+		Token:             &lexer.Token{Type: lexer.EXTRA, Val: id.Token.Val},
+		Children:          children,
+		Type:              "param",
+		Payload:           "!<param>",
+		_genCodeStartLine: MAX_START_LINE,
+		_genCodeEndLine:   MIN_START_LINE,
+	})
+	return err
+}
+
 // block → { decls stmts }
 func BlockDeclsStmts(w *Walker) error {
 	children := w.Tokens.PopTopN(4)
@@ -243,24 +665,16 @@ func declsEpsilonDoWhile(w *Walker) {
 	}
 	if do.Token.SpecificType() == lexer.ReservedWordDo {
 		w.Environment.LoopLabelStack.Push(w.GetCurrentLabelCount())
-		w.EnterLoop()
+		w.EnterLoop(loopLabel(w, 1))
 		return
 	}
 }
 
-// decl → type id;
+// decl → type_spec id;
 func Decl(w *Walker) error {
 	children := w.Tokens.PopTopN(3)
 	t, id := children[0], children[1]
-	l := -1
-	switch children[0].Type {
-	case "type-basic":
-		l = declBasic(w, t, id)
-	case "type-array":
-		l = declArray(w, t, id)
-	default:
-		fmt.Println("Error: Unknown type in decl")
-	}
+	_, l, err := decl(w, t, id)
 	w.Tokens.Push(&ASTNode{
 		raw:               joinChildren(children),
 		Token:             &lexer.Token{Type: lexer.EXTRA, Val: id.Token.Val},
@@ -270,339 +684,1807 @@ func Decl(w *Walker) error {
 		_genCodeStartLine: min(l, id._genCodeStartLine),
 		_genCodeEndLine:   l,
 	})
-	return nil
+	return unreported(err)
 }
 
-func declBasic(w *Walker, basic *ASTNode, id *ASTNode) int {
-	item := &SymbolTableItem{
-		Variable:       id.Token.Val,
-		VariableSize:   basic.Token.AllocSize(),
-		Type:           SymbolTableItemTypeVariable,
-		UnderlyingType: basic.Token.SpecificType().ToString(),
-	}
-	addr, err := w.SymbolTable.Register(item)
+// decl registers the variable and emits its allocation.
+func decl(w *Walker, t *ASTNode, id *ASTNode) (*SymbolTableItem, int, error) {
+	item, err := typeItem(t, id.Token.Val)
 	if err != nil {
-		fmt.Printf("Error: %v\n", err)
-		return -1
-	}
-	return w.Emit("alloc", fmt.Sprintf("$(%#x)", addr), strconv.Itoa(item.VariableSize), getInitialValue(basic.Token))
-}
-
-func declArray(w *Walker, array *ASTNode, id *ASTNode) int {
-	payload := array.Payload.(*_GenRuleArrayPayload)
-	if payload == nil {
-		fmt.Println("Error: Array dimension is nil")
-		return -1
-	}
-	item := &SymbolTableItem{
-		Variable:         id.Token.Val,
-		VariableSize:     4, // size of pointer
-		ArraySize:        payload.GetArraySize(),
-		ArrayElementSize: payload.BasicType.AllocSize(),
-		Dimension:        payload.GetDimension(),
-		Type:             SymbolTableItemTypeArray,
-		UnderlyingType:   fmt.Sprintf("!ptr<%s>", payload.BasicType.SpecificType().ToString()),
+		return nil, -1, err
 	}
+	item.Declaration = id.Token.Span
 	addr, err := w.SymbolTable.Register(item)
 	if err != nil {
-		fmt.Printf("Error: %v\n", err)
+		return nil, -1, err
 	}
-	return w.Emit("alloc", fmt.Sprintf("$(%#x)", addr), strconv.Itoa(item.ArrayElementSize*item.ArraySize), getInitialValue(payload.BasicType))
+	return item, w.Emit("alloc", item.FormatAddr(addr), strconv.Itoa(item.size()), typeInitialValue(t)), nil
 }
 
-// type → type [ num ]
-func TypeArray(w *Walker) error {
-	children := w.Tokens.PopTopN(4)
-	if children[2].Token.Type != lexer.INTEGER {
-		fmt.Println("Error: Array size must be an integer")
-	}
-	size, err := strconv.Atoi(children[2].Token.Val)
-	if err != nil {
-		fmt.Printf("Error: %v\n", err)
-		size = -1
-	}
-	var dimension []int
-	var basicType *lexer.Token
-	var variable string
-	if payload, ok := children[0].Payload.(*_GenRuleArrayPayload); ok {
-		dimension = append(payload.Dimension, size)
-		basicType = payload.BasicType
-		variable = payload.Variable
-	} else {
-		dimension = []int{size}
-		basicType = children[0].Token
-		variable = children[0].Token.Val
+// decl → type_spec id = bool ;
+//
+// The value is stored after the allocation.
+func DeclInit(w *Walker) error {
+	children := w.Tokens.PopTopN(5)
+	t, id, value := children[0], children[1], children[3]
+	item, l, err := decl(w, t, id)
+	end := l
+	if err == nil {
+		switch {
+		case item.Type != SymbolTableItemTypeVariable:
+			err = i18n.Errorf("%s must be initialized by a list", id.Token.Val)
+		case item.Struct != nil:
+			err = i18n.Errorf("struct %s cannot be initialized", id.Token.Val)
+		default:
+			err = checkValueType(w, item.UnderlyingType, value, id.Token.Val)
+			src, _, cerr := convertImplicitly(w, item.UnderlyingType, value)
+			if err == nil {
+				err = cerr
+			}
+			end = w.Emit("mov", item.AddrString(), src)
+		}
 	}
 	w.Tokens.Push(&ASTNode{
 		raw:               joinChildren(children),
-		Token:             &lexer.Token{Type: lexer.EXTRA, Val: fmt.Sprintf("%s[%s]", children[0].raw, children[2].raw)},
+		Token:             &lexer.Token{Type: lexer.EXTRA, Val: id.Token.Val},
 		Children:          children,
-		Type:              "type-array",
-		Payload:           &_GenRuleArrayPayload{Dimension: dimension, BasicType: basicType, Variable: variable},
-		_genCodeStartLine: min(children[0]._genCodeStartLine, children[2]._genCodeStartLine),
-		_genCodeEndLine:   max(children[0]._genCodeEndLine, children[2]._genCodeEndLine),
+		Type:              "decl",
+		Payload:           "!<decl>",
+		_genCodeStartLine: min(l, value._genCodeStartLine),
+		_genCodeEndLine:   end,
 	})
-	return nil
+	return unreported(err)
 }
 
-// type → basic
-func TypeBasic(w *Walker) error {
-	children := w.Tokens.PopTopN(1)
+// decl → type_spec id = { init_list } ;
+//
+// The initializers of an array are nested like its dimensions, e.g. `int[2][3] a = {{1, 2, 3}, {4, 5, 6}};`,
+// and there are as many of them as the size of each dimension. The elements are stored after the allocation.
+func DeclInitList(w *Walker) error {
+	children := w.Tokens.PopTopN(7)
+	t, id, list := children[0], children[1], children[4]
+	item, l, err := decl(w, t, id)
+	end := l
+	if err == nil {
+		var values []*ASTNode
+		switch {
+		case item.Type != SymbolTableItemTypeArray:
+			err = i18n.Errorf("%s is not an array and cannot be initialized by a list", id.Token.Val)
+		case item.Struct != nil:
+			err = i18n.Errorf("array of structs %s cannot be initialized", id.Token.Val)
+		default:
+			values, err = initValues(list.Payload.(*_GenRuleInitPayload), item.Dimension)
+		}
+		if err != nil {
+			err = i18n.Errorf("initializer of %s: %w", id.Token.Val, err)
+			values = nil
+		}
+		elem := elementType(item.UnderlyingType)
+		for i, value := range values {
+			if verr := checkValueType(w, elem, value, id.Token.Val); verr != nil && err == nil {
+				err = verr
+			}
+			src, _, cerr := convertImplicitly(w, elem, value)
+			if cerr != nil && err == nil {
+				err = cerr
+			}
+			end = w.Emit("mov", item.FormatAddr(item.Address+item.ArrayElementSize*i/4), src)
+		}
+	}
 	w.Tokens.Push(&ASTNode{
-		raw:               children[0].raw,
-		Token:             children[0].Token,
+		raw:               joinChildren(children),
+		Token:             &lexer.Token{Type: lexer.EXTRA, Val: id.Token.Val},
 		Children:          children,
-		Type:              "type-basic",
-		Payload:           "!<basic>",
-		_genCodeStartLine: children[0]._genCodeStartLine,
-		_genCodeEndLine:   children[0]._genCodeEndLine,
+		Type:              "decl",
+		Payload:           "!<decl>",
+		_genCodeStartLine: min(l, list._genCodeStartLine),
+		_genCodeEndLine:   end,
 	})
-	return nil
+	return unreported(err)
 }
 
-// stmts → stmts stmt
-func Stmts(w *Walker) error {
-	children := w.Tokens.PopTopN(2)
-	w.Tokens.Push(&ASTNode{
-		raw:               joinChildren(children),
-		Token:             &lexer.Token{Type: lexer.EXTRA, Val: "stmts"},
-		Children:          children,
-		Type:              "stmts",
-		Payload:           "!<stmt>",
-		_genCodeStartLine: min(children[0]._genCodeStartLine, children[1]._genCodeStartLine),
-		_genCodeEndLine:   max(children[0]._genCodeEndLine, children[1]._genCodeEndLine),
-	})
-	return nil
+// _GenRuleInitPayload is an initializer, either a value or a list of initializers.
+type _GenRuleInitPayload struct {
+	Value *ASTNode
+	List  []*_GenRuleInitPayload
 }
 
-// stmts → ε
-func StmtsEpsilon(w *Walker) error {
+func (_GenRuleInitPayload) String() string {
+	return "!<init>"
+}
+
+// initValues checks that the list of initializers matches the dimension and returns the values in row-major order.
+func initValues(init *_GenRuleInitPayload, dimension []int) ([]*ASTNode, error) {
+	if len(init.List) != dimension[0] {
+		return nil, i18n.Errorf("expected %d initializers, got %d", dimension[0], len(init.List))
+	}
+	var values []*ASTNode
+	for _, e := range init.List {
+		if len(dimension) == 1 {
+			if e.Value == nil {
+				return nil, i18n.Errorf("expected a value, got a list")
+			}
+			values = append(values, e.Value)
+			continue
+		}
+		if e.Value != nil {
+			return nil, i18n.Errorf("expected a list of %d initializers, got %s", dimension[1], e.Value.raw)
+		}
+		v, err := initValues(e, dimension[1:])
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, v...)
+	}
+	return values, nil
+}
+
+// init_list → init_list , init
+func InitList(w *Walker) error {
+	children := w.Tokens.PopTopN(3)
+	list, init := children[0].Payload.(*_GenRuleInitPayload), children[2].Payload.(*_GenRuleInitPayload)
 	w.Tokens.Push(&ASTNode{
-		raw:               "",
-		Token:             &lexer.Token{Type: lexer.EXTRA, Val: "stmts-epsilon"},
-		Children:          nil,
-		Type:              "stmts-epsilon",
-		Payload:           "!<stmt>",
-		_genCodeStartLine: MAX_START_LINE,
-		_genCodeEndLine:   MIN_START_LINE,
+		raw:               joinChildren(children),
+		Token:             &lexer.Token{Type: lexer.EXTRA, Val: "init-list"},
+		Children:          children,
+		Type:              "init-list",
+		Payload:           &_GenRuleInitPayload{List: append(slices.Clone(list.List), init)},
+		_genCodeStartLine: min(children[0]._genCodeStartLine, children[2]._genCodeStartLine),
+		_genCodeEndLine:   max(children[0]._genCodeEndLine, children[2]._genCodeEndLine),
 	})
 	return nil
 }
 
-// stmt → matched_stmt
-func StmtMatchedStmt(w *Walker) error {
+// init_list → init
+func InitListInit(w *Walker) error {
 	children := w.Tokens.PopTopN(1)
 	w.Tokens.Push(&ASTNode{
 		raw:               children[0].raw,
-		Token:             &lexer.Token{Type: lexer.EXTRA, Val: "stmt-matched"},
+		Token:             &lexer.Token{Type: lexer.EXTRA, Val: "init-list"},
 		Children:          children,
-		Type:              "stmt-matched",
-		Payload:           "!<matched-stmt>",
+		Type:              "init-list",
+		Payload:           &_GenRuleInitPayload{List: []*_GenRuleInitPayload{children[0].Payload.(*_GenRuleInitPayload)}},
 		_genCodeStartLine: children[0]._genCodeStartLine,
 		_genCodeEndLine:   children[0]._genCodeEndLine,
 	})
 	return nil
 }
 
-// stmt → unmatched_stmt
-func StmtUnmatchedStmt(w *Walker) error {
+// init → bool
+func InitBool(w *Walker) error {
 	children := w.Tokens.PopTopN(1)
 	w.Tokens.Push(&ASTNode{
 		raw:               children[0].raw,
-		Token:             &lexer.Token{Type: lexer.EXTRA, Val: "stmt-unmatched"},
+		Token:             &lexer.Token{Type: lexer.EXTRA, Val: children[0].Token.Val},
 		Children:          children,
-		Type:              "stmt-unmatched",
-		Payload:           "!<unmatched-stmt>",
+		Type:              "init-bool",
+		Payload:           &_GenRuleInitPayload{Value: children[0]},
 		_genCodeStartLine: children[0]._genCodeStartLine,
 		_genCodeEndLine:   children[0]._genCodeEndLine,
 	})
 	return nil
 }
 
-// stmt → decls
-func StmtDecls(w *Walker) error {
-	children := w.Tokens.PopTopN(1)
+// init → { init_list }
+func InitBraced(w *Walker) error {
+	children := w.Tokens.PopTopN(3)
+	w.Tokens.Push(&ASTNode{
+		raw:               joinChildren(children),
+		Token:             &lexer.Token{Type: lexer.EXTRA, Val: "init-braced"},
+		Children:          children,
+		Type:              "init-braced",
+		Payload:           children[1].Payload,
+		_genCodeStartLine: children[1]._genCodeStartLine,
+		_genCodeEndLine:   children[1]._genCodeEndLine,
+	})
+	return nil
+}
+
+// typeItem returns the item of a variable, a parameter or a field of the type, not registered yet.
+// The error of an unknown type was reported by the rule of the type.
+func typeItem(t *ASTNode, name string) (*SymbolTableItem, error) {
+	switch t.Type {
+	case "type-unknown":
+		return nil, _GenRuleFoldError{i18n.Errorf("unknown type %s of %s", t.raw, name)}
+	case "type-basic":
+		return &SymbolTableItem{
+			Variable:       name,
+			VariableSize:   allocSize(t.Token),
+			Type:           SymbolTableItemTypeVariable,
+			UnderlyingType: t.Token.SpecificType().ToString(),
+		}, nil
+	case "type-struct":
+		st := t.Payload.(*SymbolTableItem)
+		return &SymbolTableItem{
+			Variable:       name,
+			VariableSize:   st.VariableSize,
+			Type:           SymbolTableItemTypeVariable,
+			UnderlyingType: st.Variable,
+			Struct:         st,
+		}, nil
+	case "type-pointer":
+		return &SymbolTableItem{
+			Variable:       name,
+			VariableSize:   4, // size of pointer
+			Type:           SymbolTableItemTypeVariable,
+			UnderlyingType: typeName(t),
+		}, nil
+	case "type-array":
+		payload := t.Payload.(*_GenRuleArrayPayload)
+		item := &SymbolTableItem{
+			Variable:     name,
+			VariableSize: 4, // size of pointer
+			ArraySize:    payload.GetArraySize(),
+			Dimension:    payload.GetDimension(),
+			Type:         SymbolTableItemTypeArray,
+		}
+		switch {
+		case payload.Struct != nil:
+			item.ArrayElementSize = payload.Struct.VariableSize
+			item.UnderlyingType = fmt.Sprintf("!ptr<%s>", payload.Struct.Variable)
+			item.Struct = payload.Struct
+		case payload.Pointer != "":
+			item.ArrayElementSize = 4 // size of pointer
+			item.UnderlyingType = fmt.Sprintf("!ptr<%s>", payload.Pointer)
+		default:
+			item.ArrayElementSize = allocSize(payload.BasicType)
+			item.UnderlyingType = fmt.Sprintf("!ptr<%s>", payload.BasicType.SpecificType().ToString())
+		}
+		return item, nil
+	}
+	return nil, i18n.Errorf("unknown type %s of %s", t.raw, name)
+}
+
+// typeInitialValue returns the initial value of the storage of a variable of the type, structs being zeroed.
+func typeInitialValue(t *ASTNode) string {
+	switch t.Type {
+	case "type-basic":
+		return getInitialValue(t.Token)
+	case "type-pointer":
+		return "<nullptr>"
+	case "type-array":
+		if payload := t.Payload.(*_GenRuleArrayPayload); payload.BasicType != nil {
+			return getInitialValue(payload.BasicType)
+		}
+	}
+	return "0"
+}
+
+// typeName returns the underlying type of a value of the type, e.g. int, Point or *int.
+func typeName(t *ASTNode) string {
+	switch t.Type {
+	case "type-basic":
+		return t.Token.SpecificType().ToString()
+	case "type-struct":
+		return t.Payload.(*SymbolTableItem).Variable
+	case "type-pointer":
+		return t.Payload.(*_GenRuleValuePayload).Type
+	}
+	return t.raw
+}
+
+// typeSize returns the size of a value of the underlying type, -1 if it is unknown.
+func typeSize(w *Walker, typ string) int {
+	if isPointer(typ) {
+		return 4 // size of pointer
+	}
+	if basic := lexer.NewTypeToken(typ); basic.SpecificType() != lexer.Unknown {
+		return allocSize(basic)
+	}
+	if st, _, err := w.SymbolTable.Lookup(typ); err == nil && st.Type == SymbolTableItemTypeStruct {
+		return st.VariableSize
+	}
+	return -1
+}
+
+// isPointer checks if the underlying type is a pointer type.
+func isPointer(typ string) bool {
+	return strings.HasPrefix(typ, "*")
+}
+
+// elementType returns the type of the elements of the underlying type of an array, e.g. int for !ptr<int>.
+func elementType(typ string) string {
+	return strings.TrimSuffix(strings.TrimPrefix(typ, "!ptr<"), ">")
+}
+
+// decl → type_decl
+func DeclTypeDecl(w *Walker) error {
+	children := w.Tokens.PopTopN(1)
+	w.Tokens.Push(&ASTNode{
+		raw:               children[0].raw,
+		Token:             &lexer.Token{Type: lexer.EXTRA, Val: children[0].Token.Val},
+		Children:          children,
+		Type:              "decl",
+		Payload:           "!<type_decl>",
+		_genCodeStartLine: MAX_START_LINE,
+		_genCodeEndLine:   MIN_START_LINE,
+	})
+	return nil
+}
+
+// decl → const_decl
+func DeclConstDecl(w *Walker) error {
+	children := w.Tokens.PopTopN(1)
+	w.Tokens.Push(&ASTNode{
+		raw:               children[0].raw,
+		Token:             &lexer.Token{Type: lexer.EXTRA, Val: children[0].Token.Val},
+		Children:          children,
+		Type:              "decl",
+		Payload:           "!<const_decl>",
+		_genCodeStartLine: MAX_START_LINE,
+		_genCodeEndLine:   MIN_START_LINE,
+	})
+	return nil
+}
+
+// const_decl → const id = bool ;
+//
+// The value is evaluated at compile time, its operations being folded, and the code emitted for a value that is
+// not constant is dropped, as a constant takes no storage: its uses are substituted by its value, see LocId.
+func ConstDecl(w *Walker) error {
+	children := w.Tokens.PopTopN(5)
+	id, value := children[1], children[3]
+	// the code of the value is the last emitted
+	if start := value._genCodeStartLine; start < w.GetCurrentLabelCount() {
+		w.ThreeAddress = w.ThreeAddress[:start]
+	}
+	v, err := constEval(value)
+	if err == nil {
+		_, err = w.SymbolTable.Register(&SymbolTableItem{
+			Variable:       id.Token.Val,
+			Type:           SymbolTableItemTypeConstant,
+			UnderlyingType: v.Type(),
+			Value:          v.String(),
+			Declaration:    id.Token.Span,
+		})
+	} else if _, reported := err.(_GenRuleFoldError); reported {
+		err = nil
+	} else {
+		err = i18n.Errorf("constant %s: %w", id.Token.Val, err)
+	}
+	w.Tokens.Push(&ASTNode{
+		raw:               joinChildren(children),
+		Token:             &lexer.Token{Type: lexer.EXTRA, Val: id.Token.Val},
+		Children:          children,
+		Type:              "const-decl",
+		Payload:           "!<const>",
+		_genCodeStartLine: MAX_START_LINE,
+		_genCodeEndLine:   MIN_START_LINE,
+	})
+	return err
+}
+
+// _GenRuleConstValue is the value of a constant expression, an integer or a floating-point number.
+type _GenRuleConstValue struct {
+	Int     int64
+	Float   float64
+	IsFloat bool
+}
+
+func (v _GenRuleConstValue) float() float64 {
+	if v.IsFloat {
+		return v.Float
+	}
+	return float64(v.Int)
+}
+
+// Type returns the underlying type of a constant of the value.
+func (v _GenRuleConstValue) Type() string {
+	if v.IsFloat {
+		return lexer.TypeFloat64.ToString()
+	}
+	return lexer.TypeInt.ToString()
+}
+
+// String returns the value as an immediate.
+func (v _GenRuleConstValue) String() string {
+	if !v.IsFloat {
+		return strconv.FormatInt(v.Int, 10)
+	}
+	s := strconv.FormatFloat(v.Float, 'f', -1, 64)
+	if !strings.ContainsAny(s, ".eEnN") {
+		s += ".0"
+	}
+	return s
+}
+
+// constConvert converts the value to the numeric type, wrapping an integer around the size of the type.
+func constConvert(v _GenRuleConstValue, to string) (_GenRuleConstValue, error) {
+	kind, size := numericKind(to)
+	switch kind {
+	case 'f':
+		if size == 4 {
+			return _GenRuleConstValue{Float: float64(float32(v.float())), IsFloat: true}, nil
+		}
+		return _GenRuleConstValue{Float: v.float(), IsFloat: true}, nil
+	case 'i', 'u':
+		i := v.Int
+		if v.IsFloat {
+			i = int64(v.Float)
+		}
+		if bits := uint(size * 8); bits < 64 {
+			i &= 1<<bits - 1
+			if kind == 'i' && i>>(bits-1) != 0 {
+				i -= 1 << bits
+			}
+		}
+		return _GenRuleConstValue{Int: i}, nil
+	}
+	return v, i18n.Errorf("cannot convert %s to %s", v.Type(), to)
+}
+
+func constInt(b bool) _GenRuleConstValue {
+	if b {
+		return _GenRuleConstValue{Int: 1}
+	}
+	return _GenRuleConstValue{}
+}
+
+// constEval evaluates the expression of the node at compile time, following its children down to the literals
+// and the constants. The operators are those of the children, so the evaluation does not depend on the code emitted.
+func constEval(node *ASTNode) (_GenRuleConstValue, error) {
+	if err, ok := node.Payload.(_GenRuleFoldError); ok {
+		return _GenRuleConstValue{}, err
+	}
+	switch node.Type {
+	case "factor-num":
+		// a literal out of range has the error of FactorNum
+		i, _ := node.Children[0].Token.Value.(int64)
+		return _GenRuleConstValue{Int: i}, nil
+	case "factor-real":
+		f, _ := node.Children[0].Token.Value.(float64)
+		return _GenRuleConstValue{Float: f, IsFloat: true}, nil
+	case "factor-true", "factor-false":
+		b, _ := node.Children[0].Token.Value.(bool)
+		return constInt(b), nil
+	case "factor-char":
+		r, _ := node.Children[0].Token.Value.(rune)
+		return _GenRuleConstValue{Int: int64(r)}, nil
+	case "factor-cast":
+		v, err := constEval(node.Children[2])
+		if err != nil {
+			return v, err
+		}
+		return constConvert(v, node.Payload.(*_GenRuleValuePayload).Type)
+	case "loc-const":
+		item := node.Payload.(*_GenRuleLocPayload).Item
+		if item.UnderlyingType == lexer.TypeFloat64.ToString() {
+			f, err := strconv.ParseFloat(item.Value, 64)
+			return _GenRuleConstValue{Float: f, IsFloat: true}, err
+		}
+		i, err := strconv.ParseInt(item.Value, 10, 64)
+		return _GenRuleConstValue{Int: i}, err
+	}
+	children := node.Children
+	switch {
+	case strings.HasPrefix(string(node.Type), "loc-"):
+		// a variable, see the constants above
+	case len(children) == 1:
+		return constEval(children[0])
+	case len(children) == 2 && children[0].Token.Type == lexer.OPERATOR:
+		x, err := constEval(children[1])
+		if err != nil {
+			return x, err
+		}
+		return constUnary(children[0].Token.Val, x)
+	case len(children) == 3 && children[0].Token.Val == "(":
+		return constEval(children[1])
+	case len(children) == 3 && children[1].Token.Type == lexer.OPERATOR:
+		x, err := constEval(children[0])
+		if err != nil {
+			return x, err
+		}
+		y, err := constEval(children[2])
+		if err != nil {
+			return y, err
+		}
+		return constBinary(children[1].Token.Val, x, y)
+	}
+	return _GenRuleConstValue{}, i18n.Errorf("%s is not a constant expression", node.raw)
+}
+
+// foldConst folds the operation on the n nodes at the top of the stack when all its operands are constants:
+// the node of type typ is pushed with the value as an immediate, and nothing is emitted nor allocated for it.
+// It reports whether the operation was folded, with the error of its evaluation, e.g. an overflow.
+func foldConst(w *Walker, n int, typ Symbol) (bool, error) {
+	for k := range n {
+		child, ok := w.Tokens.PeekAtK(k)
+		if !ok {
+			return false, nil
+		}
+		if child.Token.Type == lexer.OPERATOR {
+			continue
+		}
+		if _, err := constEval(child); err != nil || child._genCodeStartLine != MAX_START_LINE {
+			return false, nil
+		}
+	}
+	children := w.Tokens.PopTopN(n)
+	node := &ASTNode{
+		raw:               joinChildren(children),
+		Children:          children,
+		Type:              typ,
+		Payload:           "!const(size=4)",
+		_genCodeStartLine: MAX_START_LINE,
+		_genCodeEndLine:   MIN_START_LINE,
+	}
+	v, err := constEval(node)
+	if err != nil {
+		node.Payload = _GenRuleFoldError{err}
+	}
+	node.Token = &lexer.Token{Type: lexer.EXTRA, Val: v.String()}
+	w.Tokens.Push(node)
+	return true, err
+}
+
+// _GenRuleFoldError is the error of an operation on constants, reported by the rule folding it, see foldConst,
+// or of a literal out of range, see FactorNum. It is the payload of the node, so that the constant expressions
+// containing it do not report it again. It is also the error of a variable of an unknown type, see typeItem.
+type _GenRuleFoldError struct {
+	error
+}
+
+// unreported returns nil for an error already reported, see _GenRuleFoldError, and the error otherwise.
+func unreported(err error) error {
+	if _, reported := err.(_GenRuleFoldError); reported {
+		return nil
+	}
+	return err
+}
+
+func constUnary(op string, x _GenRuleConstValue) (_GenRuleConstValue, error) {
+	switch op {
+	case "-":
+		if x.IsFloat {
+			return _GenRuleConstValue{Float: -x.Float, IsFloat: true}, nil
+		}
+		if x.Int == math.MinInt64 {
+			return x, i18n.Errorf("constant %s overflows int64", new(big.Int).Neg(big.NewInt(x.Int)))
+		}
+		return _GenRuleConstValue{Int: -x.Int}, nil
+	case "!":
+		return constInt(x.float() == 0), nil
+	}
+	return x, i18n.Errorf("operator %s is not allowed in a constant expression", op)
+}
+
+func constBinary(op string, x, y _GenRuleConstValue) (_GenRuleConstValue, error) {
+	switch op {
+	case "&&":
+		return constInt(x.float() != 0 && y.float() != 0), nil
+	case "||":
+		return constInt(x.float() != 0 || y.float() != 0), nil
+	}
+	if x.IsFloat || y.IsFloat {
+		a, b := x.float(), y.float()
+		switch op {
+		case "+":
+			return _GenRuleConstValue{Float: a + b, IsFloat: true}, nil
+		case "-":
+			return _GenRuleConstValue{Float: a - b, IsFloat: true}, nil
+		case "*":
+			return _GenRuleConstValue{Float: a * b, IsFloat: true}, nil
+		case "/":
+			if b == 0 {
+				return x, i18n.Errorf("division by zero")
+			}
+			return _GenRuleConstValue{Float: a / b, IsFloat: true}, nil
+		case "==":
+			return constInt(a == b), nil
+		case "!=":
+			return constInt(a != b), nil
+		case "<":
+			return constInt(a < b), nil
+		case "<=":
+			return constInt(a <= b), nil
+		case ">":
+			return constInt(a > b), nil
+		case ">=":
+			return constInt(a >= b), nil
+		}
+		return x, i18n.Errorf("operator %s is not defined on floating-point numbers", op)
+	}
+	a, b := x.Int, y.Int
+	switch op {
+	case "+", "-", "*":
+		// computed exactly, to report an overflow of int64 with the constant like Go
+		r := map[string]func(x, y *big.Int) *big.Int{
+			"+": new(big.Int).Add, "-": new(big.Int).Sub, "*": new(big.Int).Mul,
+		}[op](big.NewInt(a), big.NewInt(b))
+		if !r.IsInt64() {
+			return x, i18n.Errorf("constant %s overflows int64", r)
+		}
+		return _GenRuleConstValue{Int: r.Int64()}, nil
+	case "/", "%":
+		if b == 0 {
+			return x, i18n.Errorf("division by zero")
+		}
+		if op == "/" {
+			if a == math.MinInt64 && b == -1 {
+				return x, i18n.Errorf("constant %s overflows int64", new(big.Int).Neg(big.NewInt(a)))
+			}
+			return _GenRuleConstValue{Int: a / b}, nil
+		}
+		return _GenRuleConstValue{Int: a % b}, nil
+	case "&":
+		return _GenRuleConstValue{Int: a & b}, nil
+	case "|":
+		return _GenRuleConstValue{Int: a | b}, nil
+	case "^":
+		return _GenRuleConstValue{Int: a ^ b}, nil
+	case "<<", ">>":
+		if b < 0 {
+			return x, i18n.Errorf("negative shift count %d", b)
+		}
+		if op == "<<" {
+			if b >= 64 && a != 0 {
+				return x, i18n.Errorf("constant %s overflows int64", fmt.Sprintf("%d << %d", a, b))
+			}
+			if r := new(big.Int).Lsh(big.NewInt(a), uint(b)); !r.IsInt64() {
+				return x, i18n.Errorf("constant %s overflows int64", r)
+			}
+			return _GenRuleConstValue{Int: a << b}, nil
+		}
+		return _GenRuleConstValue{Int: a >> b}, nil
+	case "==":
+		return constInt(a == b), nil
+	case "!=":
+		return constInt(a != b), nil
+	case "<":
+		return constInt(a < b), nil
+	case "<=":
+		return constInt(a <= b), nil
+	case ">":
+		return constInt(a > b), nil
+	case ">=":
+		return constInt(a >= b), nil
+	}
+	return x, i18n.Errorf("operator %s is not allowed in a constant expression", op)
+}
+
+// type_decl → type id struct { fields }
+func TypeDecl(w *Walker) error {
+	children := w.Tokens.PopTopN(6)
+	id, fields := children[1], children[4]
+	st := NewStructType(id.Token.Val, fields.Payload.([]*SymbolTableItem))
+	st.Declaration = id.Token.Span
+	_, err := w.SymbolTable.Register(st)
+	w.Tokens.Push(&ASTNode{
+		raw:               joinChildren(children),
+		Token:             &lexer.Token{Type: lexer.EXTRA, Val: id.Token.Val},
+		Children:          children,
+		Type:              "type-decl",
+		Payload:           st,
+		_genCodeStartLine: MAX_START_LINE,
+		_genCodeEndLine:   MIN_START_LINE,
+	})
+	return err
+}
+
+// fields → fields field
+func Fields(w *Walker) error {
+	children := w.Tokens.PopTopN(2)
+	fields, field := children[0].Payload.([]*SymbolTableItem), children[1].Payload.(*SymbolTableItem)
+	var err error
+	if slices.ContainsFunc(fields, func(f *SymbolTableItem) bool { return f.Variable == field.Variable }) {
+		err = i18n.Errorf("duplicate field %s", field.Variable)
+	} else {
+		fields = append(slices.Clone(fields), field)
+	}
+	w.Tokens.Push(&ASTNode{
+		raw:               joinChildren(children),
+		Token:             &lexer.Token{Type: lexer.EXTRA, Val: "fields"},
+		Children:          children,
+		Type:              "fields",
+		Payload:           fields,
+		_genCodeStartLine: MAX_START_LINE,
+		_genCodeEndLine:   MIN_START_LINE,
+	})
+	return err
+}
+
+// fields → ε
+func FieldsEpsilon(w *Walker) error {
+	w.Tokens.Push(&ASTNode{
+		raw:               "",
+		Token:             &lexer.Token{Type: lexer.EXTRA, Val: "fields-epsilon"},
+		Children:          nil,
+		Type:              "fields-epsilon",
+		Payload:           []*SymbolTableItem{},
+		_genCodeStartLine: MAX_START_LINE,
+		_genCodeEndLine:   MIN_START_LINE,
+	})
+	return nil
+}
+
+// field → type_spec id ;
+func Field(w *Walker) error {
+	children := w.Tokens.PopTopN(3)
+	t, id := children[0], children[1]
+	item, err := typeItem(t, id.Token.Val)
+	if err == nil && item.size() <= 0 {
+		err = i18n.Errorf("invalid size of field %s", id.Token.Val)
+	}
+	if err != nil {
+		item = &SymbolTableItem{Variable: id.Token.Val, Type: SymbolTableItemTypeUnknown}
+	}
+	item.Declaration = id.Token.Span
+	w.Tokens.Push(&ASTNode{
+		raw:               joinChildren(children),
+		Token:             &lexer.Token{Type: lexer.EXTRA, Val: id.Token.Val},
+		Children:          children,
+		Type:              "field",
+		Payload:           item,
+		_genCodeStartLine: MAX_START_LINE,
+		_genCodeEndLine:   MIN_START_LINE,
+	})
+	return unreported(err)
+}
+
+// type_spec → struct id
+func TypeStruct(w *Walker) error {
+	children := w.Tokens.PopTopN(2)
+	id := children[1]
+	st, _, err := w.SymbolTable.Lookup(id.Token.Val)
+	if err == nil && st.Type != SymbolTableItemTypeStruct {
+		err = i18n.Errorf("%s is not a struct type", id.Token.Val)
+	}
+	node := &ASTNode{
+		raw:               joinChildren(children),
+		Token:             &lexer.Token{Type: lexer.EXTRA, Val: id.Token.Val},
+		Children:          children,
+		Type:              "type-struct",
+		Payload:           st,
+		_genCodeStartLine: MAX_START_LINE,
+		_genCodeEndLine:   MIN_START_LINE,
+	}
+	if err != nil {
+		node.Type, node.Payload = "type-unknown", "!<type>"
+	}
+	w.Tokens.Push(node)
+	return err
+}
+
+// type_spec → type_spec [ num ]
+func TypeArray(w *Walker) error {
+	children := w.Tokens.PopTopN(4)
+	if children[2].Token.Type != lexer.INTEGER {
+		err := i18n.Errorf("array size %s must be an integer", children[2].Token.Val)
+		typeArray(w, children, -1, true)
+		return err
+	}
+	size, err := literalInt(children[2].Token)
+	typeArray(w, children, size, err != nil)
+	return err
+}
+
+// literalInt returns the value of an integer literal as a size or an index, or -1 if it is not in the range of int32.
+func literalInt(t *lexer.Token) (int, error) {
+	i, ok := t.Value.(int64)
+	if !ok || i > math.MaxInt32 {
+		return -1, i18n.Errorf("integer %s is out of range", t.Val)
+	}
+	return int(i), nil
+}
+
+// type_spec → type_spec [ id ]
+//
+// The size is an integer constant.
+func TypeArrayConst(w *Walker) error {
+	children := w.Tokens.PopTopN(4)
+	size := -1
+	item, _, err := w.SymbolTable.Lookup(children[2].Token.Val)
+	if err == nil {
+		if item.Type != SymbolTableItemTypeConstant || item.UnderlyingType != lexer.TypeInt.ToString() {
+			err = i18n.Errorf("array size %s must be an integer constant", children[2].Token.Val)
+		} else {
+			size, err = strconv.Atoi(item.Value)
+		}
+	}
+	typeArray(w, children, size, err != nil)
+	return err
+}
+
+// typeArray pushes the array type of the size from the `type_spec [ size ]` children, or an unknown type if the
+// size failed, the error being reported by the rule of the size.
+func typeArray(w *Walker, children []*ASTNode, size int, failed bool) {
+	var dimension []int
+	var basicType *lexer.Token
+	var variable string
+	var st *SymbolTableItem
+	var pointer string
+	if payload, ok := children[0].Payload.(*_GenRuleArrayPayload); ok {
+		dimension = append(payload.Dimension, size)
+		basicType = payload.BasicType
+		variable = payload.Variable
+		st = payload.Struct
+		pointer = payload.Pointer
+	} else {
+		dimension = []int{size}
+		basicType = children[0].Token
+		variable = children[0].Token.Val
+		st, _ = children[0].Payload.(*SymbolTableItem)
+		if p, ok := children[0].Payload.(*_GenRuleValuePayload); ok {
+			pointer = p.Type
+		}
+	}
+	node := &ASTNode{
+		raw:               joinChildren(children),
+		Token:             &lexer.Token{Type: lexer.EXTRA, Val: fmt.Sprintf("%s[%s]", children[0].raw, children[2].raw)},
+		Children:          children,
+		Type:              "type-array",
+		Payload:           &_GenRuleArrayPayload{Dimension: dimension, BasicType: basicType, Variable: variable, Struct: st, Pointer: pointer},
+		_genCodeStartLine: min(children[0]._genCodeStartLine, children[2]._genCodeStartLine),
+		_genCodeEndLine:   max(children[0]._genCodeEndLine, children[2]._genCodeEndLine),
+	}
+	// the size or the type of the elements failed, and was reported by its rule
+	if failed || children[0].Type == "type-unknown" {
+		node.Type, node.Payload = "type-unknown", "!<type>"
+	}
+	w.Tokens.Push(node)
+}
+
+// type_spec → * type_spec
+func TypePointer(w *Walker) error {
+	children := w.Tokens.PopTopN(2)
+	var err error
+	if children[1].Type == "type-array" {
+		err = i18n.Errorf("pointers to arrays are not supported: %s", joinChildren(children))
+	}
+	typ := "*" + typeName(children[1])
+	w.Tokens.Push(&ASTNode{
+		raw:               joinChildren(children),
+		Token:             &lexer.Token{Type: lexer.EXTRA, Val: typ},
+		Children:          children,
+		Type:              "type-pointer",
+		Payload:           &_GenRuleValuePayload{Type: typ},
+		_genCodeStartLine: children[1]._genCodeStartLine,
+		_genCodeEndLine:   children[1]._genCodeEndLine,
+	})
+	return err
+}
+
+// type_spec → basic
+func TypeBasic(w *Walker) error {
+	children := w.Tokens.PopTopN(1)
+	w.Tokens.Push(&ASTNode{
+		raw:               children[0].raw,
+		Token:             children[0].Token,
+		Children:          children,
+		Type:              "type-basic",
+		Payload:           "!<basic>",
+		_genCodeStartLine: children[0]._genCodeStartLine,
+		_genCodeEndLine:   children[0]._genCodeEndLine,
+	})
+	return nil
+}
+
+// stmts → stmts stmt
+func Stmts(w *Walker) error {
+	children := w.Tokens.PopTopN(2)
+	w.Tokens.Push(&ASTNode{
+		raw:               joinChildren(children),
+		Token:             &lexer.Token{Type: lexer.EXTRA, Val: "stmts"},
+		Children:          children,
+		Type:              "stmts",
+		Payload:           "!<stmt>",
+		_genCodeStartLine: min(children[0]._genCodeStartLine, children[1]._genCodeStartLine),
+		_genCodeEndLine:   max(children[0]._genCodeEndLine, children[1]._genCodeEndLine),
+	})
+	return nil
+}
+
+// stmts → ε
+func StmtsEpsilon(w *Walker) error {
+	w.Tokens.Push(&ASTNode{
+		raw:               "",
+		Token:             &lexer.Token{Type: lexer.EXTRA, Val: "stmts-epsilon"},
+		Children:          nil,
+		Type:              "stmts-epsilon",
+		Payload:           "!<stmt>",
+		_genCodeStartLine: MAX_START_LINE,
+		_genCodeEndLine:   MIN_START_LINE,
+	})
+	return nil
+}
+
+// stmt → matched_stmt
+func StmtMatchedStmt(w *Walker) error {
+	children := w.Tokens.PopTopN(1)
+	w.Tokens.Push(&ASTNode{
+		raw:               children[0].raw,
+		Token:             &lexer.Token{Type: lexer.EXTRA, Val: "stmt-matched"},
+		Children:          children,
+		Type:              "stmt-matched",
+		Payload:           "!<matched-stmt>",
+		_genCodeStartLine: children[0]._genCodeStartLine,
+		_genCodeEndLine:   children[0]._genCodeEndLine,
+	})
+	return nil
+}
+
+// stmt → decl
+func StmtDecl(w *Walker) error {
+	children := w.Tokens.PopTopN(1)
+	w.Tokens.Push(&ASTNode{
+		raw:               children[0].raw,
+		Token:             &lexer.Token{Type: lexer.EXTRA, Val: "stmt-decl"},
+		Children:          children,
+		Type:              "stmt-decl",
+		Payload:           "!<decl>",
+		_genCodeStartLine: children[0]._genCodeStartLine,
+		_genCodeEndLine:   children[0]._genCodeEndLine,
+	})
+	return nil
+}
+
+// matched_stmt → loc = bool ;
+func MatchedStmtAssign(w *Walker) error {
+	children := w.Tokens.PopTopN(4)
+	first, l, err := emitAssign(w, children[0], children[2])
+	w.Tokens.Push(&ASTNode{
+		raw: joinChildren(children),
+		Token: &lexer.Token{
+			Type: lexer.EXTRA,
+			Val:  "stmt",
+		},
+		Children:          children,
+		Type:              "stmt-assign",
+		Payload:           "!copy(!dist:!src)",
+		_genCodeStartLine: min(first, children[0]._genCodeStartLine, children[2]._genCodeStartLine),
+		_genCodeEndLine:   l,
+	})
+	return err
+}
+
+// matched_stmt → * unary = bool ;
+func MatchedStmtStore(w *Walker) error {
+	children := w.Tokens.PopTopN(5)
+	ptr, value := children[1], children[3]
+	elem, err := pointee(w, ptr)
+	if err == nil {
+		err = checkValueType(w, elem, value, joinChildren(children[:2]))
+	}
+	src, first, cerr := convertImplicitly(w, elem, value)
+	if err == nil {
+		err = cerr
+	}
+	l := w.Emit("store", ptr.Token.Val, src)
+	w.Tokens.Push(&ASTNode{
+		raw:               joinChildren(children),
+		Token:             &lexer.Token{Type: lexer.EXTRA, Val: "stmt"},
+		Children:          children,
+		Type:              "stmt-store",
+		Payload:           "!store(!ptr:!src)",
+		_genCodeStartLine: min(first, l, ptr._genCodeStartLine, value._genCodeStartLine),
+		_genCodeEndLine:   l,
+	})
+	return err
+}
+
+// pointee returns the type of the value the pointer points to, which must be loadable.
+func pointee(w *Walker, ptr *ASTNode) (string, error) {
+	typ := valueType(w, ptr)
+	if !isPointer(typ) {
+		return "", i18n.Errorf("cannot dereference non-pointer %s", ptr.raw)
+	}
+	elem := typ[1:]
+	if !isPointer(elem) && lexer.NewTypeToken(elem).SpecificType() == lexer.Unknown {
+		return elem, i18n.Errorf("cannot load a value of type %s through %s", elem, ptr.raw)
+	}
+	return elem, nil
+}
+
+// emitAssign emits the assignment of the value to the loc, converted implicitly to the type of the loc.
+// It returns the first and the last line emitted, MAX_START_LINE and MIN_START_LINE if nothing is emitted
+// for a loc which cannot be assigned.
+func emitAssign(w *Walker, loc *ASTNode, value *ASTNode) (int, int, error) {
+	if err := checkAssignable(loc); err != nil {
+		return MAX_START_LINE, MIN_START_LINE, err
+	}
+	err := checkValueType(w, valueType(w, loc), value, loc.raw)
+	src, first, cerr := convertImplicitly(w, valueType(w, loc), value)
+	if err == nil {
+		err = cerr
+	}
+	l := w.Emit("mov", loc.Token.Val, src)
+	return min(first, l), l, err
+}
+
+// convertImplicitly emits the conversion of the value to the type to, if it is needed and lossless, see conversion.
+// Constants are not converted, like the literals whose type is unknown, except a float constant becoming the
+// integer immediate it is equal to, checkValueType reporting one with a fraction.
+// It returns the converted operand and the line of the conversion, MAX_START_LINE if there is none.
+func convertImplicitly(w *Walker, to string, value *ASTNode) (string, int, error) {
+	if v, err := constEval(value); err == nil {
+		if kind, _ := numericKind(to); v.IsFloat && (kind == 'i' || kind == 'u') {
+			return strconv.FormatInt(int64(v.Float), 10), MAX_START_LINE, nil
+		}
+		return value.Token.Val, MAX_START_LINE, nil
+	}
+	op, err := conversion(valueType(w, value), to, false)
+	if op == "" || err != nil {
+		return value.Token.Val, MAX_START_LINE, err
+	}
+	result := w.SymbolTable.TempVar(max(typeSize(w, to), 4))
+	return result, w.Emit(op, result, value.Token.Val), nil
+}
+
+// numericKind returns the kind of a numeric type, 'i' for a signed integer, 'u' for an unsigned integer
+// or 'f' for a floating-point number, with its size. The kind of any other type is 0.
+func numericKind(typ string) (byte, int) {
+	basic := lexer.NewTypeToken(typ)
+	switch basic.SpecificType() {
+	case lexer.TypeInt, lexer.TypeInt8, lexer.TypeInt16, lexer.TypeInt32, lexer.TypeInt64:
+		return 'i', basic.AllocSize()
+	case lexer.TypeUnsignedInt, lexer.TypeUnsignedInt8, lexer.TypeUnsignedInt16, lexer.TypeUnsignedInt32,
+		lexer.TypeUnsignedInt64, lexer.TypeByte:
+		return 'u', basic.AllocSize()
+	case lexer.TypeFloat, lexer.TypeFloat32, lexer.TypeFloat64:
+		return 'f', basic.AllocSize()
+	}
+	return 0, 0
+}
+
+// conversion returns the opcode converting a value of the type from to the type to, "" if the value is unchanged.
+//
+// A numeric type converts explicitly to any other numeric type, and implicitly when no value is lost: to a larger
+// integer type of the same signedness or signed, to a floating-point type at least as large, or from an integer
+// type whose bits fit in the mantissa of the floating-point type. Any other type only converts explicitly to
+// itself, the implicit assignments of those being checked by checkValueType.
+func conversion(from, to string, explicit bool) (string, error) {
+	if from == "" || from == to {
+		return "", nil
+	}
+	fk, fs := numericKind(from)
+	tk, ts := numericKind(to)
+	if fk == 0 || tk == 0 {
+		if explicit {
+			return "", i18n.Errorf("cannot convert %s to %s", from, to)
+		}
+		return "", nil
+	}
+	op, lossless := "", false
+	switch {
+	case fk == 'f' && tk == 'f':
+		switch {
+		case fs < ts:
+			op, lossless = "fpext", true
+		case fs > ts:
+			op = "fptrunc"
+		default:
+			lossless = true
+		}
+	case fk == 'f':
+		op = map[byte]string{'i': "fptosi", 'u': "fptoui"}[tk]
+	case tk == 'f':
+		bits := fs * 8
+		if fk == 'i' {
+			bits-- // the sign is apart from the mantissa
+		}
+		op, lossless = map[byte]string{'i': "sitofp", 'u': "uitofp"}[fk], bits <= mantissaBits[ts]
+	case fs < ts:
+		op, lossless = map[byte]string{'i': "sext", 'u': "zext"}[fk], fk == tk || fk == 'u'
+	case fs > ts:
+		op = "trunc"
+	default:
+		// the same bits, reinterpreted if the signedness differs
+		lossless = fk == tk
+	}
+	if !explicit && !lossless {
+		return "", i18n.Errorf("cannot use a value of type %s as %s without a conversion", from, to)
+	}
+	return op, nil
+}
+
+// mantissaBits are the bits of precision of the floating-point types by size, the integers of at most as many
+// bits being exact.
+var mantissaBits = map[int]int{4: 24, 8: 53}
+
+// checkValueType reports a value mixing a string and another value with the type dist of its destination,
+// a constant value out of the range of dist, and a float constant with a fraction for an integer dist.
+func checkValueType(w *Walker, dist string, value *ASTNode, name string) error {
+	src := valueType(w, value)
+	if src != "" && (isPointer(dist) || isPointer(src)) && dist != src {
+		return i18n.Errorf("cannot assign %s of type %s to %s of type %s", value.raw, src, name, dist)
+	}
+	if dist == "string" && src != "string" {
+		return i18n.Errorf("cannot assign non-string %s to string %s", value.raw, name)
+	}
+	if dist != "string" && src == "string" {
+		return i18n.Errorf("cannot assign string %s to %s", value.raw, name)
+	}
+	v, err := constEval(value)
+	if err != nil {
+		return nil
+	}
+	constant := v.String()
+	if v.IsFloat {
+		constant = strconv.FormatFloat(v.Float, 'g', -1, 64)
+	}
+	if constOverflows(v, dist) {
+		return i18n.Errorf("cannot assign %s to %s: constant %s overflows %s", value.raw, name, constant, dist)
+	}
+	if kind, _ := numericKind(dist); v.IsFloat && (kind == 'i' || kind == 'u') && v.Float != math.Trunc(v.Float) {
+		return i18n.Errorf("cannot assign %s to %s: constant %s truncated to %s", value.raw, name, constant, dist)
+	}
+	return nil
+}
+
+// constOverflows reports whether the constant does not fit in the numeric type, as 300 in an int8 or 1e20 in
+// an int64.
+func constOverflows(v _GenRuleConstValue, typ string) bool {
+	kind, size := numericKind(typ)
+	bits := uint(size * 8)
+	switch {
+	case v.IsFloat && kind == 'i':
+		return v.Float < -math.Ldexp(1, int(bits-1)) || v.Float >= math.Ldexp(1, int(bits-1))
+	case v.IsFloat && kind == 'u':
+		return v.Float < 0 || v.Float >= math.Ldexp(1, int(bits))
+	case v.IsFloat:
+		return kind == 'f' && size == 4 && math.Abs(v.Float) > math.MaxFloat32
+	case kind == 'i':
+		return bits < 64 && (v.Int < -1<<(bits-1) || v.Int >= 1<<(bits-1))
+	case kind == 'u':
+		return v.Int < 0 || bits < 64 && v.Int >= 1<<bits
+	}
+	return false
+}
+
+// valueType returns the underlying type of the value of the node, following the nodes with a single child
+// down to a literal, a variable or a call. It returns an empty string if the type is unknown.
+func valueType(w *Walker, node *ASTNode) string {
+	for {
+		if p, ok := node.Payload.(*_GenRuleValuePayload); ok {
+			return p.Type
+		}
+		switch node.Type {
+		case "factor-str":
+			return lexer.TypeString.ToString()
+		case "factor-char":
+			return lexer.TypeByte.ToString()
+		case "factor-bool":
+			node = node.Children[1]
+			continue
+		case "loc-id", "loc-const", "loc-array", "loc-field":
+			return node.Payload.(*_GenRuleLocPayload).ValueType()
+		case "factor-call":
+			fn, _, err := w.SymbolTable.Lookup(node.Children[0].Token.Val)
+			if err != nil {
+				return ""
+			}
+			return fn.ReturnType
+		}
+		if len(node.Children) != 1 {
+			return ""
+		}
+		node = node.Children[0]
+	}
+}
+
+// matched_stmt → loc ++ ; | loc -- ; | ++ loc ; | -- loc ;
+func MatchedStmtIncDec(w *Walker) error {
+	children := w.Tokens.PopTopN(3)
+	loc, op, step, err := incDecOperand(w, children[:2])
+	first, l := emitIncDec(w, loc, op, step, err)
+	w.Tokens.Push(&ASTNode{
+		raw: joinChildren(children),
+		Token: &lexer.Token{
+			Type: lexer.EXTRA,
+			Val:  "stmt",
+		},
+		Children:          children,
+		Type:              "stmt-inc-dec",
+		Payload:           "!<loc>",
+		_genCodeStartLine: min(first, loc._genCodeStartLine),
+		_genCodeEndLine:   l,
+	})
+	return err
+}
+
+// matched_stmt → if ( bool ) matched_stmt else matched_stmt
+func MatchedStmtIfElse(w *Walker) error {
+	children := w.Tokens.PopTopN(7)
+	w.Tokens.Push(&ASTNode{
+		raw:               joinChildren(children),
+		Token:             &lexer.Token{Type: lexer.EXTRA, Val: "matched-stmt-if-else"},
+		Children:          children,
+		Type:              "stmt-if-else",
+		Payload:           "!<if-else>",
+		_genCodeStartLine: children[2]._genCodeStartLine,
+		_genCodeEndLine:   children[6]._genCodeEndLine,
+	})
+	// the goto labels of the then branch and of the else branch, unless it is an else if, see branch
+	labels := 2
+	if isIfStmt(children[6]) {
+		labels = 1
+	}
+	n := w.Environment.LabelStack.PopTopN(2)
+	m := w.Environment.EndIfStmtStack.PopTopN(labels)
+	w.EmitLabel(n[1], fmt.Sprintf("L%d", m[0]+1), "jmp")
+	for _, l := range m {
+		w.EmitGoto(l, w.GetCurrentLabelCount())
+	}
+	return nil
+}
+
+// matched_stmt → if ( bool ) matched_stmt
+func MatchedStmtIf(w *Walker) error {
+	children := w.Tokens.PopTopN(5)
+	w.Tokens.Push(&ASTNode{
+		raw:               joinChildren(children),
+		Token:             &lexer.Token{Type: lexer.EXTRA, Val: "matched-stmt-if"},
+		Children:          children,
+		Type:              "stmt-if",
+		Payload:           "!<if>",
+		_genCodeStartLine: children[2]._genCodeStartLine,
+		_genCodeEndLine:   children[4]._genCodeEndLine,
+	})
+	n := w.Environment.LabelStack.PopTopN(2)
+	m := w.Environment.EndIfStmtStack.PopTopN(1)
+	w.EmitLabel(n[1], fmt.Sprintf("L%d", m[0]+1), "jmp")
+	w.EmitGoto(m[0], w.GetCurrentLabelCount())
+	return nil
+}
+
+// matched_stmt → while ( bool ) stmt
+func MatchedStmtWhile(w *Walker) error {
+	children := w.Tokens.PopTopN(5)
+	w.Tokens.Push(&ASTNode{
+		raw:               joinChildren(children),
+		Token:             &lexer.Token{Type: lexer.EXTRA, Val: "matched-stmt-while"},
+		Children:          children,
+		Type:              "stmt-while",
+		Payload:           "!<while>",
+		_genCodeStartLine: children[2]._genCodeStartLine,
+		_genCodeEndLine:   children[4]._genCodeEndLine,
+	})
+	n := w.Environment.LabelStack.PopTopN(2)
+	m := w.Environment.EndIfStmtStack.PopTopN(1)
+	w.EmitLabel(n[1], fmt.Sprintf("L%d", m[0]+1), "jmp")
+	w.EmitGoto(m[0], children[2]._genCodeStartLine)
+
+	w.ExitLoop(m[0]+1, children[2]._genCodeStartLine)
+	return nil
+}
+
+// matched_stmt → do stmt while ( bool ) ;
+func MatchedStmtDoWhile(w *Walker) error {
+	children := w.Tokens.PopTopN(7)
+	w.Tokens.Push(&ASTNode{
+		raw:               joinChildren(children),
+		Token:             &lexer.Token{Type: lexer.EXTRA, Val: "matched-stmt-do-while"},
+		Children:          children,
+		Type:              "stmt-do-while",
+		Payload:           "!<do-while>",
+		_genCodeStartLine: children[1]._genCodeStartLine,
+		_genCodeEndLine:   children[4]._genCodeEndLine,
+	})
+	n := w.Environment.LabelStack.PopTopN(2)
+	m := w.Environment.LoopLabelStack.PopTopN(1)
+	w.AdjustJMP(n[0], m[0])
+	w.EmitLabel(n[1], fmt.Sprintf("L%d", n[1]+1), "jmp")
+
+	w.ExitLoop(n[1]+1, children[4]._genCodeStartLine)
+	return nil
+}
+
+// matched_stmt → break ;
+func MatchedStmtBreak(w *Walker) error {
+	children := w.Tokens.PopTopN(2)
+	l, err := w.AddBreakLabel("")
+	w.Tokens.Push(&ASTNode{
+		raw:               children[0].raw,
+		Token:             &lexer.Token{Type: lexer.EXTRA, Val: "matched-stmt-break"},
+		Children:          children,
+		Type:              "stmt-break",
+		Payload:           "!<break>",
+		_genCodeStartLine: l,
+		_genCodeEndLine:   l,
+	})
+	return err
+}
+
+// matched_stmt → break id ;
+func MatchedStmtBreakLabel(w *Walker) error {
+	children := w.Tokens.PopTopN(3)
+	l, err := w.AddBreakLabel(children[1].Token.Val)
+	w.Tokens.Push(&ASTNode{
+		raw:               joinChildren(children[:2]),
+		Token:             &lexer.Token{Type: lexer.EXTRA, Val: "matched-stmt-break-label"},
+		Children:          children,
+		Type:              "stmt-break",
+		Payload:           "!<break>",
+		_genCodeStartLine: l,
+		_genCodeEndLine:   l,
+	})
+	return err
+}
+
+// matched_stmt → continue ;
+func MatchedStmtContinue(w *Walker) error {
+	children := w.Tokens.PopTopN(2)
+	l, err := w.AddContinueLabel("")
 	w.Tokens.Push(&ASTNode{
 		raw:               children[0].raw,
-		Token:             &lexer.Token{Type: lexer.EXTRA, Val: "stmt-decls"},
+		Token:             &lexer.Token{Type: lexer.EXTRA, Val: "matched-stmt-continue"},
+		Children:          children,
+		Type:              "stmt-continue",
+		Payload:           "!<continue>",
+		_genCodeStartLine: l,
+		_genCodeEndLine:   l,
+	})
+	return err
+}
+
+// matched_stmt → continue id ;
+func MatchedStmtContinueLabel(w *Walker) error {
+	children := w.Tokens.PopTopN(3)
+	l, err := w.AddContinueLabel(children[1].Token.Val)
+	w.Tokens.Push(&ASTNode{
+		raw:               joinChildren(children[:2]),
+		Token:             &lexer.Token{Type: lexer.EXTRA, Val: "matched-stmt-continue-label"},
+		Children:          children,
+		Type:              "stmt-continue",
+		Payload:           "!<continue>",
+		_genCodeStartLine: l,
+		_genCodeEndLine:   l,
+	})
+	return err
+}
+
+// matched_stmt → id : matched_stmt
+func MatchedStmtLabeled(w *Walker) error {
+	children := w.Tokens.PopTopN(3)
+	w.Tokens.Push(&ASTNode{
+		raw:               joinChildren(children),
+		Token:             &lexer.Token{Type: lexer.EXTRA, Val: "matched-stmt-labeled"},
 		Children:          children,
-		Type:              "stmt-decls",
-		Payload:           "!<decls>",
-		_genCodeStartLine: children[0]._genCodeStartLine,
-		_genCodeEndLine:   children[0]._genCodeEndLine,
+		Type:              "stmt-labeled",
+		Payload:           "!<label>",
+		_genCodeStartLine: children[2]._genCodeStartLine,
+		_genCodeEndLine:   children[2]._genCodeEndLine,
 	})
 	return nil
 }
 
-// unmatched_stmt → if ( bool ) unmatched_stmt
-func UnmatchedStmtIf(w *Walker) error {
-	children := w.Tokens.PopTopN(5)
+// matched_stmt → for ( for_init ; bool ; for_step ) stmt
+//
+// The step is emitted before the body, so the generated code is laid out as:
+//
+//	init
+//	cond:  ...; jnz body; jmp exit
+//	step:  ...; jmp cond
+//	body:  ...; jmp step
+//	exit:
+func MatchedStmtFor(w *Walker) error {
+	children := w.Tokens.PopTopN(9)
+	n := w.Environment.LabelStack.PopTopN(2)
+	l := w.Emit("jmp", fmt.Sprintf("L%d", n[1]+1))
+	w.EmitLabel(n[1], fmt.Sprintf("L%d", l+1), "jmp")
 	w.Tokens.Push(&ASTNode{
 		raw:               joinChildren(children),
-		Token:             &lexer.Token{Type: lexer.EXTRA, Val: "unmatched-stmt-if"},
+		Token:             &lexer.Token{Type: lexer.EXTRA, Val: "matched-stmt-for"},
 		Children:          children,
-		Type:              "stmt-if",
-		Payload:           "!<if>",
-		_genCodeStartLine: children[2]._genCodeStartLine,
-		_genCodeEndLine:   children[4]._genCodeEndLine,
+		Type:              "stmt-for",
+		Payload:           "!<for>",
+		_genCodeStartLine: min(children[2]._genCodeStartLine, children[4]._genCodeStartLine),
+		_genCodeEndLine:   l,
 	})
+
+	w.ExitLoop(l+1, n[1]+1)
 	return nil
 }
 
-// unmatched_stmt → if ( bool ) matched_stmt else unmatched_stmt
-func UnmatchedStmtIfElse(w *Walker) error {
-	children := w.Tokens.PopTopN(7)
+// for_init → loc = bool
+func ForInit(w *Walker) error {
+	children := w.Tokens.PopTopN(3)
+	first, l, err := emitAssign(w, children[0], children[2])
 	w.Tokens.Push(&ASTNode{
 		raw:               joinChildren(children),
-		Token:             &lexer.Token{Type: lexer.EXTRA, Val: "unmatched-stmt-if-else"},
+		Token:             &lexer.Token{Type: lexer.EXTRA, Val: "for-init"},
 		Children:          children,
-		Type:              "stmt-if-else",
-		Payload:           "!<if-else>",
-		_genCodeStartLine: children[2]._genCodeStartLine,
-		_genCodeEndLine:   children[6]._genCodeEndLine,
+		Type:              "for-init",
+		Payload:           "!copy(!dist:!src)",
+		_genCodeStartLine: min(first, children[0]._genCodeStartLine, children[2]._genCodeStartLine),
+		_genCodeEndLine:   l,
+	})
+	return err
+}
+
+// for_init → ε
+func ForInitEpsilon(w *Walker) error {
+	w.Tokens.Push(&ASTNode{
+		raw:               "",
+		Token:             &lexer.Token{Type: lexer.EXTRA, Val: "for-init-epsilon"},
+		Children:          nil,
+		Type:              "for-init-epsilon",
+		Payload:           "!<for-init>",
+		_genCodeStartLine: MAX_START_LINE,
+		_genCodeEndLine:   MIN_START_LINE,
 	})
 	return nil
 }
 
-// matched_stmt → loc = bool ;
-func MatchedStmtAssign(w *Walker) error {
-	children := w.Tokens.PopTopN(4)
-	dist := children[0].Token.Val
-	src := children[2].Token.Val
-	l := w.Emit("mov", dist, src)
+// for_step → loc = bool
+func ForStep(w *Walker) error {
+	children := w.Tokens.PopTopN(3)
+	first, _, err := emitAssign(w, children[0], children[2])
+	jmp := forStepLoop(w)
 	w.Tokens.Push(&ASTNode{
-		raw: joinChildren(children),
-		Token: &lexer.Token{
-			Type: lexer.EXTRA,
-			Val:  "stmt",
-		},
+		raw:               joinChildren(children),
+		Token:             &lexer.Token{Type: lexer.EXTRA, Val: "for-step"},
 		Children:          children,
-		Type:              "stmt-assign",
+		Type:              "for-step",
 		Payload:           "!copy(!dist:!src)",
-		_genCodeStartLine: min(l, children[0]._genCodeStartLine, children[2]._genCodeStartLine),
-		_genCodeEndLine:   l,
+		_genCodeStartLine: min(first, jmp, children[0]._genCodeStartLine, children[2]._genCodeStartLine),
+		_genCodeEndLine:   jmp,
+	})
+	return err
+}
+
+// for_step → loc ++ | loc -- | ++ loc | -- loc
+func ForStepIncDec(w *Walker) error {
+	children := w.Tokens.PopTopN(2)
+	loc, op, step, err := incDecOperand(w, children)
+	first, _ := emitIncDec(w, loc, op, step, err)
+	jmp := forStepLoop(w)
+	w.Tokens.Push(&ASTNode{
+		raw:               joinChildren(children),
+		Token:             &lexer.Token{Type: lexer.EXTRA, Val: "for-step"},
+		Children:          children,
+		Type:              "for-step",
+		Payload:           "!<loc>",
+		_genCodeStartLine: min(first, jmp, loc._genCodeStartLine),
+		_genCodeEndLine:   jmp,
+	})
+	return err
+}
+
+// for_step → ε
+func ForStepEpsilon(w *Walker) error {
+	jmp := forStepLoop(w)
+	w.Tokens.Push(&ASTNode{
+		raw:               "",
+		Token:             &lexer.Token{Type: lexer.EXTRA, Val: "for-step-epsilon"},
+		Children:          nil,
+		Type:              "for-step-epsilon",
+		Payload:           "!<for-step>",
+		_genCodeStartLine: jmp,
+		_genCodeEndLine:   jmp,
 	})
 	return nil
 }
 
-// matched_stmt → if ( bool ) matched_stmt else matched_stmt
-func MatchedStmtIfElse(w *Walker) error {
-	prevEl, _ := w.Tokens.PeekAtK(7)
+// forStepLoop closes the step of a for loop with a jump back to the condition,
+// points the condition's jnz at the body (which starts right after the step) and enters the loop.
+// The tokens are expected to be `for ( for_init ; bool ;` on top of the stack.
+func forStepLoop(w *Walker) int {
+	cond, _ := w.Tokens.PeekAtK(1)
+	jnz, _ := w.Environment.LabelStack.PeekAtK(1)
+	jmp := w.Emit("jmp", fmt.Sprintf("L%d", cond._genCodeStartLine))
+	w.EmitLabel(jnz, fmt.Sprintf("L%d", jmp+1), "jnz", cond.Token.Val)
+	w.EnterLoop(loopLabel(w, 5))
+	return jmp
+}
+
+// matched_stmt → switch ( expr ) { cases }
+//
+// The case bodies are emitted before the dispatch code, which is only known once all the cases are seen:
+//
+//	jmp dispatch
+//	case bodies, each ending with a jump to exit
+//	dispatch: compare chain or jump table
+//	exit:
+func MatchedStmtSwitch(w *Walker) error {
 	children := w.Tokens.PopTopN(7)
+	expr := children[2]
+	payload := children[5].Payload.(*_GenRuleSwitchPayload)
+
+	start := w.GetCurrentLabelCount()
+	w.EmitGoto(payload.Dispatch, start)
+	var end int
+	if isSwitchDense(payload.Cases) {
+		end = emitSwitchJumpTable(w, expr.Token.Val, payload)
+	} else {
+		end = emitSwitchCompareChain(w, expr.Token.Val, payload)
+	}
 	w.Tokens.Push(&ASTNode{
 		raw:               joinChildren(children),
-		Token:             &lexer.Token{Type: lexer.EXTRA, Val: "matched-stmt-if-else"},
+		Token:             &lexer.Token{Type: lexer.EXTRA, Val: "matched-stmt-switch"},
 		Children:          children,
-		Type:              "stmt-if-else",
-		Payload:           "!<if-else>",
-		_genCodeStartLine: children[2]._genCodeStartLine,
-		_genCodeEndLine:   children[6]._genCodeEndLine,
+		Type:              "stmt-switch",
+		Payload:           payload,
+		_genCodeStartLine: min(expr._genCodeStartLine, payload.Dispatch),
+		_genCodeEndLine:   end,
 	})
-	if prevEl.Token.SpecificType() != lexer.ReservedWordElse {
-		n := w.Environment.LabelStack.PopTopN(2)
-		m := w.Environment.EndIfStmtStack.PopTopN(2)
-		w.EmitLabel(n[1], fmt.Sprintf("L%d", m[0]+1), "jmp")
-		w.EmitGoto(m[0], w.GetCurrentLabelCount())
-		w.EmitGoto(m[1], w.GetCurrentLabelCount())
-	} else {
-		n := w.Environment.LabelStack.PopTopN(2)
-		m := w.Environment.EndIfStmtStack.PopTopN(min(2, w.Environment.EndIfStmtStack.Size()))
-		w.EmitLabel(n[1], fmt.Sprintf("L%d", m[0]+1), "jmp")
-		// only fill the first block
-		// in case of `if (condition) { ... } else if (condition) { ... } else { ... }`
-		// we should delegate the next endif to the next block
-		w.EmitGoto(m[0], w.GetCurrentLabelCount())
-		if len(m) > 1 {
-			w.Environment.EndIfStmtStack.Push(m[1])
+	w.ExitSwitch(end + 1)
+	return nil
+}
+
+func isSwitchDense(cases []*_GenRuleSwitchCase) bool {
+	if len(cases) < switchJumpTableMinCases {
+		return false
+	}
+	lo, hi := cases[0].Value, cases[0].Value
+	for _, c := range cases {
+		lo, hi = min(lo, c.Value), max(hi, c.Value)
+	}
+	// hi-lo is computed in uint64, as it overflows int64 for the labels from MinInt64 to MaxInt64
+	return uint64(hi)-uint64(lo) < uint64(len(cases)*switchJumpTableMaxSpread)
+}
+
+// emitSwitchCompareChain emits `eq t, expr, value; jnz case, t` for every case,
+// followed by a jump to the default case (or the exit). It returns the last line emitted.
+func emitSwitchCompareChain(w *Walker, expr string, payload *_GenRuleSwitchPayload) int {
+	exit := w.GetCurrentLabelCount() + 2*len(payload.Cases) + 1
+	result := w.SymbolTable.TempVar(4)
+	for _, c := range payload.Cases {
+		w.Emit("eq", result, expr, strconv.FormatInt(c.Value, 10))
+		w.Emit("jnz", fmt.Sprintf("L%d", c.Line), result)
+	}
+	if payload.Default != nil {
+		return w.Emit("jmp", fmt.Sprintf("L%d", payload.Default.Line))
+	}
+	return w.Emit("jmp", fmt.Sprintf("L%d", exit))
+}
+
+// emitSwitchJumpTable emits `jtab expr, lo, size, default` followed by `size` jumps,
+// the i-th of them being taken when expr equals lo+i. Values out of range and holes
+// in the table go to the default case (or the exit). It returns the last line emitted.
+func emitSwitchJumpTable(w *Walker, expr string, payload *_GenRuleSwitchPayload) int {
+	lo, hi := payload.Cases[0].Value, payload.Cases[0].Value
+	for _, c := range payload.Cases {
+		lo, hi = min(lo, c.Value), max(hi, c.Value)
+	}
+	size := int64(uint64(hi)-uint64(lo)) + 1 // small, see isSwitchDense
+	fallback := fmt.Sprintf("L%d", w.GetCurrentLabelCount()+int(size)+1)
+	if payload.Default != nil {
+		fallback = fmt.Sprintf("L%d", payload.Default.Line)
+	}
+	table := make([]string, size)
+	for i := range table {
+		table[i] = fallback
+	}
+	for _, c := range payload.Cases {
+		table[c.Value-lo] = fmt.Sprintf("L%d", c.Line)
+	}
+	l := w.Emit("jtab", expr, strconv.FormatInt(lo, 10), strconv.FormatInt(size, 10), fallback)
+	for _, target := range table {
+		l = w.Emit("jmp", target)
+	}
+	return l
+}
+
+// cases → cases case_clause
+func Cases(w *Walker) error {
+	children := w.Tokens.PopTopN(2)
+	payload := children[0].Payload.(*_GenRuleSwitchPayload)
+	clause := children[1].Payload.(*_GenRuleSwitchCase)
+	var err error
+	if clause.IsIllegal {
+		// the error is reported by CaseClause, the body without a label is never reached
+	} else if clause.IsDefault {
+		if payload.Default != nil {
+			err = i18n.Errorf("multiple defaults in switch")
+		} else {
+			payload.Default = clause
 		}
+	} else if slices.ContainsFunc(payload.Cases, func(c *_GenRuleSwitchCase) bool {
+		return c.Value == clause.Value
+	}) {
+		err = i18n.Errorf("duplicate case %d in switch", clause.Value)
+	} else {
+		payload.Cases = append(payload.Cases, clause)
+	}
+	payload.End = children[1]._genCodeEndLine
+	w.Tokens.Push(&ASTNode{
+		raw:               joinChildren(children),
+		Token:             &lexer.Token{Type: lexer.EXTRA, Val: "cases"},
+		Children:          children,
+		Type:              "cases",
+		Payload:           payload,
+		_genCodeStartLine: min(children[0]._genCodeStartLine, children[1]._genCodeStartLine),
+		_genCodeEndLine:   max(children[0]._genCodeEndLine, children[1]._genCodeEndLine),
+	})
+	return err
+}
+
+// cases → ε
+func CasesEpsilon(w *Walker) error {
+	// `switch ( expr ) {` is on top of the stack
+	w.EnterSwitch(loopLabel(w, 4))
+	l := w.Emit("jmp", "yyy")
+	w.Tokens.Push(&ASTNode{
+		raw:               "",
+		Token:             &lexer.Token{Type: lexer.EXTRA, Val: "cases-epsilon"},
+		Children:          nil,
+		Type:              "cases-epsilon",
+		Payload:           &_GenRuleSwitchPayload{Dispatch: l, End: l},
+		_genCodeStartLine: l,
+		_genCodeEndLine:   l,
+	})
+	return nil
+}
+
+// case_clause → case bool : stmts
+// The value of the case is a constant expression, e.g. -1 or a constant, evaluated at compile time.
+func CaseClause(w *Walker) error {
+	children := w.Tokens.PopTopN(4)
+	value, err := constEval(children[1])
+	illegal := err != nil || value.IsFloat
+	if _, reported := err.(_GenRuleFoldError); reported {
+		err = nil
+	} else if err == nil && value.IsFloat {
+		err = i18n.Errorf("case %s must be an integer", children[1].raw)
+	} else if err != nil {
+		err = i18n.Errorf("illegal case %s: %w", children[1].raw, err)
 	}
+	caseClause(w, children, &_GenRuleSwitchCase{Value: value.Int, IsIllegal: illegal})
+	return err
+}
+
+// case_clause → default : stmts
+func CaseClauseDefault(w *Walker) error {
+	children := w.Tokens.PopTopN(3)
+	caseClause(w, children, &_GenRuleSwitchCase{IsDefault: true})
 	return nil
 }
 
-// matched_stmt → if ( bool ) matched_stmt
-func MatchedStmtIf(w *Walker) error {
-	prevEl, _ := w.Tokens.PeekAtK(5)
-	children := w.Tokens.PopTopN(5)
+// caseClause closes a case body with a jump to the exit of the switch.
+// The body starts right after the previous case clause.
+func caseClause(w *Walker, children []*ASTNode, clause *_GenRuleSwitchCase) {
+	cases, _ := w.Tokens.Peek()
+	clause.Line = cases.Payload.(*_GenRuleSwitchPayload).End + 1
+	l, _ := w.AddBreakLabel("")
 	w.Tokens.Push(&ASTNode{
 		raw:               joinChildren(children),
-		Token:             &lexer.Token{Type: lexer.EXTRA, Val: "matched-stmt-if"},
+		Token:             &lexer.Token{Type: lexer.EXTRA, Val: "case-clause"},
 		Children:          children,
-		Type:              "stmt-if",
-		Payload:           "!<if>",
-		_genCodeStartLine: children[2]._genCodeStartLine,
-		_genCodeEndLine:   children[4]._genCodeEndLine,
+		Type:              "case-clause",
+		Payload:           clause,
+		_genCodeStartLine: clause.Line,
+		_genCodeEndLine:   l,
 	})
-	n := w.Environment.LabelStack.PopTopN(2)
-	m := w.Environment.EndIfStmtStack.PopTopN(1)
-	w.EmitLabel(n[1], fmt.Sprintf("L%d", m[0]+1), "jmp")
-	w.EmitGoto(m[0], w.GetCurrentLabelCount())
-	if prevEl.Token.SpecificType() == lexer.ReservedWordElse {
-		w.Environment.EndIfStmtStack.Push(m[0])
+}
+
+// loopLabel returns the name of the loop whose keyword is the k-th token from the top of the stack,
+// i.e. `id` in `id : while ...`, or "" if the loop is unlabelled.
+func loopLabel(w *Walker, k int) string {
+	colon, _ := w.Tokens.PeekAtK(k + 1)
+	if colon == nil || colon.Token.SpecificType() != lexer.DelimiterColon {
+		return ""
 	}
-	return nil
+	id, _ := w.Tokens.PeekAtK(k + 2)
+	if id == nil || id.Token.SpecificType() != lexer.Identifier {
+		return ""
+	}
+	return id.Token.Val
 }
 
-// matched_stmt → while ( bool ) stmt
-func MatchedStmtWhile(w *Walker) error {
+// matched_stmt → return bool ;
+func MatchedStmtReturn(w *Walker) error {
+	children := w.Tokens.PopTopN(3)
+	var err error
+	if fn := w.SymbolTable.CurrentFunction(); fn == nil {
+		err = i18n.Errorf("return outside of a function")
+	} else if fn.ReturnType == "" {
+		err = i18n.Errorf("function %s returns nothing, but a value is returned", fn.Variable)
+	}
+	l := w.Emit("ret", children[1].Token.Val)
+	w.Tokens.Push(&ASTNode{
+		raw:               joinChildren(children),
+		Token:             &lexer.Token{Type: lexer.EXTRA, Val: "matched-stmt-return"},
+		Children:          children,
+		Type:              "stmt-return",
+		Payload:           "!<return>",
+		_genCodeStartLine: min(l, children[1]._genCodeStartLine),
+		_genCodeEndLine:   l,
+	})
+	return err
+}
+
+// matched_stmt → return ;
+func MatchedStmtReturnVoid(w *Walker) error {
+	children := w.Tokens.PopTopN(2)
+	var err error
+	if fn := w.SymbolTable.CurrentFunction(); fn == nil {
+		err = i18n.Errorf("return outside of a function")
+	} else if fn.ReturnType != "" {
+		err = i18n.Errorf("function %s returns %s, but no value is returned", fn.Variable, fn.ReturnType)
+	}
+	l := w.Emit("ret", "")
+	w.Tokens.Push(&ASTNode{
+		raw:               joinChildren(children),
+		Token:             &lexer.Token{Type: lexer.EXTRA, Val: "matched-stmt-return"},
+		Children:          children,
+		Type:              "stmt-return",
+		Payload:           "!<return>",
+		_genCodeStartLine: l,
+		_genCodeEndLine:   l,
+	})
+	return err
+}
+
+// matched_stmt → id ( args ) ;
+func MatchedStmtCall(w *Walker) error {
 	children := w.Tokens.PopTopN(5)
+	_, start, l, err := emitCall(w, children[0].Token.Val, children[2], false)
 	w.Tokens.Push(&ASTNode{
 		raw:               joinChildren(children),
-		Token:             &lexer.Token{Type: lexer.EXTRA, Val: "matched-stmt-while"},
+		Token:             &lexer.Token{Type: lexer.EXTRA, Val: "matched-stmt-call"},
 		Children:          children,
-		Type:              "stmt-while",
-		Payload:           "!<while>",
-		_genCodeStartLine: children[2]._genCodeStartLine,
-		_genCodeEndLine:   children[4]._genCodeEndLine,
+		Type:              "stmt-call",
+		Payload:           "!<call>",
+		_genCodeStartLine: start,
+		_genCodeEndLine:   l,
 	})
-	n := w.Environment.LabelStack.PopTopN(2)
-	m := w.Environment.EndIfStmtStack.PopTopN(1)
-	w.EmitLabel(n[1], fmt.Sprintf("L%d", m[0]+1), "jmp")
-	w.EmitGoto(m[0], children[2]._genCodeStartLine)
+	return err
+}
+
+// args → arg_list
+func Args(w *Walker) error {
+	children := w.Tokens.PopTopN(1)
+	w.Tokens.Push(&ASTNode{
+		raw:               children[0].raw,
+		Token:             &lexer.Token{Type: lexer.EXTRA, Val: "args"},
+		Children:          children,
+		Type:              "args",
+		Payload:           children[0].Payload,
+		_genCodeStartLine: children[0]._genCodeStartLine,
+		_genCodeEndLine:   children[0]._genCodeEndLine,
+	})
+	return nil
+}
 
-	w.ExitLoop(m[0] + 1)
+// args → ε
+func ArgsEpsilon(w *Walker) error {
+	w.Tokens.Push(&ASTNode{
+		raw:               "",
+		Token:             &lexer.Token{Type: lexer.EXTRA, Val: "args-epsilon"},
+		Children:          nil,
+		Type:              "args-epsilon",
+		Payload:           &_GenRuleArgsPayload{},
+		_genCodeStartLine: MAX_START_LINE,
+		_genCodeEndLine:   MIN_START_LINE,
+	})
 	return nil
 }
 
-// matched_stmt → do stmt while ( bool ) ;
-func MatchedStmtDoWhile(w *Walker) error {
-	children := w.Tokens.PopTopN(7)
+// arg_list → arg_list , bool
+func ArgList(w *Walker) error {
+	children := w.Tokens.PopTopN(3)
+	payload := children[0].Payload.(*_GenRuleArgsPayload)
+	payload.Args = append(payload.Args, children[2].Token.Val)
 	w.Tokens.Push(&ASTNode{
 		raw:               joinChildren(children),
-		Token:             &lexer.Token{Type: lexer.EXTRA, Val: "matched-stmt-do-while"},
+		Token:             &lexer.Token{Type: lexer.EXTRA, Val: "arg-list"},
 		Children:          children,
-		Type:              "stmt-do-while",
-		Payload:           "!<do-while>",
-		_genCodeStartLine: children[1]._genCodeStartLine,
-		_genCodeEndLine:   children[4]._genCodeEndLine,
+		Type:              "arg-list",
+		Payload:           payload,
+		_genCodeStartLine: min(children[0]._genCodeStartLine, children[2]._genCodeStartLine),
+		_genCodeEndLine:   max(children[0]._genCodeEndLine, children[2]._genCodeEndLine),
 	})
-	n := w.Environment.LabelStack.PopTopN(2)
-	m := w.Environment.LoopLabelStack.PopTopN(1)
-	w.AdjustJMP(n[0], m[0])
-	w.EmitLabel(n[1], fmt.Sprintf("L%d", n[1]+1), "jmp")
-
-	w.ExitLoop(n[1] + 1)
 	return nil
 }
 
-// matched_stmt → break ;
-func MatchedStmtBreak(w *Walker) error {
-	children := w.Tokens.PopTopN(2)
-	l := w.AddBreakLabel()
+// arg_list → bool
+func ArgListBool(w *Walker) error {
+	children := w.Tokens.PopTopN(1)
 	w.Tokens.Push(&ASTNode{
 		raw:               children[0].raw,
-		Token:             &lexer.Token{Type: lexer.EXTRA, Val: "matched-stmt-break"},
+		Token:             &lexer.Token{Type: lexer.EXTRA, Val: "arg-list"},
 		Children:          children,
-		Type:              "stmt-break",
-		Payload:           "!<break>",
-		_genCodeStartLine: l,
-		_genCodeEndLine:   l,
+		Type:              "arg-list",
+		Payload:           &_GenRuleArgsPayload{Args: []string{children[0].Token.Val}},
+		_genCodeStartLine: children[0]._genCodeStartLine,
+		_genCodeEndLine:   children[0]._genCodeEndLine,
 	})
 	return nil
 }
 
 // matched_stmt → block
 func MatchedStmtBlock(w *Walker) error {
-	matchedStmtBlockIfWhileElse(w)
 	children := w.Tokens.PopTopN(1)
 	w.Tokens.Push(&ASTNode{
 		raw:               children[0].raw,
@@ -616,97 +2498,139 @@ func MatchedStmtBlock(w *Walker) error {
 	return nil
 }
 
-func matchedStmtBlockIfWhileElse(w *Walker) {
-	doelse, _ := w.Tokens.PeekAtK(1)
-	if doelse == nil {
-		return
-	}
-	if doelse.Token.SpecificType() == lexer.ReservedWordElse {
-		w.NewGotoLabel()
-		return
-	}
-	ifwhile, _ := w.Tokens.PeekAtK(4)
-	if ifwhile == nil {
-		return
-	}
-	if ifwhile.Token.SpecificType() == lexer.ReservedWordIf || ifwhile.Token.SpecificType() == lexer.ReservedWordWhile {
-		w.NewGotoLabel()
-		return
+// branch wraps the rule of a matched_stmt, which ends the statement with a goto label when it is the body
+// of an if, an else or a while, braced or not: the jump over the else branch after the then branch, backfilled
+// by MatchedStmtIf or MatchedStmtIfElse, or the jump back to the condition of a while, see MatchedStmtWhile.
+func branch(rule Rule) Rule {
+	return func(w *Walker) error {
+		err := rule(w)
+		stmt, _ := w.Tokens.Peek()
+		if stmt == nil {
+			return err
+		}
+		doelse, _ := w.Tokens.PeekAtK(1)
+		if doelse == nil {
+			return err
+		}
+		if doelse.Token.SpecificType() == lexer.ReservedWordElse {
+			// the else if has no jump of its own, its branches jump to the end of the chain
+			if !isIfStmt(stmt) {
+				w.NewGotoLabel()
+			}
+			return err
+		}
+		ifwhile, _ := w.Tokens.PeekAtK(4)
+		if ifwhile == nil {
+			return err
+		}
+		if ifwhile.Token.SpecificType() == lexer.ReservedWordIf || ifwhile.Token.SpecificType() == lexer.ReservedWordWhile {
+			w.NewGotoLabel()
+		}
+		return err
 	}
 }
 
+func isIfStmt(node *ASTNode) bool {
+	return node.Type == "stmt-if" || node.Type == "stmt-if-else"
+}
+
 // loc → loc [ num ]
 func LocArray(w *Walker) error {
 	children := w.Tokens.PopTopN(4)
-	loc, num := children[0], children[2]
-	index, err := strconv.Atoi(num.Token.Val)
-	if err != nil {
-		fmt.Printf("Error: %v\n", err)
-		index = -1
+	loc, num := children[0].Payload.(*_GenRuleLocPayload), children[2]
+	var err error
+	if num.Token.Type != lexer.INTEGER {
+		err = i18n.Errorf("index %s must be an integer", num.Token.Val)
 	}
-	var dimension []int
-	var variable string
-	var addr int
-	if payload, ok := loc.Payload.(*_GenRuleArrayPayload); ok {
-		dimension = append(payload.Dimension, index)
-		variable = payload.Variable
-		addr, _, err = w.SymbolTable.ArrayAddress(variable, dimension)
-	} else {
-		dimension = []int{index}
-		variable = loc.Children[0].raw
-		addr, _, err = w.SymbolTable.ArrayAddress(variable, dimension)
+	index, aerr := literalInt(num.Token)
+	if aerr != nil {
+		err = aerr
 	}
-
-	if err != nil {
-		fmt.Printf("Error: %v\n", err)
+	payload := &_GenRuleLocPayload{}
+	if loc.Item != nil {
+		payload = loc.index(index)
+		if _, aerr := payload.Address(); aerr != nil {
+			err = aerr
+		}
 	}
-
-	addrStr := fmt.Sprintf("$(%#x)", addr)
-
 	w.Tokens.Push(&ASTNode{
-		raw: joinChildren(children),
-		Token: &lexer.Token{
-			Type: lexer.EXTRA,
-			Val:  addrStr,
-		},
+		raw:               joinChildren(children),
+		Token:             &lexer.Token{Type: lexer.EXTRA, Val: payload.AddrString()},
 		Children:          children,
 		Type:              "loc-array",
-		Payload:           &_GenRuleArrayPayload{Dimension: dimension, Variable: variable},
-		_genCodeStartLine: min(loc._genCodeStartLine, num._genCodeStartLine),
-		_genCodeEndLine:   max(loc._genCodeEndLine, num._genCodeEndLine),
+		Payload:           payload,
+		_genCodeStartLine: min(children[0]._genCodeStartLine, num._genCodeStartLine),
+		_genCodeEndLine:   max(children[0]._genCodeEndLine, num._genCodeEndLine),
 	})
-	return nil
+	return err
+}
+
+// loc → loc . id
+//
+// The address of a field is the address of the struct plus the offset of the field, computed at compile time
+// like the address of an element of an array.
+func LocField(w *Walker) error {
+	children := w.Tokens.PopTopN(3)
+	loc, id := children[0].Payload.(*_GenRuleLocPayload), children[2]
+	payload := &_GenRuleLocPayload{}
+	var err error
+	if st := loc.StructType(); st == nil {
+		err = i18n.Errorf("%s is not a struct", children[0].raw)
+	} else if base, aerr := loc.Address(); aerr != nil {
+		err = aerr
+	} else if addr, field, ferr := st.FieldAddress(base, id.Token.Val); ferr != nil {
+		err = ferr
+	} else {
+		payload = &_GenRuleLocPayload{Root: loc.Root, Item: field, Base: addr}
+		w.fields[id.Token.Span.StartOffset] = field
+	}
+	w.Tokens.Push(&ASTNode{
+		raw:               joinChildren(children),
+		Token:             &lexer.Token{Type: lexer.EXTRA, Val: payload.AddrString()},
+		Children:          children,
+		Type:              "loc-field",
+		Payload:           payload,
+		_genCodeStartLine: children[0]._genCodeStartLine,
+		_genCodeEndLine:   children[0]._genCodeEndLine,
+	})
+	return err
 }
 
 // loc → id
 func LocId(w *Walker) error {
 	children := w.Tokens.PopTopN(1)
-	addr := "$(nullptr)"
-	i, _, err := w.SymbolTable.Lookup(children[0].Token.Val)
-	if err != nil {
-		fmt.Printf("Error: %v\n", err)
-		return nil
+	payload := &_GenRuleLocPayload{}
+	item, _, err := w.SymbolTable.Lookup(children[0].Token.Val)
+	t := Symbol("loc-id")
+	if err == nil {
+		switch item.Type {
+		case SymbolTableItemTypeVariable, SymbolTableItemTypeArray:
+			payload = &_GenRuleLocPayload{Root: item, Item: item, Base: item.Address}
+		case SymbolTableItemTypeConstant:
+			// substituted by its value, see AddrString
+			payload = &_GenRuleLocPayload{Root: item, Item: item}
+			t = "loc-const"
+		default:
+			err = i18n.Errorf("%s is not a variable", children[0].Token.Val)
+		}
 	}
-	addr = fmt.Sprintf("$(%#x)", i.Address)
 	w.Tokens.Push(&ASTNode{
 		raw:               children[0].raw,
-		Token:             &lexer.Token{Type: lexer.EXTRA, Val: addr},
+		Token:             &lexer.Token{Type: lexer.EXTRA, Val: payload.AddrString()},
 		Children:          children,
-		Type:              "loc-id",
-		Payload:           "!dist:!ptr(size=4)",
+		Type:              t,
+		Payload:           payload,
 		_genCodeStartLine: MAX_START_LINE,
 		_genCodeEndLine:   MIN_START_LINE,
 	})
-	return nil
+	return err
 }
 
 // bool → bool'
 func Bool(w *Walker) error {
 	children := w.Tokens.PopTopN(1)
-	prev, _ := w.Tokens.Peek()
-	if prev.Token.SpecificType() != lexer.OperatorAssignment {
-		resultAddr := w.SymbolTable.TempAddr(4)
-		resultStr := fmt.Sprintf("$(%#x)", resultAddr)
+	if !boolIsValue(w) {
+		resultStr := w.SymbolTable.TempVar(4)
 		l := w.Emit("cmp", resultStr, children[0].Token.Val, "0")
 		w.Tokens.Push(&ASTNode{
 			raw:               children[0].raw,
@@ -732,6 +2656,25 @@ func Bool(w *Walker) error {
 	return nil
 }
 
+// boolIsValue checks if the bool being reduced is used as a value, i.e. it is assigned,
+// passed as an argument, returned or the value of a case, rather than tested.
+func boolIsValue(w *Walker) bool {
+	prev, _ := w.Tokens.Peek()
+	switch prev.Token.SpecificType() {
+	case lexer.OperatorAssignment, lexer.DelimiterComma, lexer.ReservedWordReturn, lexer.ReservedWordCase:
+		return true
+	case lexer.DelimiterLeftBrace:
+		// { bool: the first initializer of a list
+		return true
+	case lexer.DelimiterLeftParenthesis:
+		// ( bool: tested if it is the condition of an if or a while, a value otherwise,
+		// e.g. the first argument of a call or an expression in parentheses
+		k, _ := w.Tokens.PeekAtK(1)
+		return k != nil && k.Token.SpecificType() != lexer.ReservedWordIf && k.Token.SpecificType() != lexer.ReservedWordWhile
+	}
+	return false
+}
+
 func boolLookbackIfWhile(w *Walker) {
 	ifwhile, _ := w.Tokens.PeekAtK(2)
 	if ifwhile == nil {
@@ -745,17 +2688,30 @@ func boolLookbackIfWhile(w *Walker) {
 		if ifwhile.Token.SpecificType() == lexer.ReservedWordWhile {
 			do, _ := w.Tokens.PeekAtK(4)
 			if do == nil || do.Token.SpecificType() != lexer.ReservedWordDo {
-				w.EnterLoop()
+				w.EnterLoop(loopLabel(w, 2))
 			}
 		}
+		return
+	}
+	// for ( for_init ; bool: the jnz is backfilled by the step, see forStepLoop
+	semicolon, _ := w.Tokens.PeekAtK(1)
+	loop, _ := w.Tokens.PeekAtK(4)
+	if semicolon == nil || loop == nil {
+		return
+	}
+	if semicolon.Token.SpecificType() == lexer.DelimiterSemicolon && loop.Token.SpecificType() == lexer.ReservedWordFor {
+		w.NewLabel()
+		w.NewLabel()
 	}
 }
 
 // bool' → bool' || join
 func BoolPrime(w *Walker) error {
-	result := w.SymbolTable.TempAddr(4)
+	if folded, err := foldConst(w, 3, "bool-prime"); folded {
+		return err
+	}
+	resultStr := w.SymbolTable.TempVar(4)
 	children := w.Tokens.PopTopN(3)
-	resultStr := fmt.Sprintf("$(%#x)", result)
 	l := w.Emit("or", resultStr, children[0].Token.Val, children[2].Token.Val)
 	w.Tokens.Push(&ASTNode{
 		raw: joinChildren(children),
@@ -787,11 +2743,13 @@ func BoolPrimeJoin(w *Walker) error {
 	return nil
 }
 
-// join → join && equality
+// join → join && bitwise
 func Join(w *Walker) error {
-	result := w.SymbolTable.TempAddr(4)
+	if folded, err := foldConst(w, 3, "join"); folded {
+		return err
+	}
+	resultStr := w.SymbolTable.TempVar(4)
 	children := w.Tokens.PopTopN(3)
-	resultStr := fmt.Sprintf("$(%#x)", result)
 	l := w.Emit("and", resultStr, children[0].Token.Val, children[2].Token.Val)
 	w.Tokens.Push(&ASTNode{
 		raw: joinChildren(children),
@@ -808,14 +2766,98 @@ func Join(w *Walker) error {
 	return nil
 }
 
-// join → equality
-func JoinEquality(w *Walker) error {
+// join → bitwise
+func JoinBitwise(w *Walker) error {
+	children := w.Tokens.PopTopN(1)
+	w.Tokens.Push(&ASTNode{
+		raw:               children[0].raw,
+		Token:             &lexer.Token{Type: lexer.EXTRA, Val: children[0].Token.Val},
+		Children:          children,
+		Type:              "join-bitwise",
+		Payload:           "!<bitwise>",
+		_genCodeStartLine: children[0]._genCodeStartLine,
+		_genCodeEndLine:   children[0]._genCodeEndLine,
+	})
+	return nil
+}
+
+// bitwise → bitwise | bitwise
+func BitOr(w *Walker) error {
+	if folded, err := foldConst(w, 3, "bit-or"); folded {
+		return err
+	}
+	resultStr := w.SymbolTable.TempVar(4)
+	children := w.Tokens.PopTopN(3)
+	l := w.Emit("bor", resultStr, children[0].Token.Val, children[2].Token.Val)
+	w.Tokens.Push(&ASTNode{
+		raw: joinChildren(children),
+		Token: &lexer.Token{
+			Type: lexer.EXTRA,
+			Val:  resultStr,
+		},
+		Children:          children,
+		Type:              "bit-or",
+		Payload:           "!dist:!ptr(size=4)",
+		_genCodeStartLine: min(l, children[0]._genCodeStartLine, children[2]._genCodeStartLine),
+		_genCodeEndLine:   l,
+	})
+	return nil
+}
+
+// bitwise → bitwise ^ bitwise
+func BitXor(w *Walker) error {
+	if folded, err := foldConst(w, 3, "bit-xor"); folded {
+		return err
+	}
+	resultStr := w.SymbolTable.TempVar(4)
+	children := w.Tokens.PopTopN(3)
+	l := w.Emit("xor", resultStr, children[0].Token.Val, children[2].Token.Val)
+	w.Tokens.Push(&ASTNode{
+		raw: joinChildren(children),
+		Token: &lexer.Token{
+			Type: lexer.EXTRA,
+			Val:  resultStr,
+		},
+		Children:          children,
+		Type:              "bit-xor",
+		Payload:           "!dist:!ptr(size=4)",
+		_genCodeStartLine: min(l, children[0]._genCodeStartLine, children[2]._genCodeStartLine),
+		_genCodeEndLine:   l,
+	})
+	return nil
+}
+
+// bitwise → bitwise & bitwise
+func BitAnd(w *Walker) error {
+	if folded, err := foldConst(w, 3, "bit-and"); folded {
+		return err
+	}
+	resultStr := w.SymbolTable.TempVar(4)
+	children := w.Tokens.PopTopN(3)
+	l := w.Emit("band", resultStr, children[0].Token.Val, children[2].Token.Val)
+	w.Tokens.Push(&ASTNode{
+		raw: joinChildren(children),
+		Token: &lexer.Token{
+			Type: lexer.EXTRA,
+			Val:  resultStr,
+		},
+		Children:          children,
+		Type:              "bit-and",
+		Payload:           "!dist:!ptr(size=4)",
+		_genCodeStartLine: min(l, children[0]._genCodeStartLine, children[2]._genCodeStartLine),
+		_genCodeEndLine:   l,
+	})
+	return nil
+}
+
+// bitwise → equality
+func BitwiseEquality(w *Walker) error {
 	children := w.Tokens.PopTopN(1)
 	w.Tokens.Push(&ASTNode{
 		raw:               children[0].raw,
 		Token:             &lexer.Token{Type: lexer.EXTRA, Val: children[0].Token.Val},
 		Children:          children,
-		Type:              "join-equality",
+		Type:              "bitwise-equality",
 		Payload:           "!<equality>",
 		_genCodeStartLine: children[0]._genCodeStartLine,
 		_genCodeEndLine:   children[0]._genCodeEndLine,
@@ -825,9 +2867,11 @@ func JoinEquality(w *Walker) error {
 
 // equality → equality == rel
 func Equality(w *Walker) error {
-	result := w.SymbolTable.TempAddr(4)
+	if folded, err := foldConst(w, 3, "equality"); folded {
+		return err
+	}
+	resultStr := w.SymbolTable.TempVar(4)
 	children := w.Tokens.PopTopN(3)
-	resultStr := fmt.Sprintf("$(%#x)", result)
 	l := w.Emit("eq", resultStr, children[0].Token.Val, children[2].Token.Val)
 	w.Tokens.Push(&ASTNode{
 		raw: joinChildren(children),
@@ -847,9 +2891,11 @@ func Equality(w *Walker) error {
 
 // equality → equality != rel
 func NotEquality(w *Walker) error {
-	result := w.SymbolTable.TempAddr(4)
+	if folded, err := foldConst(w, 3, "not-equality"); folded {
+		return err
+	}
+	resultStr := w.SymbolTable.TempVar(4)
 	children := w.Tokens.PopTopN(3)
-	resultStr := fmt.Sprintf("$(%#x)", result)
 	l := w.Emit("ne", resultStr, children[0].Token.Val, children[2].Token.Val)
 	w.Tokens.Push(&ASTNode{
 		raw: joinChildren(children),
@@ -883,9 +2929,11 @@ func EqualityRelational(w *Walker) error {
 
 // rel → expr < expr
 func RelationalLess(w *Walker) error {
-	result := w.SymbolTable.TempAddr(4)
+	if folded, err := foldConst(w, 3, "less"); folded {
+		return err
+	}
+	resultStr := w.SymbolTable.TempVar(4)
 	children := w.Tokens.PopTopN(3)
-	resultStr := fmt.Sprintf("$(%#x)", result)
 	l := w.Emit("ls", children[0].Token.Val, children[2].Token.Val)
 	w.Tokens.Push(&ASTNode{
 		raw: joinChildren(children),
@@ -904,9 +2952,11 @@ func RelationalLess(w *Walker) error {
 
 // rel → expr > expr
 func RelationalGreater(w *Walker) error {
-	result := w.SymbolTable.TempAddr(4)
+	if folded, err := foldConst(w, 3, "greater"); folded {
+		return err
+	}
+	resultStr := w.SymbolTable.TempVar(4)
 	children := w.Tokens.PopTopN(3)
-	resultStr := fmt.Sprintf("$(%#x)", result)
 	l := w.Emit("gt", children[0].Token.Val, children[2].Token.Val)
 	w.Tokens.Push(&ASTNode{
 		raw: joinChildren(children),
@@ -925,9 +2975,11 @@ func RelationalGreater(w *Walker) error {
 
 // rel → expr <= expr
 func RelationalLessEqual(w *Walker) error {
-	result := w.SymbolTable.TempAddr(4)
+	if folded, err := foldConst(w, 3, "less-equal"); folded {
+		return err
+	}
+	resultStr := w.SymbolTable.TempVar(4)
 	children := w.Tokens.PopTopN(3)
-	resultStr := fmt.Sprintf("$(%#x)", result)
 	l := w.Emit("le", resultStr, children[0].Token.Val, children[2].Token.Val)
 	w.Tokens.Push(&ASTNode{
 		raw: joinChildren(children),
@@ -946,9 +2998,11 @@ func RelationalLessEqual(w *Walker) error {
 
 // rel → expr >= expr
 func RelationalGreaterEqual(w *Walker) error {
-	result := w.SymbolTable.TempAddr(4)
+	if folded, err := foldConst(w, 3, "greater-equal"); folded {
+		return err
+	}
+	resultStr := w.SymbolTable.TempVar(4)
 	children := w.Tokens.PopTopN(3)
-	resultStr := fmt.Sprintf("$(%#x)", result)
 	l := w.Emit("ge", resultStr, children[0].Token.Val, children[2].Token.Val)
 	w.Tokens.Push(&ASTNode{
 		raw: joinChildren(children),
@@ -980,11 +3034,62 @@ func RelationalExpr(w *Walker) error {
 	return nil
 }
 
+// expr → expr << expr
+func ShiftLeft(w *Walker) error {
+	if folded, err := foldConst(w, 3, "shift-left"); folded {
+		return err
+	}
+	resultStr := w.SymbolTable.TempVar(4)
+	children := w.Tokens.PopTopN(3)
+	l := w.Emit("shl", resultStr, children[0].Token.Val, children[2].Token.Val)
+	w.Tokens.Push(&ASTNode{
+		raw: joinChildren(children),
+		Token: &lexer.Token{
+			Type: lexer.EXTRA,
+			Val:  resultStr,
+		},
+		Children:          children,
+		Type:              "shift-left",
+		Payload:           "!dist:!ptr(size=4)",
+		_genCodeStartLine: min(l, children[0]._genCodeStartLine, children[2]._genCodeStartLine),
+		_genCodeEndLine:   l,
+	})
+	return nil
+}
+
+// expr → expr >> expr
+func ShiftRight(w *Walker) error {
+	if folded, err := foldConst(w, 3, "shift-right"); folded {
+		return err
+	}
+	resultStr := w.SymbolTable.TempVar(4)
+	children := w.Tokens.PopTopN(3)
+	l := w.Emit("shr", resultStr, children[0].Token.Val, children[2].Token.Val)
+	w.Tokens.Push(&ASTNode{
+		raw: joinChildren(children),
+		Token: &lexer.Token{
+			Type: lexer.EXTRA,
+			Val:  resultStr,
+		},
+		Children:          children,
+		Type:              "shift-right",
+		Payload:           "!dist:!ptr(size=4)",
+		_genCodeStartLine: min(l, children[0]._genCodeStartLine, children[2]._genCodeStartLine),
+		_genCodeEndLine:   l,
+	})
+	return nil
+}
+
 // expr → expr + term
 func ExprPlus(w *Walker) error {
-	result := w.SymbolTable.TempAddr(4)
+	if folded, err := foldConst(w, 3, "plus"); folded {
+		return err
+	}
+	resultStr := w.SymbolTable.TempVar(4)
 	children := w.Tokens.PopTopN(3)
-	resultStr := fmt.Sprintf("$(%#x)", result)
+	if isPointer(valueType(w, children[0])) || isPointer(valueType(w, children[2])) {
+		return pointerArith(w, "add", resultStr, children)
+	}
 	l := w.Emit("add", resultStr, children[0].Token.Val, children[2].Token.Val)
 	w.Tokens.Push(&ASTNode{
 		raw: joinChildren(children),
@@ -1003,9 +3108,14 @@ func ExprPlus(w *Walker) error {
 
 // expr → expr - term
 func ExprMinus(w *Walker) error {
-	result := w.SymbolTable.TempAddr(4)
+	if folded, err := foldConst(w, 3, "minus"); folded {
+		return err
+	}
+	resultStr := w.SymbolTable.TempVar(4)
 	children := w.Tokens.PopTopN(3)
-	resultStr := fmt.Sprintf("$(%#x)", result)
+	if isPointer(valueType(w, children[0])) || isPointer(valueType(w, children[2])) {
+		return pointerArith(w, "sub", resultStr, children)
+	}
 	l := w.Emit("sub", resultStr, children[0].Token.Val, children[2].Token.Val)
 	w.Tokens.Push(&ASTNode{
 		raw: joinChildren(children),
@@ -1022,6 +3132,77 @@ func ExprMinus(w *Walker) error {
 	return nil
 }
 
+// pointerArith emits `p + n`, `n + p`, `p - n` or `p - q`, where n is scaled by the size of the values pointed to
+// in words, the unit of the addresses, so that p + 1 points to the value next to p like the element addresses of
+// an array, and the difference of two pointers is a number of values.
+func pointerArith(w *Walker, op string, result string, children []*ASTNode) error {
+	x, y := children[0], children[2]
+	tx, ty := valueType(w, x), valueType(w, y)
+	if !isPointer(tx) && op == "add" {
+		x, y, tx, ty = y, x, ty, tx
+	}
+	var err error
+	size := typeSize(w, strings.TrimPrefix(tx, "*"))
+	if !isPointer(tx) || size <= 0 || (isPointer(ty) && (op != "sub" || ty != tx)) {
+		err = i18n.Errorf("invalid pointer arithmetic %s", joinChildren(children))
+		size = 4
+	}
+	num, den := wordScale(size)
+	start := w.GetCurrentLabelCount()
+	typ := tx
+	var l int
+	if isPointer(ty) {
+		l = w.Emit("sub", result, x.Token.Val, y.Token.Val)
+		diff := result
+		if den != 1 {
+			diff = w.SymbolTable.TempVar(4)
+			l = w.Emit("mul", diff, result, strconv.Itoa(den))
+		}
+		if num != 1 {
+			result = diff
+			diff = w.SymbolTable.TempVar(4)
+			l = w.Emit("div", diff, result, strconv.Itoa(num))
+		}
+		result, typ = diff, lexer.TypeInt.ToString()
+	} else {
+		offset := y.Token.Val
+		if n, aerr := strconv.Atoi(offset); aerr == nil {
+			offset = strconv.Itoa(n * num / den)
+		} else {
+			if num != 1 {
+				offset = w.SymbolTable.TempVar(4)
+				w.Emit("mul", offset, y.Token.Val, strconv.Itoa(num))
+			}
+			if den != 1 {
+				words := w.SymbolTable.TempVar(4)
+				w.Emit("div", words, offset, strconv.Itoa(den))
+				offset = words
+			}
+		}
+		l = w.Emit(op, result, x.Token.Val, offset)
+	}
+	w.Tokens.Push(&ASTNode{
+		raw:               joinChildren(children),
+		Token:             &lexer.Token{Type: lexer.EXTRA, Val: result},
+		Children:          children,
+		Type:              "pointer-arith",
+		Payload:           &_GenRuleValuePayload{Type: typ},
+		_genCodeStartLine: min(start, x._genCodeStartLine, y._genCodeStartLine),
+		_genCodeEndLine:   l,
+	})
+	return err
+}
+
+// wordScale returns the ratio num/den converting a number of values of the size in bytes to words, e.g. 2/1 for
+// 8 bytes and 1/4 for a byte, as the addresses of the symbol table count 4-byte words.
+func wordScale(size int) (num, den int) {
+	g := 4
+	for size%g != 0 {
+		g /= 2
+	}
+	return size / g, 4 / g
+}
+
 // expr → term
 func ExprTerm(w *Walker) error {
 	children := w.Tokens.PopTopN(1)
@@ -1039,9 +3220,11 @@ func ExprTerm(w *Walker) error {
 
 // term → term * unary
 func TermMult(w *Walker) error {
-	result := w.SymbolTable.TempAddr(4)
+	if folded, err := foldConst(w, 3, "mult"); folded {
+		return err
+	}
+	resultStr := w.SymbolTable.TempVar(4)
 	children := w.Tokens.PopTopN(3)
-	resultStr := fmt.Sprintf("$(%#x)", result)
 	l := w.Emit("mul", resultStr, children[0].Token.Val, children[2].Token.Val)
 	w.Tokens.Push(&ASTNode{
 		raw: joinChildren(children),
@@ -1060,9 +3243,11 @@ func TermMult(w *Walker) error {
 
 // term → term / unary
 func TermDiv(w *Walker) error {
-	result := w.SymbolTable.TempAddr(4)
+	if folded, err := foldConst(w, 3, "div"); folded {
+		return err
+	}
+	resultStr := w.SymbolTable.TempVar(4)
 	children := w.Tokens.PopTopN(3)
-	resultStr := fmt.Sprintf("$(%#x)", result)
 	l := w.Emit("div", resultStr, children[0].Token.Val, children[2].Token.Val)
 	w.Tokens.Push(&ASTNode{
 		raw: joinChildren(children),
@@ -1079,6 +3264,29 @@ func TermDiv(w *Walker) error {
 	return nil
 }
 
+// term → term % unary
+func TermMod(w *Walker) error {
+	if folded, err := foldConst(w, 3, "mod"); folded {
+		return err
+	}
+	resultStr := w.SymbolTable.TempVar(4)
+	children := w.Tokens.PopTopN(3)
+	l := w.Emit("mod", resultStr, children[0].Token.Val, children[2].Token.Val)
+	w.Tokens.Push(&ASTNode{
+		raw: joinChildren(children),
+		Token: &lexer.Token{
+			Type: lexer.EXTRA,
+			Val:  resultStr,
+		},
+		Children:          children,
+		Type:              "mod",
+		Payload:           "!dist:!ptr(size=4)",
+		_genCodeStartLine: min(l, children[0]._genCodeStartLine, children[2]._genCodeStartLine),
+		_genCodeEndLine:   l,
+	})
+	return nil
+}
+
 // term → unary
 func TermUnary(w *Walker) error {
 	children := w.Tokens.PopTopN(1)
@@ -1096,15 +3304,17 @@ func TermUnary(w *Walker) error {
 
 // unary → -unary
 func UnaryNeg(w *Walker) error {
-	result := w.SymbolTable.TempAddr(4)
-	addr := fmt.Sprintf("$(%#x)", result)
+	if folded, err := foldConst(w, 2, "neg"); folded {
+		return err
+	}
+	addr := w.SymbolTable.TempVar(4)
 	children := w.Tokens.PopTopN(2)
 	l := w.Emit(addr, "neg", children[1].Token.Val)
 	w.Tokens.Push(&ASTNode{
 		raw: joinChildren(children),
 		Token: &lexer.Token{
 			Type: lexer.EXTRA,
-			Val:  fmt.Sprintf("$(%#x)", result),
+			Val:  addr,
 		},
 		Children:          children,
 		Type:              "neg",
@@ -1117,15 +3327,17 @@ func UnaryNeg(w *Walker) error {
 
 // unary → !unary
 func UnaryNot(w *Walker) error {
-	result := w.SymbolTable.TempAddr(4)
-	addr := fmt.Sprintf("$(%#x)", result)
+	if folded, err := foldConst(w, 2, "not"); folded {
+		return err
+	}
+	addr := w.SymbolTable.TempVar(4)
 	children := w.Tokens.PopTopN(2)
 	l := w.Emit(addr, "not", children[1].Token.Val)
 	w.Tokens.Push(&ASTNode{
 		raw: joinChildren(children),
 		Token: &lexer.Token{
 			Type: lexer.EXTRA,
-			Val:  fmt.Sprintf("$(%#x)", result),
+			Val:  addr,
 		},
 		Children:          children,
 		Type:              "not",
@@ -1136,6 +3348,72 @@ func UnaryNot(w *Walker) error {
 	return nil
 }
 
+// unary → *unary
+//
+// The value pointed to is loaded, as a pointer is an address rather than the storage of a variable.
+func UnaryDeref(w *Walker) error {
+	children := w.Tokens.PopTopN(2)
+	ptr := children[1]
+	elem, err := pointee(w, ptr)
+	result := w.SymbolTable.TempVar(max(typeSize(w, elem), 4))
+	l := w.Emit("load", result, ptr.Token.Val)
+	w.Tokens.Push(&ASTNode{
+		raw:               joinChildren(children),
+		Token:             &lexer.Token{Type: lexer.EXTRA, Val: result},
+		Children:          children,
+		Type:              "deref",
+		Payload:           &_GenRuleValuePayload{Type: elem},
+		_genCodeStartLine: min(l, ptr._genCodeStartLine),
+		_genCodeEndLine:   l,
+	})
+	return err
+}
+
+// unary → &loc
+//
+// A pointer is the address of a byte, see pointerArith. The address of an array is the address of its first element.
+func UnaryAddr(w *Walker) error {
+	children := w.Tokens.PopTopN(2)
+	loc := children[1]
+	var err error
+	if loc.Type == "loc-const" {
+		err = i18n.Errorf("cannot take the address of constant %s", loc.raw)
+	}
+	typ := "*" + elementType(loc.Payload.(*_GenRuleLocPayload).ValueType())
+	result := w.SymbolTable.TempVar(4)
+	l := w.Emit("lea", result, loc.Token.Val)
+	w.Tokens.Push(&ASTNode{
+		raw:               joinChildren(children),
+		Token:             &lexer.Token{Type: lexer.EXTRA, Val: result},
+		Children:          children,
+		Type:              "address",
+		Payload:           &_GenRuleValuePayload{Type: typ},
+		_genCodeStartLine: min(l, loc._genCodeStartLine),
+		_genCodeEndLine:   l,
+	})
+	return err
+}
+
+// unary → ++loc | --loc
+func UnaryIncDec(w *Walker) error {
+	children := w.Tokens.PopTopN(2)
+	loc, op, step, err := incDecOperand(w, children)
+	first, l := emitIncDec(w, loc, op, step, err)
+	w.Tokens.Push(&ASTNode{
+		raw: joinChildren(children),
+		Token: &lexer.Token{
+			Type: lexer.EXTRA,
+			Val:  loc.Token.Val,
+		},
+		Children:          children,
+		Type:              "pre-inc-dec",
+		Payload:           "!<loc>",
+		_genCodeStartLine: min(first, loc._genCodeStartLine),
+		_genCodeEndLine:   l,
+	})
+	return err
+}
+
 // unary → factor
 func UnaryFactor(w *Walker) error {
 	children := w.Tokens.PopTopN(1)
@@ -1166,6 +3444,54 @@ func FactorBool(w *Walker) error {
 	return nil
 }
 
+// factor → id ( args )
+func FactorCall(w *Walker) error {
+	children := w.Tokens.PopTopN(4)
+	result, start, l, err := emitCall(w, children[0].Token.Val, children[2], true)
+	w.Tokens.Push(&ASTNode{
+		raw:               joinChildren(children),
+		Token:             &lexer.Token{Type: lexer.EXTRA, Val: result},
+		Children:          children,
+		Type:              "factor-call",
+		Payload:           "!dist:!ptr(size=4)",
+		_genCodeStartLine: start,
+		_genCodeEndLine:   l,
+	})
+	return err
+}
+
+// emitCall emits the params and the call of the function with the arguments of the args node.
+// If the value of the call is used, the result is stored in a new temporary variable, which is returned.
+// It returns the result, the first and the last line of the call.
+func emitCall(w *Walker, name string, args *ASTNode, value bool) (string, int, int, error) {
+	operands := args.Payload.(*_GenRuleArgsPayload).Args
+	fn, _, err := w.SymbolTable.Lookup(name)
+	if err != nil {
+		return "$(nullptr)", args._genCodeStartLine, args._genCodeEndLine, err
+	}
+	if fn.Type != SymbolTableItemTypeFunction {
+		return "$(nullptr)", args._genCodeStartLine, args._genCodeEndLine, i18n.Errorf("%s is not a function", name)
+	}
+	if len(operands) != len(fn.Params) {
+		err = i18n.Errorf("call of %s: expected %d arguments, got %d", name, len(fn.Params), len(operands))
+	}
+	start := w.GetCurrentLabelCount()
+	for _, operand := range operands {
+		w.Emit("param", operand)
+	}
+	entry := fmt.Sprintf("L%d", fn.Address)
+	if !value {
+		l := w.Emit("call", entry, strconv.Itoa(len(operands)))
+		return "", min(start, args._genCodeStartLine), l, err
+	}
+	if fn.ReturnType == "" {
+		err = i18n.Errorf("call of %s: function returns nothing", name)
+	}
+	result := w.SymbolTable.TempVar(max(fn.VariableSize, 4))
+	l := w.Emit("call", result, entry, strconv.Itoa(len(operands)))
+	return result, min(start, args._genCodeStartLine), l, err
+}
+
 // factor → loc
 func FactorLoc(w *Walker) error {
 	children := w.Tokens.PopTopN(1)
@@ -1181,27 +3507,141 @@ func FactorLoc(w *Walker) error {
 	return nil
 }
 
+// factor → loc++ | loc--
+func FactorIncDec(w *Walker) error {
+	resultStr := w.SymbolTable.TempVar(4)
+	children := w.Tokens.PopTopN(2)
+	loc, op, step, err := incDecOperand(w, children)
+	mov := w.Emit("mov", resultStr, loc.Token.Val)
+	_, l := emitIncDec(w, loc, op, step, err)
+	w.Tokens.Push(&ASTNode{
+		raw: joinChildren(children),
+		Token: &lexer.Token{
+			Type: lexer.EXTRA,
+			Val:  resultStr,
+		},
+		Children:          children,
+		Type:              "post-inc-dec",
+		Payload:           "!dist:!ptr(size=4)",
+		_genCodeStartLine: min(mov, loc._genCodeStartLine),
+		_genCodeEndLine:   max(mov, l),
+	})
+	return err
+}
+
+// incDecOperand splits `loc ++`, `loc --`, `++ loc` or `-- loc` into the loc, the opcode and the step of one, or
+// of the words of one value for a pointer like p + 1. It reports a loc that cannot be assigned.
+func incDecOperand(w *Walker, children []*ASTNode) (*ASTNode, string, string, error) {
+	loc, op := children[0], children[1]
+	if loc.Token.Type == lexer.OPERATOR {
+		loc, op = op, loc
+	}
+	step := "1"
+	if typ := valueType(w, loc); isPointer(typ) {
+		if size := typeSize(w, strings.TrimPrefix(typ, "*")); size > 0 {
+			num, den := wordScale(size)
+			step = strconv.Itoa(num / den)
+		}
+	}
+	if op.Token.Val == "--" {
+		return loc, "sub", step, checkAssignable(loc)
+	}
+	return loc, "add", step, checkAssignable(loc)
+}
+
+// emitIncDec emits the increment or the decrement split by incDecOperand, unless it failed with err.
+// It returns the line emitted as the first and the last one, MAX_START_LINE and MIN_START_LINE if there is none.
+func emitIncDec(w *Walker, loc *ASTNode, op, step string, err error) (int, int) {
+	if err != nil {
+		return MAX_START_LINE, MIN_START_LINE
+	}
+	l := w.Emit(op, loc.Token.Val, loc.Token.Val, step)
+	return l, l
+}
+
+// checkAssignable reports a loc referring to a constant, which has no storage.
+func checkAssignable(loc *ASTNode) error {
+	if loc.Type == "loc-const" {
+		return i18n.Errorf("cannot assign to constant %s", loc.raw)
+	}
+	return nil
+}
+
+// factor → str
+func FactorStr(w *Walker) error {
+	children := w.Tokens.PopTopN(1)
+	offset := w.ReadOnlyData.Intern(children[0].Token.Val)
+	w.Tokens.Push(&ASTNode{
+		raw:               children[0].raw,
+		Token:             &lexer.Token{Type: lexer.EXTRA, Val: formatReadOnlyAddr(offset)},
+		Children:          children,
+		Type:              "factor-str",
+		Payload:           "!ptr(.rodata)",
+		_genCodeStartLine: MAX_START_LINE,
+		_genCodeEndLine:   MIN_START_LINE,
+	})
+	return nil
+}
+
+// formatReadOnlyAddr formats the address of a string constant at the offset of the .rodata section.
+func formatReadOnlyAddr(offset int) string {
+	return fmt.Sprintf("$(.rodata+%#x)", offset)
+}
+
+// factor → char
+//
+// A char is an immediate, its code point.
+func FactorChar(w *Walker) error {
+	children := w.Tokens.PopTopN(1)
+	r, ok := children[0].Token.Value.(rune)
+	var err error
+	if !ok {
+		err = i18n.Errorf("illegal char literal '%s'", children[0].Token.Val)
+	}
+	w.Tokens.Push(&ASTNode{
+		raw:               children[0].raw,
+		Token:             &lexer.Token{Type: lexer.EXTRA, Val: strconv.Itoa(int(r))},
+		Children:          children,
+		Type:              "factor-char",
+		Payload:           "!<char>",
+		_genCodeStartLine: MAX_START_LINE,
+		_genCodeEndLine:   MIN_START_LINE,
+	})
+	return err
+}
+
 // factor → num
+//
+// The immediate is the constant of the literal, e.g. 31 for 0x1F. A literal out of the range of int64 is
+// reported here, once, like the overflows of the folding, see foldConst.
 func FactorNum(w *Walker) error {
 	children := w.Tokens.PopTopN(1)
+	var payload any = "!const(size=4)"
+	var err error
+	if _, ok := children[0].Token.Value.(int64); !ok {
+		err = i18n.Errorf("constant %s overflows int64", children[0].Token.Val)
+		payload = _GenRuleFoldError{err}
+	}
 	w.Tokens.Push(&ASTNode{
 		raw:               children[0].raw,
-		Token:             &lexer.Token{Type: lexer.EXTRA, Val: children[0].raw},
+		Token:             &lexer.Token{Type: lexer.EXTRA, Val: children[0].Token.Constant},
 		Children:          children,
 		Type:              "factor-num",
-		Payload:           "!const(size=4)",
+		Payload:           payload,
 		_genCodeStartLine: MAX_START_LINE,
 		_genCodeEndLine:   MIN_START_LINE,
 	})
-	return nil
+	return err
 }
 
 // factor → real
+//
+// The immediate is the constant of the literal, e.g. 0.25 for 0x1p-2.
 func FactorReal(w *Walker) error {
 	children := w.Tokens.PopTopN(1)
 	w.Tokens.Push(&ASTNode{
 		raw:               children[0].raw,
-		Token:             &lexer.Token{Type: lexer.EXTRA, Val: children[0].raw},
+		Token:             &lexer.Token{Type: lexer.EXTRA, Val: children[0].Token.Constant},
 		Children:          children,
 		Type:              "factor-real",
 		Payload:           "!const(size=8)",
@@ -1241,6 +3681,44 @@ func FactorFalse(w *Walker) error {
 	return nil
 }
 
+// factor → basic ( bool )
+//
+// The value is converted to the basic type, see conversion. A constant is converted at compile time.
+func FactorCast(w *Walker) error {
+	children := w.Tokens.PopTopN(4)
+	value := children[2]
+	to := children[0].Token.SpecificType().ToString()
+	result, start, end := value.Token.Val, value._genCodeStartLine, value._genCodeEndLine
+	var err error
+	if v, cerr := constEval(value); cerr == nil && value._genCodeStartLine == MAX_START_LINE {
+		if _, err = conversion(v.Type(), to, true); err == nil {
+			v, err = constConvert(v, to)
+			result = v.String()
+		}
+	} else {
+		from := valueType(w, value)
+		if from == "" && cerr == nil {
+			from = v.Type()
+		}
+		var op string
+		if op, err = conversion(from, to, true); op != "" && err == nil {
+			result = w.SymbolTable.TempVar(max(typeSize(w, to), 4))
+			end = w.Emit(op, result, value.Token.Val)
+			start = min(start, end)
+		}
+	}
+	w.Tokens.Push(&ASTNode{
+		raw:               joinChildren(children),
+		Token:             &lexer.Token{Type: lexer.EXTRA, Val: result},
+		Children:          children,
+		Type:              "factor-cast",
+		Payload:           &_GenRuleValuePayload{Type: to},
+		_genCodeStartLine: start,
+		_genCodeEndLine:   end,
+	})
+	return err
+}
+
 func joinChildren(children []*ASTNode) string {
 	res := []string{}
 	for _, child := range children {
@@ -1267,6 +3745,14 @@ func getInitialValue(token *lexer.Token) string {
 	return "<nullptr>"
 }
 
+// allocSize returns the size of a value of the basic type, where a string is a reference to its data.
+func allocSize(basic *lexer.Token) int {
+	if basic.SpecificType() == lexer.TypeString {
+		return 4
+	}
+	return basic.AllocSize()
+}
+
 const (
 	MAX_START_LINE = 0x7FFFFFFF
 	MIN_START_LINE = 0x80000000
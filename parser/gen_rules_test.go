@@ -0,0 +1,874 @@
+package parser_test
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"app/lexer"
+	. "app/parser"
+)
+
+var (
+	sharedParser     *Parser
+	sharedParserOnce sync.Once
+)
+
+func ensureSharedParser() {
+	sharedParserOnce.Do(func() {
+		sharedParser = NewParser()
+		sharedParser.EnsureTable()
+	})
+}
+
+// parseThreeAddress parses src with the default grammar and returns the generated three-address code,
+// one instruction per element with the fields separated by a single space, and the errors logged.
+func parseThreeAddress(src string) (code []string, errs []string) {
+	ensureSharedParser()
+	var logs []string
+	sharedParser.Parse(lexer.NewLexer(strings.NewReader(src)), func(s string) {
+		logs = append(logs, s)
+	})
+	started := false
+	for _, s := range logs {
+		if strings.HasPrefix(s, "Error") {
+			errs = append(errs, s)
+		}
+		if strings.Contains(s, "Three Address Code") {
+			started = true
+			continue
+		}
+		if strings.HasPrefix(s, "---") {
+			break
+		}
+		if started && strings.TrimSpace(s) != "" {
+			code = append(code, strings.Join(strings.Fields(s), " "))
+		}
+	}
+	return code, errs
+}
+
+func expectThreeAddress(t *testing.T, src string, expected []string) {
+	t.Helper()
+	code, errs := parseThreeAddress(src)
+	for _, err := range errs {
+		t.Errorf("Unexpected %s", err)
+	}
+	if len(code) != len(expected) {
+		t.Fatalf("Expected %d instructions, got %d:\n%s", len(expected), len(code), strings.Join(code, "\n"))
+	}
+	for i := range code {
+		if code[i] != expected[i] {
+			t.Errorf("Expected `%s`, got `%s`", expected[i], code[i])
+		}
+	}
+}
+
+func TestGenRules_If(t *testing.T) {
+	tests := []struct {
+		name     string
+		src      string
+		expected []string
+	}{
+		{
+			name: "if-else",
+			src:  `{ int a; if (a == 1) a = 1; else a = 2; }`,
+			expected: []string{
+				"L0 jmp L1",
+				"L1 alloc $(0x10000000) 4 0",
+				"L2 eq $(0x10000001) $(0x10000000) 1",
+				"L3 cmp $(0x10000002) $(0x10000001) 0",
+				"L4 jnz L6 $(0x10000002)",
+				"L5 jmp L8",
+				"L6 mov $(0x10000000) 1",
+				"L7 jmp L10",
+				"L8 mov $(0x10000000) 2",
+				"L9 jmp L10",
+				"L10 exit 0",
+			},
+		},
+		{
+			// the else belongs to the nearest if
+			name: "dangling-else",
+			src:  `{ int a; if (a == 1) if (a == 2) a = 2; else a = 3; }`,
+			expected: []string{
+				"L0 jmp L1",
+				"L1 alloc $(0x10000000) 4 0",
+				"L2 eq $(0x10000001) $(0x10000000) 1",
+				"L3 cmp $(0x10000002) $(0x10000001) 0",
+				"L4 jnz L6 $(0x10000002)",
+				"L5 jmp L15",
+				"L6 eq $(0x10000003) $(0x10000000) 2",
+				"L7 cmp $(0x10000004) $(0x10000003) 0",
+				"L8 jnz L10 $(0x10000004)",
+				"L9 jmp L12",
+				"L10 mov $(0x10000000) 2",
+				"L11 jmp L14",
+				"L12 mov $(0x10000000) 3",
+				"L13 jmp L14",
+				"L14 jmp L15",
+				"L15 exit 0",
+			},
+		},
+		{
+			name: "else-if",
+			src:  `{ int a; if (a == 1) a = 1; else if (a == 2) a = 2; else a = 3; }`,
+			expected: []string{
+				"L0 jmp L1",
+				"L1 alloc $(0x10000000) 4 0",
+				"L2 eq $(0x10000001) $(0x10000000) 1",
+				"L3 cmp $(0x10000002) $(0x10000001) 0",
+				"L4 jnz L6 $(0x10000002)",
+				"L5 jmp L8",
+				"L6 mov $(0x10000000) 1",
+				"L7 jmp L16",
+				"L8 eq $(0x10000003) $(0x10000000) 2",
+				"L9 cmp $(0x10000004) $(0x10000003) 0",
+				"L10 jnz L12 $(0x10000004)",
+				"L11 jmp L14",
+				"L12 mov $(0x10000000) 2",
+				"L13 jmp L16",
+				"L14 mov $(0x10000000) 3",
+				"L15 jmp L16",
+				"L16 exit 0",
+			},
+		},
+		{
+			name: "while",
+			src:  `{ int a; while (a < 2) a++; }`,
+			expected: []string{
+				"L0 jmp L1",
+				"L1 alloc $(0x10000000) 4 0",
+				"L2 ls $(0x10000000) 2",
+				"L3 cmp $(0x10000002) $(0x10000001) 0",
+				"L4 jnz L6 $(0x10000002)",
+				"L5 jmp L8",
+				"L6 add $(0x10000000) $(0x10000000) 1",
+				"L7 jmp L2",
+				"L8 exit 0",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expectThreeAddress(t, tt.src, tt.expected)
+		})
+	}
+}
+
+func TestGenRules_For(t *testing.T) {
+	tests := []struct {
+		name     string
+		src      string
+		expected []string
+	}{
+		{
+			name: "for",
+			src:  `{ int i; for (i = 0; i < 2; i = i + 1) { i = i; } }`,
+			expected: []string{
+				"L0 jmp L1",
+				"L1 alloc $(0x10000000) 4 0",
+				"L2 mov $(0x10000000) 0",
+				"L3 ls $(0x10000000) 2",
+				"L4 cmp $(0x10000002) $(0x10000001) 0",
+				"L5 jnz L10 $(0x10000002)",
+				"L6 jmp L12",
+				"L7 add $(0x10000003) $(0x10000000) 1",
+				"L8 mov $(0x10000000) $(0x10000003)",
+				"L9 jmp L3",
+				"L10 mov $(0x10000000) $(0x10000000)",
+				"L11 jmp L7",
+				"L12 exit 0",
+			},
+		},
+		{
+			name: "for-empty-init-step",
+			src:  `{ int i; for (; true;) { break; } }`,
+			expected: []string{
+				"L0 jmp L1",
+				"L1 alloc $(0x10000000) 4 0",
+				"L2 cmp $(0x10000001) 1 0",
+				"L3 jnz L6 $(0x10000001)",
+				"L4 jmp L8",
+				"L5 jmp L2",
+				"L6 jmp L8",
+				"L7 jmp L5",
+				"L8 exit 0",
+			},
+		},
+		{
+			name: "continue",
+			src:  `{ int i; while (true) { continue; } do { continue; } while (false); }`,
+			expected: []string{
+				"L0 jmp L1",
+				"L1 alloc $(0x10000000) 4 0",
+				"L2 cmp $(0x10000001) 1 0",
+				"L3 jnz L5 $(0x10000001)",
+				"L4 jmp L7",
+				"L5 jmp L2",
+				"L6 jmp L2",
+				"L7 jmp L8",
+				"L8 cmp $(0x10000002) 0 0",
+				"L9 jnz L7 $(0x10000002)",
+				"L10 jmp L11",
+				"L11 exit 0",
+			},
+		},
+		{
+			name: "labelled",
+			src: `{
+				int i;
+				int j;
+				outer: for (i = 0; i < 2; i = i + 1) {
+					for (j = 0; j < 2; j = j + 1) {
+						if (j == 1) { continue outer; }
+						break outer;
+					}
+				}
+			}`,
+			expected: []string{
+				"L0 jmp L1",
+				"L1 alloc $(0x10000000) 4 0",
+				"L2 alloc $(0x10000001) 4 0",
+				"L3 mov $(0x10000000) 0",
+				"L4 ls $(0x10000000) 2",
+				"L5 cmp $(0x10000003) $(0x10000002) 0",
+				"L6 jnz L11 $(0x10000003)",
+				"L7 jmp L28",
+				"L8 add $(0x10000004) $(0x10000000) 1",
+				"L9 mov $(0x10000000) $(0x10000004)",
+				"L10 jmp L4",
+				"L11 mov $(0x10000001) 0",
+				"L12 ls $(0x10000001) 2",
+				"L13 cmp $(0x10000006) $(0x10000005) 0",
+				"L14 jnz L19 $(0x10000006)",
+				"L15 jmp L27",
+				"L16 add $(0x10000007) $(0x10000001) 1",
+				"L17 mov $(0x10000001) $(0x10000007)",
+				"L18 jmp L12",
+				"L19 eq $(0x10000008) $(0x10000001) 1",
+				"L20 cmp $(0x10000009) $(0x10000008) 0",
+				"L21 jnz L23 $(0x10000009)",
+				"L22 jmp L25",
+				"L23 jmp L8",
+				"L24 jmp L25",
+				"L25 jmp L28",
+				"L26 jmp L16",
+				"L27 jmp L8",
+				"L28 exit 0",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expectThreeAddress(t, tt.src, tt.expected)
+		})
+	}
+}
+
+func TestGenRules_Switch(t *testing.T) {
+	tests := []struct {
+		name     string
+		src      string
+		expected []string
+	}{
+		{
+			name: "compare-chain",
+			src:  `{ int a; switch (a) { case 1: a = 1; case 3: a = 3; break; default: a = 0; } }`,
+			expected: []string{
+				"L0 jmp L1",
+				"L1 alloc $(0x10000000) 4 0",
+				"L2 jmp L10",
+				"L3 mov $(0x10000000) 1",
+				"L4 jmp L15",
+				"L5 mov $(0x10000000) 3",
+				"L6 jmp L15",
+				"L7 jmp L15",
+				"L8 mov $(0x10000000) 0",
+				"L9 jmp L15",
+				"L10 eq $(0x10000001) $(0x10000000) 1",
+				"L11 jnz L3 $(0x10000001)",
+				"L12 eq $(0x10000001) $(0x10000000) 3",
+				"L13 jnz L5 $(0x10000001)",
+				"L14 jmp L8",
+				"L15 exit 0",
+			},
+		},
+		{
+			name: "jump-table",
+			src:  `{ int a; switch (a) { case 0: a = 0; case 1: a = 1; case 2: a = 2; case 4: a = 4; } }`,
+			expected: []string{
+				"L0 jmp L1",
+				"L1 alloc $(0x10000000) 4 0",
+				"L2 jmp L11",
+				"L3 mov $(0x10000000) 0",
+				"L4 jmp L17",
+				"L5 mov $(0x10000000) 1",
+				"L6 jmp L17",
+				"L7 mov $(0x10000000) 2",
+				"L8 jmp L17",
+				"L9 mov $(0x10000000) 4",
+				"L10 jmp L17",
+				"L11 jtab $(0x10000000) 0 5 L17",
+				"L12 jmp L3",
+				"L13 jmp L5",
+				"L14 jmp L7",
+				"L15 jmp L17",
+				"L16 jmp L9",
+				"L17 exit 0",
+			},
+		},
+		{
+			name: "continue-through-switch",
+			src:  `{ int a; while (true) { switch (a) { case 7: continue; } } }`,
+			expected: []string{
+				"L0 jmp L1",
+				"L1 alloc $(0x10000000) 4 0",
+				"L2 cmp $(0x10000001) 1 0",
+				"L3 jnz L5 $(0x10000001)",
+				"L4 jmp L12",
+				"L5 jmp L8",
+				"L6 jmp L2",
+				"L7 jmp L11",
+				"L8 eq $(0x10000002) $(0x10000000) 7",
+				"L9 jnz L6 $(0x10000002)",
+				"L10 jmp L11",
+				"L11 jmp L2",
+				"L12 exit 0",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expectThreeAddress(t, tt.src, tt.expected)
+		})
+	}
+
+	// the values of the cases are constant expressions
+	code, errs := parseThreeAddress(`{ const N = 2; int a; switch (a) { case -1: a = 1; case N + 1: a = 3; } }`)
+	for _, err := range errs {
+		t.Errorf("Unexpected %s", err)
+	}
+	for _, expected := range []string{"eq $(0x10000001) $(0x10000000) -1", "eq $(0x10000001) $(0x10000000) 3"} {
+		if !slices.ContainsFunc(code, func(s string) bool { return strings.HasSuffix(s, expected) }) {
+			t.Errorf("Expected `%s`, got:\n%s", expected, strings.Join(code, "\n"))
+		}
+	}
+
+	// the labels from MinInt64 to MaxInt64 are too sparse for a jump table
+	code, errs = parseThreeAddress(`{ int a; switch (a) { case -9223372036854775807 - 1: a = 1; case 0: a = 2; case 2: a = 3; case 9223372036854775807: a = 4; } }`)
+	for _, err := range errs {
+		t.Errorf("Unexpected %s", err)
+	}
+	for _, expected := range []string{"eq $(0x10000001) $(0x10000000) -9223372036854775808", "eq $(0x10000001) $(0x10000000) 9223372036854775807"} {
+		if !slices.ContainsFunc(code, func(s string) bool { return strings.HasSuffix(s, expected) }) {
+			t.Errorf("Expected `%s`, got:\n%s", expected, strings.Join(code, "\n"))
+		}
+	}
+
+	// a label which fails is reported once, and is not a duplicate of the others
+	_, errs = parseThreeAddress(`{ int a; switch (a) { case 1.5: a = 1; case "a": a = 2; case a: a = 3; case 0: a = 0; } }`)
+	if len(errs) != 3 || slices.ContainsFunc(errs, func(s string) bool { return strings.Contains(s, "duplicate") }) {
+		t.Errorf("Expected an error for each of the 3 illegal labels, got %v", errs)
+	}
+
+	for _, src := range []string{
+		`{ int a; switch (a) { case 1: a = 1; case 1: a = 2; } }`,
+		`{ int a; switch (a) { case 1: a = 1; case 2 - 1: a = 2; } }`,
+		`{ int a; switch (a) { case a: a = 1; } }`,
+		`{ int a; switch (a) { case 1.5: a = 1; } }`,
+		`{ int a; switch (a) { default: a = 1; default: a = 2; } }`,
+	} {
+		if _, errs := parseThreeAddress(src); len(errs) == 0 {
+			t.Errorf("Expected an error for %s", src)
+		}
+	}
+}
+
+func TestGenRules_Func(t *testing.T) {
+	tests := []struct {
+		name     string
+		src      string
+		expected []string
+	}{
+		{
+			name: "call",
+			src:  `func add(int a, int b) int { return a + b; } { int x; x = add(1, x); }`,
+			expected: []string{
+				"L0 jmp L5",
+				"L1 enter add 12",
+				"L2 add $(fp+0x2) $(fp+0x0) $(fp+0x1)",
+				"L3 ret $(fp+0x2)",
+				"L4 ret",
+				"L5 alloc $(0x10000000) 4 0",
+				"L6 param 1",
+				"L7 param $(0x10000000)",
+				"L8 call $(0x10000001) L1 2",
+				"L9 mov $(0x10000000) $(0x10000001)",
+				"L10 exit 0",
+			},
+		},
+		{
+			name: "recursion-and-call-stmt",
+			src:  `func f(int n) { int m; m = n - 1; f(m); } { f(3); }`,
+			expected: []string{
+				"L0 jmp L8",
+				"L1 enter f 12",
+				"L2 alloc $(fp+0x1) 4 0",
+				"L3 sub $(fp+0x2) $(fp+0x0) 1",
+				"L4 mov $(fp+0x1) $(fp+0x2)",
+				"L5 param $(fp+0x1)",
+				"L6 call L1 1",
+				"L7 ret",
+				"L8 param 3",
+				"L9 call L1 1",
+				"L10 exit 0",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expectThreeAddress(t, tt.src, tt.expected)
+		})
+	}
+
+	for _, src := range []string{
+		`func f(int a) { } { f(); }`,
+		`func f() { } { int a; a = f(); }`,
+		`func f() int { return; } { }`,
+		`func f() { return 1; } { }`,
+		`func f() { } func f() { } { }`,
+		`{ int a; a = g(); }`,
+		`{ return; }`,
+	} {
+		if _, errs := parseThreeAddress(src); len(errs) == 0 {
+			t.Errorf("Expected an error for %s", src)
+		}
+	}
+}
+
+func TestGenRules_Operators(t *testing.T) {
+	tests := []struct {
+		name     string
+		src      string
+		expected []string
+	}{
+		{
+			name: "precedence",
+			src:  `{ int a; int b; a = b % 3 + 1 << 2 | a & b ^ 1; }`,
+			expected: []string{
+				"L0 jmp L1",
+				"L1 alloc $(0x10000000) 4 0",
+				"L2 alloc $(0x10000001) 4 0",
+				"L3 mod $(0x10000002) $(0x10000001) 3",
+				"L4 add $(0x10000003) $(0x10000002) 1",
+				"L5 shl $(0x10000004) $(0x10000003) 2",
+				"L6 band $(0x10000005) $(0x10000000) $(0x10000001)",
+				"L7 xor $(0x10000006) $(0x10000005) 1",
+				"L8 bor $(0x10000007) $(0x10000004) $(0x10000006)",
+				"L9 mov $(0x10000000) $(0x10000007)",
+				"L10 exit 0",
+			},
+		},
+		{
+			name: "associativity",
+			src:  `{ int a; int b; a = a << b + 1 >> 2; b = a | b | a & b == 1; }`,
+			expected: []string{
+				"L0 jmp L1",
+				"L1 alloc $(0x10000000) 4 0",
+				"L2 alloc $(0x10000001) 4 0",
+				"L3 add $(0x10000002) $(0x10000001) 1",
+				"L4 shl $(0x10000003) $(0x10000000) $(0x10000002)",
+				"L5 shr $(0x10000004) $(0x10000003) 2",
+				"L6 mov $(0x10000000) $(0x10000004)",
+				"L7 bor $(0x10000005) $(0x10000000) $(0x10000001)",
+				"L8 eq $(0x10000006) $(0x10000001) 1",
+				"L9 band $(0x10000007) $(0x10000000) $(0x10000006)",
+				"L10 bor $(0x10000008) $(0x10000005) $(0x10000007)",
+				"L11 mov $(0x10000001) $(0x10000008)",
+				"L12 exit 0",
+			},
+		},
+		{
+			name: "increment-decrement",
+			src:  `{ int a; int b; int[2] c; c[1]++; --a; b = a++ - --c[0] >> 1; }`,
+			expected: []string{
+				"L0 jmp L1",
+				"L1 alloc $(0x10000000) 4 0",
+				"L2 alloc $(0x10000001) 4 0",
+				"L3 alloc $(0x10000002) 8 0",
+				"L4 add $(0x10000003) $(0x10000003) 1",
+				"L5 sub $(0x10000000) $(0x10000000) 1",
+				"L6 mov $(0x10000004) $(0x10000000)",
+				"L7 add $(0x10000000) $(0x10000000) 1",
+				"L8 sub $(0x10000002) $(0x10000002) 1",
+				"L9 sub $(0x10000005) $(0x10000004) $(0x10000002)",
+				"L10 shr $(0x10000006) $(0x10000005) 1",
+				"L11 mov $(0x10000001) $(0x10000006)",
+				"L12 exit 0",
+			},
+		},
+		{
+			name: "for-step",
+			src:  `{ int i; for (i = 0; i < 2; i++) { } }`,
+			expected: []string{
+				"L0 jmp L1",
+				"L1 alloc $(0x10000000) 4 0",
+				"L2 mov $(0x10000000) 0",
+				"L3 ls $(0x10000000) 2",
+				"L4 cmp $(0x10000002) $(0x10000001) 0",
+				"L5 jnz L9 $(0x10000002)",
+				"L6 jmp L10",
+				"L7 add $(0x10000000) $(0x10000000) 1",
+				"L8 jmp L3",
+				"L9 jmp L7",
+				"L10 exit 0",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expectThreeAddress(t, tt.src, tt.expected)
+		})
+	}
+}
+
+func TestGenRules_Literals(t *testing.T) {
+	src := `{ string s; byte c; s = "a"; s = "b"; s = "a"; c = 'x'; }`
+	expectThreeAddress(t, src, []string{
+		"L0 jmp L1",
+		"L1 alloc $(0x10000000) 4 <nullptr>",
+		"L2 alloc $(0x10000001) 1 0",
+		"L3 mov $(0x10000000) $(.rodata+0x0)",
+		"L4 mov $(0x10000000) $(.rodata+0x2)",
+		"L5 mov $(0x10000000) $(.rodata+0x0)",
+		"L6 mov $(0x10000001) 120",
+		"L7 exit 0",
+	})
+
+	var logs []string
+	sharedParser.Parse(lexer.NewLexer(strings.NewReader(src)), func(s string) {
+		logs = append(logs, strings.Join(strings.Fields(s), " "))
+	})
+	for _, expected := range []string{`$(.rodata+0x0) "a"`, `$(.rodata+0x2) "b"`} {
+		if !slices.Contains(logs, expected) {
+			t.Errorf("Expected `%s` in the .rodata section", expected)
+		}
+	}
+
+	for _, src := range []string{
+		`{ int a; a = "a"; }`,
+		`{ string s; s = 1; }`,
+		`{ string s; int a; s = a; }`,
+		`func f() int { return 1; } { string s; s = f(); }`,
+	} {
+		if _, errs := parseThreeAddress(src); len(errs) == 0 {
+			t.Errorf("Expected an error for %s", src)
+		}
+	}
+}
+
+func TestGenRules_NumberLiterals(t *testing.T) {
+	src := `{ int[0x2] a; int8 b = -128; uint8 c = 255; float f; a[0b1] = 0x1F; f = 1E-2; }`
+	expectThreeAddress(t, src, []string{
+		"L0 jmp L1",
+		"L1 alloc $(0x10000000) 8 0",
+		"L2 alloc $(0x10000002) 1 0",
+		"L3 mov $(0x10000002) -128",
+		"L4 alloc $(0x10000003) 1 0",
+		"L5 mov $(0x10000003) 255",
+		"L6 alloc $(0x10000004) 4 0.0f",
+		"L7 mov $(0x10000001) 31",
+		"L8 mov $(0x10000004) 0.01",
+		"L9 exit 0",
+	})
+
+	for _, src := range []string{
+		`{ int8 a; a = 300; }`,
+		`{ int8 a = 100 + 28; }`,
+		`{ uint u = -1; }`,
+		`{ byte c = '中'; }`,
+		`{ int a = 2147483648; }`,
+		`{ float32 f = 1e300; }`,
+	} {
+		if _, errs := parseThreeAddress(src); len(errs) == 0 {
+			t.Errorf("Expected an overflow for %s", src)
+		}
+	}
+}
+
+func TestGenRules_Struct(t *testing.T) {
+	src := `type Point struct { int x; int y; }
+type Shape struct { byte kind; float64 area; struct Point[2] corners; }
+{ struct Point p; struct Shape[2] shapes; p.y = 1; shapes[1].corners[1].y = p.y; shapes[0].kind = 2; }`
+	expectThreeAddress(t, src, []string{
+		"L0 jmp L1",
+		"L1 alloc $(0x10000000) 8 0",
+		"L2 alloc $(0x10000002) 64 <nullptr>",
+		"L3 mov $(0x10000001) 1",
+		"L4 mov $(0x10000011) $(0x10000001)",
+		"L5 mov $(0x10000002) 2",
+		"L6 exit 0",
+	})
+
+	for _, src := range []string{
+		`type P struct { int x; int x; } { }`,
+		`type P struct { int x; } { struct P p; p.z = 1; }`,
+		`{ int a; a.x = 1; }`,
+		`{ struct Q q; }`,
+		`type P struct { int x; } { struct P[2] p; p[2].x = 1; }`,
+	} {
+		if _, errs := parseThreeAddress(src); len(errs) == 0 {
+			t.Errorf("Expected an error for %s", src)
+		}
+	}
+}
+
+func TestGenRules_Const(t *testing.T) {
+	src := `const N = 2 + 1;
+{ const M = N * 4 - (1 << 2); const PI = 3.5 * 2; int[N] a; float f; a[2] = M % N; f = PI; }`
+	expectThreeAddress(t, src, []string{
+		"L0 jmp L1",
+		"L1 alloc $(0x10000000) 12 0",
+		"L2 alloc $(0x10000003) 4 0.0f",
+		"L3 mov $(0x10000002) 2",
+		"L4 mov $(0x10000003) 7.0",
+		"L5 exit 0",
+	})
+
+	// the folding, the literal or the array size reports an overflow with the exact constant, once
+	for src, expected := range map[string]string{
+		`{ int64 a; a = 18446744073709551615; }`:                  "constant 18446744073709551615 overflows int64",
+		`{ const N = -18446744073709551615; }`:                    "constant 18446744073709551615 overflows int64",
+		`{ int a; a = a + 0x8000000000000000; }`:                  "constant 0x8000000000000000 overflows int64",
+		`{ const N = 9223372036854775807 + 1; }`:                  "constant 9223372036854775808 overflows int64",
+		`{ int a; a = -9223372036854775807 - 2; }`:                "constant -9223372036854775809 overflows int64",
+		`{ int64 a; a = 4611686018427387904 * 2 + a; }`:           "constant 9223372036854775808 overflows int64",
+		`{ const N = 3 << 62; }`:                                  "constant 13835058055282163712 overflows int64",
+		`{ const N = 1 << 64; }`:                                  "constant 1 << 64 overflows int64",
+		`{ int a; switch (a) { case 2 * 4611686018427387904: } }`: "constant 9223372036854775808 overflows int64",
+		`{ int[9223372036854775808] a; }`:                         "integer 9223372036854775808 is out of range",
+		`type P struct { int[3000000000] a; } { }`:                "integer 3000000000 is out of range",
+	} {
+		_, errs := parseThreeAddress(src)
+		if len(errs) != 1 || !strings.Contains(errs[0], expected) {
+			t.Errorf("Expected %s for %s, got %v", expected, src, errs)
+		}
+	}
+
+	for _, src := range []string{
+		`{ const N = 1; N = 2; }`,
+		`{ const N = 1; N++; }`,
+		`{ const N = 1; for (N = 0; true; N++) { } }`,
+		`{ int a; const N = a + 1; }`,
+		`{ const N = 1 / 0; }`,
+		`{ const N = 1.5; int[N] a; }`,
+		`{ int n; int[n] a; }`,
+		`{ const N = 1; const N = 2; }`,
+	} {
+		if _, errs := parseThreeAddress(src); len(errs) == 0 {
+			t.Errorf("Expected an error for %s", src)
+		}
+	}
+
+	// nothing is stored to a constant, which has no storage
+	for _, src := range []string{
+		`{ const N = 5; N = 4; }`,
+		`{ const N = 5; N++; --N; }`,
+		`{ const N = 5; int a; a = ++N + N--; }`,
+		`{ const N = 5; for (N = 4; true; N++) { } }`,
+	} {
+		code, errs := parseThreeAddress(src)
+		if len(errs) == 0 {
+			t.Errorf("Expected an error for %s", src)
+		}
+		for _, line := range code {
+			if f := strings.Fields(line); len(f) > 2 && f[2] == "5" {
+				t.Errorf("Expected no store to the constant for %s, got %s", src, line)
+			}
+		}
+	}
+}
+
+func TestGenRules_Init(t *testing.T) {
+	src := `{ int a = 1; string s = "x"; int[2][2] m = {{1, 2}, {3, a}}; }`
+	expectThreeAddress(t, src, []string{
+		"L0 jmp L1",
+		"L1 alloc $(0x10000000) 4 0",
+		"L2 mov $(0x10000000) 1",
+		"L3 alloc $(0x10000001) 4 <nullptr>",
+		"L4 mov $(0x10000001) $(.rodata+0x0)",
+		"L5 alloc $(0x10000002) 16 0",
+		"L6 mov $(0x10000002) 1",
+		"L7 mov $(0x10000003) 2",
+		"L8 mov $(0x10000004) 3",
+		"L9 mov $(0x10000005) $(0x10000000)",
+		"L10 exit 0",
+	})
+
+	for _, src := range []string{
+		`{ int[3] a = {1, 2}; }`,
+		`{ int[2] a = {1, 2, 3}; }`,
+		`{ int[2][2] a = {1, 2, 3, 4}; }`,
+		`{ int[2] a = {{1, 2}, {3, 4}}; }`,
+		`{ int[2] a = 1; }`,
+		`{ int a = {1}; }`,
+		`{ string s = 1; }`,
+		`{ int[2] a = {"x", 1}; }`,
+	} {
+		if _, errs := parseThreeAddress(src); len(errs) == 0 {
+			t.Errorf("Expected an error for %s", src)
+		}
+	}
+}
+
+func TestGenRules_Pointer(t *testing.T) {
+	src := `{ int x; *int p; int n; p = &x; *p = 2; n = *(p + 1) + 1; p = p - n; n = p - p; }`
+	expectThreeAddress(t, src, []string{
+		"L0 jmp L1",
+		"L1 alloc $(0x10000000) 4 0",
+		"L2 alloc $(0x10000001) 4 <nullptr>",
+		"L3 alloc $(0x10000002) 4 0",
+		"L4 lea $(0x10000003) $(0x10000000)",
+		"L5 mov $(0x10000001) $(0x10000003)",
+		"L6 store $(0x10000001) 2",
+		"L7 add $(0x10000004) $(0x10000001) 1",
+		"L8 load $(0x10000005) $(0x10000004)",
+		"L9 add $(0x10000006) $(0x10000005) 1",
+		"L10 mov $(0x10000002) $(0x10000006)",
+		"L11 sub $(0x10000007) $(0x10000001) $(0x10000002)",
+		"L12 mov $(0x10000001) $(0x10000007)",
+		"L13 sub $(0x10000008) $(0x10000001) $(0x10000001)",
+		"L14 mov $(0x10000002) $(0x10000008)",
+		"L15 exit 0",
+	})
+
+	// a * after the declarations starts a dereference or the pointer type of another declaration
+	for _, src := range []string{
+		`{ int x; *int p; *p = 1; }`,
+		`{ int x; x = 1; *int p; p = &x; **int pp; pp = &p; **pp = 2; }`,
+		`{ int x; *int[2] ps; ps[1] = &x; *ps[1] = 1; }`,
+		`func f(*int p) *int { *p = 1; return p; } { int x; *f(&x) = 2; }`,
+	} {
+		if _, errs := parseThreeAddress(src); len(errs) != 0 {
+			t.Errorf("Unexpected %v for %s", errs, src)
+		}
+	}
+
+	for _, src := range []string{
+		`{ int x; x = *x; }`,
+		`{ int x; *x = 1; }`,
+		`{ int x; *int p; x = p; }`,
+		`{ int x; *int p; p = x; }`,
+		`{ byte b; *int p; p = &b; }`,
+		`{ *int p; *int q; p = p + q; }`,
+		`{ const N = 1; *int p; p = &N; }`,
+		`{ *int p; string s; s = *p; }`,
+	} {
+		if _, errs := parseThreeAddress(src); len(errs) == 0 {
+			t.Errorf("Expected an error for %s", src)
+		}
+	}
+}
+
+func TestGenRules_PointerScale(t *testing.T) {
+	src := `{ int64[3] a; *int64 p; int n; p = &a[0] + n; n = p - p; }`
+	expectThreeAddress(t, src, []string{
+		"L0 jmp L1",
+		"L1 alloc $(0x10000000) 24 0",
+		"L2 alloc $(0x10000006) 4 <nullptr>",
+		"L3 alloc $(0x10000007) 4 0",
+		"L4 lea $(0x10000008) $(0x10000000)",
+		"L5 mul $(0x1000000a) $(0x10000007) 2",
+		"L6 add $(0x10000009) $(0x10000008) $(0x1000000a)",
+		"L7 mov $(0x10000006) $(0x10000009)",
+		"L8 sub $(0x1000000b) $(0x10000006) $(0x10000006)",
+		"L9 div $(0x1000000c) $(0x1000000b) 2",
+		"L10 mov $(0x10000007) $(0x1000000c)",
+		"L11 exit 0",
+	})
+
+	// &a[0] + k is &a[k], and p++ steps p like p + 1
+	addr := func(operand string) int64 {
+		n, _ := strconv.ParseInt(strings.TrimSuffix(strings.TrimPrefix(operand, "$("), ")"), 0, 64)
+		return n
+	}
+	for _, typ := range []string{"int", "int64", "struct S"} {
+		for k := range 3 {
+			src := fmt.Sprintf("type S struct { int x; int y; int z; } { %[1]s[3] a; *%[1]s p; p = &a[0] + %[2]d; p = &a[%[2]d]; p = p + 1; p++; }", typ, k)
+			code, errs := parseThreeAddress(src)
+			if len(errs) != 0 {
+				t.Errorf("Unexpected %v for %s", errs, src)
+				continue
+			}
+			var leas, steps []string
+			for _, c := range code {
+				fields := strings.Fields(c)
+				switch fields[1] {
+				case "lea":
+					leas = append(leas, fields[3])
+				case "add":
+					steps = append(steps, fields[4])
+				}
+			}
+			if len(leas) != 2 || len(steps) != 3 {
+				t.Errorf("Unexpected code for %s: %v", src, code)
+				continue
+			}
+			if offset, _ := strconv.ParseInt(steps[0], 0, 64); addr(leas[0])+offset != addr(leas[1]) {
+				t.Errorf("&a[0] + %d is %s + %s, expected %s for %s", k, leas[0], steps[0], leas[1], typ)
+			}
+			if steps[1] != steps[2] {
+				t.Errorf("p++ steps %s, expected %s of p + 1 for %s", steps[2], steps[1], typ)
+			}
+		}
+	}
+}
+
+func TestGenRules_Cast(t *testing.T) {
+	src := `{ int a; int8 b; int64 d; float f; float64 g; int16 s; b = int8(a); d = a; g = a; f = s; a = int(f); b = int8(300); a = 2.0; }`
+	expectThreeAddress(t, src, []string{
+		"L0 jmp L1",
+		"L1 alloc $(0x10000000) 4 0",
+		"L2 alloc $(0x10000001) 1 0",
+		"L3 alloc $(0x10000002) 8 0",
+		"L4 alloc $(0x10000004) 4 0.0f",
+		"L5 alloc $(0x10000005) 8 0.0",
+		"L6 alloc $(0x10000007) 2 0",
+		"L7 trunc $(0x10000008) $(0x10000000)",
+		"L8 mov $(0x10000001) $(0x10000008)",
+		"L9 sext $(0x10000009) $(0x10000000)",
+		"L10 mov $(0x10000002) $(0x10000009)",
+		"L11 sitofp $(0x1000000b) $(0x10000000)",
+		"L12 mov $(0x10000005) $(0x1000000b)",
+		"L13 sitofp $(0x1000000d) $(0x10000007)",
+		"L14 mov $(0x10000004) $(0x1000000d)",
+		"L15 fptosi $(0x1000000e) $(0x10000004)",
+		"L16 mov $(0x10000000) $(0x1000000e)",
+		"L17 mov $(0x10000001) 44",
+		"L18 mov $(0x10000000) 2",
+		"L19 exit 0",
+	})
+
+	for _, src := range []string{
+		`{ int a; int8 b; b = a; }`,
+		`{ int a; uint b; b = a; }`,
+		`{ int a; float64 g; a = g; }`,
+		`{ int a; float f; f = a; }`,
+		`{ int64 d; float64 g; g = d; }`,
+		`{ int a; a = 0.25; }`,
+		`{ int a; a = 0x1p-2; }`,
+		`{ uint a; a = 1.5 * 3; }`,
+		`{ int64 a; a = 1e19; }`,
+		`{ int a; bool b; b = bool(a); }`,
+		`{ string s; int a; a = int(s); }`,
+		`{ *int p; int a; a = int(p); }`,
+	} {
+		if _, errs := parseThreeAddress(src); len(errs) == 0 {
+			t.Errorf("Expected an error for %s", src)
+		}
+	}
+}
@@ -1,6 +1,8 @@
 package parser
 
 import (
+	"cmp"
+	"maps"
 	"slices"
 
 	. "app/utils/collections"
@@ -10,6 +12,47 @@ type Grammar struct {
 	AugmentedProduction Production
 	Productions         []Production
 	Terminals           Set[Terminal]
+
+	// Derived records the non-terminals introduced by EliminateLeftRecursion and LeftFactor.
+	Derived map[Symbol]DerivedSymbol
+
+	// Precedences are the precedence declarations of the terminals, from the lowest to the highest precedence.
+	Precedences []PrecedenceDeclaration
+}
+
+type Associativity string
+
+const (
+	LEFT     Associativity = "left"
+	RIGHT    Associativity = "right"
+	NONASSOC Associativity = "nonassoc"
+)
+
+// PrecedenceDeclaration declares terminals of the same precedence and associativity, like %left a b in yacc.
+type PrecedenceDeclaration struct {
+	Associativity Associativity
+	Terminals     []Terminal
+}
+
+// Precedence is the precedence level of a terminal or a production, the higher the tighter.
+type Precedence struct {
+	Level         int
+	Associativity Associativity
+}
+
+type DerivedSymbolKind int
+
+const (
+	// DerivedTail is A' in A → β A', A' → α A' | ε, which replaces the left recursion A → A α | β.
+	DerivedTail DerivedSymbolKind = iota
+	// DerivedFactor is A' in A → α A', A' → β1 | β2, which factors out the common prefix of A → α β1 | α β2.
+	DerivedFactor
+)
+
+// DerivedSymbol describes a non-terminal introduced by a grammar transformation.
+type DerivedSymbol struct {
+	From Symbol // the non-terminal whose productions it was split from
+	Kind DerivedSymbolKind
 }
 
 func NewGrammar() *Grammar {
@@ -17,6 +60,7 @@ func NewGrammar() *Grammar {
 		AugmentedProduction: AugmentedProduction,
 		Productions:         Productions,
 		Terminals:           Terminals,
+		Precedences:         slices.Clone(Precedences),
 	}
 }
 
@@ -26,6 +70,80 @@ func (g *Grammar) Copy() Grammar {
 		AugmentedProduction: g.AugmentedProduction,
 		Productions:         slices.Clone(g.Productions),
 		Terminals:           g.Terminals.Copy(),
+		Derived:             maps.Clone(g.Derived),
+		Precedences:         slices.Clone(g.Precedences),
+	}
+}
+
+// Left declares the terminals as left-associative, with a higher precedence than the previous declarations.
+func (g *Grammar) Left(terminals ...Terminal) *Grammar {
+	return g.declarePrecedence(LEFT, terminals)
+}
+
+// Right declares the terminals as right-associative, with a higher precedence than the previous declarations.
+func (g *Grammar) Right(terminals ...Terminal) *Grammar {
+	return g.declarePrecedence(RIGHT, terminals)
+}
+
+// NonAssoc declares the terminals as non-associative, with a higher precedence than the previous declarations.
+func (g *Grammar) NonAssoc(terminals ...Terminal) *Grammar {
+	return g.declarePrecedence(NONASSOC, terminals)
+}
+
+func (g *Grammar) declarePrecedence(associativity Associativity, terminals []Terminal) *Grammar {
+	g.Precedences = append(g.Precedences, PrecedenceDeclaration{Associativity: associativity, Terminals: terminals})
+	return g
+}
+
+// TerminalPrecedence returns the precedence of the terminal, from the last declaration containing it.
+func (g *Grammar) TerminalPrecedence(terminal Terminal) (Precedence, bool) {
+	for i := len(g.Precedences) - 1; i >= 0; i-- {
+		if slices.Contains(g.Precedences[i].Terminals, terminal) {
+			return Precedence{Level: i + 1, Associativity: g.Precedences[i].Associativity}, true
+		}
+	}
+	return Precedence{}, false
+}
+
+// ProductionPrecedence returns the precedence of the production, i.e. the one of its Prec terminal if set,
+// otherwise the one of the rightmost terminal of its body, like yacc.
+func (g *Grammar) ProductionPrecedence(production Production) (Precedence, bool) {
+	if production.Prec != "" {
+		return g.TerminalPrecedence(production.Prec)
+	}
+	for i := len(production.Body) - 1; i >= 0; i-- {
+		if symbol := production.Body[i]; !symbol.IsEpsilon() && g.IsTerminal(symbol) {
+			return g.TerminalPrecedence(Terminal(symbol))
+		}
+	}
+	return Precedence{}, false
+}
+
+// ResolveShiftReduce resolves a shift/reduce conflict on the terminal with the precedence declarations, like yacc:
+// the action with the higher precedence wins, and on a tie %left reduces, %right shifts and %nonassoc is an error.
+// It reports false if the terminal or the production has no precedence.
+func (g *Grammar) ResolveShiftReduce(production Production, terminal Terminal, shift Action, reduce Action) (Action, bool) {
+	terminalPrecedence, ok := g.TerminalPrecedence(terminal)
+	if !ok {
+		return Action{}, false
+	}
+	productionPrecedence, ok := g.ProductionPrecedence(production)
+	if !ok {
+		return Action{}, false
+	}
+	switch {
+	case productionPrecedence.Level > terminalPrecedence.Level:
+		return reduce, true
+	case productionPrecedence.Level < terminalPrecedence.Level:
+		return shift, true
+	}
+	switch terminalPrecedence.Associativity {
+	case LEFT:
+		return reduce, true
+	case RIGHT:
+		return shift, true
+	default:
+		return Action{Type: ERROR}, true
 	}
 }
 
@@ -48,3 +166,244 @@ func (g *Grammar) GetIndex(production Production) int {
 		return p.Equals(production)
 	})
 }
+
+// NonTerminals returns the heads of the grammar's productions in the order of their first production.
+func (g *Grammar) NonTerminals() []Symbol {
+	var heads []Symbol
+	for _, production := range g.Productions {
+		if !slices.Contains(heads, production.Head) {
+			heads = append(heads, production.Head)
+		}
+	}
+	return heads
+}
+
+// EliminateLeftRecursion returns an equivalent grammar without left recursion.
+// The non-terminals are ordered A1..An; a production Ai → Aj γ with j < i is replaced by Ai → δ γ
+// for every Aj → δ if Aj can derive a sentential form starting with Ai, then the immediate left recursion
+// A → A α | β is replaced by A → β A', A' → α A' | ε. Left recursion hidden behind nullable symbols is kept.
+// The rules of the productions are dropped, and the introduced non-terminals are recorded in Derived,
+// so that a parse tree of the result can be folded back into the shape of the original grammar.
+func (g *Grammar) EliminateLeftRecursion() Grammar {
+	result := g.Copy()
+	if result.Derived == nil {
+		result.Derived = make(map[Symbol]DerivedSymbol)
+	}
+	order := result.NonTerminals()
+	productions := result.groupProductions()
+
+	var output []Symbol
+	for i, ai := range order {
+		for _, aj := range order[:i] {
+			if !leftDerives(productions, aj, ai) {
+				continue
+			}
+			var rewritten []Production
+			for _, production := range productions[ai] {
+				if production.Body[0] != aj {
+					rewritten = append(rewritten, production)
+					continue
+				}
+				for _, substitute := range productions[aj] {
+					body := slices.Concat(withoutEpsilon(substitute.Body), production.Body[1:])
+					rewritten = append(rewritten, Production{
+						Head:        ai,
+						Body:        withEpsilon(body),
+						Substituted: slices.Concat([]*Production{&substitute}, production.Substituted),
+					})
+				}
+			}
+			productions[ai] = rewritten
+		}
+		output = append(output, ai)
+
+		var recursive, others []Production
+		for _, production := range productions[ai] {
+			if production.Body[0] == ai {
+				recursive = append(recursive, production)
+			} else {
+				others = append(others, production)
+			}
+		}
+		if len(recursive) == 0 {
+			continue
+		}
+
+		tail := result.freshSymbol(ai, productions)
+		result.Derived[tail] = DerivedSymbol{From: ai, Kind: DerivedTail}
+		productions[ai] = nil
+		for _, production := range others {
+			productions[ai] = append(productions[ai], Production{
+				Head:        ai,
+				Body:        append(withoutEpsilon(production.Body), tail),
+				Substituted: production.Substituted,
+			})
+		}
+		for _, production := range recursive {
+			if len(production.Body) == 1 {
+				continue // A → A derives nothing new
+			}
+			productions[tail] = append(productions[tail], Production{
+				Head:        tail,
+				Body:        append(slices.Clone(production.Body[1:]), tail),
+				Substituted: production.Substituted,
+			})
+		}
+		productions[tail] = append(productions[tail], Production{Head: tail, Body: []Symbol{EPSILON}})
+		output = append(output, tail)
+	}
+
+	result.Productions = nil
+	for _, head := range output {
+		result.Productions = append(result.Productions, productions[head]...)
+	}
+	return result
+}
+
+// LeftFactor returns an equivalent grammar in which no two productions of a non-terminal start with the same symbol.
+// The productions A → α β1 | α β2 with the longest common prefix α are replaced by A → α A', A' → β1 | β2.
+// As with EliminateLeftRecursion, the rules of the productions are dropped and the introduced non-terminals are
+// recorded in Derived.
+func (g *Grammar) LeftFactor() Grammar {
+	result := g.Copy()
+	if result.Derived == nil {
+		result.Derived = make(map[Symbol]DerivedSymbol)
+	}
+	order := result.NonTerminals()
+	productions := result.groupProductions()
+
+	for i := 0; i < len(order); i++ {
+		head := order[i]
+		var groups [][]Production
+		for _, production := range productions[head] {
+			index := slices.IndexFunc(groups, func(group []Production) bool {
+				return !group[0].Body[0].IsEpsilon() && group[0].Body[0] == production.Body[0]
+			})
+			if index == -1 {
+				groups = append(groups, []Production{production})
+			} else {
+				groups[index] = append(groups[index], production)
+			}
+		}
+
+		var factored []Production
+		var derived []Symbol
+		for _, group := range groups {
+			if len(group) == 1 {
+				factored = append(factored, group[0])
+				continue
+			}
+			prefix := group[0].Body
+			for _, production := range group[1:] {
+				n := 0
+				for n < len(prefix) && n < len(production.Body) && prefix[n] == production.Body[n] {
+					n++
+				}
+				prefix = prefix[:n]
+			}
+
+			rest := result.freshSymbol(head, productions)
+			result.Derived[rest] = DerivedSymbol{From: head, Kind: DerivedFactor}
+			productions[rest] = []Production{} // reserve the symbol
+			factored = append(factored, Production{Head: head, Body: append(slices.Clone(prefix), rest)})
+			for _, production := range group {
+				productions[rest] = append(productions[rest], Production{
+					Head:        rest,
+					Body:        withEpsilon(slices.Clone(production.Body[len(prefix):])),
+					Substituted: production.Substituted,
+				})
+			}
+			// the ε alternative goes last, so that the longest alternative wins an LL(1) conflict, e.g. the dangling else
+			slices.SortStableFunc(productions[rest], func(a, b Production) int {
+				return cmp.Compare(boolInt(a.Body[0].IsEpsilon()), boolInt(b.Body[0].IsEpsilon()))
+			})
+			derived = append(derived, rest)
+		}
+		productions[head] = factored
+		order = slices.Insert(order, i+1, derived...)
+	}
+
+	result.Productions = nil
+	for _, head := range order {
+		result.Productions = append(result.Productions, productions[head]...)
+	}
+	return result
+}
+
+// groupProductions groups the productions of the grammar by their heads.
+func (g *Grammar) groupProductions() map[Symbol][]Production {
+	productions := make(map[Symbol][]Production)
+	for _, production := range g.Productions {
+		production.Body = withEpsilon(production.Body)
+		productions[production.Head] = append(productions[production.Head], production)
+	}
+	return productions
+}
+
+// freshSymbol returns base followed by as many primes as needed to name a new non-terminal.
+func (g *Grammar) freshSymbol(base Symbol, productions map[Symbol][]Production) Symbol {
+	used := func(symbol Symbol) bool {
+		if g.IsTerminal(symbol) || symbol == g.AugmentedProduction.Head {
+			return true
+		}
+		for head, group := range productions {
+			if head == symbol {
+				return true
+			}
+			for _, production := range group {
+				if slices.Contains(production.Body, symbol) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	symbol := base + "'"
+	for used(symbol) {
+		symbol += "'"
+	}
+	return symbol
+}
+
+// leftDerives checks if from derives a sentential form starting with to, following the leading symbols of the productions.
+func leftDerives(productions map[Symbol][]Production, from Symbol, to Symbol) bool {
+	visited := Set[Symbol]{}
+	stack := []Symbol{from}
+	for len(stack) > 0 {
+		symbol := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		for _, production := range productions[symbol] {
+			first := production.Body[0]
+			if first == to {
+				return true
+			}
+			if _, isNonTerminal := productions[first]; isNonTerminal && !visited.Contains(first) {
+				visited.Add(first)
+				stack = append(stack, first)
+			}
+		}
+	}
+	return false
+}
+
+// withoutEpsilon returns a copy of the body without ε.
+func withoutEpsilon(body []Symbol) []Symbol {
+	return slices.DeleteFunc(slices.Clone(body), func(symbol Symbol) bool {
+		return symbol.IsEpsilon()
+	})
+}
+
+// withEpsilon returns the body, or ε if it is empty.
+func withEpsilon(body []Symbol) []Symbol {
+	if len(body) == 0 {
+		return []Symbol{EPSILON}
+	}
+	return body
+}
+
+func boolInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,115 @@
+package parser_test
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+
+	. "app/parser"
+	. "app/utils/collections"
+)
+
+func TestGrammar_EliminateLeftRecursion(t *testing.T) {
+	tests := []struct {
+		name     string
+		grammar  Grammar
+		expected []Production
+	}{
+		{
+			name: "Immediate",
+			grammar: Grammar{
+				AugmentedProduction: Production{Head: "S", Body: []Symbol{"E"}},
+				Productions: []Production{
+					{Head: "E", Body: []Symbol{"E", "+", "T"}},
+					{Head: "E", Body: []Symbol{"T"}},
+					{Head: "T", Body: []Symbol{"T", "*", "F"}},
+					{Head: "T", Body: []Symbol{"F"}},
+					{Head: "F", Body: []Symbol{"(", "E", ")"}},
+					{Head: "F", Body: []Symbol{"id"}},
+				},
+				Terminals: Set[Terminal]{}.AddAll("(", ")", "+", "*", "id", EPSILON, TERMINATE),
+			},
+			expected: []Production{
+				{Head: "E", Body: []Symbol{"T", "E'"}},
+				{Head: "E'", Body: []Symbol{"+", "T", "E'"}},
+				{Head: "E'", Body: []Symbol{EPSILON}},
+				{Head: "T", Body: []Symbol{"F", "T'"}},
+				{Head: "T'", Body: []Symbol{"*", "F", "T'"}},
+				{Head: "T'", Body: []Symbol{EPSILON}},
+				{Head: "F", Body: []Symbol{"(", "E", ")"}},
+				{Head: "F", Body: []Symbol{"id"}},
+			},
+		},
+		{
+			name: "Indirect",
+			grammar: Grammar{
+				AugmentedProduction: Production{Head: "S'", Body: []Symbol{"S"}},
+				Productions: []Production{
+					{Head: "S", Body: []Symbol{"A", "a"}},
+					{Head: "S", Body: []Symbol{"b"}},
+					{Head: "A", Body: []Symbol{"A", "c"}},
+					{Head: "A", Body: []Symbol{"S", "d"}},
+					{Head: "A", Body: []Symbol{EPSILON}},
+				},
+				Terminals: Set[Terminal]{}.AddAll("a", "b", "c", "d", EPSILON, TERMINATE),
+			},
+			expected: []Production{
+				{Head: "S", Body: []Symbol{"A", "a"}},
+				{Head: "S", Body: []Symbol{"b"}},
+				{Head: "A", Body: []Symbol{"b", "d", "A'"}},
+				{Head: "A", Body: []Symbol{"A'"}},
+				{Head: "A'", Body: []Symbol{"c", "A'"}},
+				{Head: "A'", Body: []Symbol{"a", "d", "A'"}},
+				{Head: "A'", Body: []Symbol{EPSILON}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := tt.grammar.EliminateLeftRecursion()
+			expectProductions(t, g.Productions, tt.expected)
+		})
+	}
+}
+
+func TestGrammar_LeftFactor(t *testing.T) {
+	g := Grammar{
+		AugmentedProduction: Production{Head: "start", Body: []Symbol{"S"}},
+		Productions: []Production{
+			{Head: "S", Body: []Symbol{"i", "E", "t", "S"}},
+			{Head: "S", Body: []Symbol{"i", "E", "t", "S", "e", "S"}},
+			{Head: "S", Body: []Symbol{"a"}},
+			{Head: "E", Body: []Symbol{"b", "c"}},
+			{Head: "E", Body: []Symbol{"b", "c", "d"}},
+			{Head: "E", Body: []Symbol{"b", "e"}},
+		},
+		Terminals: Set[Terminal]{}.AddAll("a", "b", "c", "d", "e", "i", "t", EPSILON, TERMINATE),
+	}
+	factored := g.LeftFactor()
+	expectProductions(t, factored.Productions, []Production{
+		{Head: "S", Body: []Symbol{"i", "E", "t", "S", "S'"}},
+		{Head: "S", Body: []Symbol{"a"}},
+		{Head: "S'", Body: []Symbol{"e", "S"}},
+		{Head: "S'", Body: []Symbol{EPSILON}},
+		{Head: "E", Body: []Symbol{"b", "E'"}},
+		{Head: "E'", Body: []Symbol{"c", "E''"}},
+		{Head: "E'", Body: []Symbol{"e"}},
+		{Head: "E''", Body: []Symbol{"d"}},
+		{Head: "E''", Body: []Symbol{EPSILON}},
+	})
+	for symbol, from := range map[Symbol]Symbol{"S'": "S", "E'": "E", "E''": "E'"} {
+		if derived := factored.Derived[symbol]; derived.Kind != DerivedFactor || derived.From != from {
+			t.Errorf("Expected %s to be factored from %s, got %+v", symbol, from, derived)
+		}
+	}
+}
+
+func expectProductions(t *testing.T, productions []Production, expected []Production) {
+	t.Helper()
+	for _, production := range productions {
+		fmt.Printf("%s -> %v\n", production.Head, production.Body)
+	}
+	if !slices.EqualFunc(productions, expected, func(a, b Production) bool { return a.Equals(b) }) {
+		t.Errorf("Expected %v, got %v", expected, productions)
+	}
+}
@@ -0,0 +1,115 @@
+package parser
+
+import "app/utils/i18n"
+
+// the Chinese messages of the parser, keyed by the English ones
+func init() {
+	i18n.Register(i18n.ZH, map[string]string{
+		"Error: %v":           "错误：%v",
+		"Error: %v\n":         "错误：%v\n",
+		"Three Address Code:": "三地址码：",
+		"Warning: Optimized symbols may cause reduce-reduce conflict": "警告：优化后的符号可能导致归约-归约冲突",
+		"Warning: rule is nil for production %s -> %s":                "警告：产生式 %s -> %s 没有规则",
+
+		// the grammar and the parsing tables
+		"the augmented production %s has no body":                                 "增广产生式 %s 没有产生式体",
+		"undeclared symbols, neither terminals nor heads: %v":                     "未声明的符号，既不是终结符也不是产生式头：%v",
+		"terminals used as heads: %v":                                             "用作产生式头的终结符：%v",
+		"unreachable non-terminals: %v":                                           "不可达的非终结符：%v",
+		"unproductive non-terminals: %v":                                          "不能推导出终结符串的非终结符：%v",
+		"terminal %s has several precedence declarations":                         "终结符 %s 有多个优先级声明",
+		"conflict in action table: state %d, terminal %s[shift] %d, [reduce] %d":  "动作表冲突：状态 %d，终结符 %s[移入] %d，[归约] %d",
+		"conflict in action table: state %d, terminal %s[reduce] %d, [reduce] %d": "动作表冲突：状态 %d，终结符 %s[归约] %d，[归约] %d",
+
+		// the syntax errors
+		"no action found for state %d and symbol %s":      "状态 %d 遇到符号 %s 时没有可用的动作",
+		"no goto state found for state %d and symbol %s":  "状态 %d 遇到符号 %s 时没有可转移的状态",
+		"non-associative symbol %s in state %d":           "状态 %[2]d 中的符号 %[1]s 不满足结合性",
+		"unexpected state %d and symbol %s":               "意外的状态 %d 和符号 %s",
+		"expected %s, got %s":                             "应为 %s，实际为 %s",
+		"no production found for %s and symbol %s":        "%s 遇到符号 %s 时没有可用的产生式",
+		"the grammar is not LL(1):\n%w":                   "文法不是 LL(1) 文法：\n%w",
+		"the input was not accepted":                      "输入未被接受",
+		"cannot build the AST: %v":                        "无法构建抽象语法树：%v",
+		"cannot build the AST: the root is not a program": "无法构建抽象语法树：根节点不是程序",
+
+		// the symbol table
+		"item %s is not a struct":                         "%s 不是结构体",
+		"struct %s has no field %s":                       "结构体 %s 没有字段 %s",
+		"item %s is not an array":                         "%s 不是数组",
+		"too many indices for item %s":                    "%s 的下标过多",
+		"index out of bounds for dimension %d of item %s": "%[2]s 的第 %[1]d 维下标越界",
+		"index out of bounds for item %s":                 "%s 的下标越界",
+		"no function scope to exit":                       "没有可退出的函数作用域",
+		"no scope to exit":                                "没有可退出的作用域",
+		"no scope to register item":                       "没有可登记符号的作用域",
+		"item %s already exists in scope":                 "%s 已在作用域中定义",
+		"invalid variable size for item %s":               "%s 的变量大小无效",
+		"invalid array size for item %s":                  "%s 的数组大小无效",
+		"invalid array element size for item %s":          "%s 的数组元素大小无效",
+		"no scope to lookup item":                         "没有可查找符号的作用域",
+		"item %s not found in any scope":                  "在所有作用域中都找不到 %s",
+
+		// the jumps and the loops
+		"invalid line format: %s":      "无效的行格式：%s",
+		"invalid jump instruction: %s": "无效的跳转指令：%s",
+		"label %s is not a loop":       "标签 %s 不是循环",
+		"undefined loop label %s":      "未定义的循环标签 %s",
+		"not in a loop":                "不在循环中",
+		"break: %w":                    "break：%w",
+		"continue: %w":                 "continue：%w",
+
+		// the semantic errors
+		"%s is not a constant expression":                                          "%s 不是常量表达式",
+		"%s is not a function":                                                     "%s 不是函数",
+		"%s is not a struct type":                                                  "%s 不是结构体类型",
+		"%s is not a struct":                                                       "%s 不是结构体",
+		"%s is not a variable":                                                     "%s 不是变量",
+		"%s is not an array and cannot be initialized by a list":                   "%s 不是数组，不能用列表初始化",
+		"%s must be initialized by a list":                                         "%s 必须用列表初始化",
+		"array of structs %s cannot be initialized":                                "结构体数组 %s 不能初始化",
+		"array size %s must be an integer":                                         "数组大小 %s 必须是整数",
+		"array size %s must be an integer constant":                                "数组大小 %s 必须是整数常量",
+		"call of %s: expected %d arguments, got %d":                                "调用 %s：应有 %d 个参数，实际为 %d 个",
+		"call of %s: function returns nothing":                                     "调用 %s：函数没有返回值",
+		"cannot assign %s of type %s to %s of type %s":                             "不能将 %[2]s 类型的 %[1]s 赋值给 %[4]s 类型的 %[3]s",
+		"cannot assign %s to %s: constant %s overflows %s":                         "不能将 %s 赋值给 %s：常量 %s 溢出 %s",
+		"cannot assign %s to %s: constant %s truncated to %s":                      "不能将 %s 赋值给 %s：常量 %s 被截断为 %s",
+		"cannot assign non-string %s to string %s":                                 "不能将非字符串 %s 赋值给字符串 %s",
+		"cannot assign string %s to %s":                                            "不能将字符串 %s 赋值给 %s",
+		"cannot assign to constant %s":                                             "不能给常量 %s 赋值",
+		"cannot convert %s to %s":                                                  "不能将 %s 转换为 %s",
+		"cannot dereference non-pointer %s":                                        "不能解引用非指针 %s",
+		"cannot load a value of type %s through %s":                                "不能通过 %[2]s 读取 %[1]s 类型的值",
+		"cannot take the address of constant %s":                                   "不能取常量 %s 的地址",
+		"cannot use a value of type %s as %s without a conversion":                 "不能在不转换的情况下将 %s 类型的值用作 %s",
+		"constant %s overflows int64":                                              "常量 %s 溢出 int64",
+		"constant %s: %w":                                                          "常量 %s：%w",
+		"division by zero":                                                         "除数为零",
+		"duplicate case %d in switch":                                              "switch 中有重复的 case %d",
+		"duplicate field %s":                                                       "重复的字段 %s",
+		"expected %d initializers, got %d":                                         "应有 %d 个初始值，实际为 %d 个",
+		"expected a list of %d initializers, got %s":                               "应为 %d 个初始值的列表，实际为 %s",
+		"expected a value, got a list":                                             "应为单个值，实际为列表",
+		"function %s returns %s, but no value is returned":                         "函数 %s 返回 %s，但没有返回值",
+		"function %s returns nothing, but a value is returned":                     "函数 %s 没有返回值，但返回了一个值",
+		"function %s: only return values of a basic or pointer type are supported": "函数 %s：只支持基本类型或指针类型的返回值",
+		"illegal case %s: %w":                                                      "非法的 case %s：%w",
+		"case %s must be an integer":                                               "case %s 必须是整数",
+		"illegal char literal '%s'":                                                "非法的字符字面量 '%s'",
+		"index %s must be an integer":                                              "下标 %s 必须是整数",
+		"initializer of %s: %w":                                                    "%s 的初始值：%w",
+		"integer %s is out of range":                                               "整数 %s 超出范围",
+		"invalid pointer arithmetic %s":                                            "无效的指针运算 %s",
+		"invalid size of field %s":                                                 "字段 %s 的大小无效",
+		"multiple defaults in switch":                                              "switch 中有多个 default",
+		"negative shift count %d":                                                  "移位次数 %d 为负数",
+		"operator %s is not allowed in a constant expression":                      "常量表达式中不允许使用运算符 %s",
+		"operator %s is not defined on floating-point numbers":                     "浮点数不支持运算符 %s",
+		"parameter %s: only parameters of a basic or pointer type are supported":   "参数 %s：只支持基本类型或指针类型的参数",
+		"pointers to arrays are not supported: %s":                                 "不支持指向数组的指针：%s",
+		"return outside of a function":                                             "函数之外的 return",
+		"struct %s cannot be initialized":                                          "结构体 %s 不能初始化",
+		"unknown type %s of %s":                                                    "%[2]s 的类型 %[1]s 未知",
+	})
+}
@@ -0,0 +1,94 @@
+package parser
+
+import (
+	"sort"
+
+	"app/ast"
+	"app/lexer"
+)
+
+// Error is an error of the input at a span of it: a lexical error at its ILLEGAL token, a syntax error at the
+// token which cannot be read, or an error of a rule at the tokens of the production reduced.
+// Its message is the one of the error it wraps.
+type Error struct {
+	Span lexer.Span
+	Err  error
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Index is what an editor needs to know of an input besides its errors, e.g. to show the item of an identifier
+// or to go to its declaration.
+type Index struct {
+	File        *ast.File    // the typed AST, nil if the input cannot be parsed
+	Identifiers []Identifier // in the order of the input, but for the tokens skipped after a syntax error
+	Scopes      []*Scope     // all the scopes, see SymbolTable.LegacyScopes
+
+	// the items by the offset of their declaration, the fields of the struct types included
+	declarations map[int64]*SymbolTableItem
+	// the fields by the offset of their name after a dot, found through the struct type of the left operand
+	fields map[int64]*SymbolTableItem
+}
+
+// Identifier is an identifier of the input with the scope it was read in.
+// The scope of the name of a field after a dot is nil, as it is not looked up in the scopes but in the struct
+// type of the left operand.
+type Identifier struct {
+	Token lexer.Token
+	Scope *Scope
+}
+
+// Index parses the input like Parse, without logging, and returns its index together with the errors,
+// which are Error values when their span is known. The index is returned even if the input cannot be parsed.
+func (p *Parser) Index(l *lexer.Lexer) (*Index, error) {
+	index := &Index{}
+	file, err := p.parse(l, func(string) {}, index)
+	index.File = file
+	index.declarations = map[int64]*SymbolTableItem{}
+	for _, scope := range index.Scopes {
+		for _, item := range scope.Items {
+			index.declarations[item.Declaration.StartOffset] = item
+			for _, field := range item.Fields {
+				index.declarations[field.Declaration.StartOffset] = field
+			}
+		}
+	}
+	return index, err
+}
+
+// At returns the identifier at the offset, the end of the identifier included.
+func (x *Index) At(offset int64) (Identifier, bool) {
+	i := sort.Search(len(x.Identifiers), func(i int) bool {
+		return x.Identifiers[i].Token.Span.EndOffset >= offset
+	})
+	if i < len(x.Identifiers) && x.Identifiers[i].Token.Span.StartOffset <= offset {
+		return x.Identifiers[i], true
+	}
+	return Identifier{}, false
+}
+
+// Resolve returns the item an identifier refers to, or nil if there is none.
+// An identifier declaring an item refers to it. The others are looked up like SymbolTable.Lookup does, from the
+// scope of the identifier to the outermost one, skipping the items declared after the identifier, which the rules
+// did not know yet when they looked it up. The name of a field after a dot refers to the field the rule of
+// loc . id found in the struct type of its left operand.
+func (x *Index) Resolve(id Identifier) *SymbolTableItem {
+	if item, ok := x.declarations[id.Token.Span.StartOffset]; ok && item.Variable == id.Token.Val {
+		return item
+	}
+	if id.Scope == nil {
+		return x.fields[id.Token.Span.StartOffset]
+	}
+	for scope := id.Scope; scope != nil; scope = scope.Parent {
+		if item, ok := scope.Items[id.Token.Val]; ok && item.Declaration.StartOffset < id.Token.Span.StartOffset {
+			return item
+		}
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package parser_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"app/lexer"
+	. "app/parser"
+)
+
+// at returns the offset of the nth occurrence of the text in the source, counting from 0.
+func at(src, text string, nth int) int64 {
+	offset := 0
+	for i := 0; i <= nth; i++ {
+		j := strings.Index(src[offset:], text)
+		if j < 0 {
+			return -1
+		}
+		offset += j
+		if i < nth {
+			offset += len(text)
+		}
+	}
+	return int64(offset)
+}
+
+func TestIndex_Resolve(t *testing.T) {
+	ensureSharedParser()
+	src := `type P struct {
+    int x;
+}
+func f(int a) int {
+    return a;
+}
+{
+    int a;
+    struct P p;
+    {
+        int a;
+        a = 1;
+    }
+    a = f(a);
+    p.x = a;
+}`
+	index, err := sharedParser.Index(lexer.NewRecoveringLexer(strings.NewReader(src)))
+	if err != nil || index.File == nil {
+		t.Fatalf("Expected the input to be parsed, got %v", err)
+	}
+	tests := []struct {
+		name        string
+		offset      int64
+		declaration int64 // -1 if the identifier refers to no item
+		typ         SymbolTableItemType
+	}{
+		{"struct", at(src, "P p", 0), at(src, "P", 0), SymbolTableItemTypeStruct},
+		{"field declaration", at(src, "x", 0), at(src, "x", 0), SymbolTableItemTypeVariable},
+		{"field after a dot", at(src, "x =", 0), at(src, "x", 0), SymbolTableItemTypeVariable},
+		{"parameter", at(src, "a;", 0), at(src, "a)", 0), SymbolTableItemTypeVariable},
+		{"shadowing variable", at(src, "a = 1", 0), at(src, "a;", 2), SymbolTableItemTypeVariable},
+		{"shadowed variable", at(src, "a = f", 0), at(src, "a;", 1), SymbolTableItemTypeVariable},
+		{"argument", at(src, "a);", 0), at(src, "a;", 1), SymbolTableItemTypeVariable},
+		{"function", at(src, "f(a)", 0), at(src, "f(", 0), SymbolTableItemTypeFunction},
+		{"end of the identifier", at(src, "p.x", 0) + 1, at(src, "p;", 0), SymbolTableItemTypeVariable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id, ok := index.At(tt.offset)
+			if !ok {
+				t.Fatalf("Expected an identifier at %d", tt.offset)
+			}
+			item := index.Resolve(id)
+			switch {
+			case tt.declaration < 0 && item != nil:
+				t.Errorf("Expected %s to refer to no item, got %+v", id.Token.Val, item)
+			case tt.declaration < 0:
+			case item == nil:
+				t.Errorf("Expected %s to refer to an item", id.Token.Val)
+			case item.Declaration.StartOffset != tt.declaration || item.Type != tt.typ:
+				t.Errorf("Expected %s to refer to the %s declared at %d, got the %s declared at %d", id.Token.Val, tt.typ, tt.declaration, item.Type, item.Declaration.StartOffset)
+			}
+		})
+	}
+
+	if _, ok := index.At(at(src, "int", 1)); ok {
+		t.Errorf("Expected no identifier at a keyword")
+	}
+}
+
+func TestIndex_Errors(t *testing.T) {
+	ensureSharedParser()
+	tests := []struct {
+		src  string
+		text string // the text the error is at
+	}{
+		{"{ int a; a = 1 #; }", "#"},
+		{"{ int a; a = ; }", ";"},
+		{"{ int a; a = b; }", "b"},
+		{"{ int a; int a; }", "int a;"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			_, err := sharedParser.Index(lexer.NewRecoveringLexer(strings.NewReader(tt.src)))
+			var e *Error
+			if !errors.As(err, &e) {
+				t.Fatalf("Expected an error with a span, got %v", err)
+			}
+			if text := tt.src[e.Span.StartOffset:e.Span.EndOffset]; text != tt.text {
+				t.Errorf("Expected the error %v at %q, got %q", e, tt.text, text)
+			}
+		})
+	}
+}
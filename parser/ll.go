@@ -0,0 +1,251 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"app/lexer"
+	. "app/utils/collections"
+	"app/utils/i18n"
+)
+
+// LL1Table maps a non-terminal and a lookahead terminal to the index of the production to expand.
+type LL1Table map[Symbol]map[Terminal]int
+
+// LL1Conflict reports a cell of the LL(1) table predicted by more than one production.
+type LL1Conflict struct {
+	NonTerminal Symbol
+	Lookahead   Terminal
+	Productions []Production
+}
+
+func (c LL1Conflict) Error() string {
+	bodies := make([]string, len(c.Productions))
+	for i, production := range c.Productions {
+		bodies[i] = fmt.Sprintf("%s -> %v", production.Head, production.Body)
+	}
+	return fmt.Sprintf("LL(1) conflict for %s on %s: %s", c.NonTerminal, c.Lookahead, strings.Join(bodies, " | "))
+}
+
+// BuildLL1Table constructs the LL(1) predictive table of the grammar and returns its conflicts,
+// which are kept in LL1Conflicts as well.
+// A production A → α is predicted by the terminals of FIRST(α), and by FOLLOW(A) if α is nullable.
+// When several productions are predicted by the same terminal, the first one is kept in the table.
+func (p *Parser) BuildLL1Table() []LL1Conflict {
+	p.EnsureFollowSet()
+	p.LL1Table = make(LL1Table)
+
+	var conflicts []LL1Conflict
+	for index, production := range p.Grammar.Productions {
+		if _, exists := p.LL1Table[production.Head]; !exists {
+			p.LL1Table[production.Head] = make(map[Terminal]int)
+		}
+		row := p.LL1Table[production.Head]
+
+		predict := Set[Terminal]{}
+		for terminal := range p.findLookaheads(production.Body, EPSILON) {
+			if terminal.IsEpsilon() {
+				predict = predict.Union(p.FollowSet[production.Head])
+			} else {
+				predict.Add(terminal)
+			}
+		}
+
+		for terminal := range predict {
+			existing, exists := row[terminal]
+			if !exists {
+				row[terminal] = index
+				continue
+			}
+			i := slices.IndexFunc(conflicts, func(c LL1Conflict) bool {
+				return c.NonTerminal == production.Head && c.Lookahead == terminal
+			})
+			if i == -1 {
+				conflicts = append(conflicts, LL1Conflict{
+					NonTerminal: production.Head,
+					Lookahead:   terminal,
+					Productions: []Production{p.Grammar.Productions[existing]},
+				})
+				i = len(conflicts) - 1
+			}
+			conflicts[i].Productions = append(conflicts[i].Productions, production)
+		}
+	}
+	p.LL1Conflicts = conflicts
+	return conflicts
+}
+
+func (p *Parser) EnsureLL1Table() {
+	p._mu.Lock()
+	defer p._mu.Unlock()
+	if p.LL1Table == nil {
+		p.BuildLL1Table()
+	}
+}
+
+// llNode is a node of the parse tree built by ParseLL, in the shape of the grammar of the parser.
+type llNode struct {
+	symbol     Symbol
+	production *Production // nil for terminals
+	token      *lexer.Token
+	children   []*llNode
+
+	ast *ASTNode // set for nodes already folded into the shape of the original grammar
+}
+
+// ParseLL parses the input tokens with the table-driven LL(1) algorithm and returns the parse tree.
+// The non-terminals introduced by EliminateLeftRecursion and LeftFactor are folded back, so the tree has
+// the same shape as the one returned by ParseTree for the grammar before the transformations.
+// It fails with the conflicts of the table if the grammar is not LL(1).
+func (p *Parser) ParseLL(l *lexer.Lexer) (*ASTNode, error) {
+	p.EnsureLL1Table()
+	if len(p.LL1Conflicts) > 0 {
+		errs := make([]error, len(p.LL1Conflicts))
+		for i, conflict := range p.LL1Conflicts {
+			errs[i] = conflict
+		}
+		return nil, i18n.Errorf("the grammar is not LL(1):\n%w", errors.Join(errs...))
+	}
+
+	root := &llNode{symbol: p.Grammar.AugmentedProduction.Body[0]}
+	stack := Stack[*llNode]{}
+	stack.Push(root)
+
+	token, symbol, err := p.nextSymbol(l)
+	if err != nil {
+		return nil, err
+	}
+	for !stack.IsEmpty() {
+		top, _ := stack.Pop()
+		if p.Grammar.IsTerminal(top.symbol) {
+			if top.symbol != symbol {
+				return nil, i18n.Errorf("expected %s, got %s", top.symbol, symbol)
+			}
+			top.token = token
+			if token, symbol, err = p.nextSymbol(l); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		index, ok := p.LL1Table[top.symbol][Terminal(symbol)]
+		if !ok {
+			return nil, i18n.Errorf("no production found for %s and symbol %s", top.symbol, symbol)
+		}
+		top.production = &p.Grammar.Productions[index]
+		for _, s := range top.production.Body {
+			if !s.IsEpsilon() {
+				top.children = append(top.children, &llNode{symbol: s})
+			}
+		}
+		for i := len(top.children) - 1; i >= 0; i-- {
+			stack.Push(top.children[i])
+		}
+	}
+	if symbol != TERMINATE {
+		return nil, i18n.Errorf("expected %s, got %s", TERMINATE, symbol)
+	}
+	return p.restore(root), nil
+}
+
+func (p *Parser) nextSymbol(l *lexer.Lexer) (*lexer.Token, Symbol, error) {
+	token, err := l.NextToken()
+	for token.Type == lexer.COMMENT && err == nil {
+		token, err = l.NextToken()
+	}
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, "", err
+	}
+	if token.Type == lexer.ILLEGAL {
+		return nil, "", errors.New(token.Diagnostic)
+	}
+	// the last token may be returned together with io.EOF, the next call returns the EOF token
+	if errors.Is(err, io.EOF) && token.Val == "" {
+		token.Type = lexer.EOF
+	}
+	return &token, p.Reflect(&token), nil
+}
+
+// restore converts the node into the shape of the original grammar.
+func (p *Parser) restore(n *llNode) *ASTNode {
+	if n.ast != nil {
+		return n.ast
+	}
+	if n.production == nil {
+		return p.Token2ASTNode(n.token)
+	}
+	items, production := p.flatten(n)
+	return p.fold(n.symbol, items, production)
+}
+
+// flatten returns the children of the node with the factored non-terminals spliced in,
+// and the production that determines their shape, i.e. the one of the innermost factored non-terminal.
+func (p *Parser) flatten(n *llNode) ([]*llNode, *Production) {
+	var items []*llNode
+	production := n.production
+	for _, child := range n.children {
+		if derived, ok := p.Grammar.Derived[child.symbol]; ok && derived.Kind == DerivedFactor {
+			var sub []*llNode
+			sub, production = p.flatten(child)
+			items = append(items, sub...)
+			continue
+		}
+		items = append(items, child)
+	}
+	return items, production
+}
+
+// fold builds the node of the original non-terminal symbol from the items derived by the production,
+// applying the left-recursive tail A' of A → β A' as A → A α for every A' → α A'.
+func (p *Parser) fold(symbol Symbol, items []*llNode, production *Production) *ASTNode {
+	tail := p.popTail(symbol, &items)
+	node := p.shape(symbol, items, production)
+	for tail != nil {
+		var tailProduction *Production
+		items, tailProduction = p.flatten(tail)
+		tail = p.popTail(symbol, &items)
+		if len(items) == 0 {
+			break // A' → ε
+		}
+		node = p.shape(symbol, append([]*llNode{{ast: node}}, items...), tailProduction)
+	}
+	return node
+}
+
+func (p *Parser) popTail(symbol Symbol, items *[]*llNode) *llNode {
+	if len(*items) == 0 {
+		return nil
+	}
+	last := (*items)[len(*items)-1]
+	if derived, ok := p.Grammar.Derived[last.symbol]; ok && derived.Kind == DerivedTail && derived.From == symbol {
+		*items = (*items)[:len(*items)-1]
+		return last
+	}
+	return nil
+}
+
+// shape builds the node of the symbol, wrapping the leading items into the substituted productions first.
+func (p *Parser) shape(symbol Symbol, items []*llNode, production *Production) *ASTNode {
+	for _, substituted := range production.Substituted {
+		k := len(withoutEpsilon(substituted.Body))
+		inner := p.fold(substituted.Head, items[:k], substituted)
+		items = append([]*llNode{{ast: inner}}, items[k:]...)
+	}
+	children := make([]*ASTNode, len(items))
+	for i, item := range items {
+		children[i] = p.restore(item)
+	}
+	return newParseTreeNode(symbol, children)
+}
+
+func newParseTreeNode(symbol Symbol, children []*ASTNode) *ASTNode {
+	return &ASTNode{
+		raw:      joinChildren(children),
+		Token:    &lexer.Token{Type: lexer.EXTRA, Val: string(symbol)},
+		Children: children,
+		Type:     symbol,
+	}
+}
@@ -0,0 +1,124 @@
+package parser_test
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	"app/lexer"
+	. "app/parser"
+	. "app/utils/collections"
+)
+
+// expressionGrammar is the expression part of the default grammar, from bool down to loc, without calls
+// and without the ambiguous productions of the operators ordered by precedence declarations,
+// which an LL(1) parser cannot use.
+func expressionGrammar() Grammar {
+	heads := []Symbol{"bool", "bool'", "join", "bitwise", "equality", "rel", "expr", "term", "unary", "factor", "loc"}
+	g := NewGrammar().Copy()
+	g.AugmentedProduction = Production{Head: "start", Body: []Symbol{"bool"}}
+	g.Productions = slices.DeleteFunc(g.Productions, func(p Production) bool {
+		ambiguous := len(p.Body) == 3 && p.Body[0] == p.Head && p.Body[2] == p.Head
+		return !slices.Contains(heads, p.Head) || slices.Contains(p.Body, "args") || ambiguous
+	})
+	return g
+}
+
+func TestParser_BuildLL1Table(t *testing.T) {
+	g := Grammar{
+		AugmentedProduction: Production{Head: "start", Body: []Symbol{"S"}},
+		Productions: []Production{
+			{Head: "S", Body: []Symbol{"i", "E", "t", "S"}},
+			{Head: "S", Body: []Symbol{"i", "E", "t", "S", "e", "S"}},
+			{Head: "S", Body: []Symbol{"a"}},
+			{Head: "E", Body: []Symbol{"b"}},
+		},
+		Terminals: Set[Terminal]{}.AddAll("a", "b", "e", "i", "t", EPSILON, TERMINATE),
+	}
+	dangling := g.LeftFactor()
+	p := &Parser{Grammar: &dangling}
+	conflicts := p.BuildLL1Table()
+	for _, conflict := range conflicts {
+		fmt.Println(conflict.Error())
+	}
+	if len(conflicts) != 1 || conflicts[0].NonTerminal != "S'" || conflicts[0].Lookahead != "e" {
+		t.Fatalf("Expected a conflict for S' on e, got %v", conflicts)
+	}
+	if production := dangling.Productions[p.LL1Table["S'"]["e"]]; !production.Equals(Production{Head: "S'", Body: []Symbol{"e", "S"}}) {
+		t.Errorf("Expected S' -> e S on e, got %v", production)
+	}
+	// the grammar is not LL(1), so it is not parsed with the first production of the conflict
+	if _, err := p.ParseLL(lexer.NewLexer(strings.NewReader("a"))); err == nil || !strings.Contains(err.Error(), conflicts[0].Error()) {
+		t.Errorf("Expected the conflict as an error, got %v", err)
+	}
+
+	expression := expressionGrammar()
+	eliminated := expression.EliminateLeftRecursion()
+	ll := eliminated.LeftFactor()
+	p = &Parser{Grammar: &ll}
+	if conflicts := p.BuildLL1Table(); len(conflicts) != 0 {
+		t.Errorf("Expected no conflicts, got %v", conflicts)
+	}
+}
+
+func TestParser_ParseLL(t *testing.T) {
+	indirect := Grammar{
+		AugmentedProduction: Production{Head: "start", Body: []Symbol{"S"}},
+		Productions: []Production{
+			{Head: "S", Body: []Symbol{"A"}},
+			{Head: "A", Body: []Symbol{"S", "+"}},
+			{Head: "A", Body: []Symbol{"num"}},
+		},
+		Terminals: Set[Terminal]{}.AddAll("num", "+", EPSILON, TERMINATE),
+	}
+	tests := []struct {
+		name    string
+		grammar Grammar
+		inputs  []string
+	}{
+		{
+			name:    "Expression",
+			grammar: expressionGrammar(),
+			inputs: []string{
+				"a",
+				"a - b - c",
+				"a + b * (c - 1) < 3 || !d && e[1][2] == 4",
+				"-a / 2 >= b != (true || false)",
+			},
+		},
+		{
+			name:    "Indirect",
+			grammar: indirect,
+			inputs:  []string{"1", "1 +", "1 + + +"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lr := &Parser{Grammar: &tt.grammar}
+			eliminated := tt.grammar.EliminateLeftRecursion()
+			transformed := eliminated.LeftFactor()
+			ll := &Parser{Grammar: &transformed}
+			for _, input := range tt.inputs {
+				expected, err := lr.ParseTree(lexer.NewLexer(strings.NewReader(input)))
+				if err != nil {
+					t.Fatalf("LR(1) parse of %s failed: %v", input, err)
+				}
+				actual, err := ll.ParseLL(lexer.NewLexer(strings.NewReader(input)))
+				if err != nil {
+					t.Fatalf("LL(1) parse of %s failed: %v", input, err)
+				}
+				fmt.Print(actual.TreeString(0))
+				if actual.TreeString(0) != expected.TreeString(0) {
+					t.Errorf("Parse trees of %s differ, expected:\n%s", input, expected.TreeString(0))
+				}
+			}
+		})
+	}
+
+	eliminated := indirect.EliminateLeftRecursion()
+	p := &Parser{Grammar: &eliminated}
+	if _, err := p.ParseLL(lexer.NewLexer(strings.NewReader("1 + 1"))); err == nil {
+		t.Errorf("Expected an error for 1 + 1")
+	}
+}
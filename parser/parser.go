@@ -5,73 +5,233 @@ import (
 	"fmt"
 	"io"
 	"slices"
+	"strconv"
 
+	"app/ast"
 	"app/lexer"
+	"app/utils/i18n"
 	"app/utils/log"
 )
 
 // Parse is the main function that parses the input tokens using the LR(1) parser algorithm.
 // It takes a lexer.Lexer instance and a logger function as arguments.
 // The logger function is used to log messages during the parsing process.
-func (p *Parser) Parse(l *lexer.Lexer, logger func(string)) {
+// It returns the typed AST of the input, which is nil when the input cannot be parsed,
+// together with the errors found by the rules of the productions.
+// With a recovering lexer, the ILLEGAL tokens are reported and skipped, so that all the lexical
+// errors are returned. After a syntax error the parse goes on at the next statement or declaration,
+// see Walker.Recover, so that the later syntax errors are returned as well, without the errors of the rules.
+// The errors are Error values when their span is known.
+func (p *Parser) Parse(l *lexer.Lexer, logger func(string)) (*ast.File, error) {
+	return p.parse(l, logger, nil)
+}
+
+// parse is Parse, which also fills the index with the identifiers and the scopes if it is not nil, see Index.
+func (p *Parser) parse(l *lexer.Lexer, logger func(string), index *Index) (*ast.File, error) {
+	// the comments are kept for the typed AST
+	l.CollectComments()
 	walker := p.NewWalker()
 	walker.SymbolTable.EnterScope()
+	if index != nil {
+		defer func() {
+			index.Scopes, index.fields = walker.SymbolTable.LegacyScopes, walker.fields
+		}()
+	}
+	var found []error // the lexical and syntax errors, in the order they are found
+	syntax := false
+	quiet := false    // no token was shifted since the last syntax error, the next one is not reported
+	skipping := false // the tokens are skipped up to one which can follow the symbol pushed by Walker.Recover
+	afterDot := false
+tokens:
 	for {
 		token, err := l.NextToken()
 		if err != nil && !errors.Is(err, io.EOF) {
-			logger(fmt.Sprintf("Error: %v", err))
-			return
+			logger(i18n.Sprintf("Error: %v", err))
+			return nil, errors.Join(append(found, err)...)
+		}
+		if token.Type == lexer.COMMENT {
+			continue
+		}
+		if token.Type == lexer.ILLEGAL {
+			err := &Error{Span: token.Span, Err: errors.New(token.Diagnostic)}
+			logger(i18n.Sprintf("Error: %v", err))
+			found = append(found, err)
+			continue
 		}
 
 		if errors.Is(err, io.EOF) {
 			token.Type = lexer.EOF
 		}
 		symbol := p.Reflect(&token)
-		if token.SpecificType() == lexer.DelimiterLeftBrace {
-			walker.SymbolTable.EnterScope()
-		}
 
-		for {
+		skipped := skipping && !walker.Expects(symbol)
+		for !skipped {
 			logger(fmt.Sprintf("State: %v\nSymbols: %v\nSymbol: %s\n", walker.States, walker.Symbols, symbol))
 			action, err := walker.Next(symbol)
 			if err != nil {
-				logger(fmt.Sprintf("Error: %v", err))
-				return
+				if !quiet {
+					err = &Error{Span: token.Span, Err: err}
+					logger(i18n.Sprintf("Error: %v", err))
+					found = append(found, err)
+				}
+				syntax = true
+				if !walker.Recover() {
+					found = append(found, illegalTokens(l, logger)...)
+					break tokens
+				}
+				// a token failing again right after the recovery is skipped, so that the parse moves on
+				skipped = quiet || !walker.Expects(symbol)
+				skipping, quiet = skipped, true
+				continue
 			}
 			logger(fmt.Sprintf("Token: (%s, %s), Action: %v\n\n", token.Type.ToString(), token.Val, action))
 			if action.Type != REDUCE {
+				skipping, quiet = false, false
 				break
 			}
 		}
+		if skipped {
+			if symbol == TERMINATE {
+				break
+			}
+			continue
+		}
 
+		// the scope is entered after the reductions triggered by the brace,
+		// which may still belong to the previous function
+		if token.SpecificType() == lexer.DelimiterLeftBrace {
+			walker.SymbolTable.EnterScope()
+		}
 		if token.SpecificType() == lexer.DelimiterRightBrace {
 			walker.SymbolTable.ExitScope()
 		}
 
 		if symbol == TERMINATE {
-			logger("Parsing completed successfully.")
+			// the parse is not successful with lexical or semantic errors, which are logged
+			if len(walker.Errors) == 0 && len(found) == 0 {
+				logger("Parsing completed successfully.")
+			}
 			break
 		}
 
+		if index != nil && token.Type == lexer.IDENTIFIER {
+			id := Identifier{Token: token, Scope: walker.SymbolTable.CurrentScope}
+			if afterDot {
+				id.Scope = nil
+			}
+			index.Identifiers = append(index.Identifiers, id)
+		}
+		afterDot = token.SpecificType() == lexer.DelimiterDot
+
 		walker.Tokens.Push(p.Token2ASTNode(&token))
 	}
+	if syntax {
+		return nil, errors.Join(found...)
+	}
 
-	logger("\n\nThree Address Code:\n")
+	for _, err := range walker.Errors {
+		logger(i18n.Sprintf("Error: %v\n", err))
+	}
+
+	if len(walker.ReadOnlyData.Strings) > 0 {
+		logger(fmt.Sprintf("\n\nRead-only Data (.rodata, %d bytes):\n", walker.ReadOnlyData.Size()))
+		for _, s := range walker.ReadOnlyData.Strings {
+			logger(fmt.Sprintf("%16s %s\n", formatReadOnlyAddr(walker.ReadOnlyData.Offset(s)), strconv.Quote(s)))
+		}
+	}
+
+	logger("\n\n" + i18n.T("Three Address Code:") + "\n")
 	for _, line := range walker.ThreeAddress {
 		// fmt.Println(line)
 		logger(fmt.Sprintln(line))
 	}
 
-	for _, scope := range walker.SymbolTable.LegacyScopes[1:] {
+	for _, scope := range walker.SymbolTable.LegacyScopes {
+		if scope.ID == 0 && len(scope.Items) == 0 {
+			continue
+		}
 		logger("-------------------------------------\n")
 		logger(fmt.Sprintf("Scope: %v\n", scope.ID))
 		for _, item := range scope.Items {
-			logger(fmt.Sprintf("Variable: %s, Type: %s, Address: %#x\n", item.Variable, item.UnderlyingType, item.Address))
+			switch {
+			case item.Type == SymbolTableItemTypeFunction:
+				logger(fmt.Sprintf("Function: %s, Type: %s, Entry: L%d, Frame Size: %d\n", item.Variable, item.UnderlyingType, item.Address, item.FrameSize))
+			case item.Type == SymbolTableItemTypeStruct:
+				logger(fmt.Sprintf("Struct: %s, Size: %d, Alignment: %d\n", item.Variable, item.VariableSize, item.Alignment))
+				for _, field := range item.Fields {
+					logger(fmt.Sprintf("  Field: %s, Type: %s, Offset: %d\n", field.Variable, field.UnderlyingType, field.Address))
+				}
+			case item.Type == SymbolTableItemTypeConstant:
+				logger(fmt.Sprintf("Constant: %s, Type: %s, Value: %s\n", item.Variable, item.UnderlyingType, item.Value))
+			case item.Local:
+				logger(fmt.Sprintf("Variable: %s, Type: %s, Address: fp+%#x\n", item.Variable, item.UnderlyingType, item.Address))
+			default:
+				logger(fmt.Sprintf("Variable: %s, Type: %s, Address: %#x\n", item.Variable, item.UnderlyingType, item.Address))
+			}
 			if item.Type == SymbolTableItemTypeArray {
 				logger(fmt.Sprintf("Array Size: %d, Element Size: %d\n", item.ArraySize, item.ArrayElementSize))
 			}
 		}
 	}
+
+	errs := append(found, walker.Errors...)
+	if walker.ast == nil {
+		return nil, errors.Join(append(errs, errors.New(i18n.T("the input was not accepted")))...)
+	}
+	file, err := BuildAST(walker.ast.Root, BuildComments(l.Comments()))
+	if err != nil {
+		return nil, errors.Join(append(errs, err)...)
+	}
+	return file, errors.Join(errs...)
+}
+
+// illegalTokens reads the rest of the input and returns the errors of its ILLEGAL tokens.
+func illegalTokens(l *lexer.Lexer, logger func(string)) []error {
+	var errs []error
+	for {
+		token, err := l.NextToken()
+		if token.Type == lexer.ILLEGAL {
+			errs = append(errs, &Error{Span: token.Span, Err: errors.New(token.Diagnostic)})
+			logger(i18n.Sprintf("Error: %v", errs[len(errs)-1]))
+		}
+		if err != nil || token.Type == lexer.EOF {
+			return errs
+		}
+	}
+}
+
+// ParseTree parses the input tokens with the LR(1) parser and returns the parse tree, without running the rules
+// of the productions. Each node of a non-terminal has the head of its production as type.
+func (p *Parser) ParseTree(l *lexer.Lexer) (*ASTNode, error) {
+	walker := p.NewWalker()
+	for i := range walker.Grammar.Productions {
+		production := walker.Grammar.Productions[i]
+		walker.Grammar.Productions[i].Rule = func(w *Walker) error {
+			children := w.Tokens.PopTopN(len(withoutEpsilon(production.Body)))
+			w.Tokens.Push(newParseTreeNode(production.Head, children))
+			return nil
+		}
+	}
+	for {
+		token, symbol, err := p.nextSymbol(l)
+		if err != nil {
+			return nil, err
+		}
+		for {
+			action, err := walker.Next(symbol)
+			if err != nil {
+				return nil, err
+			}
+			if action.Type == ACCEPT {
+				root, _ := walker.Tokens.Pop()
+				return root, nil
+			}
+			if action.Type != REDUCE {
+				break
+			}
+		}
+		walker.Tokens.Push(p.Token2ASTNode(token))
+	}
 }
 
 // Reflect converts a lexer.Token to a Symbol.
@@ -82,6 +242,10 @@ func (p *Parser) Reflect(token *lexer.Token) Symbol {
 		return "num"
 	case lexer.FLOAT:
 		return "real"
+	case lexer.STRING:
+		return "str"
+	case lexer.CHAR:
+		return "char"
 	case lexer.IDENTIFIER:
 		return "id"
 	case lexer.TYPE:
@@ -99,6 +263,12 @@ func (p *Parser) EnsureFirstSet() {
 	}
 }
 
+func (p *Parser) EnsureFollowSet() {
+	if len(p.FollowSet) == 0 {
+		p.BuildFollowSet()
+	}
+}
+
 func (p *Parser) EnsureSymbols() {
 	if len(p.Symbols) == 0 {
 		p.BuildSymbols()
@@ -125,7 +295,7 @@ func (p *Parser) OptimizedHeadsCheck() {
 		if OptimizedSymbols.Contains(production.Head) || slices.ContainsFunc(production.Body, func(symbol Symbol) bool {
 			return OptimizedSymbols.Contains(symbol)
 		}) {
-			fmt.Println(log.Sprintf(log.Argument{FrontColor: log.Yellow, Highlight: true, Format: "Warning: Optimized symbols may cause reduce-reduce conflict", Args: []any{}}))
+			fmt.Println(log.Sprintf(log.Argument{FrontColor: log.Yellow, Highlight: true, Format: i18n.T("Warning: Optimized symbols may cause reduce-reduce conflict"), Args: []any{}}))
 			if OptimizedSymbols.Contains(production.Head) {
 				fmt.Print(log.Sprintf(log.Argument{FrontColor: log.Red, Underline: true, Format: "%v", Args: []any{production.Head}}))
 			} else {
@@ -5,6 +5,7 @@ import (
 	"slices"
 
 	. "app/utils/collections"
+	"app/utils/i18n"
 	"app/utils/log"
 )
 
@@ -13,6 +14,13 @@ type Production struct {
 	Body []Symbol
 
 	Rule Rule
+
+	// Prec gives the production the precedence of the terminal, like %prec in yacc, see Grammar.ProductionPrecedence.
+	Prec Terminal
+
+	// Substituted lists the productions substituted for the leading non-terminal of the body
+	// when eliminating indirect left recursion, innermost first, see Grammar.EliminateLeftRecursion.
+	Substituted []*Production
 }
 
 type Rule func(*Walker) error
@@ -28,7 +36,7 @@ func (p *Production) Equals(other Production) bool {
 // HandleRule executes the rule associated with the production if it is not nil.
 func (p *Production) HandleRule(walker *Walker) error {
 	if p.Rule == nil {
-		fmt.Println(log.Sprintf(log.Argument{FrontColor: log.Yellow, Highlight: true, Format: "Warning: rule is nil for production %s -> %s", Args: []any{p.Head, p.Body}}))
+		fmt.Println(log.Sprintf(log.Argument{FrontColor: log.Yellow, Highlight: true, Format: i18n.T("Warning: rule is nil for production %s -> %s"), Args: []any{p.Head, p.Body}}))
 		return nil
 	}
 	return p.Rule(walker)
@@ -55,27 +63,52 @@ const (
 
 var Terminals = Set[Terminal]{}.AddAll(
 	// Brackets and punctuation
-	"{", "}", ";", "[", "]", "(", ")",
+	"{", "}", ";", "[", "]", "(", ")", ":", ",", ".",
 
 	// Arithmetic operators
-	"+", "-", "*", "/",
+	"+", "-", "*", "/", "%", "++", "--",
+
+	// Bitwise operators
+	"&", "|", "^", "<<", ">>",
 
 	// Logical and comparison operators
 	"||", "&&", "==", "!=", "<", "<=", ">", ">=", "!", "=", "!=",
 
 	// Keywords
-	"if", "else", "while", "do", "break",
+	"if", "else", "while", "do", "break", "for", "continue", "switch", "case", "default",
+	"func", "return", "type", "struct", "const",
 
 	// Literals
 	"true", "false",
 
 	// Types
-	"basic", "id", "num", "real",
+	"basic", "id", "num", "real", "str", "char",
 
 	// Special symbols
 	EPSILON, TERMINATE,
 )
 
+// Precedences resolve the dangling else like yacc: the if without else has the precedence of the pseudo-terminal
+// LOWER_THAN_ELSE, lower than the one of else, so that an else is shifted and belongs to the nearest if.
+// The pointer types resolve the * the same way: *int[2] is an array of pointers as the reduce of * type_spec is
+// above [, and the stmts → ε after the declarations of a block has the precedence of HIGHER_THAN_STAR, so that a
+// * there starts the statements, either a dereference or the pointer type of a declaration by stmt → decl.
+// They also order the bitwise operators and the shifts, from the lowest to the highest precedence as in C,
+// all left-associative. + and - are declared above the shifts only for the conflicts with them, the other
+// operators are still layered in the productions.
+var Precedences = []PrecedenceDeclaration{
+	{Associativity: NONASSOC, Terminals: []Terminal{"LOWER_THAN_ELSE"}},
+	{Associativity: NONASSOC, Terminals: []Terminal{"else"}},
+	{Associativity: NONASSOC, Terminals: []Terminal{"["}},
+	{Associativity: NONASSOC, Terminals: []Terminal{"*"}},
+	{Associativity: NONASSOC, Terminals: []Terminal{"HIGHER_THAN_STAR"}},
+	{Associativity: LEFT, Terminals: []Terminal{"|"}},
+	{Associativity: LEFT, Terminals: []Terminal{"^"}},
+	{Associativity: LEFT, Terminals: []Terminal{"&"}},
+	{Associativity: LEFT, Terminals: []Terminal{"<<", ">>"}},
+	{Associativity: LEFT, Terminals: []Terminal{"+", "-"}},
+}
+
 var AugmentedProduction = Production{
 	Head: "program'",
 	Body: []Symbol{"program"},
@@ -84,12 +117,84 @@ var AugmentedProduction = Production{
 var OptimizedSymbols = Set[Symbol]{}.AddAll()
 
 var Productions = []Production{
-	// program → block
+	// program → funcs block
 	{
 		Head: "program",
-		Body: []Symbol{"block"},
+		Body: []Symbol{"funcs", "block"},
 		Rule: GenRules.Program,
 	},
+	// funcs → funcs func_decl | funcs type_decl | funcs const_decl | ε
+	{
+		Head: "funcs",
+		Body: []Symbol{"funcs", "func_decl"},
+		Rule: GenRules.Funcs,
+	},
+	{
+		Head: "funcs",
+		Body: []Symbol{"funcs", "type_decl"},
+		Rule: GenRules.Funcs,
+	},
+	{
+		Head: "funcs",
+		Body: []Symbol{"funcs", "const_decl"},
+		Rule: GenRules.Funcs,
+	},
+	{
+		Head: "funcs",
+		Body: []Symbol{EPSILON}, // ε
+		Rule: GenRules.FuncsEpsilon,
+	},
+	// func_decl → func_sig block
+	{
+		Head: "func_decl",
+		Body: []Symbol{"func_sig", "block"},
+		Rule: GenRules.FuncDecl,
+	},
+	// func_sig → func_head ( params ) | func_head ( params ) type_spec
+	{
+		Head: "func_sig",
+		Body: []Symbol{"func_head", "(", "params", ")"},
+		Rule: GenRules.FuncSig,
+	},
+	{
+		Head: "func_sig",
+		Body: []Symbol{"func_head", "(", "params", ")", "type_spec"},
+		Rule: GenRules.FuncSigReturn,
+	},
+	// func_head → func id
+	{
+		Head: "func_head",
+		Body: []Symbol{"func", "id"},
+		Rule: GenRules.FuncHead,
+	},
+	// params → param_list | ε
+	{
+		Head: "params",
+		Body: []Symbol{"param_list"},
+		Rule: GenRules.Params,
+	},
+	{
+		Head: "params",
+		Body: []Symbol{EPSILON}, // ε
+		Rule: GenRules.ParamsEpsilon,
+	},
+	// param_list → param_list , param | param
+	{
+		Head: "param_list",
+		Body: []Symbol{"param_list", ",", "param"},
+		Rule: GenRules.ParamList,
+	},
+	{
+		Head: "param_list",
+		Body: []Symbol{"param"},
+		Rule: GenRules.ParamListParam,
+	},
+	// param → type_spec id
+	{
+		Head: "param",
+		Body: []Symbol{"type_spec", "id"},
+		Rule: GenRules.Param,
+	},
 	// block → { decls stmts }
 	// ** optimized to combined_decls_stmts **
 	// block → { combined_decls_stmts }
@@ -115,24 +220,111 @@ var Productions = []Production{
 		Body: []Symbol{EPSILON}, // ε
 		Rule: GenRules.DeclsEpsilon,
 	},
-	// decl → type id;
+	// decl → type_spec id; | type_spec id = bool; | type_spec id = { init_list }; | type_decl | const_decl
 	{
 		Head: "decl",
-		Body: []Symbol{"type", "id", ";"},
+		Body: []Symbol{"type_spec", "id", ";"},
 		Rule: GenRules.Decl,
 	},
-	// type → type[num] | basic
 	{
-		Head: "type",
-		Body: []Symbol{"type", "[", "num", "]"},
+		Head: "decl",
+		Body: []Symbol{"type_spec", "id", "=", "bool", ";"},
+		Rule: GenRules.DeclInit,
+	},
+	{
+		Head: "decl",
+		Body: []Symbol{"type_spec", "id", "=", "{", "init_list", "}", ";"},
+		Rule: GenRules.DeclInitList,
+	},
+	{
+		Head: "decl",
+		Body: []Symbol{"type_decl"},
+		Rule: GenRules.DeclTypeDecl,
+	},
+	{
+		Head: "decl",
+		Body: []Symbol{"const_decl"},
+		Rule: GenRules.DeclConstDecl,
+	},
+	// init_list → init_list , init | init
+	{
+		Head: "init_list",
+		Body: []Symbol{"init_list", ",", "init"},
+		Rule: GenRules.InitList,
+	},
+	{
+		Head: "init_list",
+		Body: []Symbol{"init"},
+		Rule: GenRules.InitListInit,
+	},
+	// init → bool | { init_list }
+	{
+		Head: "init",
+		Body: []Symbol{"bool"},
+		Rule: GenRules.InitBool,
+	},
+	{
+		Head: "init",
+		Body: []Symbol{"{", "init_list", "}"},
+		Rule: GenRules.InitBraced,
+	},
+	// const_decl → const id = bool;
+	{
+		Head: "const_decl",
+		Body: []Symbol{"const", "id", "=", "bool", ";"},
+		Rule: GenRules.ConstDecl,
+	},
+	// type_spec → type_spec[num] | type_spec[id] | *type_spec | basic | struct id
+	{
+		Head: "type_spec",
+		Body: []Symbol{"type_spec", "[", "num", "]"},
 		Rule: GenRules.TypeArray,
 	},
 	{
-		Head: "type",
+		Head: "type_spec",
+		Body: []Symbol{"type_spec", "[", "id", "]"},
+		Rule: GenRules.TypeArrayConst,
+	},
+	{
+		Head: "type_spec",
+		Body: []Symbol{"*", "type_spec"},
+		Rule: GenRules.TypePointer,
+	},
+	{
+		Head: "type_spec",
 		Body: []Symbol{"basic"},
 		Rule: GenRules.TypeBasic,
 	},
+	{
+		Head: "type_spec",
+		Body: []Symbol{"struct", "id"},
+		Rule: GenRules.TypeStruct,
+	},
+	// type_decl → type id struct { fields }
+	{
+		Head: "type_decl",
+		Body: []Symbol{"type", "id", "struct", "{", "fields", "}"},
+		Rule: GenRules.TypeDecl,
+	},
+	// fields → fields field | ε
+	{
+		Head: "fields",
+		Body: []Symbol{"fields", "field"},
+		Rule: GenRules.Fields,
+	},
+	{
+		Head: "fields",
+		Body: []Symbol{EPSILON}, // ε
+		Rule: GenRules.FieldsEpsilon,
+	},
+	// field → type_spec id ;
+	{
+		Head: "field",
+		Body: []Symbol{"type_spec", "id", ";"},
+		Rule: GenRules.Field,
+	},
 	// stmts → stmts stmt | ε
+	// ** a * after the declarations of a block is resolved by the reduce of ε, see Precedences **
 	{
 		Head: "stmts",
 		Body: []Symbol{"stmts", "stmt"},
@@ -142,9 +334,9 @@ var Productions = []Production{
 		Head: "stmts",
 		Body: []Symbol{EPSILON}, // ε
 		Rule: GenRules.StmtsEpsilon,
+		Prec: "HIGHER_THAN_STAR",
 	},
-	// stmt → matched_stmt | unmatched_stmt
-	// ** solved else-hanging problem **
+	// stmt → matched_stmt | decl
 	{
 		Head: "stmt",
 		Body: []Symbol{"matched_stmt"},
@@ -152,33 +344,44 @@ var Productions = []Production{
 	},
 	{
 		Head: "stmt",
-		Body: []Symbol{"unmatched_stmt"},
-		Rule: GenRules.StmtUnmatchedStmt,
+		Body: []Symbol{"decl"},
+		Rule: GenRules.StmtDecl,
 	},
+	// matched_stmt → loc = bool ;
 	{
-		Head: "stmt",
-		Body: []Symbol{"decls"},
-		Rule: GenRules.StmtDecls,
+		Head: "matched_stmt",
+		Body: []Symbol{"loc", "=", "bool", ";"},
+		Rule: GenRules.MatchedStmtAssign,
 	},
-	// unmatched_stmt → if ( bool ) unmatched_stmt
+	// matched_stmt → * unary = bool ;
 	{
-		Head: "unmatched_stmt",
-		Body: []Symbol{"if", "(", "bool", ")", "unmatched_stmt"},
-		Rule: GenRules.UnmatchedStmtIf,
+		Head: "matched_stmt",
+		Body: []Symbol{"*", "unary", "=", "bool", ";"},
+		Rule: GenRules.MatchedStmtStore,
 	},
-	// unmatched_stmt → if ( bool ) matched_stmt else unmatched_stmt
+	// matched_stmt → loc ++ ; | loc -- ; | ++ loc ; | -- loc ;
 	{
-		Head: "unmatched_stmt",
-		Body: []Symbol{"if", "(", "bool", ")", "matched_stmt", "else", "unmatched_stmt"},
-		Rule: GenRules.UnmatchedStmtIfElse,
+		Head: "matched_stmt",
+		Body: []Symbol{"loc", "++", ";"},
+		Rule: GenRules.MatchedStmtIncDec,
 	},
-	// matched_stmt → loc = bool ;
 	{
 		Head: "matched_stmt",
-		Body: []Symbol{"loc", "=", "bool", ";"},
-		Rule: GenRules.MatchedStmtAssign,
+		Body: []Symbol{"loc", "--", ";"},
+		Rule: GenRules.MatchedStmtIncDec,
+	},
+	{
+		Head: "matched_stmt",
+		Body: []Symbol{"++", "loc", ";"},
+		Rule: GenRules.MatchedStmtIncDec,
+	},
+	{
+		Head: "matched_stmt",
+		Body: []Symbol{"--", "loc", ";"},
+		Rule: GenRules.MatchedStmtIncDec,
 	},
 	// matched_stmt → if ( bool ) matched_stmt else matched_stmt | if ( bool ) matched_stmt
+	// ** the dangling else is resolved by the shift of else, see Precedences **
 	{
 		Head: "matched_stmt",
 		Body: []Symbol{"if", "(", "bool", ")", "matched_stmt", "else", "matched_stmt"},
@@ -188,6 +391,7 @@ var Productions = []Production{
 		Head: "matched_stmt",
 		Body: []Symbol{"if", "(", "bool", ")", "matched_stmt"},
 		Rule: GenRules.MatchedStmtIf,
+		Prec: "LOWER_THAN_ELSE",
 	},
 	// matched_stmt → while ( bool ) stmt
 	{
@@ -207,18 +411,162 @@ var Productions = []Production{
 		Body: []Symbol{"break", ";"},
 		Rule: GenRules.MatchedStmtBreak,
 	},
+	// matched_stmt → break id ;
+	{
+		Head: "matched_stmt",
+		Body: []Symbol{"break", "id", ";"},
+		Rule: GenRules.MatchedStmtBreakLabel,
+	},
+	// matched_stmt → continue ; | continue id ;
+	{
+		Head: "matched_stmt",
+		Body: []Symbol{"continue", ";"},
+		Rule: GenRules.MatchedStmtContinue,
+	},
+	{
+		Head: "matched_stmt",
+		Body: []Symbol{"continue", "id", ";"},
+		Rule: GenRules.MatchedStmtContinueLabel,
+	},
+	// matched_stmt → for ( for_init ; bool ; for_step ) stmt
+	{
+		Head: "matched_stmt",
+		Body: []Symbol{"for", "(", "for_init", ";", "bool", ";", "for_step", ")", "stmt"},
+		Rule: GenRules.MatchedStmtFor,
+	},
+	// for_init → loc = bool | ε
+	{
+		Head: "for_init",
+		Body: []Symbol{"loc", "=", "bool"},
+		Rule: GenRules.ForInit,
+	},
+	{
+		Head: "for_init",
+		Body: []Symbol{EPSILON}, // ε
+		Rule: GenRules.ForInitEpsilon,
+	},
+	// for_step → loc = bool | ε
+	{
+		Head: "for_step",
+		Body: []Symbol{"loc", "=", "bool"},
+		Rule: GenRules.ForStep,
+	},
+	// for_step → loc ++ | loc -- | ++ loc | -- loc
+	{
+		Head: "for_step",
+		Body: []Symbol{"loc", "++"},
+		Rule: GenRules.ForStepIncDec,
+	},
+	{
+		Head: "for_step",
+		Body: []Symbol{"loc", "--"},
+		Rule: GenRules.ForStepIncDec,
+	},
+	{
+		Head: "for_step",
+		Body: []Symbol{"++", "loc"},
+		Rule: GenRules.ForStepIncDec,
+	},
+	{
+		Head: "for_step",
+		Body: []Symbol{"--", "loc"},
+		Rule: GenRules.ForStepIncDec,
+	},
+	{
+		Head: "for_step",
+		Body: []Symbol{EPSILON}, // ε
+		Rule: GenRules.ForStepEpsilon,
+	},
+	// matched_stmt → switch ( expr ) { cases }
+	{
+		Head: "matched_stmt",
+		Body: []Symbol{"switch", "(", "expr", ")", "{", "cases", "}"},
+		Rule: GenRules.MatchedStmtSwitch,
+	},
+	// cases → cases case_clause | ε
+	{
+		Head: "cases",
+		Body: []Symbol{"cases", "case_clause"},
+		Rule: GenRules.Cases,
+	},
+	{
+		Head: "cases",
+		Body: []Symbol{EPSILON}, // ε
+		Rule: GenRules.CasesEpsilon,
+	},
+	// case_clause → case bool : stmts | default : stmts
+	{
+		Head: "case_clause",
+		Body: []Symbol{"case", "bool", ":", "stmts"},
+		Rule: GenRules.CaseClause,
+	},
+	{
+		Head: "case_clause",
+		Body: []Symbol{"default", ":", "stmts"},
+		Rule: GenRules.CaseClauseDefault,
+	},
+	// matched_stmt → id : matched_stmt
+	{
+		Head: "matched_stmt",
+		Body: []Symbol{"id", ":", "matched_stmt"},
+		Rule: GenRules.MatchedStmtLabeled,
+	},
+	// matched_stmt → return bool ; | return ;
+	{
+		Head: "matched_stmt",
+		Body: []Symbol{"return", "bool", ";"},
+		Rule: GenRules.MatchedStmtReturn,
+	},
+	{
+		Head: "matched_stmt",
+		Body: []Symbol{"return", ";"},
+		Rule: GenRules.MatchedStmtReturnVoid,
+	},
+	// matched_stmt → id ( args ) ;
+	{
+		Head: "matched_stmt",
+		Body: []Symbol{"id", "(", "args", ")", ";"},
+		Rule: GenRules.MatchedStmtCall,
+	},
+	// args → arg_list | ε
+	{
+		Head: "args",
+		Body: []Symbol{"arg_list"},
+		Rule: GenRules.Args,
+	},
+	{
+		Head: "args",
+		Body: []Symbol{EPSILON}, // ε
+		Rule: GenRules.ArgsEpsilon,
+	},
+	// arg_list → arg_list , bool | bool
+	{
+		Head: "arg_list",
+		Body: []Symbol{"arg_list", ",", "bool"},
+		Rule: GenRules.ArgList,
+	},
+	{
+		Head: "arg_list",
+		Body: []Symbol{"bool"},
+		Rule: GenRules.ArgListBool,
+	},
 	// matched_stmt → block
 	{
 		Head: "matched_stmt",
 		Body: []Symbol{"block"},
 		Rule: GenRules.MatchedStmtBlock,
 	},
-	// loc → loc[num] | id
+	// loc → loc[num] | loc.id | id
 	{
 		Head: "loc",
 		Body: []Symbol{"loc", "[", "num", "]"},
 		Rule: GenRules.LocArray,
 	},
+	{
+		Head: "loc",
+		Body: []Symbol{"loc", ".", "id"},
+		Rule: GenRules.LocField,
+	},
 	{
 		Head: "loc",
 		Body: []Symbol{"id"},
@@ -240,16 +588,38 @@ var Productions = []Production{
 		Body: []Symbol{"join"},
 		Rule: GenRules.BoolPrimeJoin,
 	},
-	// join → join && equality | equality
+	// join → join && bitwise | bitwise
 	{
 		Head: "join",
-		Body: []Symbol{"join", "&&", "equality"},
+		Body: []Symbol{"join", "&&", "bitwise"},
 		Rule: GenRules.Join,
 	},
 	{
 		Head: "join",
+		Body: []Symbol{"bitwise"},
+		Rule: GenRules.JoinBitwise,
+	},
+	// bitwise → bitwise | bitwise | bitwise ^ bitwise | bitwise & bitwise | equality
+	// ** ambiguous, the precedence and associativity of the operators are declared in Precedences **
+	{
+		Head: "bitwise",
+		Body: []Symbol{"bitwise", "|", "bitwise"},
+		Rule: GenRules.BitOr,
+	},
+	{
+		Head: "bitwise",
+		Body: []Symbol{"bitwise", "^", "bitwise"},
+		Rule: GenRules.BitXor,
+	},
+	{
+		Head: "bitwise",
+		Body: []Symbol{"bitwise", "&", "bitwise"},
+		Rule: GenRules.BitAnd,
+	},
+	{
+		Head: "bitwise",
 		Body: []Symbol{"equality"},
-		Rule: GenRules.JoinEquality,
+		Rule: GenRules.BitwiseEquality,
 	},
 	// equality → equality == rel | equality != rel | rel
 	{
@@ -293,7 +663,18 @@ var Productions = []Production{
 		Body: []Symbol{"expr"},
 		Rule: GenRules.RelationalExpr,
 	},
-	// expr → expr+term | expr-term | term
+	// expr → expr<<expr | expr>>expr | expr+term | expr-term | term
+	// ** ambiguous in the shifts, the precedence and associativity of the operators are declared in Precedences **
+	{
+		Head: "expr",
+		Body: []Symbol{"expr", "<<", "expr"},
+		Rule: GenRules.ShiftLeft,
+	},
+	{
+		Head: "expr",
+		Body: []Symbol{"expr", ">>", "expr"},
+		Rule: GenRules.ShiftRight,
+	},
 	{
 		Head: "expr",
 		Body: []Symbol{"expr", "+", "term"},
@@ -309,7 +690,7 @@ var Productions = []Production{
 		Body: []Symbol{"term"},
 		Rule: GenRules.ExprTerm,
 	},
-	// term → term*unary | term/unary | unary
+	// term → term*unary | term/unary | term%unary | unary
 	{
 		Head: "term",
 		Body: []Symbol{"term", "*", "unary"},
@@ -320,12 +701,17 @@ var Productions = []Production{
 		Body: []Symbol{"term", "/", "unary"},
 		Rule: GenRules.TermDiv,
 	},
+	{
+		Head: "term",
+		Body: []Symbol{"term", "%", "unary"},
+		Rule: GenRules.TermMod,
+	},
 	{
 		Head: "term",
 		Body: []Symbol{"unary"},
 		Rule: GenRules.TermUnary,
 	},
-	// unary → !unary | -unary | factor
+	// unary → !unary | -unary | *unary | &loc | ++loc | --loc | factor
 	{
 		Head: "unary",
 		Body: []Symbol{"!", "unary"},
@@ -336,12 +722,42 @@ var Productions = []Production{
 		Body: []Symbol{"-", "unary"},
 		Rule: GenRules.UnaryNeg,
 	},
+	{
+		Head: "unary",
+		Body: []Symbol{"*", "unary"},
+		Rule: GenRules.UnaryDeref,
+	},
+	{
+		Head: "unary",
+		Body: []Symbol{"&", "loc"},
+		Rule: GenRules.UnaryAddr,
+	},
+	{
+		Head: "unary",
+		Body: []Symbol{"++", "loc"},
+		Rule: GenRules.UnaryIncDec,
+	},
+	{
+		Head: "unary",
+		Body: []Symbol{"--", "loc"},
+		Rule: GenRules.UnaryIncDec,
+	},
 	{
 		Head: "unary",
 		Body: []Symbol{"factor"},
 		Rule: GenRules.UnaryFactor,
 	},
-	// factor → (bool) | loc | num | real | true | false
+	// factor → (bool) | loc | loc++ | loc-- | num | real | str | char | true | false | id ( args ) | basic ( bool )
+	{
+		Head: "factor",
+		Body: []Symbol{"id", "(", "args", ")"},
+		Rule: GenRules.FactorCall,
+	},
+	{
+		Head: "factor",
+		Body: []Symbol{"basic", "(", "bool", ")"},
+		Rule: GenRules.FactorCast,
+	},
 	{
 		Head: "factor",
 		Body: []Symbol{"(", "bool", ")"},
@@ -352,6 +768,16 @@ var Productions = []Production{
 		Body: []Symbol{"loc"},
 		Rule: GenRules.FactorLoc,
 	},
+	{
+		Head: "factor",
+		Body: []Symbol{"loc", "++"},
+		Rule: GenRules.FactorIncDec,
+	},
+	{
+		Head: "factor",
+		Body: []Symbol{"loc", "--"},
+		Rule: GenRules.FactorIncDec,
+	},
 	{
 		Head: "factor",
 		Body: []Symbol{"num"},
@@ -362,6 +788,16 @@ var Productions = []Production{
 		Body: []Symbol{"real"},
 		Rule: GenRules.FactorReal,
 	},
+	{
+		Head: "factor",
+		Body: []Symbol{"str"},
+		Rule: GenRules.FactorStr,
+	},
+	{
+		Head: "factor",
+		Body: []Symbol{"char"},
+		Rule: GenRules.FactorChar,
+	},
 	{
 		Head: "factor",
 		Body: []Symbol{"true"},
@@ -3,6 +3,11 @@ package parser
 import (
 	"fmt"
 	"maps"
+	"slices"
+
+	"app/lexer"
+	. "app/utils/collections"
+	"app/utils/i18n"
 )
 
 func (p *Parser) BuildTable() {
@@ -37,7 +42,7 @@ func (t LRTable) Insert(state *State, grammar *Grammar) {
 			if item.Lookahead == TERMINATE && item.Production.Equals(grammar.AugmentedProduction) {
 				err = t.ActionTable.Register(state.Index, Action{Type: ACCEPT, Number: 0}, TERMINATE)
 			} else {
-				err = t.ActionTable.Register(state.Index, Action{Type: REDUCE, Number: grammar.GetIndex(item.Production)}, item.Lookahead)
+				err = t.registerAction(state.Index, Action{Type: REDUCE, Number: grammar.GetIndex(item.Production)}, item.Lookahead, grammar)
 			}
 		} else {
 			symbol := item.Production.Body[item.Dot]
@@ -47,7 +52,7 @@ func (t LRTable) Insert(state *State, grammar *Grammar) {
 			if grammar.IsNonTerminal(symbol) {
 				err = t.GotoTable.Register(state.Index, state.Transitions[symbol].Index, symbol)
 			} else {
-				err = t.ActionTable.Register(state.Index, Action{Type: SHIFT, Number: state.Transitions[symbol].Index}, Terminal(symbol))
+				err = t.registerAction(state.Index, Action{Type: SHIFT, Number: state.Transitions[symbol].Index}, Terminal(symbol), grammar)
 			}
 		}
 		if err != nil {
@@ -56,6 +61,28 @@ func (t LRTable) Insert(state *State, grammar *Grammar) {
 	}
 }
 
+// registerAction registers the action like ActionTable.Register, except that a shift/reduce conflict
+// is resolved with the precedence declarations of the grammar when possible.
+// An ERROR action, registered for a non-associative terminal, is kept.
+func (t LRTable) registerAction(stateIndex int, action Action, terminal Terminal, grammar *Grammar) error {
+	if existing, exists := t.ActionTable[stateIndex][terminal]; exists {
+		if existing.Type == ERROR {
+			return nil
+		}
+		shift, reduce := existing, action
+		if action.Type == SHIFT {
+			shift, reduce = action, existing
+		}
+		if shift.Type == SHIFT && reduce.Type == REDUCE {
+			if resolved, ok := grammar.ResolveShiftReduce(grammar.Productions[reduce.Number], terminal, shift, reduce); ok {
+				t.ActionTable[stateIndex][terminal] = resolved
+				return nil
+			}
+		}
+	}
+	return t.ActionTable.Register(stateIndex, action, terminal)
+}
+
 type Action struct {
 	Type   ActionType
 	Number int
@@ -77,9 +104,9 @@ func (t ActionTable) Register(stateIndex int, action Action, terminal Terminal)
 
 	if _, exists := t[stateIndex][terminal]; exists {
 		if t[stateIndex][terminal].Type == SHIFT && action.Type == REDUCE {
-			return fmt.Errorf("conflict in action table: state %d, terminal %s[shift] %d, [reduce] %d", stateIndex, terminal, t[stateIndex][terminal].Number, action.Number)
+			return i18n.Errorf("conflict in action table: state %d, terminal %s[shift] %d, [reduce] %d", stateIndex, terminal, t[stateIndex][terminal].Number, action.Number)
 		} else if t[stateIndex][terminal].Type == REDUCE && action.Type == REDUCE {
-			return fmt.Errorf("conflict in action table: state %d, terminal %s[reduce] %d, [reduce] %d", stateIndex, terminal, t[stateIndex][terminal].Number, action.Number)
+			return i18n.Errorf("conflict in action table: state %d, terminal %s[reduce] %d, [reduce] %d", stateIndex, terminal, t[stateIndex][terminal].Number, action.Number)
 		}
 	}
 
@@ -102,7 +129,7 @@ func (t GotoTable) Register(stateIndex, nextStateIndex int, symbol Symbol) error
 
 	// ignore conflict
 	//if _, exists := t[stateIndex][symbol]; exists {
-	//	return fmt.Errorf("conflict in goto table: state %d, symbol %s", stateIndex, symbol)
+	//	return i18n.Errorf("conflict in goto table: state %d, symbol %s", stateIndex, symbol)
 	//}
 
 	t[stateIndex][symbol] = nextStateIndex
@@ -123,14 +150,140 @@ type SymbolTableItem struct {
 	Variable string
 	Type     SymbolTableItemType
 	Address  int
+	Local    bool // addressed relative to the frame pointer of the enclosing function
 
 	UnderlyingType string
+	Value          string // value of a constant, an immediate
 
 	VariableSize int
 
 	ArraySize        int
 	ArrayElementSize int
 	Dimension        []int
+
+	Params     []*SymbolTableItem // parameters of a function, in order
+	ReturnType string             // return type of a function, "" if it returns nothing
+	FrameSize  int                // size of the activation record of a function
+
+	Struct    *SymbolTableItem   // struct type of a variable, a field or the elements of an array, if any
+	Fields    []*SymbolTableItem // fields of a struct type, in order, whose Address is their offset in bytes
+	Alignment int                // alignment of a struct type in bytes
+
+	Declaration lexer.Span // span of the identifier declaring the item, see Index
+}
+
+// NewStructType lays out the fields of a struct type in order, each at an offset aligned to its alignment,
+// and pads the struct to a multiple of the largest alignment, like C. The size of the type is its VariableSize.
+// The three-address code addresses words, so a field is aligned to a word at least.
+func NewStructType(name string, fields []*SymbolTableItem) *SymbolTableItem {
+	item := &SymbolTableItem{
+		Variable:       name,
+		Type:           SymbolTableItemTypeStruct,
+		UnderlyingType: "struct",
+		Fields:         fields,
+		Alignment:      4,
+	}
+	offset := 0
+	for _, field := range fields {
+		alignment := field.alignment()
+		offset = (offset + alignment - 1) / alignment * alignment
+		field.Address = offset
+		offset += field.size()
+		item.Alignment = max(item.Alignment, alignment)
+	}
+	item.VariableSize = (offset + item.Alignment - 1) / item.Alignment * item.Alignment
+	return item
+}
+
+// size returns the size in bytes of the storage of a variable or a field.
+func (item *SymbolTableItem) size() int {
+	if item.Type == SymbolTableItemTypeArray {
+		return item.ArrayElementSize * item.ArraySize
+	}
+	return item.VariableSize
+}
+
+// alignment returns the alignment in bytes of a variable or a field, the one of its struct type
+// or the size of its elements capped to 8, and a word at least.
+func (item *SymbolTableItem) alignment() int {
+	if item.Struct != nil {
+		return item.Struct.Alignment
+	}
+	size := item.VariableSize
+	if item.Type == SymbolTableItemTypeArray {
+		size = item.ArrayElementSize
+	}
+	return max(min(size, 8), 4)
+}
+
+// Field returns the field of a struct type with the name.
+func (item *SymbolTableItem) Field(name string) (*SymbolTableItem, error) {
+	if item.Type != SymbolTableItemTypeStruct {
+		return nil, i18n.Errorf("item %s is not a struct", item.Variable)
+	}
+	for _, field := range item.Fields {
+		if field.Variable == name {
+			return field, nil
+		}
+	}
+	return nil, i18n.Errorf("struct %s has no field %s", item.Variable, name)
+}
+
+// FieldAddress returns the address of the field of the struct type for a struct stored at base, and the field.
+func (item *SymbolTableItem) FieldAddress(base int, name string) (int, *SymbolTableItem, error) {
+	field, err := item.Field(name)
+	if err != nil {
+		return -1, nil, err
+	}
+	return base + field.Address/4, field, nil
+}
+
+// ElementAddress returns the address of the element at the indices of the array item, for an array stored at base.
+// The missing trailing indices are 0, i.e. the address of a sub-array is the one of its first element.
+func (item *SymbolTableItem) ElementAddress(base int, dimension []int) (int, error) {
+	if item.Type != SymbolTableItemTypeArray {
+		return -1, i18n.Errorf("item %s is not an array", item.Variable)
+	}
+	if len(dimension) > len(item.Dimension) {
+		return -1, i18n.Errorf("too many indices for item %s", item.Variable)
+	}
+	dimension = slices.Clone(dimension)
+	for i := len(dimension); i < len(item.Dimension); i++ {
+		dimension = append(dimension, 0)
+	}
+	offset := 0
+	for i, dim := range dimension {
+		if dim < 0 || dim >= item.Dimension[i] {
+			return -1, i18n.Errorf("index out of bounds for dimension %d of item %s", i, item.Variable)
+		}
+		multiplier := 1
+		for j := i + 1; j < len(item.Dimension); j++ {
+			multiplier *= item.Dimension[j]
+		}
+		offset += dim * multiplier
+	}
+
+	if offset < 0 || offset >= item.ArraySize {
+		return -1, i18n.Errorf("index out of bounds for item %s", item.Variable)
+	}
+	return base + (item.ArrayElementSize * offset / 4), nil
+}
+
+// AddrString returns the operand referring to the item in the three-address code.
+func (item *SymbolTableItem) AddrString() string {
+	return item.FormatAddr(item.Address)
+}
+
+// FormatAddr formats an address in the same storage as the item, e.g. an element of an array.
+func (item *SymbolTableItem) FormatAddr(addr int) string {
+	return formatAddr(addr, item.Local)
+}
+
+func formatAddr(addr int, local bool) string {
+	if local {
+		return fmt.Sprintf("$(fp+%#x)", addr)
+	}
+	return fmt.Sprintf("$(%#x)", addr)
 }
 
 type SymbolTableItemType string
@@ -139,6 +292,8 @@ const (
 	SymbolTableItemTypeVariable SymbolTableItemType = "variable"
 	SymbolTableItemTypeArray    SymbolTableItemType = "array"
 	SymbolTableItemTypeConstant SymbolTableItemType = "constant"
+	SymbolTableItemTypeFunction SymbolTableItemType = "function"
+	SymbolTableItemTypeStruct   SymbolTableItemType = "struct"
 	SymbolTableItemTypeUnknown  SymbolTableItemType = "unknown"
 )
 
@@ -147,6 +302,8 @@ type Scope struct {
 	Level  int
 	Items  map[string]*SymbolTableItem
 	Parent *Scope
+
+	Function *SymbolTableItem // the function whose parameters are declared in this scope, if any
 }
 
 type SymbolTable struct {
@@ -156,6 +313,7 @@ type SymbolTable struct {
 	ExitFunction  func(*Scope) error
 
 	addrCounter int
+	frames      Stack[int] // address counters saved when entering the scope of a function
 }
 
 const (
@@ -176,19 +334,34 @@ func NewSymbolTable(enter, exit func(*Scope) error) *SymbolTable {
 
 // EnterScope creates a new scope and sets it as the current scope in the symbol table.
 func (st *SymbolTable) EnterScope() error {
+	return st.enterScope(nil)
+}
+
+// EnterFunctionScope creates the scope of the given function, where its parameters are declared.
+// The items registered in it and in its children are allocated in the activation record of the
+// function, starting from offset 0, until ExitFunctionScope is called.
+func (st *SymbolTable) EnterFunctionScope(function *SymbolTableItem) error {
+	st.frames.Push(st.addrCounter)
+	st.addrCounter = 0
+	return st.enterScope(function)
+}
+
+func (st *SymbolTable) enterScope(function *SymbolTableItem) error {
 	if st.CurrentScope == nil {
 		st.CurrentScope = &Scope{
-			ID:     len(st.LegacyScopes),
-			Level:  0,
-			Items:  make(map[string]*SymbolTableItem),
-			Parent: nil,
+			ID:       len(st.LegacyScopes),
+			Level:    0,
+			Items:    make(map[string]*SymbolTableItem),
+			Parent:   nil,
+			Function: function,
 		}
 	} else {
 		st.CurrentScope = &Scope{
-			ID:     len(st.LegacyScopes),
-			Level:  st.CurrentScope.Level + 1,
-			Items:  make(map[string]*SymbolTableItem),
-			Parent: st.CurrentScope,
+			ID:       len(st.LegacyScopes),
+			Level:    st.CurrentScope.Level + 1,
+			Items:    make(map[string]*SymbolTableItem),
+			Parent:   st.CurrentScope,
+			Function: function,
 		}
 	}
 	st.LegacyScopes = append(st.LegacyScopes, st.CurrentScope)
@@ -201,10 +374,31 @@ func (st *SymbolTable) EnterScope() error {
 	return nil
 }
 
+// ExitFunctionScope exits the scope of the current function, recording the size of its activation record.
+func (st *SymbolTable) ExitFunctionScope() error {
+	if st.CurrentScope == nil || st.CurrentScope.Function == nil {
+		return i18n.Errorf("no function scope to exit")
+	}
+	st.CurrentScope.Function.FrameSize = st.addrCounter * 4
+	err := st.ExitScope()
+	st.addrCounter, _ = st.frames.Pop()
+	return err
+}
+
+// CurrentFunction returns the function enclosing the current scope, or nil if there is none.
+func (st *SymbolTable) CurrentFunction() *SymbolTableItem {
+	for scope := st.CurrentScope; scope != nil; scope = scope.Parent {
+		if scope.Function != nil {
+			return scope.Function
+		}
+	}
+	return nil
+}
+
 // ExitScope exits the current scope and sets the parent scope as the current scope.
 func (st *SymbolTable) ExitScope() error {
 	if st.CurrentScope == nil {
-		return fmt.Errorf("no scope to exit")
+		return i18n.Errorf("no scope to exit")
 	}
 
 	if st.ExitFunction != nil {
@@ -221,17 +415,20 @@ func (st *SymbolTable) ExitScope() error {
 // It checks for conflicts and ensures that the item is valid before adding it.
 func (st *SymbolTable) Register(item *SymbolTableItem) (int, error) {
 	if st.CurrentScope == nil {
-		return -1, fmt.Errorf("no scope to register item")
+		return -1, i18n.Errorf("no scope to register item")
 	}
 
 	if _, exists := st.CurrentScope.Items[item.Variable]; exists {
-		return -1, fmt.Errorf("item %s already exists in scope", item.Variable)
+		return -1, i18n.Errorf("item %s already exists in scope", item.Variable)
 	}
 
-	if item.VariableSize <= 0 {
-		return -1, fmt.Errorf("invalid variable size for item %s", item.Variable)
+	// functions, struct types and constants take no storage
+	storage := item.Type != SymbolTableItemTypeFunction && item.Type != SymbolTableItemTypeStruct && item.Type != SymbolTableItemTypeConstant
+	if item.VariableSize <= 0 && storage {
+		return -1, i18n.Errorf("invalid variable size for item %s", item.Variable)
 	}
 	st.CurrentScope.Items[item.Variable] = item
+	item.Local = storage && st.CurrentFunction() != nil
 	switch item.Type {
 	case SymbolTableItemTypeVariable:
 		item.Address = st.addrCounter
@@ -241,10 +438,10 @@ func (st *SymbolTable) Register(item *SymbolTableItem) (int, error) {
 		}
 	case SymbolTableItemTypeArray:
 		if item.ArraySize <= 0 {
-			return -1, fmt.Errorf("invalid array size for item %s", item.Variable)
+			return -1, i18n.Errorf("invalid array size for item %s", item.Variable)
 		}
 		if item.ArrayElementSize <= 0 {
-			return -1, fmt.Errorf("invalid array element size for item %s", item.Variable)
+			return -1, i18n.Errorf("invalid array element size for item %s", item.Variable)
 		}
 		item.Address = st.addrCounter
 		st.addrCounter += item.ArrayElementSize * item.ArraySize / 4
@@ -257,36 +454,17 @@ func (st *SymbolTable) Register(item *SymbolTableItem) (int, error) {
 
 func (st *SymbolTable) ArrayAddress(variable string, dimension []int) (int, int, error) {
 	if st.CurrentScope == nil {
-		return -1, -1, fmt.Errorf("no scope to lookup item")
+		return -1, -1, i18n.Errorf("no scope to lookup item")
 	}
 	item, _, err := st.Lookup(variable)
 	if err != nil {
 		return -1, -1, err
 	}
-	if item.Type != SymbolTableItemTypeArray {
-		return -1, -1, fmt.Errorf("item %s is not an array", variable)
-	}
-	if len(dimension) != len(item.Dimension) {
-		for i := len(dimension); i < len(item.Dimension); i++ {
-			dimension = append(dimension, 0)
-		}
-	}
-	offset := 0
-	for i, dim := range dimension {
-		if dim < 0 || dim >= item.Dimension[i] {
-			return -1, -1, fmt.Errorf("index out of bounds for dimension %d of item %s", i, variable)
-		}
-		multiplier := 1
-		for j := i + 1; j < len(item.Dimension); j++ {
-			multiplier *= item.Dimension[j]
-		}
-		offset += dim * multiplier
-	}
-
-	if offset < 0 || offset >= item.ArraySize {
-		return -1, -1, fmt.Errorf("index out of bounds for item %s", variable)
+	addr, err := item.ElementAddress(item.Address, dimension)
+	if err != nil {
+		return -1, -1, err
 	}
-	return item.Address + (item.ArrayElementSize * offset / 4), item.ArraySize, nil
+	return addr, item.ArraySize, nil
 }
 
 // arrayAddress returns the address of the specified variable in the symbol table.
@@ -294,14 +472,14 @@ func (st *SymbolTable) ArrayAddress(variable string, dimension []int) (int, int,
 // It returns the address of the variable and an error if any.
 func (st *SymbolTable) arrayAddress(variable string, offset int) (int, int, error) {
 	if st.CurrentScope == nil {
-		return -1, -1, fmt.Errorf("no scope to lookup item")
+		return -1, -1, i18n.Errorf("no scope to lookup item")
 	}
 	item, _, err := st.Lookup(variable)
 	if err != nil {
 		return -1, -1, err
 	}
 	if item.Type != SymbolTableItemTypeArray {
-		return -1, -1, fmt.Errorf("item %s is not an array", variable)
+		return -1, -1, i18n.Errorf("item %s is not an array", variable)
 	}
 	return item.Address + (item.ArrayElementSize * offset / 4), item.ArraySize, nil
 }
@@ -312,7 +490,7 @@ func (st *SymbolTable) arrayAddress(variable string, offset int) (int, int, erro
 // If the item is not found, it returns an error indicating that the item was not found in any scope.
 func (st *SymbolTable) Lookup(variable string) (item *SymbolTableItem, findInCurrentScope bool, err error) {
 	if st.CurrentScope == nil {
-		return nil, false, fmt.Errorf("no scope to lookup item")
+		return nil, false, i18n.Errorf("no scope to lookup item")
 	}
 
 	scope := st.CurrentScope
@@ -323,7 +501,7 @@ func (st *SymbolTable) Lookup(variable string) (item *SymbolTableItem, findInCur
 		scope = scope.Parent
 	}
 
-	return nil, false, fmt.Errorf("item %s not found in any scope", variable)
+	return nil, false, i18n.Errorf("item %s not found in any scope", variable)
 }
 
 // TempAddr generates a temporary address for a variable in the symbol table.
@@ -337,3 +515,9 @@ func (st *SymbolTable) TempAddr(size int) int {
 	}
 	return addr
 }
+
+// TempVar generates a temporary variable of the given size and returns the operand referring to it.
+// Inside a function, the temporary variable is allocated in the activation record of the function.
+func (st *SymbolTable) TempVar(size int) string {
+	return formatAddr(st.TempAddr(size), st.CurrentFunction() != nil)
+}
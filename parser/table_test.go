@@ -2,8 +2,11 @@ package parser_test
 
 import (
 	"fmt"
+	"slices"
+	"strings"
 	"testing"
 
+	"app/lexer"
 	. "app/parser"
 	. "app/utils/collections"
 )
@@ -68,3 +71,86 @@ func TestParser_BuildTable(t *testing.T) {
 		})
 	}
 }
+
+func TestParser_BuildTable_Precedence(t *testing.T) {
+	g := Grammar{
+		AugmentedProduction: Production{Head: "S", Body: []Symbol{"E"}},
+		Productions: []Production{
+			{Head: "E", Body: []Symbol{"E", "+", "E"}},
+			{Head: "E", Body: []Symbol{"E", "-", "E"}},
+			{Head: "E", Body: []Symbol{"E", "*", "E"}},
+			{Head: "E", Body: []Symbol{"E", "^", "E"}},
+			{Head: "E", Body: []Symbol{"E", "<", "E"}},
+			{Head: "E", Body: []Symbol{"-", "E"}, Prec: "UMINUS"},
+			{Head: "E", Body: []Symbol{"(", "E", ")"}},
+			{Head: "E", Body: []Symbol{"id"}},
+		},
+		Terminals: Set[Terminal]{}.AddAll("+", "-", "*", "^", "<", "(", ")", "id", EPSILON, TERMINATE),
+	}
+	g.NonAssoc("<").Left("+", "-").Left("*").Right("^").Right("UMINUS")
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{input: "a - b - c", expected: "((a - b) - c)"},
+		{input: "a + b * c", expected: "(a + (b * c))"},
+		{input: "a * b + c", expected: "((a * b) + c)"},
+		{input: "a ^ b ^ c", expected: "(a ^ (b ^ c))"},
+		{input: "- a * b", expected: "((- a) * b)"},
+		{input: "a < b + c", expected: "(a < (b + c))"},
+	}
+	p := &Parser{Grammar: &g}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			tree, err := p.ParseTree(lexer.NewLexer(strings.NewReader(tt.input)))
+			if err != nil {
+				t.Fatalf("Parse of %s failed: %v", tt.input, err)
+			}
+			actual := parenthesize(tree)
+			fmt.Printf("%s => %s\n", tt.input, actual)
+			if actual != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, actual)
+			}
+		})
+	}
+
+	if _, err := p.ParseTree(lexer.NewLexer(strings.NewReader("a < b < c"))); err == nil {
+		t.Errorf("Expected an error for the non-associative a < b < c")
+	}
+}
+
+func TestParser_BuildTable_DanglingElse(t *testing.T) {
+	ensureSharedParser()
+	found := false
+	for _, state := range sharedParser.States {
+		for _, item := range state.Items {
+			if item.Production.Head != "matched_stmt" || !slices.Equal(item.Production.Body, []Symbol{"if", "(", "bool", ")", "matched_stmt"}) ||
+				item.Dot != len(item.Production.Body) || item.Lookahead != "else" {
+				continue
+			}
+			found = true
+			if action := sharedParser.Table.ActionTable[state.Index]["else"]; action.Type != SHIFT {
+				t.Errorf("Expected the else to be shifted in state %d, got %v", state.Index, action)
+			}
+		}
+	}
+	if !found {
+		t.Errorf("Expected a state reducing the if without else on else")
+	}
+}
+
+// parenthesize renders the parse tree with the children of every node with several children in parentheses.
+func parenthesize(node *ASTNode) string {
+	switch len(node.Children) {
+	case 0:
+		return node.Token.Val
+	case 1:
+		return parenthesize(node.Children[0])
+	}
+	parts := make([]string, len(node.Children))
+	for i, child := range node.Children {
+		parts[i] = parenthesize(child)
+	}
+	return "(" + strings.Join(parts, " ") + ")"
+}
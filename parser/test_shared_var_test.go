@@ -1,10 +1,20 @@
 package parser_test
 
 import (
+	"os"
+	"testing"
+
 	. "app/parser"
 	. "app/utils/collections"
+	"app/utils/i18n"
 )
 
+// the tests check the messages in English
+func TestMain(m *testing.M) {
+	i18n.SetLocale(i18n.EN)
+	os.Exit(m.Run())
+}
+
 var grammars = []Grammar{
 	{
 		AugmentedProduction: Production{Head: "S'", Body: []Symbol{"S"}},
@@ -3,6 +3,8 @@ package parser
 import (
 	"fmt"
 	"slices"
+	"strconv"
+	"strings"
 	"sync"
 
 	. "app/utils/collections"
@@ -12,12 +14,17 @@ type Parser struct {
 	Grammar *Grammar
 	Symbols Set[Symbol]
 
-	FirstSet FirstSet
+	FirstSet  FirstSet
+	FollowSet FollowSet
 
 	States States
 
 	Table *LRTable
 
+	LL1Table LL1Table
+	// LL1Conflicts are the conflicts of the LL1Table, see BuildLL1Table.
+	LL1Conflicts []LL1Conflict
+
 	_mu sync.Mutex
 }
 
@@ -34,6 +41,8 @@ func NewParser() *Parser {
 
 type FirstSet map[Symbol]Set[Terminal]
 
+type FollowSet map[Symbol]Set[Terminal]
+
 type State struct {
 	Index       int
 	Items       LR1Items
@@ -75,8 +84,19 @@ type LR1Item struct {
 }
 
 // AsKey generates a unique key for the LR1Item based on its production, dot position, and lookahead symbol.
+// It is built without fmt, as it is computed for every item of every state.
 func (i *LR1Item) AsKey() string {
-	return fmt.Sprintf("%s\a%s\a%d\a%s", i.Production.Head, i.Production.Body, i.Dot, i.Lookahead)
+	var b strings.Builder
+	b.WriteString(string(i.Production.Head))
+	for _, symbol := range i.Production.Body {
+		b.WriteByte(' ')
+		b.WriteString(string(symbol))
+	}
+	b.WriteByte('\a')
+	b.WriteString(strconv.Itoa(i.Dot))
+	b.WriteByte('\a')
+	b.WriteString(string(i.Lookahead))
+	return b.String()
 }
 
 // String returns a string representation of the LR1Item.
@@ -112,6 +132,17 @@ func (i *LR1Item) Equals(other LR1Item) bool {
 
 type LR1Items []LR1Item
 
+// Key generates a key for the items from the keys of the items, regardless of their order,
+// so that two sets of items are equal if and only if their keys are.
+func (items LR1Items) Key() string {
+	keys := make([]string, len(items))
+	for i, item := range items {
+		keys[i] = item.AsKey()
+	}
+	slices.Sort(keys)
+	return strings.Join(keys, "\n")
+}
+
 // Contains checks if the LR1Items slice contains a specific LR1Item.
 func (items *LR1Items) Contains(other LR1Item) bool {
 	return slices.ContainsFunc(*items, func(item LR1Item) bool {
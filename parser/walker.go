@@ -2,9 +2,11 @@ package parser
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
 
 	. "app/utils/collections"
+	"app/utils/i18n"
 )
 
 // Walker is a structure that represents the current state of the parser
@@ -22,19 +24,59 @@ type Walker struct {
 
 	Environment  *Environment
 	ThreeAddress []string
+	ReadOnlyData *StringPool // the string constants, i.e. the .rodata section
+	Errors       []error     // errors raised by the rules, e.g. duplicate case labels
 
-	ast *AbstractSyntaxTree
+	ast        *AbstractSyntaxTree
+	recovering bool                       // after a syntax error, the rules are not run anymore, see Recover
+	fields     map[int64]*SymbolTableItem // the field named after each dot by the offset of the name, see Index
 }
 
 type Environment struct {
-	BreakLabelStack Stack[*[]int]
-	LoopLabelStack  Stack[int]
-	LabelStack      Stack[int]
-	EndIfStmtStack  Stack[int]
+	BreakLabelStack    Stack[*[]int]
+	ContinueLabelStack Stack[*[]int]
+	LoopNameStack      Stack[string]
+	LoopLabelStack     Stack[int]
+	LabelStack         Stack[int]
+	EndIfStmtStack     Stack[int]
 
 	LoopBooleanStartLineStack Stack[int]
 }
 
+// StringPool is the pool of the string constants, laid out NUL-terminated in the .rodata section.
+// A string is stored once however many times it is used.
+type StringPool struct {
+	Strings []string // in the order of their first use
+	offsets map[string]int
+	size    int
+}
+
+func NewStringPool() *StringPool {
+	return &StringPool{offsets: map[string]int{}}
+}
+
+// Intern adds the string to the pool if it is not there yet and returns its offset in the section.
+func (p *StringPool) Intern(s string) int {
+	if offset, ok := p.offsets[s]; ok {
+		return offset
+	}
+	offset := p.size
+	p.offsets[s] = offset
+	p.Strings = append(p.Strings, s)
+	p.size += len(s) + 1
+	return offset
+}
+
+// Offset returns the offset of an interned string.
+func (p *StringPool) Offset(s string) int {
+	return p.offsets[s]
+}
+
+// Size returns the size of the section in bytes.
+func (p *StringPool) Size() int {
+	return p.size
+}
+
 // NewEnvironment creates a new Environment instance and initializes it.
 // The Environment is used to store the current state of the parser, including
 // the current type, data type, data size, array size, variable name, etc.
@@ -70,16 +112,77 @@ func (p *Parser) NewWalker() *Walker {
 			ActionTable: p.Table.ActionTable.Copy(),
 			GotoTable:   p.Table.GotoTable.Copy(),
 		},
-		Grammar:     &g,
-		States:      states,
-		Symbols:     symbols,
-		SymbolTable: NewSymbolTable(nil, nil),
-		Environment: NewEnvironment(),
+		Grammar:      &g,
+		States:       states,
+		Symbols:      symbols,
+		SymbolTable:  NewSymbolTable(nil, nil),
+		Environment:  NewEnvironment(),
+		ReadOnlyData: NewStringPool(),
+		fields:       map[int64]*SymbolTableItem{},
 	}
+	w.SymbolTable.EnterFunction = w.enterFunction
+	w.SymbolTable.ExitFunction = w.exitFunction
 	w.Emit("jmp", "L1")
 	return w
 }
 
+// enterFunction is called by the symbol table when entering a scope.
+// For the scope of a function, it emits the entry of the function, whose line is the address of the function.
+// The size of the activation record is backfilled by exitFunction.
+func (w *Walker) enterFunction(scope *Scope) error {
+	if scope.Function == nil {
+		return nil
+	}
+	scope.Function.Address = w.Emit("enter", scope.Function.Variable, "xxx")
+	return nil
+}
+
+// exitFunction is called by the symbol table when exiting a scope.
+// For the scope of a function, it emits the implicit return at the end of the function.
+func (w *Walker) exitFunction(scope *Scope) error {
+	if scope.Function == nil {
+		return nil
+	}
+	w.Emit("ret", "")
+	w.EmitLabel(scope.Function.Address, scope.Function.Variable, "enter", strconv.Itoa(scope.Function.FrameSize))
+	return nil
+}
+
+// syncSymbols are the non-terminals at which the parse resumes after a syntax error, see Recover.
+var syncSymbols = []Symbol{"stmt", "decl", "field", "func_decl"}
+
+// Recover recovers from a syntax error in panic mode: it pops the stacks down to the first state with a goto
+// on one of the syncSymbols, and pushes this symbol as if it had just been reduced. The input must then be
+// skipped up to a symbol which can follow it, see Expects. From then on the rules are not run anymore, since
+// their tokens are lost. It returns false if no state of the stack has such a goto.
+func (w *Walker) Recover() bool {
+	for k := 0; k < w.States.Size(); k++ {
+		state, _ := w.States.PeekAtK(k)
+		for _, symbol := range syncSymbols {
+			gotoState, ok := w.Table.GotoTable[state][symbol]
+			if !ok {
+				continue
+			}
+			w.States.TrimTopN(k)
+			w.Symbols.TrimTopN(k)
+			w.Tokens.TrimTopN(k)
+			w.States.Push(gotoState)
+			w.Symbols.Push(symbol)
+			w.Tokens.Push(&ASTNode{Type: symbol})
+			w.recovering = true
+			return true
+		}
+	}
+	return false
+}
+
+// Expects reports whether the top state has an action on the terminal symbol.
+func (w *Walker) Expects(symbol Symbol) bool {
+	state, _ := w.States.Peek()
+	action, ok := w.Table.ActionTable[state][Terminal(symbol)]
+	return ok && action.Type != ERROR
+}
+
 // Next processes the next symbol in the parsing process. It takes a symbol as input
 // and returns an action and an error. The action can be SHIFT, REDUCE, ACCEPT, or ERROR.
 // The function uses the current state and the symbol to determine the appropriate action
@@ -95,7 +198,7 @@ func (w *Walker) Next(symbol Symbol) (action Action, err error) {
 	if w.Grammar.IsTerminal(symbol) {
 		action, ok := w.Table.ActionTable[topState][Terminal(symbol)]
 		if !ok {
-			return Action{Type: ERROR}, fmt.Errorf("no action found for state %d and symbol %s", topState, symbol)
+			return Action{Type: ERROR}, i18n.Errorf("no action found for state %d and symbol %s", topState, symbol)
 		}
 		switch action.Type {
 		case SHIFT:
@@ -104,8 +207,29 @@ func (w *Walker) Next(symbol Symbol) (action Action, err error) {
 			return Action{Type: SHIFT, Number: action.Number}, nil
 		case REDUCE:
 			production := w.Grammar.Productions[action.Number]
-			if err := production.HandleRule(w); err != nil {
-				fmt.Println("Error handling rule:", err)
+			size := w.Tokens.Size() - len(withoutEpsilon(production.Body))
+			var err error
+			if w.recovering {
+				// the tokens of the rules were lost by Recover, the node only keeps the stacks in step
+				w.Tokens.TrimTopN(len(withoutEpsilon(production.Body)))
+				w.Tokens.Push(&ASTNode{Type: production.Head})
+			} else {
+				err = production.HandleRule(w)
+			}
+			// the node pushed by the rule remembers its production, from which the typed AST is built
+			node, pushed := w.Tokens.Peek()
+			pushed = pushed && w.Tokens.Size() == size+1
+			if pushed {
+				node.production = &w.Grammar.Productions[action.Number]
+			}
+			if err != nil {
+				// the error is at the tokens of the node, if the rule pushed one
+				if pushed {
+					if span, ok := node.span(); ok {
+						err = &Error{Span: span, Err: err}
+					}
+				}
+				w.Errors = append(w.Errors, err)
 			}
 			for i := range production.Body {
 				if production.Body[i] == EPSILON {
@@ -117,24 +241,26 @@ func (w *Walker) Next(symbol Symbol) (action Action, err error) {
 			topState, _ = w.States.Peek()
 			gotoState, ok := w.Table.GotoTable[topState][production.Head]
 			if !ok {
-				return Action{Type: ERROR}, fmt.Errorf("no goto state found for state %d and symbol %s", topState, production.Head)
+				return Action{Type: ERROR}, i18n.Errorf("no goto state found for state %d and symbol %s", topState, production.Head)
 			}
 			w.Symbols.Push(production.Head)
 			w.States.Push(gotoState)
 			return Action{Type: REDUCE, Number: action.Number}, nil
 		case ACCEPT:
 			return Action{Type: ACCEPT, Number: 0}, nil
+		case ERROR:
+			return Action{Type: ERROR}, i18n.Errorf("non-associative symbol %s in state %d", symbol, topState)
 		}
 	} else {
 		action, ok := w.Table.GotoTable[topState][symbol]
 		if !ok {
-			return Action{Type: ERROR}, fmt.Errorf("no goto state found for state %d and symbol %s", topState, symbol)
+			return Action{Type: ERROR}, i18n.Errorf("no goto state found for state %d and symbol %s", topState, symbol)
 		}
 		w.States.Push(action)
 		w.Symbols.Push(symbol)
 		return Action{Type: GOTO, Number: action}, nil
 	}
-	return Action{Type: ERROR}, fmt.Errorf("unexpected state %d and symbol %s", topState, symbol)
+	return Action{Type: ERROR}, i18n.Errorf("unexpected state %d and symbol %s", topState, symbol)
 }
 
 // Reset resets the Walker's state, symbol, and token stacks to their initial state.
@@ -192,12 +318,16 @@ func (w *Walker) AdjustJMP(label int, jmp int) error {
 	line := w.ThreeAddress[label]
 	parts := strings.Fields(line)
 	if len(parts) < 3 {
-		return fmt.Errorf("invalid line format: %s", line)
+		return i18n.Errorf("invalid line format: %s", line)
 	}
 	if parts[1] != "jmp" && parts[1] != "jz" && parts[1] != "jnz" {
-		return fmt.Errorf("invalid jump instruction: %s", parts[1])
+		return i18n.Errorf("invalid jump instruction: %s", parts[1])
+	}
+	line = fmt.Sprintf("L%-8d %8s %16s", label, parts[1], fmt.Sprintf("L%d", jmp))
+	for _, arg := range parts[3:] {
+		line += fmt.Sprintf(" %16s", arg)
 	}
-	w.ThreeAddress[label] = fmt.Sprintf("L%-8d %8s %16s", label, parts[1], fmt.Sprintf("L%d", jmp))
+	w.ThreeAddress[label] = line
 	return nil
 }
 
@@ -206,28 +336,75 @@ func (w *Walker) GetCurrentLabelCount() int {
 	return len(w.ThreeAddress)
 }
 
-// EnterLoop pushes a new break label stack onto the environment's break label stack.
-func (w *Walker) EnterLoop() {
-	a := make([]int, 0)
-	w.Environment.BreakLabelStack.Push(&a)
+// EnterLoop pushes a new break label stack and a new continue label stack onto the environment.
+// The label is the name of the loop (e.g. `outer: while (...)`), or "" if the loop is unlabelled.
+func (w *Walker) EnterLoop(label string) {
+	b, c := make([]int, 0), make([]int, 0)
+	w.Environment.BreakLabelStack.Push(&b)
+	w.Environment.ContinueLabelStack.Push(&c)
+	w.Environment.LoopNameStack.Push(label)
 }
 
-// AddBreakLabel adds a break label to the current loop.
-func (w *Walker) AddBreakLabel() int {
-	if w.Environment.BreakLabelStack.IsEmpty() {
-		println("AddBreakLabel: BreakLabelStack is empty")
-		return -1
+// EnterSwitch pushes a new break label stack onto the environment for a switch statement.
+// A switch can be left by `break` but not continued, so its continue label stack is nil.
+func (w *Walker) EnterSwitch(label string) {
+	b := make([]int, 0)
+	w.Environment.BreakLabelStack.Push(&b)
+	w.Environment.ContinueLabelStack.Push(nil)
+	w.Environment.LoopNameStack.Push(label)
+}
+
+// loopDepth returns the depth of the loop named label, counting from the innermost loop.
+// An empty label refers to the innermost loop. If continuable is set, switch statements are skipped
+// for an empty label and rejected for a named one.
+func (w *Walker) loopDepth(label string, continuable bool) (int, error) {
+	for k := 0; k < w.Environment.LoopNameStack.Size(); k++ {
+		name, _ := w.Environment.LoopNameStack.PeekAtK(k)
+		c, _ := w.Environment.ContinueLabelStack.PeekAtK(k)
+		if label == "" && (!continuable || c != nil) {
+			return k, nil
+		}
+		if label != "" && name == label {
+			if continuable && c == nil {
+				return -1, i18n.Errorf("label %s is not a loop", label)
+			}
+			return k, nil
+		}
+	}
+	if label != "" {
+		return -1, i18n.Errorf("undefined loop label %s", label)
+	}
+	return -1, i18n.Errorf("not in a loop")
+}
+
+// AddBreakLabel adds a break label to the loop or switch named label ("" for the innermost one).
+func (w *Walker) AddBreakLabel(label string) (int, error) {
+	k, err := w.loopDepth(label, false)
+	if err != nil {
+		return -1, i18n.Errorf("break: %w", err)
 	}
 	w.ThreeAddress = append(w.ThreeAddress, fmt.Sprintf("L%-8d %8s", len(w.ThreeAddress), "nop"))
-	t, _ := w.Environment.BreakLabelStack.Pop()
+	t, _ := w.Environment.BreakLabelStack.PeekAtK(k)
 	*t = append(*t, len(w.ThreeAddress)-1)
-	w.Environment.BreakLabelStack.Push(t)
-	return len(w.ThreeAddress) - 1
+	return len(w.ThreeAddress) - 1, nil
+}
+
+// AddContinueLabel adds a continue label to the loop named label ("" for the current loop).
+func (w *Walker) AddContinueLabel(label string) (int, error) {
+	k, err := w.loopDepth(label, true)
+	if err != nil {
+		return -1, i18n.Errorf("continue: %w", err)
+	}
+	w.ThreeAddress = append(w.ThreeAddress, fmt.Sprintf("L%-8d %8s", len(w.ThreeAddress), "nop"))
+	t, _ := w.Environment.ContinueLabelStack.PeekAtK(k)
+	*t = append(*t, len(w.ThreeAddress)-1)
+	return len(w.ThreeAddress) - 1, nil
 }
 
-// ExitLoop emits a jump instruction to exit the current loop.
-// It pops the break label stack and backfills the labels with the exit line.
-func (w *Walker) ExitLoop(exit int) {
+// ExitLoop emits the jump instructions to leave the current loop.
+// It pops the break and continue label stacks, backfilling the break labels with
+// the exit line and the continue labels with the next line (where the next iteration starts).
+func (w *Walker) ExitLoop(exit int, next int) {
 	if w.Environment.BreakLabelStack.IsEmpty() {
 		println("ExitLoop: BreakLabelStack is empty")
 		return
@@ -236,4 +413,16 @@ func (w *Walker) ExitLoop(exit int) {
 	for _, label := range *l {
 		w.EmitLabel(label, fmt.Sprintf("L%d", exit), "jmp")
 	}
+	c, _ := w.Environment.ContinueLabelStack.Pop()
+	if c != nil {
+		for _, label := range *c {
+			w.EmitLabel(label, fmt.Sprintf("L%d", next), "jmp")
+		}
+	}
+	w.Environment.LoopNameStack.Pop()
+}
+
+// ExitSwitch leaves the current switch statement, backfilling its break labels with the exit line.
+func (w *Walker) ExitSwitch(exit int) {
+	w.ExitLoop(exit, exit)
 }
@@ -3,8 +3,10 @@ package parser_test
 import (
 	"fmt"
 	"slices"
+	"strings"
 	"testing"
 
+	"app/lexer"
 	. "app/parser"
 	. "app/utils/collections"
 	"app/utils/log"
@@ -75,39 +77,21 @@ func TestWalker_Next2(t *testing.T) {
 	//	fmt.Printf("Action[%d][%s] = %v\n", action.Index, action.Symbol, action.Action)
 	//}
 
-	walker := p.NewWalker()
-
-	seqs := [][]Symbol{
-		{"{", "basic", "id", ";", "}", TERMINATE},
-		{"{", "basic", "id", ";", "basic", "id", ";", "}", TERMINATE},
-		{"{", "basic", "id", ";", "id", "=", "num", ";", "}", TERMINATE},
-		{"{", "basic", "id", ";", "id", "=", "(", "num", ">", "num", ")", ";", "}", TERMINATE},
-		{"{", "basic", "id", ";", "{", "basic", "id", ";", "}", "}", TERMINATE},
-		{"{", "if", "(", "bool", ")", "{", "basic", "id", ";", "}", "else", "{", "basic", "id", ";", "}", "}", TERMINATE},
-		{
-			"{", "if", "(", "bool", ")", "{", "basic", "id", ";", "}", "else", "{", "basic", "id", ";",
-			"if", "(", "bool", ")", "{", "basic", "id", ";", "}", "else", "{", "basic", "id", ";", "}", "}", "}", TERMINATE,
-		},
+	// the rules read the tokens of the symbols, so the walker is driven by Parse, which logs its steps
+	srcs := []string{
+		"{ int a; }",
+		"{ int a; int b; }",
+		"{ int a; a = 1; }",
+		"{ int a; a = (1 > 2); }",
+		"{ int a; { int b; } }",
+		"{ int a; if (a > 1) { int b; } else { int c; } }",
+		"{ int a; if (a > 1) { int b; } else { int c; if (a > 2) { int d; } else { int e; } } }",
 	}
 	fmt.Println("=======================")
-	for _, seq := range seqs {
-		for i := 0; i < len(seq); i++ {
-			fmt.Print(log.Sprintf(log.Argument{FrontColor: log.Blue, Highlight: true, Format: "State: %v", Args: []any{walker.States}}))
-			fmt.Print(log.Sprintf(log.Argument{FrontColor: log.Yellow, Highlight: true, Format: ", Symbols: %v", Args: []any{walker.Symbols}}))
-			fmt.Print(log.Sprintf(log.Argument{FrontColor: log.Magenta, Highlight: true, Format: ", Symbol: %s", Args: []any{seq[i]}}))
-			action, err := walker.Next(seq[i])
-			fmt.Print(log.Sprintf(log.Argument{FrontColor: log.Green, Highlight: true, Format: ", Action: %v", Args: []any{action}}))
-			if err != nil {
-				fmt.Print(log.Sprintf(log.Argument{FrontColor: log.Red, Highlight: true, Format: ", Error: %v\n", Args: []any{err}}))
-				break
-			}
-			fmt.Println()
-			if action.Type == REDUCE {
-				i--
-			}
+	for _, src := range srcs {
+		if _, err := p.Parse(lexer.NewLexer(strings.NewReader(src)), func(s string) { fmt.Print(s) }); err != nil {
+			t.Errorf("Parse of %s failed: %v", src, err)
 		}
-
-		walker.Reset()
 		fmt.Println("=======================")
 	}
 }
@@ -0,0 +1,119 @@
+// Package dot writes automata as Graphviz DOT graphs, such as the diagrams of the docs.
+package dot
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+)
+
+// Path is the run of an automaton on an input: the states it went through, from the start state,
+// and the moves it made between them.
+type Path struct {
+	States []int
+	Moves  [][2]int
+}
+
+// Move records the move from a state to another one.
+func (p *Path) Move(from, to int) {
+	p.States = append(p.States, to)
+	p.Moves = append(p.Moves, [2]int{from, to})
+}
+
+// Options selects what a graph shows.
+type Options struct {
+	// States are the states drawn with the moves between them, or all the states if nil.
+	States []int
+	// Path is highlighted in the graph.
+	Path *Path
+}
+
+// Shows reports whether the state is drawn.
+func (o Options) Shows(state int) bool {
+	return o.States == nil || slices.Contains(o.States, state)
+}
+
+// OnPath reports whether the state is on the highlighted path.
+func (o Options) OnPath(state int) bool {
+	return o.Path != nil && slices.Contains(o.Path.States, state)
+}
+
+// MoveOnPath reports whether the move is on the highlighted path.
+func (o Options) MoveOnPath(from, to int) bool {
+	return o.Path != nil && slices.Contains(o.Path.Moves, [2]int{from, to})
+}
+
+// Graph is a directed graph written in DOT.
+type Graph struct {
+	w   io.Writer
+	err error
+}
+
+// NewGraph starts a graph with the attributes of its nodes, e.g. shape=circle.
+func NewGraph(w io.Writer, name string, nodeAttrs string) *Graph {
+	g := &Graph{w: w}
+	g.printf("digraph %s {\n\trankdir=LR;\n\tnode [%s];\n", Quote(name), nodeAttrs)
+	return g
+}
+
+// Node writes a node with a label and extra attributes, which are highlighted if asked to.
+func (g *Graph) Node(id int, label string, attrs string, highlighted bool) {
+	if highlighted {
+		attrs = join(attrs, "color=red, penwidth=2")
+	}
+	g.printf("\t%d [%s];\n", id, join("label="+Quote(label), attrs))
+}
+
+// Edge writes an edge with a label, which is highlighted if asked to.
+func (g *Graph) Edge(from, to int, label string, highlighted bool) {
+	attrs := "label=" + Quote(label)
+	if highlighted {
+		attrs = join(attrs, "color=red, fontcolor=red, penwidth=2")
+	}
+	g.printf("\t%d -> %d [%s];\n", from, to, attrs)
+}
+
+// Start writes the arrow pointing to the start state.
+func (g *Graph) Start(state int) {
+	g.printf("\tstart [shape=point];\n\tstart -> %d;\n", state)
+}
+
+// Close ends the graph and returns the first error of the writer.
+func (g *Graph) Close() error {
+	g.printf("}\n")
+	return g.err
+}
+
+func (g *Graph) printf(format string, args ...any) {
+	if g.err == nil {
+		_, g.err = fmt.Fprintf(g.w, format, args...)
+	}
+}
+
+func join(a, b string) string {
+	if a == "" || b == "" {
+		return a + b
+	}
+	return a + ", " + b
+}
+
+// Quote returns s as a DOT string, in which a newline ends a left-justified line.
+func Quote(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			b.WriteString(`\"`)
+		case '\\':
+			b.WriteString(`\\`)
+		case '\n':
+			b.WriteString(`\l`)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
@@ -0,0 +1,109 @@
+// Package i18n translates the messages shown to the users: the names of the token kinds, the lexical,
+// syntax and semantic errors, and the banners of the console.
+//
+// A message is written in English in the code, and is its own key in the catalogs of the other locales,
+// registered by the package using it. The fallback rules are:
+//   - the locale is the one set by SetLocale, Chinese by default, as chosen by the -lang flag or else by the
+//     environment, see FromEnvironment;
+//   - a language tag is matched by its language only, so zh_CN.UTF-8 and zh-TW are Chinese;
+//   - a message missing from the catalog of the locale is shown in English.
+package i18n
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Locale is a language the messages are shown in.
+type Locale string
+
+const (
+	ZH Locale = "zh"
+	EN Locale = "en"
+)
+
+// Locales are the supported locales.
+var Locales = []Locale{ZH, EN}
+
+var current atomic.Value
+
+var catalogs = struct {
+	sync.RWMutex
+	messages map[Locale]map[string]string
+}{messages: map[Locale]map[string]string{}}
+
+// Current returns the locale the messages are shown in.
+func Current() Locale {
+	if locale, ok := current.Load().(Locale); ok {
+		return locale
+	}
+	return ZH
+}
+
+// SetLocale sets the locale the messages are shown in, and returns the previous one.
+func SetLocale(locale Locale) Locale {
+	previous := Current()
+	current.Store(locale)
+	return previous
+}
+
+// Parse returns the locale of a language tag such as zh, en-US or zh_CN.UTF-8, if it is supported.
+func Parse(tag string) (Locale, bool) {
+	language, _, _ := strings.Cut(strings.ToLower(tag), ".")
+	language, _, _ = strings.Cut(language, "_")
+	language, _, _ = strings.Cut(language, "-")
+	for _, locale := range Locales {
+		if Locale(language) == locale {
+			return locale, true
+		}
+	}
+	return "", false
+}
+
+// FromEnvironment returns the locale of the first of LC_ALL, LC_MESSAGES and LANG which is set,
+// as the C library does, if it is supported.
+func FromEnvironment() (Locale, bool) {
+	for _, name := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		if tag := os.Getenv(name); tag != "" {
+			return Parse(tag)
+		}
+	}
+	return "", false
+}
+
+// Register adds the translations of messages, keyed by their English text, to the catalog of the locale.
+func Register(locale Locale, messages map[string]string) {
+	catalogs.Lock()
+	defer catalogs.Unlock()
+	catalog := catalogs.messages[locale]
+	if catalog == nil {
+		catalog = map[string]string{}
+		catalogs.messages[locale] = catalog
+	}
+	for message, translation := range messages {
+		catalog[message] = translation
+	}
+}
+
+// T returns the message in the current locale.
+func T(message string) string {
+	catalogs.RLock()
+	defer catalogs.RUnlock()
+	if translation, ok := catalogs.messages[Current()][message]; ok {
+		return translation
+	}
+	return message
+}
+
+// Sprintf formats the message in the current locale.
+func Sprintf(format string, args ...any) string {
+	return fmt.Sprintf(T(format), args...)
+}
+
+// Errorf returns the error of the message in the current locale, wrapping the errors of its %w verbs.
+func Errorf(format string, args ...any) error {
+	return fmt.Errorf(T(format), args...)
+}